@@ -9,4 +9,6 @@ volatile const u8 enable_rtt = 0;
 volatile const u8 enable_pca = 0;
 volatile const u8 enable_dns_tracking = 0;
 volatile const u8 enable_flows_filtering = 0;
+volatile const u8 enable_pkt_drop_stacks = 0;
+volatile const u8 enable_qdisc_tracking = 0;
 #endif //__CONFIGS_H__
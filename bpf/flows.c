@@ -24,6 +24,11 @@
 */
 #include "pkt_drops.h"
 
+/* Defines a qdisc tracker, measuring the time spent in the qdiscs and counting their drops,
+   which attaches at qdisc_enqueue and net_dev_start_xmit hooks. Is optional.
+*/
+#include "qdisc_tracker.h"
+
 /* Defines a dns tracker,
    which attaches at net_dev_queue hook. Is optional.
 */
@@ -68,16 +73,23 @@ static inline int flow_monitor(struct __sk_buff *skb, u8 direction) {
 
     //Set extra fields
     id.if_index = skb->ifindex;
+    // the TC context points to the kernel socket buffer, which holds the device namespace
+    id.netns = skb_netns((struct sk_buff *)skb);
     id.direction = direction;
 
+    // the interface profile might disable the optional features on this interface
+    u8 profile_flags = iface_profile_flags(&id);
+
     // check if this packet need to be filtered if filtering feature is enabled
-    bool skip = check_and_do_flow_filtering(&id);
-    if (skip) {
-        return TC_ACT_OK;
+    if (!(profile_flags & IFACE_PROFILE_NO_FILTER)) {
+        bool skip = check_and_do_flow_filtering(&id);
+        if (skip) {
+            return TC_ACT_OK;
+        }
     }
 
     int dns_errno = 0;
-    if (enable_dns_tracking) {
+    if (enable_dns_tracking && !(profile_flags & IFACE_PROFILE_NO_DNS)) {
         dns_errno = track_dns_packet(skb, &pkt);
     }
     // TODO: we need to add spinlock here when we deprecate versions prior to 5.1, or provide
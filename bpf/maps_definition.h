@@ -53,4 +53,31 @@ struct {
     __uint(map_flags, BPF_F_NO_PREALLOC);
 } filter_map SEC(".maps");
 
+// Key: the interface index and network namespace. Value: the IFACE_PROFILE_* flags disabling
+// optional features on the interface. Interfaces without entry use all the enabled features.
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, struct iface_profile_key_t);
+    __type(value, u8);
+    __uint(max_entries, MAX_IFACE_PROFILES);
+} iface_profiles SEC(".maps");
+
+// Key: the id of a kernel stack. Value: the addresses of the stack frames. It holds the stacks of
+// the packet drops, when enabled.
+struct {
+    __uint(type, BPF_MAP_TYPE_STACK_TRACE);
+    __uint(key_size, sizeof(u32));
+    __uint(value_size, MAX_DROP_STACK_DEPTH * sizeof(u64));
+    __uint(max_entries, MAX_DROP_STACKS);
+} drop_stacks SEC(".maps");
+
+// Key: the address of a socket buffer. Value: when and where the packet was queued. It holds the
+// packets waiting in the qdiscs, when qdisc tracking is enabled.
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, u64);
+    __type(value, struct qdisc_skb_t);
+    __uint(max_entries, MAX_QDISC_SKBS);
+} qdisc_skbs SEC(".maps");
+
 #endif //__MAPS_DEFINITION_H__
@@ -28,6 +28,7 @@ static int attach_packet_payload(void *data, void *data_end, struct __sk_buff *s
     flags |= (u64)packetSize << 32;
 
     meta.if_index = skb->ifindex;
+    meta.netns = skb_netns((struct sk_buff *)skb);
     meta.pkt_len = packetSize;
     meta.timestamp = current_time;
     if (bpf_perf_event_output(skb, &packet_record, flags, &meta, sizeof(meta))) {
@@ -5,45 +5,24 @@
 #ifndef __PKT_DROPS_H__
 #define __PKT_DROPS_H__
 
+#include <bpf_tracing.h>
 #include "utils.h"
+#include "qdisc_tracker.h"
 
 static inline int trace_pkt_drop(void *ctx, u8 state, struct sk_buff *skb,
-                                 enum skb_drop_reason reason) {
+                                 enum skb_drop_reason reason, u64 location) {
     flow_id id;
     __builtin_memset(&id, 0, sizeof(id));
 
-    u8 protocol = 0;
-    u16 family = 0, flags = 0;
+    u16 flags = 0;
 
     id.if_index = skb->skb_iif;
     // filter out TCP sockets with unknown or loopback interface
     if (id.if_index == 0 || id.if_index == 1) {
         return 0;
     }
-    // read L2 info
-    set_key_with_l2_info(skb, &id, &family);
-
-    // read L3 info
-    set_key_with_l3_info(skb, family, &id, &protocol);
-
-    // read L4 info
-    switch (protocol) {
-    case IPPROTO_TCP:
-        set_key_with_tcp_info(skb, &id, protocol, &flags);
-        break;
-    case IPPROTO_UDP:
-        set_key_with_udp_info(skb, &id, protocol);
-        break;
-    case IPPROTO_SCTP:
-        set_key_with_sctp_info(skb, &id, protocol);
-        break;
-    case IPPROTO_ICMP:
-        set_key_with_icmpv4_info(skb, &id, protocol);
-        break;
-    case IPPROTO_ICMPV6:
-        set_key_with_icmpv6_info(skb, &id, protocol);
-        break;
-    default:
+    id.netns = skb_netns(skb);
+    if (set_key_with_skb_info(skb, &id, &flags) != 0) {
         return 0;
     }
 
@@ -53,10 +32,18 @@ static inline int trace_pkt_drop(void *ctx, u8 state, struct sk_buff *skb,
         return 0;
     }
 
+    u32 stack_id = 0;
+    if (enable_pkt_drop_stacks) {
+        long sid = bpf_get_stackid(ctx, &drop_stacks, BPF_F_REUSE_STACKID);
+        if (sid >= 0) {
+            stack_id = sid + 1;
+        }
+    }
+
     long ret = 0;
     for (direction dir = INGRESS; dir < MAX_DIRECTION; dir++) {
         id.direction = dir;
-        ret = pkt_drop_lookup_and_update_flow(skb, &id, state, flags, reason);
+        ret = pkt_drop_lookup_and_update_flow(skb, &id, state, flags, reason, location, stack_id);
         if (ret == 0) {
             return 0;
         }
@@ -73,6 +60,8 @@ static inline int trace_pkt_drop(void *ctx, u8 state, struct sk_buff *skb,
         .pkt_drops.latest_state = state,
         .pkt_drops.latest_flags = flags,
         .pkt_drops.latest_drop_cause = reason,
+        .pkt_drops.latest_drop_location = location,
+        .pkt_drops.latest_drop_stack_id = stack_id,
     };
     ret = bpf_map_update_elem(&aggregated_flows, &id, &new_flow, BPF_ANY);
     if (trace_messages && ret != 0) {
@@ -82,32 +71,66 @@ static inline int trace_pkt_drop(void *ctx, u8 state, struct sk_buff *skb,
     return ret;
 }
 
-SEC("tracepoint/skb/kfree_skb")
-int kfree_skb(struct trace_event_raw_kfree_skb *args) {
+static inline int trace_skb_drop(void *ctx, void *skbaddr, enum skb_drop_reason reason,
+                                 u64 location) {
     struct sk_buff skb;
 
-    if (do_sampling == 0) {
-        return 0;
-    }
-
     __builtin_memset(&skb, 0, sizeof(skb));
 
-    bpf_probe_read(&skb, sizeof(struct sk_buff), args->skbaddr);
+    bpf_probe_read(&skb, sizeof(struct sk_buff), skbaddr);
     struct sock *sk = skb.sk;
+    u8 state = 0;
+    if (sk) {
+        // pull in details from the packet headers and the sock struct
+        bpf_probe_read(&state, sizeof(u8), (u8 *)&sk->__sk_common.skc_state);
+    }
+    if (enable_qdisc_tracking) {
+        track_qdisc_drop(&skb, (u64)skbaddr, reason);
+    }
+    return trace_pkt_drop(ctx, state, &skb, reason, location);
+}
+
+SEC("tracepoint/skb/kfree_skb")
+int kfree_skb(struct trace_event_raw_kfree_skb *args) {
+    if (do_sampling == 0) {
+        return 0;
+    }
     enum skb_drop_reason reason = args->reason;
 
     // SKB_NOT_DROPPED_YET,
     // SKB_CONSUMED,
     // SKB_DROP_REASON_NOT_SPECIFIED,
     if (reason > SKB_DROP_REASON_NOT_SPECIFIED) {
-        u8 state = 0;
-        if (sk) {
-            // pull in details from the packet headers and the sock struct
-            bpf_probe_read(&state, sizeof(u8), (u8 *)&sk->__sk_common.skc_state);
-        }
-        return trace_pkt_drop(args, state, &skb, reason);
+        return trace_skb_drop(args, args->skbaddr, reason, (u64)args->location);
     }
     return 0;
 }
 
+/*
+ * returns the address the probed kernel function returns to, that is, its caller
+ */
+static inline u64 kprobe_caller(struct pt_regs *ctx) {
+#if defined(bpf_target_x86)
+    // the return address is on the top of the stack when the function is entered
+    u64 ret = 0;
+    bpf_probe_read(&ret, sizeof(ret), (void *)PT_REGS_SP(ctx));
+    return ret;
+#elif defined(bpf_target_powerpc)
+    return ctx->link;
+#else
+    return PT_REGS_RET(ctx);
+#endif
+}
+
+// Fallback for the kernels older than 5.14, whose kfree_skb tracepoint doesn't provide the
+// drop reason. kfree_skb isn't called for the packets that are consumed normally, so all the
+// calls are drops, reported as not specified.
+SEC("kprobe/kfree_skb")
+int BPF_KPROBE(kfree_skb_kprobe, struct sk_buff *skb) {
+    if (skb == NULL || do_sampling == 0) {
+        return 0;
+    }
+    return trace_skb_drop(ctx, skb, SKB_DROP_REASON_NOT_SPECIFIED, kprobe_caller(ctx));
+}
+
 #endif //__PKT_DROPS_H__
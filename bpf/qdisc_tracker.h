@@ -0,0 +1,142 @@
+/*
+    Qdisc tracker: time spent by the egress packets in the traffic control queueing disciplines,
+    and packets dropped by them.
+*/
+
+#ifndef __QDISC_TRACKER_H__
+#define __QDISC_TRACKER_H__
+
+#include <bpf_core_read.h>
+#include "utils.h"
+
+/*
+ * returns the flow of a packet leaving an interface through its qdisc, or NULL if it isn't
+ * tracked. The egress flows are created by the TC egress hook, which runs before the qdisc.
+ */
+static inline flow_metrics *qdisc_egress_flow(struct sk_buff *skb, u32 if_index, flow_id *id) {
+    u16 flags = 0;
+
+    id->if_index = if_index;
+    id->netns = skb_netns(skb);
+    id->direction = EGRESS;
+    if (set_key_with_skb_info(skb, id, &flags) != 0) {
+        return NULL;
+    }
+    return bpf_map_lookup_elem(&aggregated_flows, id);
+}
+
+// copies the kind of a qdisc, that is the id of its operations (e.g. fq_codel), into a flow
+static inline void set_qdisc_kind(flow_metrics *flow, u64 qdisc_addr) {
+    struct Qdisc *qdisc = (struct Qdisc *)qdisc_addr;
+    const struct Qdisc_ops *ops = BPF_CORE_READ(qdisc, ops);
+    if (ops) {
+        bpf_probe_read(flow->qdisc.latest_kind, QDISC_KIND_LEN, (void *)ops->id);
+    }
+}
+
+/*
+ * counts a packet drop in the qdisc that queued the packet or, for the drops on enqueue, in the
+ * root qdisc of the device
+ */
+static inline void track_qdisc_drop(struct sk_buff *skb, u64 skbaddr,
+                                    enum skb_drop_reason reason) {
+    u64 qdisc_addr = 0;
+    u32 if_index = BPF_CORE_READ(skb, dev, ifindex);
+    struct qdisc_skb_t *queued = bpf_map_lookup_elem(&qdisc_skbs, &skbaddr);
+    if (queued != NULL) {
+        // the entry might have been left by a former packet at the same address on another device
+        if (queued->if_index == if_index) {
+            qdisc_addr = queued->qdisc;
+        }
+        bpf_map_delete_elem(&qdisc_skbs, &skbaddr);
+    }
+    if (qdisc_addr == 0 &&
+        bpf_core_enum_value_exists(enum skb_drop_reason, SKB_DROP_REASON_QDISC_DROP) &&
+        reason == bpf_core_enum_value(enum skb_drop_reason, SKB_DROP_REASON_QDISC_DROP)) {
+        qdisc_addr = (u64)BPF_CORE_READ(skb, dev, qdisc);
+    }
+    if (qdisc_addr == 0) {
+        return;
+    }
+
+    flow_id id;
+    __builtin_memset(&id, 0, sizeof(id));
+    flow_metrics *flow = qdisc_egress_flow(skb, if_index, &id);
+    if (flow == NULL) {
+        return;
+    }
+    flow->qdisc.drop_packets += 1;
+    flow->qdisc.drop_bytes += skb->len;
+    set_qdisc_kind(flow, qdisc_addr);
+    long ret = bpf_map_update_elem(&aggregated_flows, &id, flow, BPF_EXIST);
+    if (trace_messages && ret != 0) {
+        bpf_printk("error qdisc drop updating flow %d\n", ret);
+    }
+}
+
+SEC("tracepoint/qdisc/qdisc_enqueue")
+int qdisc_enqueue(struct qdisc_enqueue_args_t *args) {
+    if (!enable_qdisc_tracking || args->skbaddr == NULL) {
+        return 0;
+    }
+    u64 skbaddr = (u64)args->skbaddr;
+    struct qdisc_skb_t queued = {
+        .enqueue_ts = bpf_ktime_get_ns(),
+        .qdisc = (u64)args->qdisc,
+        .if_index = args->ifindex,
+    };
+    long ret = bpf_map_update_elem(&qdisc_skbs, &skbaddr, &queued, BPF_ANY);
+    if (trace_messages && ret != 0) {
+        bpf_printk("error storing qdisc packet %d\n", ret);
+    }
+    return 0;
+}
+
+/*
+ * measures the queueing time when the packet is handed to the device driver. Unlike the
+ * qdisc_dequeue tracepoint, which only reports the first packet of a dequeued batch, this
+ * tracepoint is hit by every transmitted packet. The packets bypassing the qdisc, which have no
+ * entry, are ignored.
+ */
+SEC("tracepoint/net/net_dev_start_xmit")
+int qdisc_xmit(struct trace_event_raw_net_dev_start_xmit *args) {
+    if (!enable_qdisc_tracking || args->skbaddr == NULL) {
+        return 0;
+    }
+    u64 skbaddr = (u64)args->skbaddr;
+    struct qdisc_skb_t *queued = bpf_map_lookup_elem(&qdisc_skbs, &skbaddr);
+    if (queued == NULL) {
+        return 0;
+    }
+    u64 queue_time = bpf_ktime_get_ns() - queued->enqueue_ts;
+    u64 qdisc_addr = queued->qdisc;
+    u32 queued_if_index = queued->if_index;
+    bpf_map_delete_elem(&qdisc_skbs, &skbaddr);
+
+    struct sk_buff skb;
+    __builtin_memset(&skb, 0, sizeof(skb));
+    bpf_probe_read(&skb, sizeof(struct sk_buff), args->skbaddr);
+
+    // the entry might have been left by a former packet at the same address on another device
+    u32 if_index = BPF_CORE_READ(&skb, dev, ifindex);
+    if (if_index != queued_if_index) {
+        return 0;
+    }
+
+    flow_id id;
+    __builtin_memset(&id, 0, sizeof(id));
+    flow_metrics *flow = qdisc_egress_flow(&skb, if_index, &id);
+    if (flow == NULL) {
+        return 0;
+    }
+    flow->qdisc.time += queue_time;
+    flow->qdisc.packets += 1;
+    set_qdisc_kind(flow, qdisc_addr);
+    long ret = bpf_map_update_elem(&aggregated_flows, &id, flow, BPF_EXIST);
+    if (trace_messages && ret != 0) {
+        bpf_printk("error qdisc updating flow %d\n", ret);
+    }
+    return 0;
+}
+
+#endif /* __QDISC_TRACKER_H__ */
@@ -106,6 +106,7 @@ static inline int calculate_flow_rtt_tcp(struct sock *sk, struct sk_buff *skb) {
     if (id.if_index == 0 || id.if_index == 1) {
         return 0;
     }
+    id.netns = BPF_CORE_READ(sk, __sk_common.skc_net.net, ns.inum);
     len = BPF_CORE_READ(skb, len);
 
     // read L2 info
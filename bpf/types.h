@@ -56,6 +56,15 @@ typedef __u64 u64;
 #define MIN_RTT 10000u //10us
 
 #define MAX_FILTER_ENTRIES 1 // we have only one global filter
+#define MAX_IFACE_PROFILES 4096
+#define MAX_DROP_STACKS 1024
+#define MAX_DROP_STACK_DEPTH 20
+#define MAX_QDISC_SKBS (1 << 16)
+#define QDISC_KIND_LEN 16 // the size of the id of the kernel qdisc operations
+
+// Flags of the per-interface profiles, disabling the optional features on an interface
+#define IFACE_PROFILE_NO_DNS 0x01
+#define IFACE_PROFILE_NO_FILTER 0x02
 
 // according to field 61 in https://www.iana.org/assignments/ipfix/ipfix.xhtml
 typedef enum direction_t {
@@ -89,6 +98,10 @@ typedef struct flow_metrics_t {
         u16 latest_flags;
         u8 latest_state;
         u32 latest_drop_cause;
+        // address of the kernel function that dropped the latest packet
+        u64 latest_drop_location;
+        // 1 + the id of the kernel stack of the latest drop in the drop_stacks map, 0 if none
+        u32 latest_drop_stack_id;
     } __attribute__((packed)) pkt_drops;
     struct dns_record_t {
         u16 id;
@@ -97,6 +110,16 @@ typedef struct flow_metrics_t {
         u8 errno;
     } __attribute__((packed)) dns_record;
     u64 flow_rtt;
+    // traffic control queueing of the egress packets, when qdisc tracking is enabled
+    struct qdisc_t {
+        // total time that the dequeued packets spent in the qdisc, in nanoseconds
+        u64 time;
+        u32 packets;
+        u32 drop_packets;
+        u64 drop_bytes;
+        // kind of the root qdisc of the latest queued or dropped packet (e.g. fq_codel, htb)
+        u8 latest_kind[QDISC_KIND_LEN];
+    } __attribute__((packed)) qdisc;
 } __attribute__((packed)) flow_metrics;
 
 // Force emitting struct pkt_drops into the ELF.
@@ -126,6 +149,8 @@ typedef struct flow_id_t {
     u8 icmp_code;
     // OS interface index
     u32 if_index;
+    // Inode number of the interface network namespace, as in /proc/<pid>/ns/net
+    u32 netns;
 } __attribute__((packed)) flow_id;
 
 // Force emitting struct flow_id into the ELF.
@@ -145,6 +170,9 @@ const struct flow_record_t *unused3 __attribute__((unused));
 // Force emitting struct dns_record into the ELF.
 const struct dns_record_t *unused4 __attribute__((unused));
 
+// Force emitting struct qdisc_t into the ELF.
+const struct qdisc_t *unused11 __attribute__((unused));
+
 // Internal structure: Packet info structure parsed around functions.
 typedef struct pkt_info_t {
     flow_id *id;
@@ -162,6 +190,7 @@ typedef struct payload_meta_t {
     u32 if_index;
     u32 pkt_len;
     u64 timestamp; // timestamp when packet received by ebpf
+    u32 netns;     // inode of the network namespace of the interface
 } __attribute__((packed)) payload_meta;
 
 // DNS Flow record used as key to correlate DNS query and response
@@ -221,4 +250,35 @@ struct filter_value_t {
 // Force emitting struct filter_value_t into the ELF.
 const struct filter_value_t *unused9 __attribute__((unused));
 
+// identifies an interface by its index and the inode of its network namespace, as used as key
+// of the per-interface profiles map
+struct iface_profile_key_t {
+    u32 if_index;
+    u32 netns;
+} __attribute__((packed));
+// Force emitting struct iface_profile_key_t into the ELF.
+const struct iface_profile_key_t *unused10 __attribute__((unused));
+
+// a packet waiting in a qdisc, as stored in the qdisc_skbs map
+struct qdisc_skb_t {
+    u64 enqueue_ts;
+    // address of the root qdisc that queued the packet
+    u64 qdisc;
+    // device of the qdisc, to discard the entries left by former packets at the same address
+    u32 if_index;
+};
+
+// Context of the qdisc_enqueue tracepoint, as in /sys/kernel/tracing/events/qdisc/*/format. It
+// is not taken from vmlinux.h so that the programs can be loaded (although not attached) by the
+// kernels that don't have this tracepoint, older than 5.16.
+struct qdisc_enqueue_args_t {
+    u64 common;
+    void *qdisc;
+    void *txq;
+    void *skbaddr;
+    int ifindex;
+    u32 handle;
+    u32 parent;
+};
+
 #endif /* __TYPES_H__ */
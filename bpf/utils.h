@@ -1,12 +1,20 @@
 #ifndef __UTILS_H__
 #define __UTILS_H__
 
+#include <bpf_core_read.h>
 #include "types.h"
 #include "maps_definition.h"
 #include "flows_filter.h"
 
 static u8 do_sampling = 0;
 
+// Returns the inode number of the network namespace of the device of a socket buffer, which
+// tells apart the interfaces with the same index in different namespaces. Returns 0 if the
+// socket buffer has no device.
+static inline u32 skb_netns(struct sk_buff *skb) {
+    return BPF_CORE_READ(skb, dev, nd_net.net, ns.inum);
+}
+
 // sets the TCP header flags for connection information
 static inline void set_flags(struct tcphdr *th, u16 *flags) {
     //If both ACK and SYN are set, then it is server -> client communication during 3-way handshake.
@@ -260,8 +268,44 @@ static inline int set_key_with_icmpv6_info(struct sk_buff *skb, flow_id *id, u8
     return 0;
 }
 
+// sets the L2, L3 and L4 attributes of a flow from the headers of a socket buffer. Returns -1 if
+// the transport protocol isn't supported.
+static inline int set_key_with_skb_info(struct sk_buff *skb, flow_id *id, u16 *flags) {
+    u8 protocol = 0;
+    u16 family = 0;
+
+    // read L2 info
+    set_key_with_l2_info(skb, id, &family);
+
+    // read L3 info
+    set_key_with_l3_info(skb, family, id, &protocol);
+
+    // read L4 info
+    switch (protocol) {
+    case IPPROTO_TCP:
+        set_key_with_tcp_info(skb, id, protocol, flags);
+        break;
+    case IPPROTO_UDP:
+        set_key_with_udp_info(skb, id, protocol);
+        break;
+    case IPPROTO_SCTP:
+        set_key_with_sctp_info(skb, id, protocol);
+        break;
+    case IPPROTO_ICMP:
+        set_key_with_icmpv4_info(skb, id, protocol);
+        break;
+    case IPPROTO_ICMPV6:
+        set_key_with_icmpv6_info(skb, id, protocol);
+        break;
+    default:
+        return -1;
+    }
+    return 0;
+}
+
 static inline long pkt_drop_lookup_and_update_flow(struct sk_buff *skb, flow_id *id, u8 state,
-                                                   u16 flags, enum skb_drop_reason reason) {
+                                                   u16 flags, enum skb_drop_reason reason,
+                                                   u64 location, u32 stack_id) {
     flow_metrics *aggregate_flow = bpf_map_lookup_elem(&aggregated_flows, id);
     if (aggregate_flow != NULL) {
         aggregate_flow->end_mono_time_ts = bpf_ktime_get_ns();
@@ -270,6 +314,8 @@ static inline long pkt_drop_lookup_and_update_flow(struct sk_buff *skb, flow_id
         aggregate_flow->pkt_drops.latest_state = state;
         aggregate_flow->pkt_drops.latest_flags = flags;
         aggregate_flow->pkt_drops.latest_drop_cause = reason;
+        aggregate_flow->pkt_drops.latest_drop_location = location;
+        aggregate_flow->pkt_drops.latest_drop_stack_id = stack_id;
         long ret = bpf_map_update_elem(&aggregated_flows, id, aggregate_flow, BPF_EXIST);
         if (trace_messages && ret != 0) {
             bpf_printk("error packet drop updating flow %d\n", ret);
@@ -279,6 +325,18 @@ static inline long pkt_drop_lookup_and_update_flow(struct sk_buff *skb, flow_id
     return -1;
 }
 
+/*
+ * returns the IFACE_PROFILE_* flags of the interface of a flow, disabling some optional features
+ */
+static inline u8 iface_profile_flags(flow_id *id) {
+    if (!enable_dns_tracking && !enable_flows_filtering) {
+        return 0;
+    }
+    struct iface_profile_key_t key = {.if_index = id->if_index, .netns = id->netns};
+    u8 *flags = bpf_map_lookup_elem(&iface_profiles, &key);
+    return flags ? *flags : 0;
+}
+
 /*
  * check if flow filter is enabled and if we need to continue processing the packet or not
  */
@@ -24,12 +24,16 @@ import (
 
 	grpc "github.com/netobserv/netobserv-ebpf-agent/pkg/grpc/flow"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/pbflow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/utils"
 )
 
 const ipv6 = 0x86DD
 
 var (
-	port = flag.Int("listen_port", 9999, "TCP port to listen for flows")
+	port      = flag.Int("listen_port", 9999, "TCP port to listen for flows")
+	tlsCert   = flag.String("tls_cert", "", "Server certificate path. If set, TLS is enabled")
+	tlsKey    = flag.String("tls_key", "", "Server private key path")
+	tlsClient = flag.String("tls_client_ca", "", "CA certificate path to verify client certificates. If set, mutual TLS is enforced")
 )
 
 var protocolByNumber = map[uint32]string{
@@ -63,8 +67,16 @@ func main() {
 
 	receivedRecords := make(chan *pbflow.Records, 1000)
 	log.Println("starting flowlogs-dump-collector on port", *port)
+	var options []grpc.CollectorOption
+	if *tlsCert != "" {
+		reloader, err := utils.NewCertReloader(*tlsClient, *tlsCert, *tlsKey)
+		if err != nil {
+			panic(err)
+		}
+		options = append(options, grpc.WithServerTLSConfig(reloader.ServerConfig()))
+	}
 	go func() {
-		_, err := grpc.StartCollector(*port, receivedRecords)
+		_, err := grpc.StartCollector(*port, receivedRecords, options...)
 		if err != nil {
 			panic(err)
 		}
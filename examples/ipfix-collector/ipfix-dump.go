@@ -10,9 +10,10 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/exporter"
+
 	ipfixCollector "github.com/vmware/go-ipfix/pkg/collector"
 	"github.com/vmware/go-ipfix/pkg/entities"
-	"github.com/vmware/go-ipfix/pkg/registry"
 )
 
 const (
@@ -118,7 +119,10 @@ func main() {
 		Protocol:      *transportType,
 		MaxBufferSize: 1024,
 	}
-	registry.LoadRegistry()
+	// also load the NetObserv-specific information elements
+	if err := exporter.LoadIPFIXRegistry(); err != nil {
+		log.Fatalf("Can't load the IPFIX registry: %v", err)
+	}
 	cp, err := ipfixCollector.InitCollectingProcess(input)
 	if err != nil {
 		log.Fatalf("UDP Collecting Process does not start correctly: %v", err)
@@ -33,6 +33,9 @@ import (
 var (
 	PORT     = flag.Int("port", 9990, "gRPC collector port for packet stream")
 	FILENAME = flag.String("outfile", "", "Create and write to <Filename>.pcap")
+	TLSCERT  = flag.String("tls_cert", "", "Server certificate path. If set, TLS is enabled")
+	TLSKEY   = flag.String("tls_key", "", "Server private key path")
+	TLSCA    = flag.String("tls_client_ca", "", "CA certificate path to verify client certificates. If set, mutual TLS is enforced")
 )
 
 // Setting Snapshot length to 0 sets it to maximum packet size
@@ -53,7 +56,16 @@ func main() {
 	flag.Parse()
 
 	flowPackets := make(chan *pbpacket.Packet, 100)
-	collector, err := grpc.StartCollector(*PORT, flowPackets)
+	var options []grpc.CollectorOption
+	if *TLSCERT != "" {
+		reloader, err := utils.NewCertReloader(*TLSCA, *TLSCERT, *TLSKEY)
+		if err != nil {
+			fmt.Println("Loading TLS certificates failed:", err.Error())
+			os.Exit(1)
+		}
+		options = append(options, grpc.WithServerTLSConfig(reloader.ServerConfig()))
+	}
+	collector, err := grpc.StartCollector(*PORT, flowPackets, options...)
 	if err != nil {
 		fmt.Println("StartCollector failed:", err.Error())
 		os.Exit(1)
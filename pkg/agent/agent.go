@@ -2,20 +2,26 @@ package agent
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/netobserv/gopipes/pkg/node"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/decode"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/ebpf"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/exporter"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	flowgrpc "github.com/netobserv/netobserv-ebpf-agent/pkg/grpc/flow"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/ifaces"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/kernel"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
 	promo "github.com/netobserv/netobserv-ebpf-agent/pkg/prometheus"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/utils"
 
 	"github.com/cilium/ebpf/ringbuf"
 	"github.com/gavv/monotime"
@@ -101,20 +107,27 @@ type Flows struct {
 	cfg *Config
 
 	// input data providers
-	interfaces ifaces.Informer
+	interfaces *ifaces.Registerer
 	filter     InterfaceFilter
+	profiles   *interfaceProfiles
 	ebpf       ebpfFlowFetcher
 
 	// processing nodes to be wired in the buildAndStartPipeline method
-	mapTracer *flow.MapTracer
-	rbTracer  *flow.RingBufTracer
-	accounter *flow.Accounter
-	limiter   *flow.CapacityLimiter
-	deduper   node.MiddleFunc[[]*flow.Record, []*flow.Record]
-	exporter  node.TerminalFunc[[]*flow.Record]
+	mapTracer  *flow.MapTracer
+	rbTracer   *flow.RingBufTracer
+	accounter  *flow.Accounter
+	limiter    *flow.CapacityLimiter
+	deduper    node.MiddleFunc[[]*flow.Record, []*flow.Record]
+	biflows    *flow.BiflowStitcher
+	aggregator *flow.Aggregator
+	exporter   node.TerminalFunc[[]*flow.Record]
 
 	// elements used to decorate flows with extra information
 	interfaceNamer flow.InterfaceNamer
+	netnsNamer     flow.NetNSNamer
+	linker         flow.InterfaceLinker
+	dropCauseNamer flow.DropCauseNamer
+	dropLocator    flow.DropLocator
 	agentIP        net.IP
 
 	status      Status
@@ -124,10 +137,11 @@ type Flows struct {
 // ebpfFlowFetcher abstracts the interface of ebpf.FlowFetcher to allow dependency injection in tests
 type ebpfFlowFetcher interface {
 	io.Closer
-	Register(iface ifaces.Interface) error
-	AttachTCX(iface ifaces.Interface) error
+	Register(iface ifaces.Interface, profile *ebpf.InterfaceProfile) error
+	AttachTCX(iface ifaces.Interface, profile *ebpf.InterfaceProfile) error
 
 	LookupAndDeleteMap(*metrics.Metrics) map[ebpf.BpfFlowId][]ebpf.BpfFlowMetrics
+	LookupDropStack(stackID uint32) ([]uint64, error)
 	DeleteMapsStaleEntries(timeOut time.Duration)
 	ReadRingBuf() (ringbuf.Record, error)
 }
@@ -150,40 +164,26 @@ func FlowsAgent(cfg *Config) (*Flows, error) {
 	alog.Debug("agent IP: " + agentIP.String())
 
 	// initialize metrics
-	metricsSettings := &metrics.Settings{
-		PromConnectionInfo: metrics.PromConnectionInfo{
-			Address: cfg.MetricsServerAddress,
-			Port:    cfg.MetricsPort,
-		},
-		Prefix: cfg.MetricsPrefix,
-	}
-	if cfg.MetricsTLSCertPath != "" && cfg.MetricsTLSKeyPath != "" {
-		metricsSettings.PromConnectionInfo.TLS = &metrics.PromTLS{
-			CertPath: cfg.MetricsTLSCertPath,
-			KeyPath:  cfg.MetricsTLSKeyPath,
-		}
-	}
-	m := metrics.NewMetrics(metricsSettings)
+	m := metrics.NewMetrics(metricsSettings(cfg))
 
 	// configure selected exporter
-	exportFunc, err := buildFlowExporter(cfg, m)
+	exportFunc, err := buildFlowExporter(cfg, m, agentIP)
 	if err != nil {
 		return nil, err
 	}
 
-	ingress, egress := flowDirections(cfg)
 	debug := false
 	if cfg.LogLevel == logrus.TraceLevel.String() || cfg.LogLevel == logrus.DebugLevel.String() {
 		debug = true
 	}
 
 	ebpfConfig := &ebpf.FlowFetcherConfig{
-		EnableIngress:    ingress,
-		EnableEgress:     egress,
 		Debug:            debug,
 		Sampling:         cfg.Sampling,
 		CacheMaxSize:     cfg.CacheMaxFlows,
 		PktDrops:         cfg.EnablePktDrops,
+		PktDropStacks:    cfg.EnablePktDropStacks,
+		QdiscTracking:    cfg.EnableQdiscTracking,
 		DNSTracker:       cfg.EnableDNSTracking,
 		EnableRTT:        cfg.EnableRTT,
 		EnableFlowFilter: cfg.EnableFlowFilter,
@@ -199,6 +199,15 @@ func FlowsAgent(cfg *Config) (*Flows, error) {
 		},
 	}
 
+	if cfg.EnablePktDrops {
+		dropCauses, err := kernel.DropReasons()
+		if err != nil {
+			alog.WithError(err).Warn("can't read the drop reasons of the kernel: using the default drop reasons")
+		} else {
+			decode.SetPktDropCauses(dropCauses)
+		}
+	}
+
 	fetcher, err := ebpf.NewFlowFetcher(ebpfConfig)
 	if err != nil {
 		return nil, err
@@ -214,38 +223,51 @@ func flowsAgent(cfg *Config, m *metrics.Metrics,
 	exporter node.TerminalFunc[[]*flow.Record],
 	agentIP net.IP,
 ) (*Flows, error) {
-	var filter InterfaceFilter
-
-	switch {
-	case len(cfg.InterfaceIPs) > 0 && (len(cfg.Interfaces) > 0 || len(cfg.ExcludeInterfaces) > 0):
-		return nil, fmt.Errorf("INTERFACES/EXCLUDE_INTERFACES and INTERFACE_IPS are mutually exclusive")
-
-	case len(cfg.InterfaceIPs) > 0:
-		// configure ip interface filter
-		f, err := initIPInterfaceFilter(cfg.InterfaceIPs, IPsFromInterface)
-		if err != nil {
-			return nil, fmt.Errorf("configuring interface ip filter: %w", err)
-		}
-		filter = &f
+	registerer := ifaces.NewRegisterer(informer, cfg.BuffersLength)
 
-	default:
-		// configure allow/deny regexp interfaces filter
-		f, err := initRegexpInterfaceFilter(cfg.Interfaces, cfg.ExcludeInterfaces)
-		if err != nil {
-			return nil, fmt.Errorf("configuring interface filters: %w", err)
-		}
-		filter = &f
+	filter, err := initInterfaceFilter(cfg, registerer.NetNSNameFor)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := initInterfaceProfiles(cfg, registerer.NetNSNameFor)
+	if err != nil {
+		return nil, err
 	}
 
-	registerer := ifaces.NewRegisterer(informer, cfg.BuffersLength)
-
-	interfaceNamer := func(ifIndex int) string {
-		iface, ok := registerer.IfaceNameForIndex(ifIndex)
+	interfaceNamer := func(netns uint32, ifIndex int) string {
+		iface, ok := registerer.IfaceNameForIndex(netns, ifIndex)
 		if !ok {
 			return "unknown"
 		}
 		return iface
 	}
+	var linker flow.InterfaceLinker
+	if cfg.EnableInterfaceMetadata {
+		linker = func(netns uint32, ifIndex int) *flow.InterfaceLink {
+			link, ok := registerer.LinkForIndex(netns, ifIndex)
+			if !ok {
+				return nil
+			}
+			fl := flow.InterfaceLink{
+				Type:        link.Type,
+				MTU:         uint32(link.MTU),
+				Master:      link.Master,
+				PeerIfIndex: uint32(link.PeerIndex),
+			}
+			copy(fl.MAC[:], link.MAC)
+			return &fl
+		}
+	}
+	var dropCauseNamer flow.DropCauseNamer
+	var locator flow.DropLocator
+	if cfg.EnablePktDrops {
+		dropCauseNamer = decode.PktDropCauseToStr
+		symbols, err := kernel.LoadSymbols()
+		if err != nil {
+			alog.WithError(err).Warn("can't load the kernel symbols: the drop locations are reported as addresses")
+		}
+		locator = dropLocator(symbols, fetcher.LookupDropStack)
+	}
 	var promoServer *http.Server
 	if cfg.MetricsEnable {
 		promoServer = promo.InitializePrometheus(m.Settings)
@@ -260,7 +282,23 @@ func flowsAgent(cfg *Config, m *metrics.Metrics,
 	limiter := flow.NewCapacityLimiter(m)
 	var deduper node.MiddleFunc[[]*flow.Record, []*flow.Record]
 	if cfg.Deduper == DeduperFirstCome {
-		deduper = flow.Dedupe(cfg.DeduperFCExpiry, cfg.DeduperJustMark, cfg.DeduperMerge, interfaceNamer, m)
+		ranker, err := interfaceRanker(cfg, registerer.LinkForIndex, interfaceNamer)
+		if err != nil {
+			return nil, err
+		}
+		deduper = flow.Dedupe(cfg.DeduperFCExpiry, cfg.DeduperJustMark, cfg.DeduperMerge, interfaceNamer, ranker, m)
+	}
+	var biflows *flow.BiflowStitcher
+	if cfg.EnableBiflow {
+		if biflows, err = flow.NewBiflowStitcher(cfg.BiflowWindow, cfg.BiflowMaxFlows, m); err != nil {
+			return nil, err
+		}
+	}
+	var aggregator *flow.Aggregator
+	if cfg.EnableAggregation {
+		if aggregator, err = buildAggregator(cfg, m); err != nil {
+			return nil, err
+		}
 	}
 
 	return &Flows{
@@ -268,18 +306,43 @@ func flowsAgent(cfg *Config, m *metrics.Metrics,
 		exporter:       exporter,
 		interfaces:     registerer,
 		filter:         filter,
+		profiles:       profiles,
 		cfg:            cfg,
 		mapTracer:      mapTracer,
 		rbTracer:       rbTracer,
 		accounter:      accounter,
 		limiter:        limiter,
 		deduper:        deduper,
+		biflows:        biflows,
+		aggregator:     aggregator,
 		agentIP:        agentIP,
 		interfaceNamer: interfaceNamer,
+		netnsNamer:     registerer.NetNSName,
+		linker:         linker,
+		dropCauseNamer: dropCauseNamer,
+		dropLocator:    locator,
 		promoServer:    promoServer,
 	}, nil
 }
 
+// metricsSettings returns the settings of the agent metrics and of their Prometheus server
+func metricsSettings(cfg *Config) *metrics.Settings {
+	settings := &metrics.Settings{
+		PromConnectionInfo: metrics.PromConnectionInfo{
+			Address: cfg.MetricsServerAddress,
+			Port:    cfg.MetricsPort,
+		},
+		Prefix: cfg.MetricsPrefix,
+	}
+	if cfg.MetricsTLSCertPath != "" && cfg.MetricsTLSKeyPath != "" {
+		settings.PromConnectionInfo.TLS = &metrics.PromTLS{
+			CertPath: cfg.MetricsTLSCertPath,
+			KeyPath:  cfg.MetricsTLSKeyPath,
+		}
+	}
+	return settings
+}
+
 func flowDirections(cfg *Config) (ingress, egress bool) {
 	switch cfg.Direction {
 	case DirectionIngress:
@@ -294,16 +357,113 @@ func flowDirections(cfg *Config) (ingress, egress bool) {
 	}
 }
 
-func buildFlowExporter(cfg *Config, m *metrics.Metrics) (node.TerminalFunc[[]*flow.Record], error) {
+// interfaceRanker returns the deduplication preference of the interfaces, or nil if the
+// deduplicator keeps the first interface reporting a flow. The interfaces whose link attributes
+// are unknown have the lowest preference.
+func interfaceRanker(cfg *Config, linkFor func(netns uint32, ifIndex int) (*ifaces.Link, bool), namer flow.InterfaceNamer) (flow.InterfaceRanker, error) {
+	switch cfg.DeduperPreference {
+	case "", DeduperPreferNone:
+		return nil, nil
+	case DeduperPreferPhysical:
+		return func(netns uint32, ifIndex int) int {
+			if link, ok := linkFor(netns, ifIndex); ok && link.Physical() {
+				return 0
+			}
+			return 1
+		}, nil
+	case DeduperPreferVeth:
+		return func(netns uint32, ifIndex int) int {
+			if link, ok := linkFor(netns, ifIndex); ok && link.Type == "veth" {
+				return 0
+			}
+			return 1
+		}, nil
+	case DeduperPreferInterfaces:
+		if len(cfg.DeduperPreferredInterfaces) == 0 {
+			return nil, fmt.Errorf("DEDUPER_PREFERRED_INTERFACES must be set when DEDUPER_PREFERENCE is %q",
+				DeduperPreferInterfaces)
+		}
+		preferred := make([]stringMatcher, 0, len(cfg.DeduperPreferredInterfaces))
+		for _, definition := range cfg.DeduperPreferredInterfaces {
+			m, err := newStringMatcher(definition)
+			if err != nil {
+				return nil, fmt.Errorf("wrong deduper preferred interface: %w", err)
+			}
+			preferred = append(preferred, m)
+		}
+		return func(netns uint32, ifIndex int) int {
+			name := namer(netns, ifIndex)
+			for i := range preferred {
+				if preferred[i].matches(name) {
+					return i
+				}
+			}
+			return len(preferred)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown DEDUPER_PREFERENCE %q. Accepted values are: %s, %s, %s, %s",
+			cfg.DeduperPreference, DeduperPreferNone, DeduperPreferPhysical, DeduperPreferVeth, DeduperPreferInterfaces)
+	}
+}
+
+// dropLocator resolves the drop locations and the frames of the drop stacks with the kernel
+// symbols. The frames that can't be resolved are reported as addresses.
+func dropLocator(symbols *kernel.Symbols, lookupStack func(stackID uint32) ([]uint64, error)) flow.DropLocator {
+	return func(location uint64, stackID uint32) (string, []string) {
+		function := symbols.Resolve(location)
+		if stackID == 0 {
+			return function, nil
+		}
+		frames, err := lookupStack(stackID)
+		if err != nil {
+			// the stack might have been replaced by another one since the drop
+			alog.WithError(err).Debug("can't get drop stack")
+			return function, nil
+		}
+		stack := make([]string, 0, len(frames))
+		for _, frame := range frames {
+			stack = append(stack, decode.PktDropLocationToStr(frame, symbols.Resolve(frame)))
+		}
+		return function, stack
+	}
+}
+
+func buildFlowExporter(cfg *Config, m *metrics.Metrics, agentIP net.IP) (node.TerminalFunc[[]*flow.Record], error) {
 	switch cfg.Export {
 	case "grpc":
 		return buildGRPCExporter(cfg, m)
 	case "kafka":
 		return buildKafkaExporter(cfg, m)
 	case "ipfix+udp":
-		return buildIPFIXExporter(cfg, "udp")
+		return buildIPFIXExporter(cfg, m, "udp")
 	case "ipfix+tcp":
-		return buildIPFIXExporter(cfg, "tcp")
+		return buildIPFIXExporter(cfg, m, "tcp")
+	case "ipfix+file":
+		return buildIPFIXFileExporter(cfg, m)
+	case "netflow5+udp":
+		return buildNetFlowExporter(cfg, m, agentIP, 5)
+	case "netflow9+udp":
+		return buildNetFlowExporter(cfg, m, agentIP, 9)
+	case "sflow+udp":
+		sflow, err := buildSFlowExporter(cfg, m, agentIP)
+		if err != nil {
+			return nil, err
+		}
+		return sflow.ExportFlows, nil
+	case "otlp":
+		return buildOTLPExporter(cfg, m)
+	case "json+stdout":
+		return exporter.StartJSONLinesStdoutExporter(jsonLinesConfig(cfg), m).ExportFlows, nil
+	case "json+file":
+		return buildJSONLinesFileExporter(cfg, m)
+	case "syslog+udp":
+		return buildSyslogExporter(cfg, m, exporter.SyslogTransportUDP)
+	case "syslog+tcp":
+		return buildSyslogExporter(cfg, m, exporter.SyslogTransportTCP)
+	case "syslog+tls":
+		return buildSyslogExporter(cfg, m, exporter.SyslogTransportTLS)
+	case "prometheus":
+		return buildPromFlowsExporter(cfg, m)
 	case "direct-flp":
 		return buildFlowDirectFLPExporter(cfg)
 	default:
@@ -312,15 +472,202 @@ func buildFlowExporter(cfg *Config, m *metrics.Metrics) (node.TerminalFunc[[]*fl
 }
 
 func buildGRPCExporter(cfg *Config, m *metrics.Metrics) (node.TerminalFunc[[]*flow.Record], error) {
+	targets := cfg.GRPCTargets
+	if len(targets) == 0 {
+		if cfg.TargetHost == "" || cfg.TargetPort == 0 {
+			return nil, fmt.Errorf("missing target host or port: %s:%d",
+				cfg.TargetHost, cfg.TargetPort)
+		}
+		targets = []string{utils.GetSocket(cfg.TargetHost, cfg.TargetPort)}
+	}
+	switch cfg.GRPCLoadBalancing {
+	case exporter.GRPCBalanceRoundRobin, exporter.GRPCBalanceConversation:
+	default:
+		return nil, fmt.Errorf("wrong gRPC load balancing strategy %s", cfg.GRPCLoadBalancing)
+	}
+	options := []flowgrpc.ClientOption{
+		flowgrpc.WithKeepalive(cfg.GRPCKeepaliveTime, cfg.GRPCKeepaliveTimeout),
+		flowgrpc.WithMaxConnectDelay(cfg.GRPCReconnectMaxBackoff),
+	}
+	var tlsConfigs func(host string) *tls.Config
+	if cfg.GRPCEnableTLS {
+		var err error
+		if tlsConfigs, err = buildGRPCTLSConfigs(cfg); err != nil {
+			return nil, fmt.Errorf("building gRPC TLS configuration: %w", err)
+		}
+	}
+	poolConfig := flowgrpc.PoolConfig{
+		Targets:             targets,
+		ResolveInterval:     cfg.GRPCResolveInterval,
+		HealthCheckInterval: cfg.GRPCHealthCheckInterval,
+		HealthCheckTimeout:  cfg.GRPCHealthCheckTimeout,
+		ClientOptions: func(host string) []flowgrpc.ClientOption {
+			if tlsConfigs == nil {
+				return options
+			}
+			return append(options[:len(options):len(options)], flowgrpc.WithClientTLSConfig(tlsConfigs(host)))
+		},
+	}
+	sendOptions := exporter.GRPCSendOptions{
+		Timeout:        cfg.GRPCSendTimeout,
+		MaxRetries:     cfg.GRPCMaxRetries,
+		InitialBackoff: cfg.GRPCRetryInitialBackoff,
+		MaxBackoff:     cfg.GRPCRetryMaxBackoff,
+		Streaming:      cfg.GRPCStreaming,
+		Balancing:      cfg.GRPCLoadBalancing,
+	}
+	grpcExporter, err := exporter.StartGRPCProtoPool(poolConfig, cfg.GRPCMessageMaxFlows, m, sendOptions)
+	if err != nil {
+		return nil, err
+	}
+	return grpcExporter.ExportFlows, nil
+}
+
+func buildNetFlowExporter(cfg *Config, m *metrics.Metrics, agentIP net.IP, version int) (node.TerminalFunc[[]*flow.Record], error) {
 	if cfg.TargetHost == "" || cfg.TargetPort == 0 {
 		return nil, fmt.Errorf("missing target host or port: %s:%d",
 			cfg.TargetHost, cfg.TargetPort)
 	}
-	grpcExporter, err := exporter.StartGRPCProto(cfg.TargetHost, cfg.TargetPort, cfg.GRPCMessageMaxFlows, m)
+	netflowConfig := exporter.NetFlowConfig{
+		Version:         version,
+		TargetHost:      cfg.TargetHost,
+		TargetPort:      cfg.TargetPort,
+		SourceID:        cfg.NetFlowSourceID,
+		Sampling:        cfg.Sampling,
+		TemplateRefresh: cfg.NetFlowTemplateRefresh,
+	}
+	if cfg.NetFlowAgentIPAsSource {
+		netflowConfig.SourceIP = agentIP
+	}
+	netflow, err := exporter.StartNetFlow(&netflowConfig, m)
 	if err != nil {
 		return nil, err
 	}
-	return grpcExporter.ExportFlows, nil
+	return netflow.ExportFlows, nil
+}
+
+func buildSFlowExporter(cfg *Config, m *metrics.Metrics, agentIP net.IP) (*exporter.SFlow, error) {
+	if cfg.TargetHost == "" || cfg.TargetPort == 0 {
+		return nil, fmt.Errorf("missing target host or port: %s:%d",
+			cfg.TargetHost, cfg.TargetPort)
+	}
+	return exporter.StartSFlow(&exporter.SFlowConfig{
+		TargetHost:       cfg.TargetHost,
+		TargetPort:       cfg.TargetPort,
+		AgentIP:          agentIP,
+		SubAgentID:       cfg.SFlowSubAgentID,
+		Sampling:         cfg.Sampling,
+		CountersInterval: cfg.SFlowCountersInterval,
+		MaxHeaderSize:    cfg.SFlowMaxHeaderSize,
+	}, m)
+}
+
+func buildOTLPExporter(cfg *Config, m *metrics.Metrics) (node.TerminalFunc[[]*flow.Record], error) {
+	if cfg.OTLPEndpoint == "" {
+		return nil, errors.New("missing OTLP endpoint")
+	}
+	headers := map[string]string{}
+	for _, header := range cfg.OTLPHeaders {
+		key, value, ok := strings.Cut(header, "=")
+		if !ok {
+			return nil, fmt.Errorf("wrong OTLP header %q. Expected key=value", header)
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	otlpConfig := exporter.OTLPConfig{
+		Endpoint:        cfg.OTLPEndpoint,
+		Protocol:        cfg.OTLPProtocol,
+		HTTPPath:        cfg.OTLPHTTPPath,
+		Headers:         headers,
+		Compression:     cfg.OTLPCompression,
+		Timeout:         cfg.OTLPTimeout,
+		BatchMaxRecords: cfg.OTLPBatchMaxRecords,
+		BatchTimeout:    cfg.OTLPBatchTimeout,
+	}
+	if cfg.OTLPEnableTLS {
+		reloader, err := utils.NewCertReloader(cfg.OTLPTLSCACertPath, cfg.OTLPTLSUserCertPath, cfg.OTLPTLSUserKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("building OTLP TLS configuration: %w", err)
+		}
+		host, _, err := net.SplitHostPort(cfg.OTLPEndpoint)
+		if err != nil {
+			return nil, fmt.Errorf("wrong OTLP endpoint: %w", err)
+		}
+		otlpConfig.TLSConfig = reloader.ClientConfig(host, cfg.OTLPTLSInsecureSkipVerify)
+	}
+	otlpExporter, err := exporter.StartOTLP(&otlpConfig, m)
+	if err != nil {
+		return nil, err
+	}
+	return otlpExporter.ExportFlows, nil
+}
+
+func buildSyslogExporter(cfg *Config, m *metrics.Metrics, transport string) (node.TerminalFunc[[]*flow.Record], error) {
+	if cfg.TargetHost == "" || cfg.TargetPort == 0 {
+		return nil, fmt.Errorf("missing target host or port: %s:%d",
+			cfg.TargetHost, cfg.TargetPort)
+	}
+	syslogConfig := exporter.SyslogConfig{
+		TargetHost: cfg.TargetHost,
+		TargetPort: cfg.TargetPort,
+		Transport:  transport,
+		Format:     cfg.SyslogFormat,
+		Facility:   cfg.SyslogFacility,
+		RateLimit:  cfg.SyslogRateLimit,
+		RateBurst:  cfg.SyslogRateBurst,
+	}
+	if transport == exporter.SyslogTransportTLS {
+		reloader, err := utils.NewCertReloader(cfg.SyslogTLSCACertPath, cfg.SyslogTLSUserCertPath, cfg.SyslogTLSUserKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("building syslog TLS configuration: %w", err)
+		}
+		syslogConfig.TLSConfig = reloader.ClientConfig(cfg.TargetHost, cfg.SyslogTLSInsecureSkipVerify)
+	}
+	syslogExporter, err := exporter.StartSyslog(&syslogConfig, m)
+	if err != nil {
+		return nil, err
+	}
+	return syslogExporter.ExportFlows, nil
+}
+
+func buildPromFlowsExporter(cfg *Config, m *metrics.Metrics) (node.TerminalFunc[[]*flow.Record], error) {
+	if !cfg.MetricsEnable {
+		return nil, errors.New("the prometheus export requires METRICS_ENABLE to expose the flow metrics")
+	}
+	promConfig := exporter.PromFlowsConfig{
+		Labels:   cfg.FlowMetricsLabels,
+		PrefixV4: cfg.FlowMetricsSubnetPrefixV4,
+		PrefixV6: cfg.FlowMetricsSubnetPrefixV6,
+	}
+	for _, cidr := range cfg.FlowMetricsCIDRs {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("wrong FLOW_METRICS_CIDRS entry %q: %w", cidr, err)
+		}
+		promConfig.CIDRs = append(promConfig.CIDRs, ipNet)
+	}
+	promExporter, err := exporter.StartPromFlows(&promConfig, m)
+	if err != nil {
+		return nil, err
+	}
+	return promExporter.ExportFlows, nil
+}
+
+func buildAggregator(cfg *Config, m *metrics.Metrics) (*flow.Aggregator, error) {
+	aggConfig := flow.AggregatorConfig{
+		Window:           cfg.AggregationWindow,
+		MaxFlows:         cfg.AggregationMaxFlows,
+		Ignore:           cfg.AggregationIgnore,
+		EphemeralPortMin: cfg.AggregationEphemeralPortMin,
+	}
+	for _, cidr := range cfg.AggregationCIDRs {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("wrong AGGREGATION_CIDRS entry %q: %w", cidr, err)
+		}
+		aggConfig.CIDRs = append(aggConfig.CIDRs, ipNet)
+	}
+	return flow.NewAggregator(&aggConfig, m)
 }
 
 func buildFlowDirectFLPExporter(cfg *Config) (node.TerminalFunc[[]*flow.Record], error) {
@@ -355,10 +702,34 @@ func buildKafkaExporter(cfg *Config, m *metrics.Metrics) (node.TerminalFunc[[]*f
 		}
 		transport.SASL = mechanism
 	}
+	format := exporter.KafkaFormat(cfg.KafkaFormat)
+	switch format {
+	case exporter.KafkaFormatProtobuf, exporter.KafkaFormatJSON, exporter.KafkaFormatProtobufBatch:
+	default:
+		return nil, fmt.Errorf("wrong Kafka format value %s. Admitted values are "+
+			"protobuf, json, protobuf-batch", cfg.KafkaFormat)
+	}
+	key := exporter.KafkaKey(cfg.KafkaKey)
+	switch key {
+	case exporter.KafkaKeyConversation, exporter.KafkaKeySourceIP, exporter.KafkaKeyAgentIP, exporter.KafkaKeyNone:
+	default:
+		return nil, fmt.Errorf("wrong Kafka key value %s. Admitted values are "+
+			"conversation, source-ip, agent-ip, none", cfg.KafkaKey)
+	}
+	routes, err := exporter.ParseKafkaRoutes(cfg.KafkaTopicRoutes)
+	if err != nil {
+		return nil, err
+	}
+	// the messages carry their own topic when they are routed, which kafka-go doesn't allow if
+	// the writer has a topic
+	writerTopic := cfg.KafkaTopic
+	if len(routes) > 0 {
+		writerTopic = ""
+	}
 	return (&exporter.KafkaProto{
 		Writer: &kafkago.Writer{
 			Addr:      kafkago.TCP(cfg.KafkaBrokers...),
-			Topic:     cfg.KafkaTopic,
+			Topic:     writerTopic,
 			BatchSize: cfg.KafkaBatchMessages,
 			// Assigning KafkaBatchSize to BatchBytes instead of BatchSize might be confusing here.
 			// The reason is that the "standard" Kafka name for this variable is "batch.size",
@@ -377,22 +748,84 @@ func buildKafkaExporter(cfg *Config, m *metrics.Metrics) (node.TerminalFunc[[]*f
 			Transport:    &transport,
 			Balancer:     &kafkago.Hash{},
 		},
-		Metrics: m,
+		Metrics:         m,
+		Format:          format,
+		Key:             key,
+		Headers:         cfg.KafkaHeaders,
+		Sampling:        cfg.Sampling,
+		MessageMaxFlows: cfg.KafkaMessageMaxFlows,
+		Routes:          routes,
+		DefaultTopic:    cfg.KafkaTopic,
 	}).ExportFlows, nil
 }
 
-func buildIPFIXExporter(cfg *Config, proto string) (node.TerminalFunc[[]*flow.Record], error) {
+func buildIPFIXExporter(cfg *Config, m *metrics.Metrics, proto string) (node.TerminalFunc[[]*flow.Record], error) {
 	if cfg.TargetHost == "" || cfg.TargetPort == 0 {
 		return nil, fmt.Errorf("missing target host or port: %s:%d",
 			cfg.TargetHost, cfg.TargetPort)
 	}
-	ipfix, err := exporter.StartIPFIXExporter(cfg.TargetHost, cfg.TargetPort, proto)
+	ipfix, err := exporter.StartIPFIXExporter(&exporter.IPFIXConfig{
+		TargetHost:      cfg.TargetHost,
+		TargetPort:      cfg.TargetPort,
+		Transport:       proto,
+		TemplateRefresh: cfg.IPFIXTemplateRefresh,
+		StatsInterval:   cfg.IPFIXStatsInterval,
+		Sampling:        cfg.Sampling,
+		MaxMessageSize:  cfg.IPFIXMaxMessageSize,
+		Biflow:          cfg.EnableBiflow,
+	}, m)
 	if err != nil {
 		return nil, err
 	}
 	return ipfix.ExportFlows, nil
 }
 
+func buildIPFIXFileExporter(cfg *Config, m *metrics.Metrics) (node.TerminalFunc[[]*flow.Record], error) {
+	if cfg.IPFIXFileDirectory == "" {
+		return nil, errors.New("missing IPFIX_FILE_DIRECTORY")
+	}
+	ipfix, err := exporter.StartIPFIXFileExporter(&exporter.IPFIXConfig{
+		StatsInterval:  cfg.IPFIXStatsInterval,
+		Sampling:       cfg.Sampling,
+		MaxMessageSize: cfg.IPFIXMaxMessageSize,
+		Biflow:         cfg.EnableBiflow,
+	}, &exporter.RotatingFileConfig{
+		Directory:        cfg.IPFIXFileDirectory,
+		MaxSize:          cfg.IPFIXFileMaxSize,
+		RotationInterval: cfg.IPFIXFileRotationInterval,
+		Compress:         cfg.IPFIXFileCompress,
+		MaxFiles:         cfg.IPFIXFileMaxFiles,
+	}, m)
+	if err != nil {
+		return nil, err
+	}
+	return ipfix.ExportFlows, nil
+}
+
+func jsonLinesConfig(cfg *Config) *exporter.JSONLinesConfig {
+	return &exporter.JSONLinesConfig{
+		Pretty: cfg.JSONPretty,
+		Fields: cfg.JSONFields,
+	}
+}
+
+func buildJSONLinesFileExporter(cfg *Config, m *metrics.Metrics) (node.TerminalFunc[[]*flow.Record], error) {
+	if cfg.JSONFileDirectory == "" {
+		return nil, errors.New("missing JSON_FILE_DIRECTORY")
+	}
+	jsonLines, err := exporter.StartJSONLinesFileExporter(jsonLinesConfig(cfg), &exporter.RotatingFileConfig{
+		Directory:        cfg.JSONFileDirectory,
+		MaxSize:          cfg.JSONFileMaxSize,
+		RotationInterval: cfg.JSONFileRotationInterval,
+		Compress:         cfg.JSONFileCompress,
+		MaxFiles:         cfg.JSONFileMaxFiles,
+	}, m)
+	if err != nil {
+		return nil, err
+	}
+	return jsonLines.ExportFlows, nil
+}
+
 // Run a Flows agent. The function will keep running in the same thread
 // until the passed context is canceled
 func (f *Flows) Run(ctx context.Context) error {
@@ -467,7 +900,7 @@ func (f *Flows) buildAndStartPipeline(ctx context.Context) (*node.Terminal[[]*fl
 	limiter := node.AsMiddle(f.limiter.Limit,
 		node.ChannelBufferLen(f.cfg.BuffersLength))
 
-	decorator := node.AsMiddle(flow.Decorate(f.agentIP, f.interfaceNamer),
+	decorator := node.AsMiddle(flow.Decorate(f.agentIP, f.interfaceNamer, f.netnsNamer, f.linker, f.dropCauseNamer, f.dropLocator),
 		node.ChannelBufferLen(f.cfg.BuffersLength))
 
 	ebl := f.cfg.ExporterBufferLength
@@ -490,7 +923,19 @@ func (f *Flows) buildAndStartPipeline(ctx context.Context) (*node.Terminal[[]*fl
 		accounter.SendsTo(limiter)
 	}
 	limiter.SendsTo(decorator)
-	decorator.SendsTo(export)
+	// the optional stages after the decorator are chained in order
+	last := decorator
+	if f.biflows != nil {
+		biflows := node.AsMiddle(f.biflows.Stitch, node.ChannelBufferLen(f.cfg.BuffersLength))
+		last.SendsTo(biflows)
+		last = biflows
+	}
+	if f.aggregator != nil {
+		aggregator := node.AsMiddle(f.aggregator.Aggregate, node.ChannelBufferLen(f.cfg.BuffersLength))
+		last.SendsTo(aggregator)
+		last = aggregator
+	}
+	last.SendsTo(export)
 
 	alog.Debug("starting graph")
 	mapTracer.Start()
@@ -500,7 +945,8 @@ func (f *Flows) buildAndStartPipeline(ctx context.Context) (*node.Terminal[[]*fl
 
 func (f *Flows) onInterfaceAdded(iface ifaces.Interface) {
 	// ignore interfaces that do not match the user configuration acceptance/exclusion lists
-	allowed, err := f.filter.Allowed(iface.Name)
+	link, _ := f.interfaces.LinkFor(iface)
+	allowed, err := f.filter.Allowed(iface, link)
 	if err != nil {
 		alog.WithField("interface", iface).Errorf("encountered error determining if interface is allowed: %v", err)
 		return
@@ -510,11 +956,17 @@ func (f *Flows) onInterfaceAdded(iface ifaces.Interface) {
 			Debug("interface does not match the allow/exclusion filters. Ignoring")
 		return
 	}
-	alog.WithField("interface", iface).Info("interface detected. trying to attach TCX hook")
-	if err := f.ebpf.AttachTCX(iface); err != nil {
+	profile, err := f.profiles.For(iface, link)
+	if err != nil {
+		alog.WithField("interface", iface).Errorf("encountered error determining the interface profile: %v", err)
+		return
+	}
+	alog.WithField("interface", iface).WithField("profile", profile).
+		Info("interface detected. trying to attach TCX hook")
+	if err := f.ebpf.AttachTCX(iface, &profile); err != nil {
 		alog.WithField("interface", iface).WithError(err).
 			Info("can't attach to TCx hook flow ebpfFetcher. fall back to use legacy TC hook")
-		if err := f.ebpf.Register(iface); err != nil {
+		if err := f.ebpf.Register(iface, &profile); err != nil {
 			alog.WithField("interface", iface).WithError(err).
 				Warn("can't register flow ebpfFetcher. Ignoring")
 			return
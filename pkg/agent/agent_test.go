@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"net"
 	"testing"
 	"time"
@@ -10,6 +11,7 @@ import (
 	test2 "github.com/mariomac/guara/pkg/test"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/ebpf"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ifaces"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/test"
 	"github.com/stretchr/testify/assert"
@@ -201,6 +203,72 @@ func TestFlowsAgent_Decoration(t *testing.T) {
 	}
 }
 
+func TestInterfaceRanker(t *testing.T) {
+	links := map[int]*ifaces.Link{
+		1: {Type: "device"},
+		2: {Type: "veth"},
+		3: {Type: "device", Loopback: true},
+	}
+	linkFor := func(_ uint32, ifIndex int) (*ifaces.Link, bool) {
+		link, ok := links[ifIndex]
+		return link, ok
+	}
+	namer := func(_ uint32, ifIndex int) string {
+		return [...]string{"unknown", "eth0", "veth12", "lo", "br-ex"}[ifIndex]
+	}
+	for _, tc := range []struct {
+		name     string
+		cfg      Config
+		expected []int
+	}{
+		{name: "physical", cfg: Config{DeduperPreference: DeduperPreferPhysical}, expected: []int{0, 1, 1, 1}},
+		{name: "veth", cfg: Config{DeduperPreference: DeduperPreferVeth}, expected: []int{1, 0, 1, 1}},
+		{name: "interfaces", cfg: Config{DeduperPreference: DeduperPreferInterfaces,
+			DeduperPreferredInterfaces: []string{"/^br-/", "/^veth/"}}, expected: []int{2, 1, 2, 0}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ranker, err := interfaceRanker(&tc.cfg, linkFor, namer)
+			require.NoError(t, err)
+			ranks := make([]int, 0, len(tc.expected))
+			for ifIndex := 1; ifIndex <= len(tc.expected); ifIndex++ {
+				ranks = append(ranks, ranker(0, ifIndex))
+			}
+			assert.Equal(t, tc.expected, ranks)
+		})
+	}
+
+	ranker, err := interfaceRanker(&Config{DeduperPreference: DeduperPreferNone}, linkFor, namer)
+	require.NoError(t, err)
+	assert.Nil(t, ranker)
+
+	_, err = interfaceRanker(&Config{DeduperPreference: DeduperPreferInterfaces}, linkFor, namer)
+	assert.Error(t, err)
+	_, err = interfaceRanker(&Config{DeduperPreference: "fastest"}, linkFor, namer)
+	assert.Error(t, err)
+}
+
+func TestDropLocator(t *testing.T) {
+	lookupStack := func(stackID uint32) ([]uint64, error) {
+		if stackID == 1 {
+			return []uint64{0xffffffff81a2b08a, 0xffffffff81a0017b}, nil
+		}
+		return nil, errors.New("stack not found")
+	}
+	// without kernel symbols, the locations are reported as addresses
+	locator := dropLocator(nil, lookupStack)
+
+	function, stack := locator(0xffffffff81a2b08a, 0)
+	assert.Empty(t, function)
+	assert.Nil(t, stack)
+
+	_, stack = locator(0xffffffff81a2b08a, 1)
+	assert.Equal(t, []string{"0xffffffff81a2b08a", "0xffffffff81a0017b"}, stack)
+
+	// a stack that can't be found is ignored
+	_, stack = locator(0xffffffff81a2b08a, 2)
+	assert.Nil(t, stack)
+}
+
 func testAgent(t *testing.T, cfg *Config) *test.ExporterFake {
 	ebpfTracer := test.NewTracerFake()
 	export := test.NewExporterFake()
@@ -13,6 +13,12 @@ const (
 	ListenWatch      = "watch"
 	DeduperNone      = "none"
 	DeduperFirstCome = "firstCome"
+
+	DeduperPreferNone       = "none"
+	DeduperPreferPhysical   = "physical"
+	DeduperPreferVeth       = "veth"
+	DeduperPreferInterfaces = "interfaces"
+
 	DirectionIngress = "ingress"
 	DirectionEgress  = "egress"
 	DirectionBoth    = "both"
@@ -39,8 +45,10 @@ type Config struct {
 	// If the AgentIP configuration property is set, this property has no effect.
 	AgentIPType string `env:"AGENT_IP_TYPE" envDefault:"any"`
 	// Export selects the exporter protocol.
-	// Accepted values for Flows are: grpc (default), kafka, ipfix+udp, ipfix+tcp or direct-flp.
-	// Accepted values for Packets are: grpc (default) or direct-flp
+	// Accepted values for Flows are: grpc (default), kafka, ipfix+udp, ipfix+tcp, ipfix+file,
+	// netflow5+udp, netflow9+udp, sflow+udp, otlp, json+stdout, json+file, syslog+udp, syslog+tcp,
+	// syslog+tls, prometheus or direct-flp.
+	// Accepted values for Packets are: grpc (default), sflow+udp or direct-flp
 	Export string `env:"EXPORT" envDefault:"grpc"`
 	// Host is the host name or IP of the flow or packet collector, when the EXPORT variable is
 	// set to "grpc"
@@ -50,6 +58,61 @@ type Config struct {
 	// GRPCMessageMaxFlows specifies the limit, in number of flows, of each GRPC message. Messages
 	// larger than that number will be split and submitted sequentially.
 	GRPCMessageMaxFlows int `env:"GRPC_MESSAGE_MAX_FLOWS" envDefault:"10000"`
+	// GRPCStreaming submits the flows through a bidirectional gRPC stream, where the collector
+	// acknowledges each message and grants flow-control credits to the agent. If the collector
+	// does not support it, the agent falls back to unary gRPC calls.
+	GRPCStreaming bool `env:"GRPC_STREAMING" envDefault:"false"`
+	// GRPCSendTimeout is the maximum time to wait for a flows' message to be accepted by the gRPC
+	// collector. Zero means no timeout.
+	GRPCSendTimeout time.Duration `env:"GRPC_SEND_TIMEOUT" envDefault:"10s"`
+	// GRPCMaxRetries is the number of times a failed flows' message submission is retried before
+	// discarding it.
+	GRPCMaxRetries int `env:"GRPC_MAX_RETRIES" envDefault:"3"`
+	// GRPCRetryInitialBackoff is the time to wait before the first retry. It is doubled on each
+	// retry, up to GRPCRetryMaxBackoff.
+	GRPCRetryInitialBackoff time.Duration `env:"GRPC_RETRY_INITIAL_BACKOFF" envDefault:"200ms"`
+	// GRPCRetryMaxBackoff is the maximum time to wait between retries.
+	GRPCRetryMaxBackoff time.Duration `env:"GRPC_RETRY_MAX_BACKOFF" envDefault:"5s"`
+	// GRPCTargets is a comma-separated list of host:port gRPC flow collectors. If set, it overrides
+	// TargetHost and TargetPort for the flows exporter. Host names resolving to several addresses
+	// are expanded to an endpoint per address.
+	GRPCTargets []string `env:"GRPC_TARGETS" envSeparator:","`
+	// GRPCLoadBalancing is the strategy to balance the flows across the gRPC collector endpoints:
+	// round-robin (default) or conversation-hash, which submits all the flows between the same
+	// pair of IPs to the same endpoint.
+	GRPCLoadBalancing string `env:"GRPC_LOAD_BALANCING" envDefault:"round-robin"`
+	// GRPCResolveInterval is the period to resolve again the host names of the gRPC collectors.
+	// Zero disables the periodic resolution.
+	GRPCResolveInterval time.Duration `env:"GRPC_RESOLVE_INTERVAL" envDefault:"30s"`
+	// GRPCHealthCheckInterval is the period to check the health of the gRPC collector endpoints.
+	// Unhealthy endpoints are not used until they pass a health check again. Zero disables the
+	// health checks.
+	GRPCHealthCheckInterval time.Duration `env:"GRPC_HEALTH_CHECK_INTERVAL" envDefault:"10s"`
+	// GRPCHealthCheckTimeout is the maximum time to wait for a health check response
+	GRPCHealthCheckTimeout time.Duration `env:"GRPC_HEALTH_CHECK_TIMEOUT" envDefault:"5s"`
+	// GRPCKeepaliveTime is the time without activity after which a keepalive ping is sent to the
+	// gRPC collector. Zero disables the keepalive pings.
+	GRPCKeepaliveTime time.Duration `env:"GRPC_KEEPALIVE_TIME" envDefault:"0s"`
+	// GRPCKeepaliveTimeout is the time to wait for a keepalive ping acknowledgement before closing
+	// the connection.
+	GRPCKeepaliveTimeout time.Duration `env:"GRPC_KEEPALIVE_TIMEOUT" envDefault:"20s"`
+	// GRPCReconnectMaxBackoff is the maximum time to wait between reconnection attempts to a
+	// gRPC collector.
+	GRPCReconnectMaxBackoff time.Duration `env:"GRPC_RECONNECT_MAX_BACKOFF" envDefault:"30s"`
+	// GRPCEnableTLS set true to enable TLS in the connections to the gRPC flow or packet collector
+	GRPCEnableTLS bool `env:"GRPC_ENABLE_TLS" envDefault:"false"`
+	// GRPCTLSInsecureSkipVerify skips server certificate verification in gRPC TLS connections
+	GRPCTLSInsecureSkipVerify bool `env:"GRPC_TLS_INSECURE_SKIP_VERIFY" envDefault:"false"`
+	// GRPCTLSCACertPath is the path to the CA certificate used to verify the gRPC collector
+	// certificate. If unset, the system CAs are used.
+	GRPCTLSCACertPath string `env:"GRPC_TLS_CA_CERT_PATH"`
+	// GRPCTLSUserCertPath is the path to the user (client) certificate for gRPC mTLS connections
+	GRPCTLSUserCertPath string `env:"GRPC_TLS_USER_CERT_PATH"`
+	// GRPCTLSUserKeyPath is the path to the user (client) private key for gRPC mTLS connections
+	GRPCTLSUserKeyPath string `env:"GRPC_TLS_USER_KEY_PATH"`
+	// GRPCTLSServerName overrides the server name that is verified against the gRPC collector
+	// certificate. If unset, the host of each collector is used.
+	GRPCTLSServerName string `env:"GRPC_TLS_SERVER_NAME"`
 	// Interfaces contains the interface names from where flows will be collected. If empty, the agent
 	// will fetch all the interfaces in the system, excepting the ones listed in ExcludeInterfaces.
 	// If an entry is enclosed by slashes (e.g. `/br-/`), it will match as regular expression,
@@ -67,6 +130,41 @@ type Config struct {
 	// should be listened on. This allows users to specify interfaces without knowing the OS-assigned interface names.
 	// Exclusive with Interfaces/ExcludeInterfaces.
 	InterfaceIPs []string `env:"INTERFACE_IPS" envSeparator:","`
+	// InterfaceTypes contains the link types (as reported by netlink, e.g. device, veth, bridge,
+	// bond, vxlan) of the interfaces from where flows will be collected. If empty, any link type
+	// is accepted, excepting the ones listed in ExcludeInterfaceTypes. Physical NICs have the
+	// "device" type. Entries enclosed by slashes are matched as regular expressions.
+	// It is combined with the other interface selection criteria.
+	InterfaceTypes []string `env:"INTERFACE_TYPES" envSeparator:","`
+	// ExcludeInterfaceTypes contains the link types of the interfaces that will be excluded from
+	// flow tracing. Entries enclosed by slashes are matched as regular expressions.
+	ExcludeInterfaceTypes []string `env:"EXCLUDE_INTERFACE_TYPES" envSeparator:","`
+	// InterfaceMasters contains the names of the master devices (e.g. bridges or bonds) whose
+	// slave interfaces will be traced. If empty, any interface is accepted, excepting the slaves
+	// of the devices listed in ExcludeInterfaceMasters. Entries enclosed by slashes are matched as
+	// regular expressions. It is combined with the other interface selection criteria.
+	InterfaceMasters []string `env:"INTERFACE_MASTERS" envSeparator:","`
+	// ExcludeInterfaceMasters contains the names of the master devices whose slave interfaces will
+	// be excluded from flow tracing. Entries enclosed by slashes are matched as regular expressions.
+	ExcludeInterfaceMasters []string `env:"EXCLUDE_INTERFACE_MASTERS" envSeparator:","`
+	// InterfaceSelector is an expression that selects the interfaces from where flows will be
+	// collected by their name, IP, link type, master device and network namespace name, with
+	// allow and deny rules. It is a list of rules separated by semicolons, each one starting with
+	// "allow" or "deny" and followed by space-separated criterion=value pairs, e.g.
+	// "allow type=physical netns= ; allow type=veth netns=/^cni-/ ; deny master=/^bond/".
+	// If set, the Interfaces, ExcludeInterfaces, InterfaceIPs, InterfaceTypes, ExcludeInterfaceTypes,
+	// InterfaceMasters and ExcludeInterfaceMasters properties are ignored.
+	InterfaceSelector string `env:"INTERFACE_SELECTOR"`
+	// InterfaceProfiles defines per-interface settings: which hooks to attach and which optional
+	// features apply. It is a list of profiles separated by semicolons, each one made of
+	// space-separated criterion=value pairs, as in the InterfaceSelector rules, followed by "=>"
+	// and space-separated setting=value pairs, e.g. "type=veth => hooks=egress dns=false".
+	// The first profile that matches an interface applies. The settings that a profile doesn't
+	// define, and the interfaces matching no profile, follow the global configuration.
+	InterfaceProfiles string `env:"INTERFACE_PROFILES"`
+	// EnableInterfaceMetadata adds to the flows the link attributes of their interface: link type,
+	// MAC address, MTU, master device and, for veth interfaces, the index of the peer interface.
+	EnableInterfaceMetadata bool `env:"ENABLE_INTERFACE_METADATA" envDefault:"false"`
 	// ExporterBufferLength establishes the length of the buffer of flow batches (not individual flows)
 	// that can be accumulated before the Kafka or GRPC exporter. When this buffer is full (e.g.
 	// because the Kafka or GRPC endpoint is slow), incoming flow batches will be dropped. If unset,
@@ -91,8 +189,44 @@ type Config struct {
 	DeduperFCExpiry time.Duration `env:"DEDUPER_FC_EXPIRY"`
 	// DeduperJustMark will just mark duplicates (boolean field) instead of dropping them.
 	DeduperJustMark bool `env:"DEDUPER_JUST_MARK" envDefault:"false"`
-	// DeduperMerge will merge duplicated flows and generate list of interfaces and direction pairs
+	// DeduperMerge will merge duplicated flows and generate list of interfaces and direction pairs,
+	// with the bytes, packets and drops of the flow in each of them
 	DeduperMerge bool `env:"DEDUPER_MERGE" envDefault:"true"`
+	// DeduperPreference tells which interface the deduplicator keeps when a flow is observed from
+	// several interfaces. Accepted values are "none" (default: the first interface the flow is
+	// received from), "physical" (physical NICs), "veth" (veth interfaces) and "interfaces" (the
+	// interfaces listed in DeduperPreferredInterfaces). The preferred interface replaces the
+	// interface that reported a flow until then, as soon as it observes the flow.
+	DeduperPreference string `env:"DEDUPER_PREFERENCE" envDefault:"none"`
+	// DeduperPreferredInterfaces is the list of interfaces that the deduplicator prefers when
+	// DeduperPreference is "interfaces", from the most to the least preferred. Entries enclosed by
+	// slashes are matched as regular expressions.
+	DeduperPreferredInterfaces []string `env:"DEDUPER_PREFERRED_INTERFACES" envSeparator:","`
+	// EnableBiflow enables the stitching of the flows with their reverse flows, observed from the
+	// same interface during the same BiflowWindow, into bidirectional flows (RFC 5103). The
+	// initiator of the connection is inferred from the TCP flags.
+	EnableBiflow bool `env:"ENABLE_BIFLOW" envDefault:"false"`
+	// BiflowWindow is the period during which the flows are matched with their reverse flows
+	BiflowWindow time.Duration `env:"BIFLOW_WINDOW" envDefault:"10s"`
+	// BiflowMaxFlows is the number of pending flows that triggers their export before the end of
+	// the window
+	BiflowMaxFlows int `env:"BIFLOW_MAX_FLOWS" envDefault:"50000"`
+	// EnableAggregation enables the userspace aggregation of the flows before their export. The
+	// flows are re-aggregated by their ID, excluding the AggregationIgnore fields and with the
+	// addresses collapsed into the AggregationCIDRs.
+	EnableAggregation bool `env:"ENABLE_AGGREGATION" envDefault:"false"`
+	// AggregationWindow is the period after which the aggregated flows are exported
+	AggregationWindow time.Duration `env:"AGGREGATION_WINDOW" envDefault:"30s"`
+	// AggregationMaxFlows is the number of aggregated flows that triggers their export before the
+	// end of the window
+	AggregationMaxFlows int `env:"AGGREGATION_MAX_FLOWS" envDefault:"50000"`
+	// AggregationIgnore is the list of fields excluded from the aggregation key. Accepted values are
+	// "ephemeral_port", "src_port", "dst_port", "macs", "interface" and "direction".
+	AggregationIgnore []string `env:"AGGREGATION_IGNORE" envSeparator:"," envDefault:"ephemeral_port,macs"`
+	// AggregationCIDRs are the CIDRs that the flow addresses are collapsed into
+	AggregationCIDRs []string `env:"AGGREGATION_CIDRS" envSeparator:","`
+	// AggregationEphemeralPortMin is the lowest port of the ephemeral ports range
+	AggregationEphemeralPortMin uint16 `env:"AGGREGATION_EPHEMERAL_PORT_MIN" envDefault:"32768"`
 	// Direction allows selecting which flows to trace according to its direction. Accepted values
 	// are "ingress", "egress" or "both" (default).
 	Direction string `env:"DIRECTION" envDefault:"both"`
@@ -127,6 +261,19 @@ type Config struct {
 	// KafkaCompression sets the compression codec to be used to compress messages. The accepted
 	// values are: none (default), gzip, snappy, lz4, zstd.
 	KafkaCompression string `env:"KAFKA_COMPRESSION" envDefault:"none"`
+	// KafkaFormat is the encoding of the Kafka messages: protobuf (default), json or protobuf-batch
+	KafkaFormat string `env:"KAFKA_FORMAT" envDefault:"protobuf"`
+	// KafkaKey is the partition key of the Kafka messages: conversation (default), source-ip,
+	// agent-ip or none
+	KafkaKey string `env:"KAFKA_KEY" envDefault:"conversation"`
+	// KafkaHeaders adds the agent-ip, schema-version and sampling headers to the Kafka messages
+	KafkaHeaders bool `env:"KAFKA_HEADERS" envDefault:"false"`
+	// KafkaMessageMaxFlows is the maximum number of flows of each message, when KafkaFormat is
+	// protobuf-batch
+	KafkaMessageMaxFlows int `env:"KAFKA_MESSAGE_MAX_FLOWS" envDefault:"1000"`
+	// KafkaTopicRoutes is a comma-separated list of rule=topic routes, sending the flows that match
+	// the rule to the topic instead of KafkaTopic. Accepted rules are: drops, dns and rtt.
+	KafkaTopicRoutes []string `env:"KAFKA_TOPIC_ROUTES" envSeparator:","`
 	// KafkaEnableTLS set true to enable TLS
 	KafkaEnableTLS bool `env:"KAFKA_ENABLE_TLS" envDefault:"false"`
 	// KafkaTLSInsecureSkipVerify skips server certificate verification in TLS connections
@@ -145,6 +292,110 @@ type Config struct {
 	KafkaSASLClientIDPath string `env:"KAFKA_SASL_CLIENT_ID_PATH"`
 	// KafkaSASLClientSecretPath is the path to the client secret (password) for SASL auth
 	KafkaSASLClientSecretPath string `env:"KAFKA_SASL_CLIENT_SECRET_PATH"`
+	// IPFIXTemplateRefresh is the period to send again the IPFIX templates over UDP
+	IPFIXTemplateRefresh time.Duration `env:"IPFIX_TEMPLATE_REFRESH" envDefault:"1m"`
+	// IPFIXStatsInterval is the period to export the IPFIX exporting process statistics as
+	// options records. Zero disables them, as some collectors reject the options templates.
+	IPFIXStatsInterval time.Duration `env:"IPFIX_STATS_INTERVAL" envDefault:"0s"`
+	// IPFIXMaxMessageSize is the maximum size of the IPFIX messages, where the flows are batched.
+	// It must fit in the 16-bit message length, and be big enough for the largest template set.
+	IPFIXMaxMessageSize int `env:"IPFIX_MAX_MESSAGE_SIZE" envDefault:"1400"`
+	// IPFIXFileDirectory is the directory where the IPFIX files are written, when the EXPORT
+	// variable is set to "ipfix+file"
+	IPFIXFileDirectory string `env:"IPFIX_FILE_DIRECTORY"`
+	// IPFIXFileMaxSize is the size, in bytes, after which the current IPFIX file is rotated.
+	// Zero disables the size-based rotation.
+	IPFIXFileMaxSize int64 `env:"IPFIX_FILE_MAX_SIZE" envDefault:"104857600"`
+	// IPFIXFileRotationInterval is the maximum age of the current IPFIX file before it is rotated.
+	// Zero disables the time-based rotation.
+	IPFIXFileRotationInterval time.Duration `env:"IPFIX_FILE_ROTATION_INTERVAL" envDefault:"1h"`
+	// IPFIXFileCompress compresses the rotated IPFIX files with gzip
+	IPFIXFileCompress bool `env:"IPFIX_FILE_COMPRESS" envDefault:"true"`
+	// IPFIXFileMaxFiles is the maximum number of IPFIX files kept in the directory. The oldest
+	// files are removed first. Zero keeps all the files.
+	IPFIXFileMaxFiles int `env:"IPFIX_FILE_MAX_FILES" envDefault:"24"`
+	// JSONPretty indents the JSON flows over several lines, when the EXPORT variable is set to
+	// "json+stdout" or "json+file"
+	JSONPretty bool `env:"JSON_PRETTY" envDefault:"false"`
+	// JSONFields is the list of the flow fields written as JSON. Empty writes all the fields.
+	JSONFields []string `env:"JSON_FIELDS" envSeparator:","`
+	// JSONFileDirectory is the directory where the JSON files are written, when the EXPORT
+	// variable is set to "json+file"
+	JSONFileDirectory string `env:"JSON_FILE_DIRECTORY"`
+	// JSONFileMaxSize is the size, in bytes, after which the current JSON file is rotated.
+	// Zero disables the size-based rotation.
+	JSONFileMaxSize int64 `env:"JSON_FILE_MAX_SIZE" envDefault:"104857600"`
+	// JSONFileRotationInterval is the maximum age of the current JSON file before it is rotated.
+	// Zero disables the time-based rotation.
+	JSONFileRotationInterval time.Duration `env:"JSON_FILE_ROTATION_INTERVAL" envDefault:"1h"`
+	// JSONFileCompress compresses the rotated JSON files with gzip
+	JSONFileCompress bool `env:"JSON_FILE_COMPRESS" envDefault:"true"`
+	// JSONFileMaxFiles is the maximum number of JSON files kept in the directory. The oldest
+	// files are removed first. Zero keeps all the files.
+	JSONFileMaxFiles int `env:"JSON_FILE_MAX_FILES" envDefault:"24"`
+	// NetFlowSourceID is the engine ID of the NetFlow v5 packets, at most 255, and the source ID of the
+	// NetFlow v9 packets
+	NetFlowSourceID uint32 `env:"NETFLOW_SOURCE_ID" envDefault:"0"`
+	// NetFlowTemplateRefresh is the period to send again the NetFlow v9 templates
+	NetFlowTemplateRefresh time.Duration `env:"NETFLOW_TEMPLATE_REFRESH" envDefault:"1m"`
+	// NetFlowAgentIPAsSource sends the NetFlow packets from the agent IP, so collectors identify
+	// the agent by it. If the agent IP can't be bound, the default source address is used.
+	NetFlowAgentIPAsSource bool `env:"NETFLOW_AGENT_IP_AS_SOURCE" envDefault:"true"`
+	// SFlowSubAgentID distinguishes the sFlow datagrams of several agents with the same agent IP
+	SFlowSubAgentID uint32 `env:"SFLOW_SUB_AGENT_ID" envDefault:"0"`
+	// SFlowCountersInterval is the period to send the sFlow interface counter samples. Zero
+	// disables them.
+	SFlowCountersInterval time.Duration `env:"SFLOW_COUNTERS_INTERVAL" envDefault:"30s"`
+	// SFlowMaxHeaderSize is the maximum number of bytes of each packet header sampled by the
+	// Packets agent
+	SFlowMaxHeaderSize int `env:"SFLOW_MAX_HEADER_SIZE" envDefault:"128"`
+	// OTLPEndpoint is the host:port address of the OpenTelemetry collector, when the EXPORT
+	// variable is set to "otlp"
+	OTLPEndpoint string `env:"OTLP_ENDPOINT"`
+	// OTLPProtocol is the OTLP transport protocol: grpc or http/protobuf
+	OTLPProtocol string `env:"OTLP_PROTOCOL" envDefault:"grpc"`
+	// OTLPHTTPPath is the path of the OTLP logs endpoint, when OTLPProtocol is http/protobuf
+	OTLPHTTPPath string `env:"OTLP_HTTP_PATH" envDefault:"/v1/logs"`
+	// OTLPHeaders is a comma-separated list of key=value headers sent with each OTLP request
+	// (e.g. for authentication)
+	OTLPHeaders []string `env:"OTLP_HEADERS" envSeparator:","`
+	// OTLPCompression is the compression of the OTLP requests: gzip or none
+	OTLPCompression string `env:"OTLP_COMPRESSION" envDefault:"gzip"`
+	// OTLPTimeout is the maximum time to wait for each OTLP request to be accepted
+	OTLPTimeout time.Duration `env:"OTLP_TIMEOUT" envDefault:"10s"`
+	// OTLPBatchMaxRecords is the maximum number of flows per OTLP request
+	OTLPBatchMaxRecords int `env:"OTLP_BATCH_MAX_RECORDS" envDefault:"1000"`
+	// OTLPBatchTimeout is the maximum time that the flows are buffered before being sent, while
+	// they don't reach OTLPBatchMaxRecords. Zero sends the flows as soon as they are evicted.
+	OTLPBatchTimeout time.Duration `env:"OTLP_BATCH_TIMEOUT" envDefault:"1s"`
+	// OTLPEnableTLS set true to enable TLS in the connections to the OpenTelemetry collector
+	OTLPEnableTLS bool `env:"OTLP_ENABLE_TLS" envDefault:"false"`
+	// OTLPTLSInsecureSkipVerify skips server certificate verification in OTLP TLS connections
+	OTLPTLSInsecureSkipVerify bool `env:"OTLP_TLS_INSECURE_SKIP_VERIFY" envDefault:"false"`
+	// OTLPTLSCACertPath is the path to the CA certificate used to verify the OpenTelemetry
+	// collector certificate. If unset, the system CAs are used.
+	OTLPTLSCACertPath string `env:"OTLP_TLS_CA_CERT_PATH"`
+	// OTLPTLSUserCertPath is the path to the user (client) certificate for OTLP mTLS connections
+	OTLPTLSUserCertPath string `env:"OTLP_TLS_USER_CERT_PATH"`
+	// OTLPTLSUserKeyPath is the path to the user (client) private key for OTLP mTLS connections
+	OTLPTLSUserKeyPath string `env:"OTLP_TLS_USER_KEY_PATH"`
+	// SyslogFormat is the rendering of the flows in the syslog messages: structured or cef
+	SyslogFormat string `env:"SYSLOG_FORMAT" envDefault:"structured"`
+	// SyslogFacility is the facility of the syslog messages, from 0 to 23. Defaults to local0.
+	SyslogFacility int `env:"SYSLOG_FACILITY" envDefault:"16"`
+	// SyslogRateLimit is the maximum number of syslog messages per second. The flows beyond it are
+	// dropped. Zero disables the rate limiting.
+	SyslogRateLimit float64 `env:"SYSLOG_RATE_LIMIT" envDefault:"1000"`
+	// SyslogRateBurst is the maximum number of syslog messages sent at once above the rate limit
+	SyslogRateBurst int `env:"SYSLOG_RATE_BURST" envDefault:"2000"`
+	// SyslogTLSInsecureSkipVerify skips server certificate verification in syslog TLS connections
+	SyslogTLSInsecureSkipVerify bool `env:"SYSLOG_TLS_INSECURE_SKIP_VERIFY" envDefault:"false"`
+	// SyslogTLSCACertPath is the path to the CA certificate used to verify the syslog server
+	SyslogTLSCACertPath string `env:"SYSLOG_TLS_CA_CERT_PATH"`
+	// SyslogTLSUserCertPath is the path to the user (client) certificate for syslog mTLS connections
+	SyslogTLSUserCertPath string `env:"SYSLOG_TLS_USER_CERT_PATH"`
+	// SyslogTLSUserKeyPath is the path to the user (client) private key for syslog mTLS connections
+	SyslogTLSUserKeyPath string `env:"SYSLOG_TLS_USER_KEY_PATH"`
 	// ProfilePort sets the listening port for Go's Pprof tool. If it is not set, profile is disabled
 	ProfilePort int `env:"PROFILE_PORT"`
 	// Flowlogs-pipeline configuration as YAML or JSON, used when export is "direct-flp". Cf https://github.com/netobserv/flowlogs-pipeline
@@ -159,6 +410,11 @@ type Config struct {
 	ForceGC bool `env:"FORCE_GARBAGE_COLLECTION" envDefault:"true"`
 	// EnablePktDrops enable Packet drops eBPF hook to account for dropped flows
 	EnablePktDrops bool `env:"ENABLE_PKT_DROPS" envDefault:"false"`
+	// EnablePktDropStacks records the kernel stack of the packet drops, when EnablePktDrops is set
+	EnablePktDropStacks bool `env:"ENABLE_PKT_DROP_STACKS" envDefault:"false"`
+	// EnableQdiscTracking measures the time that the egress packets wait in the qdiscs and, when
+	// EnablePktDrops is set, counts the packets that the qdiscs drop
+	EnableQdiscTracking bool `env:"ENABLE_QDISC_TRACKING" envDefault:"false"`
 	// EnableDNSTracking enable DNS tracking eBPF hook to track dns query/response flows
 	EnableDNSTracking bool `env:"ENABLE_DNS_TRACKING" envDefault:"false"`
 	// StaleEntriesEvictTimeout specifies the maximum duration that stale entries are kept
@@ -178,6 +434,16 @@ type Config struct {
 	MetricsTLSKeyPath string `env:"METRICS_TLS_KEY_PATH"`
 	// MetricsPrefix is the prefix of the metrics that are sent to the server.
 	MetricsPrefix string `env:"METRICS_PREFIX" envDefault:"ebpf_agent_"`
+	// FlowMetricsLabels are the labels of the flow metrics, when the EXPORT variable is set to
+	// "prometheus". Accepted values are: src_subnet, dst_subnet, protocol, interface, direction.
+	FlowMetricsLabels []string `env:"FLOW_METRICS_LABELS" envSeparator:"," envDefault:"src_subnet,dst_subnet,protocol,interface,direction"`
+	// FlowMetricsCIDRs are the CIDRs that the addresses are grouped by in the flow metrics subnet
+	// labels. If empty, the addresses are grouped by their FlowMetricsSubnetPrefixV4/V6 first bits.
+	FlowMetricsCIDRs []string `env:"FLOW_METRICS_CIDRS" envSeparator:","`
+	// FlowMetricsSubnetPrefixV4 is the prefix length of the IPv4 subnet labels
+	FlowMetricsSubnetPrefixV4 int `env:"FLOW_METRICS_SUBNET_PREFIX_V4" envDefault:"16"`
+	// FlowMetricsSubnetPrefixV6 is the prefix length of the IPv6 subnet labels
+	FlowMetricsSubnetPrefixV6 int `env:"FLOW_METRICS_SUBNET_PREFIX_V6" envDefault:"48"`
 
 	// EnableFlowFilter enables flow filter, default is false.
 	EnableFlowFilter bool `env:"ENABLE_FLOW_FILTER" envDefault:"false"`
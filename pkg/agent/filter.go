@@ -6,18 +6,61 @@ import (
 	"net/netip"
 	"regexp"
 	"strings"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ifaces"
+)
+
+// Interface selector criteria
+const (
+	selectName   = "name"
+	selectIP     = "ip"
+	selectType   = "type"
+	selectMaster = "master"
+	selectNetNS  = "netns"
+	// selectPhysical is a pseudo link type that matches the physical NICs
+	selectPhysical = "physical"
 )
 
+var isRegexp = regexp.MustCompile("^/(.*)/$")
+
+// InterfaceFilter tells whether the agent must trace an interface, given the interface and its
+// link attributes. The link is nil if its attributes are unknown.
 type InterfaceFilter interface {
-	Allowed(iface string) (bool, error)
+	Allowed(iface ifaces.Interface, link *ifaces.Link) (bool, error)
 }
 
-type ipInterfaceFilter struct {
-	allowedIPs []netip.Prefix
-	// Almost always going to be a wrapper around getting
-	// the interface from net.InterfaceByName and then calling
-	// .Addrs() on the interface
-	ipsFromIface func(ifaceName string) ([]netip.Addr, error)
+// initInterfaceFilter builds the interface selector shared by the flows and packets agents. It
+// is parsed from the INTERFACE_SELECTOR expression if set, or otherwise built from the name, IP
+// and link criteria of the configuration, which must be all matched. netnsName returns the
+// name of the network namespace of an interface.
+func initInterfaceFilter(cfg *Config, netnsName func(ifaces.Interface) string) (InterfaceFilter, error) {
+	var sel interfaceSelector
+	var err error
+	if cfg.InterfaceSelector != "" {
+		if sel, err = parseInterfaceSelector(cfg.InterfaceSelector, IPsFromInterface); err != nil {
+			return nil, fmt.Errorf("configuring interface selector: %w", err)
+		}
+	} else {
+		if len(cfg.InterfaceIPs) > 0 && (len(cfg.Interfaces) > 0 || len(cfg.ExcludeInterfaces) > 0) {
+			return nil, fmt.Errorf("INTERFACES/EXCLUDE_INTERFACES and INTERFACE_IPS are mutually exclusive")
+		}
+		allow := map[string][]string{
+			selectName:   cfg.Interfaces,
+			selectIP:     cfg.InterfaceIPs,
+			selectType:   cfg.InterfaceTypes,
+			selectMaster: cfg.InterfaceMasters,
+		}
+		deny := map[string][]string{
+			selectName:   cfg.ExcludeInterfaces,
+			selectType:   cfg.ExcludeInterfaceTypes,
+			selectMaster: cfg.ExcludeInterfaceMasters,
+		}
+		if sel, err = newInterfaceSelector(allow, deny, IPsFromInterface); err != nil {
+			return nil, fmt.Errorf("configuring interface filters: %w", err)
+		}
+	}
+	sel.netnsName = netnsName
+	return &sel, nil
 }
 
 // Default function for getting the list of IPs configured
@@ -43,110 +86,248 @@ func IPsFromInterface(ifaceName string) ([]netip.Addr, error) {
 	return interfaceAddrs, nil
 }
 
-// initIPInterfaceFilter allows filtering network interfaces that are accepted/excluded by the user,
-// according to the provided INTERFACE_IPS from the configuration. It allows interfaces where at least
-// one of the provided CIDRs are associated with it.
-func initIPInterfaceFilter(ips []string, ipsFromIface func(ifaceName string) ([]netip.Addr, error)) (ipInterfaceFilter, error) {
-	ipIfaceFilter := ipInterfaceFilter{}
-	ipIfaceFilter.ipsFromIface = ipsFromIface
+// stringMatcher matches a string exactly, or by regular expression if it was defined between
+// slashes
+type stringMatcher struct {
+	exact string
+	re    *regexp.Regexp
+}
 
-	for _, ip := range ips {
-		prefix, err := netip.ParsePrefix(ip)
+func newStringMatcher(definition string) (stringMatcher, error) {
+	definition = strings.Trim(definition, " ")
+	// the user defined a /regexp/ between slashes: compile and store it as regular expression
+	if sm := isRegexp.FindStringSubmatch(definition); len(sm) > 1 {
+		re, err := regexp.Compile(sm[1])
 		if err != nil {
-			return ipInterfaceFilter{}, fmt.Errorf("error parsing given ip: %s: %w", ip, err)
+			return stringMatcher{}, fmt.Errorf("wrong regexp %q: %w", definition, err)
 		}
-		ipIfaceFilter.allowedIPs = append(ipIfaceFilter.allowedIPs, prefix)
+		return stringMatcher{re: re}, nil
 	}
-
-	return ipIfaceFilter, nil
+	// otherwise, store it as exact match definition
+	return stringMatcher{exact: definition}, nil
 }
 
-func (f *ipInterfaceFilter) Allowed(iface string) (bool, error) {
-	ifaceAddrs, err := f.ipsFromIface(iface)
-	if err != nil {
-		return false, fmt.Errorf("error calling ipsFromIface(): %w", err)
+func (m *stringMatcher) matches(s string) bool {
+	if m.re != nil {
+		return m.re.MatchString(s)
 	}
+	return s == m.exact
+}
 
-	for _, ifaceAddr := range ifaceAddrs {
-		for _, allowedPrefix := range f.allowedIPs {
-			if allowedPrefix.Contains(ifaceAddr) {
-				return true, nil
-			}
+// selectorRule is a set of criteria that an interface must all match. The values of the same
+// criterion are alternatives. An empty criterion matches any interface.
+type selectorRule struct {
+	names   []stringMatcher
+	ips     []netip.Prefix
+	types   []stringMatcher
+	masters []stringMatcher
+	netns   []stringMatcher
+}
+
+func (r *selectorRule) empty() bool {
+	return len(r.names)+len(r.ips)+len(r.types)+len(r.masters)+len(r.netns) == 0
+}
+
+func (r *selectorRule) add(criterion, value string) error {
+	if criterion == selectIP {
+		prefix, err := netip.ParsePrefix(strings.Trim(value, " "))
+		if err != nil {
+			return fmt.Errorf("error parsing given ip: %s: %w", value, err)
 		}
+		r.ips = append(r.ips, prefix)
+		return nil
 	}
-	return false, nil
+	m, err := newStringMatcher(value)
+	if err != nil {
+		return fmt.Errorf("wrong interface %s: %w", criterion, err)
+	}
+	switch criterion {
+	case selectName:
+		r.names = append(r.names, m)
+	case selectType:
+		r.types = append(r.types, m)
+	case selectMaster:
+		r.masters = append(r.masters, m)
+	case selectNetNS:
+		r.netns = append(r.netns, m)
+	default:
+		return fmt.Errorf("unknown interface selection criterion %q. Accepted criteria are: %s, %s, %s, %s, %s",
+			criterion, selectName, selectIP, selectType, selectMaster, selectNetNS)
+	}
+	return nil
 }
 
-type regexpInterfaceFilter struct {
-	allowedRegexpes  []*regexp.Regexp
-	allowedMatches   []string
-	excludedRegexpes []*regexp.Regexp
-	excludedMatches  []string
+// interfaceSelector selects the network interfaces that match at least one of the allow rules
+// (or any interface if there are no allow rules), and none of the deny rules.
+type interfaceSelector struct {
+	allow []selectorRule
+	deny  []selectorRule
+	// Almost always going to be a wrapper around getting
+	// the interface from net.InterfaceByName and then calling
+	// .Addrs() on the interface
+	ipsFromIface func(ifaceName string) ([]netip.Addr, error)
+	// netnsName returns the name of the network namespace of an interface. If nil, all the
+	// interfaces are considered as belonging to an unnamed namespace.
+	netnsName func(iface ifaces.Interface) string
 }
 
-// initRegexpInterfaceFilter allows filtering network interfaces that are accepted/excluded by the user,
-// according to the provided allowed and excluded interfaces from the configuration. It allows
-// matching by exact string or by regular expression
-func initRegexpInterfaceFilter(allowed, excluded []string) (regexpInterfaceFilter, error) {
-	var isRegexp = regexp.MustCompile("^/(.*)/$")
-
-	itf := regexpInterfaceFilter{}
-	for _, definition := range allowed {
-		definition = strings.Trim(definition, " ")
-		// the user defined a /regexp/ between slashes: compile and store it as regular expression
-		if sm := isRegexp.FindStringSubmatch(definition); len(sm) > 1 {
-			re, err := regexp.Compile(sm[1])
-			if err != nil {
-				return itf, fmt.Errorf("wrong interface regexp %q: %w", definition, err)
+// newInterfaceSelector creates a selector with a single allow rule, whose criteria are the
+// allowed values, and a deny rule for each denied value, so any denied value excludes an
+// interface.
+func newInterfaceSelector(allowed, denied map[string][]string, ipsFromIface func(ifaceName string) ([]netip.Addr, error)) (interfaceSelector, error) {
+	sel := interfaceSelector{ipsFromIface: ipsFromIface}
+	var allow selectorRule
+	for criterion, values := range allowed {
+		for _, value := range values {
+			if err := allow.add(criterion, value); err != nil {
+				return sel, err
 			}
-			itf.allowedRegexpes = append(itf.allowedRegexpes, re)
-		} else {
-			// otherwise, store it as exact match definition
-			itf.allowedMatches = append(itf.allowedMatches, definition)
 		}
 	}
-
-	for _, definition := range excluded {
-		definition = strings.Trim(definition, " ")
-		// the user defined a /regexp/ between slashes: compile and store it as regexp
-		if sm := isRegexp.FindStringSubmatch(definition); len(sm) > 1 {
-			re, err := regexp.Compile(sm[1])
-			if err != nil {
-				return itf, fmt.Errorf("wrong excluded interface regexp %q: %w", definition, err)
+	if !allow.empty() {
+		sel.allow = append(sel.allow, allow)
+	}
+	for criterion, values := range denied {
+		for _, value := range values {
+			var deny selectorRule
+			if err := deny.add(criterion, value); err != nil {
+				return sel, fmt.Errorf("excluded interface: %w", err)
 			}
-			itf.excludedRegexpes = append(itf.excludedRegexpes, re)
-		} else {
-			// otherwise, store it as exact match definition
-			itf.excludedMatches = append(itf.excludedMatches, definition)
+			sel.deny = append(sel.deny, deny)
 		}
 	}
-
-	return itf, nil
+	return sel, nil
 }
 
-func (itf *regexpInterfaceFilter) Allowed(name string) (bool, error) {
-	// if the allowed list is empty, any interface is allowed except if it matches the exclusion list
-	allowed := len(itf.allowedRegexpes)+len(itf.allowedMatches) == 0
-	// otherwise, we check if it appears in the allowed lists (both exact match and regexp)
-	for i := 0; !allowed && i < len(itf.allowedMatches); i++ {
-		allowed = allowed || name == itf.allowedMatches[i]
+// parseInterfaceSelector parses a selector expression: a list of rules separated by semicolons.
+// Each rule starts with "allow" or "deny", followed by space-separated criterion=value pairs, e.g.
+//
+//	allow type=physical netns= ; allow type=veth netns=/^cni-/ ; deny master=/^bond/
+func parseInterfaceSelector(expr string, ipsFromIface func(ifaceName string) ([]netip.Addr, error)) (interfaceSelector, error) {
+	sel := interfaceSelector{ipsFromIface: ipsFromIface}
+	for _, definition := range strings.Split(expr, ";") {
+		fields := strings.Fields(definition)
+		if len(fields) == 0 {
+			continue
+		}
+		var rule selectorRule
+		for _, field := range fields[1:] {
+			criterion, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return sel, fmt.Errorf("wrong interface selection criterion %q in rule %q. Expected criterion=value",
+					field, definition)
+			}
+			if err := rule.add(criterion, value); err != nil {
+				return sel, fmt.Errorf("in rule %q: %w", definition, err)
+			}
+		}
+		switch fields[0] {
+		case "allow":
+			sel.allow = append(sel.allow, rule)
+		case "deny":
+			if rule.empty() {
+				return sel, fmt.Errorf("deny rule %q has no criteria", definition)
+			}
+			sel.deny = append(sel.deny, rule)
+		default:
+			return sel, fmt.Errorf("wrong interface selection rule %q. It must start with allow or deny", definition)
+		}
 	}
-	for i := 0; !allowed && i < len(itf.allowedRegexpes); i++ {
-		allowed = allowed || itf.allowedRegexpes[i].MatchString(string(name))
+	return sel, nil
+}
+
+func (sel *interfaceSelector) Allowed(iface ifaces.Interface, link *ifaces.Link) (bool, error) {
+	// if the allow list is empty, any interface is allowed except if it matches a deny rule
+	allowed := len(sel.allow) == 0
+	for i := 0; !allowed && i < len(sel.allow); i++ {
+		matches, err := sel.matches(&sel.allow[i], iface, link)
+		if err != nil {
+			return false, err
+		}
+		allowed = matches
 	}
 	if !allowed {
 		return false, nil
 	}
-	// if the interface matches the allow lists, we still need to check that is not excluded
-	for _, match := range itf.excludedMatches {
-		if name == match {
+	// if the interface matches the allow rules, we still need to check that is not denied
+	for i := range sel.deny {
+		matches, err := sel.matches(&sel.deny[i], iface, link)
+		if err != nil {
+			return false, err
+		}
+		if matches {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (sel *interfaceSelector) matches(rule *selectorRule, iface ifaces.Interface, link *ifaces.Link) (bool, error) {
+	if len(rule.names) > 0 && !anyMatches(rule.names, iface.Name) {
+		return false, nil
+	}
+	if len(rule.types) > 0 && !typeMatches(rule.types, link) {
+		return false, nil
+	}
+	if len(rule.masters) > 0 {
+		master := ""
+		if link != nil {
+			master = link.Master
+		}
+		if !anyMatches(rule.masters, master) {
 			return false, nil
 		}
 	}
-	for _, re := range itf.excludedRegexpes {
-		if re.MatchString(string(name)) {
+	if len(rule.netns) > 0 {
+		name := ""
+		if sel.netnsName != nil {
+			name = sel.netnsName(iface)
+		}
+		if !anyMatches(rule.netns, name) {
 			return false, nil
 		}
 	}
+	if len(rule.ips) > 0 {
+		// only evaluated when the other criteria match, as it requires querying the interface
+		return sel.ipMatches(rule.ips, iface.Name)
+	}
 	return true, nil
 }
+
+func (sel *interfaceSelector) ipMatches(prefixes []netip.Prefix, ifaceName string) (bool, error) {
+	ifaceAddrs, err := sel.ipsFromIface(ifaceName)
+	if err != nil {
+		return false, fmt.Errorf("error calling ipsFromIface(): %w", err)
+	}
+	for _, ifaceAddr := range ifaceAddrs {
+		for _, allowedPrefix := range prefixes {
+			if allowedPrefix.Contains(ifaceAddr) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func anyMatches(matchers []stringMatcher, s string) bool {
+	for i := range matchers {
+		if matchers[i].matches(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeMatches checks the link type, including the physical pseudo-type. The interfaces with
+// unknown link attributes have an empty type.
+func typeMatches(matchers []stringMatcher, link *ifaces.Link) bool {
+	if link == nil {
+		return anyMatches(matchers, "")
+	}
+	for i := range matchers {
+		if matchers[i].re == nil && matchers[i].exact == selectPhysical && link.Physical() {
+			return true
+		}
+	}
+	return anyMatches(matchers, link.Type)
+}
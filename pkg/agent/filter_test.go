@@ -4,63 +4,67 @@ import (
 	"net/netip"
 	"testing"
 
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ifaces"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestInterfaces_DefaultConfig(t *testing.T) {
-	ifaces, err := initRegexpInterfaceFilter(nil, []string{"lo"})
+	filter, err := newInterfaceSelector(nil, map[string][]string{selectName: {"lo"}}, IPsFromInterface)
 	require.NoError(t, err)
 
 	// Allowed
 	for _, iface := range []string{"eth0", "br-0"} {
 		iface := iface
-		allowed, err := ifaces.Allowed(iface)
+		allowed, err := filter.Allowed(ifaces.Interface{Name: iface}, nil)
 		require.NoError(t, err)
 		assert.True(t, allowed)
 	}
 
 	// Not Allowed
-	allowed, err := ifaces.Allowed("lo")
+	allowed, err := filter.Allowed(ifaces.Interface{Name: "lo"}, nil)
 	require.NoError(t, err)
 	assert.False(t, allowed)
 }
 
 func TestInterfaceFilter_SelectingInterfaces_DefaultExclusion(t *testing.T) {
-	ifaces, err := initRegexpInterfaceFilter([]string{"eth0", "/^br-/"}, []string{"lo"})
+	filter, err := newInterfaceSelector(map[string][]string{selectName: {"eth0", "/^br-/"}},
+		map[string][]string{selectName: {"lo"}}, IPsFromInterface)
 	require.NoError(t, err)
 
 	// Allowed
 	for _, iface := range []string{"eth0", "br-0"} {
 		iface := iface
-		allowed, err := ifaces.Allowed(iface)
+		allowed, err := filter.Allowed(ifaces.Interface{Name: iface}, nil)
 		require.NoError(t, err)
 		assert.True(t, allowed)
 	}
 	// Not Allowed
 	for _, iface := range []string{"eth01", "abr-3", "lo"} {
 		iface := iface
-		allowed, err := ifaces.Allowed(iface)
+		allowed, err := filter.Allowed(ifaces.Interface{Name: iface}, nil)
 		require.NoError(t, err)
 		assert.False(t, allowed)
 	}
 }
 
 func TestInterfaceFilter_ExclusionTakesPriority(t *testing.T) {
-	ifaces, err := initRegexpInterfaceFilter([]string{"/^eth/", "/^br-/"}, []string{"eth1", "/^br-1/"})
+	filter, err := newInterfaceSelector(map[string][]string{selectName: {"/^eth/", "/^br-/"}},
+		map[string][]string{selectName: {"eth1", "/^br-1/"}}, IPsFromInterface)
 	require.NoError(t, err)
 
 	// Allowed
 	for _, iface := range []string{"eth0", "eth-10", "eth11", "br-2", "br-0"} {
 		iface := iface
-		allowed, err := ifaces.Allowed(iface)
+		allowed, err := filter.Allowed(ifaces.Interface{Name: iface}, nil)
 		require.NoError(t, err)
 		assert.True(t, allowed)
 	}
 	// Not Allowed
 	for _, iface := range []string{"eth1", "br-1", "br-10"} {
 		iface := iface
-		allowed, err := ifaces.Allowed(iface)
+		allowed, err := filter.Allowed(ifaces.Interface{Name: iface}, nil)
 		require.NoError(t, err)
 		assert.False(t, allowed)
 	}
@@ -89,21 +93,136 @@ func TestInterfaceFilter_InterfaceIPs(t *testing.T) {
 		}
 	}
 
-	ifaces, err := initIPInterfaceFilter([]string{"198.51.100.1/32", "2001:db8::1/128", "192.0.2.0/24"}, mockIPByIface)
+	filter, err := newInterfaceSelector(map[string][]string{
+		selectIP: {"198.51.100.1/32", "2001:db8::1/128", "192.0.2.0/24"},
+	}, nil, mockIPByIface)
 	require.NoError(t, err)
 
 	// Allowed
 	for _, iface := range []string{"eth0", "eth2", "eth4"} {
 		iface := iface
-		allowed, err := ifaces.Allowed(iface)
+		allowed, err := filter.Allowed(ifaces.Interface{Name: iface}, nil)
 		require.NoError(t, err)
 		assert.True(t, allowed)
 	}
 	// Not Allowed
 	for _, iface := range []string{"eth1", "eth3"} {
 		iface := iface
-		allowed, err := ifaces.Allowed(iface)
+		allowed, err := filter.Allowed(ifaces.Interface{Name: iface}, nil)
 		require.NoError(t, err)
 		assert.False(t, allowed)
 	}
 }
+
+func TestInterfaceFilter_LinkTypesAndMasters(t *testing.T) {
+	filter, err := initInterfaceFilter(&Config{
+		ExcludeInterfaces:       []string{"lo"},
+		InterfaceTypes:          []string{"device", "/^veth$/"},
+		ExcludeInterfaceMasters: []string{"/^bond/"},
+	}, nil)
+	require.NoError(t, err)
+
+	// Allowed
+	for _, link := range []*ifaces.Link{
+		{Type: "device"},
+		{Type: "veth", Master: "br-int", MasterIndex: 5},
+	} {
+		allowed, err := filter.Allowed(ifaces.Interface{Name: "eth0"}, link)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	// Not Allowed
+	for _, link := range []*ifaces.Link{
+		{Type: "bridge"},
+		{Type: "device", Master: "bond0", MasterIndex: 3},
+		nil,
+	} {
+		allowed, err := filter.Allowed(ifaces.Interface{Name: "eth0"}, link)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+	}
+	// the name filter still applies
+	allowed, err := filter.Allowed(ifaces.Interface{Name: "lo"}, &ifaces.Link{Type: "device"})
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestInterfaceSelector(t *testing.T) {
+	filter, err := initInterfaceFilter(&Config{
+		InterfaceSelector: "allow type=physical netns= ; allow type=veth netns=/^cni-/ ; deny master=/^bond/",
+		// ignored when the selector is set
+		Interfaces: []string{"eth0"},
+	}, func(iface ifaces.Interface) string {
+		if iface.Index > 10 {
+			return "cni-1234"
+		}
+		return ""
+	})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name    string
+		index   int
+		link    *ifaces.Link
+		allowed bool
+	}{
+		{name: "ens3", index: 2, link: &ifaces.Link{Type: "device"}, allowed: true},
+		{name: "veth1", index: 11, link: &ifaces.Link{Type: "veth", PeerIndex: 3}, allowed: true},
+		// bond slave
+		{name: "ens4", index: 3, link: &ifaces.Link{Type: "device", MasterIndex: 5, Master: "bond0"}},
+		// not in the root namespace
+		{name: "eth0", index: 12, link: &ifaces.Link{Type: "device"}},
+		// veth in the root namespace
+		{name: "veth2", index: 4, link: &ifaces.Link{Type: "veth"}},
+		{name: "lo", index: 1, link: &ifaces.Link{Type: "device", Loopback: true}},
+		{name: "br0", index: 6, link: &ifaces.Link{Type: "bridge"}},
+		{name: "unknown", index: 7},
+	} {
+		allowed, err := filter.Allowed(ifaces.Interface{Name: tc.name, Index: tc.index}, tc.link)
+		require.NoError(t, err)
+		assert.Equal(t, tc.allowed, allowed, tc.name)
+	}
+}
+
+func TestInterfaceSelector_NamesAndIPs(t *testing.T) {
+	mockIPByIface := func(iface string) ([]netip.Addr, error) {
+		switch iface {
+		case "eth0":
+			return []netip.Addr{netip.MustParseAddr("198.51.100.1")}, nil
+		case "eth1":
+			return []netip.Addr{netip.MustParseAddr("192.0.2.1")}, nil
+		default:
+			panic("unexpected interface name")
+		}
+	}
+	// the same criterion can be repeated to define alternatives
+	filter, err := parseInterfaceSelector("allow name=/^eth/ ip=198.51.100.0/24 ip=2001:db8::/32;allow name=br-ex",
+		mockIPByIface)
+	require.NoError(t, err)
+
+	for _, iface := range []string{"eth0", "br-ex"} {
+		allowed, err := filter.Allowed(ifaces.Interface{Name: iface}, nil)
+		require.NoError(t, err)
+		assert.True(t, allowed, iface)
+	}
+	// the IPs of the interfaces that don't match the other criteria are not queried
+	for _, iface := range []string{"eth1", "br-int"} {
+		allowed, err := filter.Allowed(ifaces.Interface{Name: iface}, nil)
+		require.NoError(t, err)
+		assert.False(t, allowed, iface)
+	}
+}
+
+func TestInterfaceSelector_WrongExpressions(t *testing.T) {
+	for _, expr := range []string{
+		"type=veth",
+		"allow kind=veth",
+		"allow type",
+		"allow name=/[/",
+		"allow ip=10.0.0.0/33",
+		"deny",
+	} {
+		_, err := parseInterfaceSelector(expr, IPsFromInterface)
+		assert.Error(t, err, expr)
+	}
+}
@@ -5,13 +5,16 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/http"
 
 	"github.com/netobserv/gopipes/pkg/node"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/ebpf"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/exporter"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	pktgrpc "github.com/netobserv/netobserv-ebpf-agent/pkg/grpc/packet"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/ifaces"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+	promo "github.com/netobserv/netobserv-ebpf-agent/pkg/prometheus"
 
 	"github.com/cilium/ebpf/perf"
 	"github.com/sirupsen/logrus"
@@ -22,8 +25,9 @@ type Packets struct {
 	cfg *Config
 
 	// input data providers
-	interfaces ifaces.Informer
+	interfaces *ifaces.Registerer
 	filter     InterfaceFilter
+	profiles   *interfaceProfiles
 	ebpf       ebpfPacketFetcher
 
 	// processing nodes to be wired in the buildAndStartPipeline method
@@ -35,13 +39,14 @@ type Packets struct {
 	interfaceNamer flow.InterfaceNamer
 	agentIP        net.IP
 
-	status Status
+	status      Status
+	promoServer *http.Server
 }
 
 type ebpfPacketFetcher interface {
 	io.Closer
-	Register(iface ifaces.Interface) error
-	AttachTCX(iface ifaces.Interface) error
+	Register(iface ifaces.Interface, profile *ebpf.InterfaceProfile) error
+	AttachTCX(iface ifaces.Interface, profile *ebpf.InterfaceProfile) error
 	LookupAndDeleteMap(*metrics.Metrics) map[int][]*byte
 	ReadPerf() (perf.Record, error)
 }
@@ -62,24 +67,24 @@ func PacketsAgent(cfg *Config) (*Packets, error) {
 		return nil, fmt.Errorf("acquiring Agent IP: %w", err)
 	}
 
+	// initialize metrics
+	m := metrics.NewMetrics(metricsSettings(cfg))
+
 	// configure selected exporter
-	packetexportFunc, err := buildPacketExporter(cfg)
+	packetexportFunc, err := buildPacketExporter(cfg, m, agentIP)
 	if err != nil {
 		return nil, err
 	}
 
-	ingress, egress := flowDirections(cfg)
 	debug := false
 	if cfg.LogLevel == logrus.TraceLevel.String() || cfg.LogLevel == logrus.DebugLevel.String() {
 		debug = true
 	}
 	ebpfConfig := &ebpf.FlowFetcherConfig{
-		EnableIngress: ingress,
-		EnableEgress:  egress,
-		Debug:         debug,
-		Sampling:      cfg.Sampling,
-		CacheMaxSize:  cfg.CacheMaxFlows,
-		EnablePCA:     cfg.EnablePCA,
+		Debug:        debug,
+		Sampling:     cfg.Sampling,
+		CacheMaxSize: cfg.CacheMaxFlows,
+		EnablePCA:    cfg.EnablePCA,
 		FilterConfig: &ebpf.FilterConfig{
 			FilterAction:          cfg.FilterAction,
 			FilterDirection:       cfg.FilterDirection,
@@ -97,7 +102,14 @@ func PacketsAgent(cfg *Config) (*Packets, error) {
 		return nil, err
 	}
 
-	return packetsAgent(cfg, informer, fetcher, packetexportFunc, agentIP)
+	packets, err := packetsAgent(cfg, informer, fetcher, packetexportFunc, agentIP)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MetricsEnable {
+		packets.promoServer = promo.InitializePrometheus(m.Settings)
+	}
+	return packets, nil
 }
 
 // packetssAgent is a private constructor with injectable dependencies, usable for tests
@@ -107,33 +119,19 @@ func packetsAgent(cfg *Config,
 	packetexporter node.TerminalFunc[[]*flow.PacketRecord],
 	agentIP net.IP,
 ) (*Packets, error) {
-	var filter InterfaceFilter
-
-	switch {
-	case len(cfg.InterfaceIPs) > 0 && (len(cfg.Interfaces) > 0 || len(cfg.ExcludeInterfaces) > 0):
-		return nil, fmt.Errorf("INTERFACES/EXCLUDE_INTERFACES and INTERFACE_IPS are mutually exclusive")
-
-	case len(cfg.InterfaceIPs) > 0:
-		// configure ip interface filter
-		f, err := initIPInterfaceFilter(cfg.InterfaceIPs, IPsFromInterface)
-		if err != nil {
-			return nil, fmt.Errorf("configuring interface ip filter: %w", err)
-		}
-		filter = &f
+	registerer := ifaces.NewRegisterer(informer, cfg.BuffersLength)
 
-	default:
-		// configure allow/deny regexp interfaces filter
-		f, err := initRegexpInterfaceFilter(cfg.Interfaces, cfg.ExcludeInterfaces)
-		if err != nil {
-			return nil, fmt.Errorf("configuring interface filters: %w", err)
-		}
-		filter = &f
+	filter, err := initInterfaceFilter(cfg, registerer.NetNSNameFor)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := initInterfaceProfiles(cfg, registerer.NetNSNameFor)
+	if err != nil {
+		return nil, err
 	}
 
-	registerer := ifaces.NewRegisterer(informer, cfg.BuffersLength)
-
-	interfaceNamer := func(ifIndex int) string {
-		iface, ok := registerer.IfaceNameForIndex(ifIndex)
+	interfaceNamer := func(netns uint32, ifIndex int) string {
+		iface, ok := registerer.IfaceNameForIndex(netns, ifIndex)
 		if !ok {
 			return "unknown"
 		}
@@ -148,6 +146,7 @@ func packetsAgent(cfg *Config,
 		ebpf:           fetcher,
 		interfaces:     registerer,
 		filter:         filter,
+		profiles:       profiles,
 		cfg:            cfg,
 		packetbuffer:   packetbuffer,
 		perfTracer:     perfTracer,
@@ -163,7 +162,18 @@ func buildGRPCPacketExporter(cfg *Config) (node.TerminalFunc[[]*flow.PacketRecor
 			cfg.TargetHost, cfg.TargetPort)
 	}
 	plog.Info("starting gRPC Packet send")
-	pcapStreamer, err := exporter.StartGRPCPacketSend(cfg.TargetHost, cfg.TargetPort)
+	options := []pktgrpc.ClientOption{
+		pktgrpc.WithKeepalive(cfg.GRPCKeepaliveTime, cfg.GRPCKeepaliveTimeout),
+		pktgrpc.WithMaxConnectDelay(cfg.GRPCReconnectMaxBackoff),
+	}
+	if cfg.GRPCEnableTLS {
+		tlsConfigs, err := buildGRPCTLSConfigs(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("building gRPC TLS configuration: %w", err)
+		}
+		options = append(options, pktgrpc.WithClientTLSConfig(tlsConfigs(cfg.TargetHost)))
+	}
+	pcapStreamer, err := exporter.StartGRPCPacketSend(cfg.TargetHost, cfg.TargetPort, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -171,10 +181,16 @@ func buildGRPCPacketExporter(cfg *Config) (node.TerminalFunc[[]*flow.PacketRecor
 	return pcapStreamer.ExportGRPCPackets, nil
 }
 
-func buildPacketExporter(cfg *Config) (node.TerminalFunc[[]*flow.PacketRecord], error) {
+func buildPacketExporter(cfg *Config, m *metrics.Metrics, agentIP net.IP) (node.TerminalFunc[[]*flow.PacketRecord], error) {
 	switch cfg.Export {
 	case "grpc":
 		return buildGRPCPacketExporter(cfg)
+	case "sflow+udp":
+		sflow, err := buildSFlowExporter(cfg, m, agentIP)
+		if err != nil {
+			return nil, err
+		}
+		return sflow.ExportPackets, nil
 	case "direct-flp":
 		return buildPacketDirectFLPExporter(cfg)
 	default:
@@ -212,6 +228,12 @@ func (p *Packets) Run(ctx context.Context) error {
 
 	plog.Debug("waiting for all nodes to finish their pending work")
 	<-graph.Done()
+	if p.promoServer != nil {
+		plog.Debug("closing prometheus server")
+		if err := p.promoServer.Close(); err != nil {
+			plog.WithError(err).Warn("error when closing prometheus server")
+		}
+	}
 
 	p.status = StatusStopped
 	plog.Info("Packets agent stopped")
@@ -269,7 +291,8 @@ func (p *Packets) buildAndStartPipeline(ctx context.Context) (*node.Terminal[[]*
 
 func (p *Packets) onInterfaceAdded(iface ifaces.Interface) {
 	// ignore interfaces that do not match the user configuration acceptance/exclusion lists
-	allowed, err := p.filter.Allowed(iface.Name)
+	link, _ := p.interfaces.LinkFor(iface)
+	allowed, err := p.filter.Allowed(iface, link)
 	if err != nil {
 		plog.WithField("[PCA]interface", iface).WithError(err).
 			Warn("couldn't determine if interface is allowed. Ignoring")
@@ -279,11 +302,18 @@ func (p *Packets) onInterfaceAdded(iface ifaces.Interface) {
 			Debug("[PCA]interface does not match the allow/exclusion filters. Ignoring")
 		return
 	}
-	plog.WithField("interface", iface).Info("interface detected. trying to attach TCX hook")
-	if err := p.ebpf.AttachTCX(iface); err != nil {
+	profile, err := p.profiles.For(iface, link)
+	if err != nil {
+		plog.WithField("[PCA]interface", iface).WithError(err).
+			Warn("couldn't determine the interface profile. Ignoring")
+		return
+	}
+	plog.WithField("interface", iface).WithField("profile", profile).
+		Info("interface detected. trying to attach TCX hook")
+	if err := p.ebpf.AttachTCX(iface, &profile); err != nil {
 		plog.WithField("[PCA]interface", iface).WithError(err).
 			Info("can't attach to TCx hook packet ebpfFetcher. fall back to use legacy TC hook")
-		if err := p.ebpf.Register(iface); err != nil {
+		if err := p.ebpf.Register(iface, &profile); err != nil {
 			plog.WithField("[PCA]interface", iface).WithError(err).
 				Warn("can't register packet ebpfFetcher. Ignoring")
 			return
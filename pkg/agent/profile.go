@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ebpf"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ifaces"
+)
+
+// Interface profile settings
+const (
+	profileHooks  = "hooks"
+	profileDNS    = "dns"
+	profilePCA    = "pca"
+	profileFilter = "filter"
+)
+
+// interfaceProfile holds the settings of the interfaces that match a selector rule
+type interfaceProfile struct {
+	rule     selectorRule
+	settings ebpf.InterfaceProfile
+}
+
+// interfaceProfiles chooses the settings of each interface: those of the first profile whose rule
+// matches the interface, or otherwise the default settings from the global configuration
+type interfaceProfiles struct {
+	defaults ebpf.InterfaceProfile
+	profiles []interfaceProfile
+	// selector evaluates the profile rules against the interfaces
+	selector interfaceSelector
+}
+
+// initInterfaceProfiles parses the INTERFACE_PROFILES expression. The settings that a profile
+// doesn't define are taken from the DIRECTION, ENABLE_DNS_TRACKING, ENABLE_FLOW_FILTER and
+// ENABLE_PCA properties. netnsName returns the name of the network namespace of an interface.
+func initInterfaceProfiles(cfg *Config, netnsName func(ifaces.Interface) string) (*interfaceProfiles, error) {
+	ingress, egress := flowDirections(cfg)
+	defaults := ebpf.InterfaceProfile{
+		Ingress:     ingress,
+		Egress:      egress,
+		DNSTracking: cfg.EnableDNSTracking,
+		FlowFilter:  cfg.EnableFlowFilter,
+		PCA:         cfg.EnablePCA,
+	}
+	profiles, err := parseInterfaceProfiles(cfg.InterfaceProfiles, defaults, IPsFromInterface)
+	if err != nil {
+		return nil, fmt.Errorf("configuring interface profiles: %w", err)
+	}
+	profiles.selector.netnsName = netnsName
+	return profiles, nil
+}
+
+// parseInterfaceProfiles parses a list of profiles separated by semicolons. Each profile is a
+// list of space-separated criterion=value pairs, as in the allow rules of the interface selector,
+// followed by "=>" and a list of space-separated setting=value pairs, e.g.
+//
+//	type=veth => hooks=egress dns=false ; type=physical => hooks=both
+func parseInterfaceProfiles(expr string, defaults ebpf.InterfaceProfile, ipsFromIface func(ifaceName string) ([]netip.Addr, error)) (*interfaceProfiles, error) {
+	ip := &interfaceProfiles{
+		defaults: defaults,
+		selector: interfaceSelector{ipsFromIface: ipsFromIface},
+	}
+	for _, definition := range strings.Split(expr, ";") {
+		if strings.TrimSpace(definition) == "" {
+			continue
+		}
+		criteria, settings, ok := strings.Cut(definition, "=>")
+		if !ok {
+			return nil, fmt.Errorf("wrong interface profile %q. Expected criteria => settings", definition)
+		}
+		profile := interfaceProfile{settings: defaults}
+		for _, field := range strings.Fields(criteria) {
+			criterion, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, fmt.Errorf("wrong interface selection criterion %q in profile %q. Expected criterion=value",
+					field, definition)
+			}
+			if err := profile.rule.add(criterion, value); err != nil {
+				return nil, fmt.Errorf("in profile %q: %w", definition, err)
+			}
+		}
+		for _, field := range strings.Fields(settings) {
+			if err := profile.set(field); err != nil {
+				return nil, fmt.Errorf("in profile %q: %w", definition, err)
+			}
+		}
+		ip.profiles = append(ip.profiles, profile)
+	}
+	return ip, nil
+}
+
+func (p *interfaceProfile) set(field string) error {
+	setting, value, ok := strings.Cut(field, "=")
+	if !ok {
+		return fmt.Errorf("wrong interface profile setting %q. Expected setting=value", field)
+	}
+	if setting == profileHooks {
+		switch value {
+		case DirectionIngress:
+			p.settings.Ingress, p.settings.Egress = true, false
+		case DirectionEgress:
+			p.settings.Ingress, p.settings.Egress = false, true
+		case DirectionBoth:
+			p.settings.Ingress, p.settings.Egress = true, true
+		default:
+			return fmt.Errorf("wrong %s value %q. Accepted values are: %s, %s, %s",
+				profileHooks, value, DirectionIngress, DirectionEgress, DirectionBoth)
+		}
+		return nil
+	}
+	var feature *bool
+	switch setting {
+	case profileDNS:
+		feature = &p.settings.DNSTracking
+	case profilePCA:
+		feature = &p.settings.PCA
+	case profileFilter:
+		feature = &p.settings.FlowFilter
+	default:
+		return fmt.Errorf("unknown interface profile setting %q. Accepted settings are: %s, %s, %s, %s",
+			setting, profileHooks, profileDNS, profilePCA, profileFilter)
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("wrong %s value %q: %w", setting, value, err)
+	}
+	*feature = enabled
+	return nil
+}
+
+// For returns the settings of an interface. The link attributes are nil if unknown.
+func (ip *interfaceProfiles) For(iface ifaces.Interface, link *ifaces.Link) (ebpf.InterfaceProfile, error) {
+	for i := range ip.profiles {
+		matches, err := ip.selector.matches(&ip.profiles[i].rule, iface, link)
+		if err != nil {
+			return ebpf.InterfaceProfile{}, err
+		}
+		if matches {
+			return ip.profiles[i].settings, nil
+		}
+	}
+	return ip.defaults, nil
+}
@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ebpf"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ifaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterfaceProfiles(t *testing.T) {
+	defaults := ebpf.InterfaceProfile{Ingress: true, Egress: true, DNSTracking: true, FlowFilter: true, PCA: true}
+	profiles, err := parseInterfaceProfiles(
+		"type=veth name=/^veth/ => hooks=egress dns=false ; "+
+			"type=physical => hooks=ingress filter=false pca=false ; "+
+			"name=/^veth/ => hooks=ingress",
+		defaults, nil)
+	require.NoError(t, err)
+
+	veth := &ifaces.Link{Type: "veth"}
+	nic := &ifaces.Link{Type: "device"}
+	for _, tc := range []struct {
+		name     string
+		iface    string
+		link     *ifaces.Link
+		expected ebpf.InterfaceProfile
+	}{
+		{name: "first matching profile", iface: "veth1", link: veth,
+			expected: ebpf.InterfaceProfile{Egress: true, FlowFilter: true, PCA: true}},
+		{name: "physical NIC", iface: "eth0", link: nic,
+			expected: ebpf.InterfaceProfile{Ingress: true, DNSTracking: true}},
+		{name: "unknown link", iface: "veth2",
+			expected: ebpf.InterfaceProfile{Ingress: true, DNSTracking: true, FlowFilter: true, PCA: true}},
+		{name: "no matching profile", iface: "br0", link: &ifaces.Link{Type: "bridge"}, expected: defaults},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			profile, err := profiles.For(ifaces.Interface{Name: tc.iface}, tc.link)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, profile)
+		})
+	}
+}
+
+func TestInterfaceProfiles_Defaults(t *testing.T) {
+	cfg := &Config{Direction: DirectionEgress, EnableDNSTracking: true,
+		InterfaceProfiles: "netns=/^cni-/ => hooks=both ; => dns=false"}
+	netnsName := func(iface ifaces.Interface) string {
+		if iface.Name == "eth0" {
+			return "cni-1234"
+		}
+		return ""
+	}
+	profiles, err := initInterfaceProfiles(cfg, netnsName)
+	require.NoError(t, err)
+
+	// the settings that a profile doesn't define are taken from the global configuration
+	profile, err := profiles.For(ifaces.Interface{Name: "eth0"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ebpf.InterfaceProfile{Ingress: true, Egress: true, DNSTracking: true}, profile)
+
+	// a profile without criteria matches any interface
+	profile, err = profiles.For(ifaces.Interface{Name: "eth1"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ebpf.InterfaceProfile{Egress: true}, profile)
+}
+
+func TestInterfaceProfiles_IPs(t *testing.T) {
+	ipsFromIface := func(ifaceName string) ([]netip.Addr, error) {
+		if ifaceName == "eth0" {
+			return []netip.Addr{netip.MustParseAddr("10.0.0.1")}, nil
+		}
+		return nil, nil
+	}
+	profiles, err := parseInterfaceProfiles("ip=10.0.0.0/8 => hooks=ingress",
+		ebpf.InterfaceProfile{Ingress: true, Egress: true}, ipsFromIface)
+	require.NoError(t, err)
+
+	profile, err := profiles.For(ifaces.Interface{Name: "eth0"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ebpf.InterfaceProfile{Ingress: true}, profile)
+
+	profile, err = profiles.For(ifaces.Interface{Name: "eth1"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ebpf.InterfaceProfile{Ingress: true, Egress: true}, profile)
+}
+
+func TestInterfaceProfiles_WrongExpressions(t *testing.T) {
+	for _, expr := range []string{
+		"type=veth",
+		"type=veth => hooks=sideways",
+		"type=veth => dns=maybe",
+		"type=veth => sampling=10",
+		"type=veth => dns",
+		"color=red => hooks=egress",
+		"type => hooks=egress",
+		"ip=10.0.0.0 => hooks=egress",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := parseInterfaceProfiles(expr, ebpf.InterfaceProfile{}, nil)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"os"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/utils"
 )
 
 func buildTLSConfig(cfg *Config) (*tls.Config, error) {
@@ -36,3 +38,20 @@ func buildTLSConfig(cfg *Config) (*tls.Config, error) {
 	}
 	return tlsConfig, nil
 }
+
+// buildGRPCTLSConfigs returns a function that provides the TLS configuration for the gRPC flow
+// and packet exporters, for a given collector host. Certificates are reloaded from disk whenever
+// they change, so they can be rotated without restarting the agent.
+func buildGRPCTLSConfigs(cfg *Config) (func(host string) *tls.Config, error) {
+	reloader, err := utils.NewCertReloader(cfg.GRPCTLSCACertPath, cfg.GRPCTLSUserCertPath, cfg.GRPCTLSUserKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	return func(host string) *tls.Config {
+		serverName := cfg.GRPCTLSServerName
+		if serverName == "" {
+			serverName = host
+		}
+		return reloader.ClientConfig(serverName, cfg.GRPCTLSInsecureSkipVerify)
+	}, nil
+}
@@ -3,6 +3,7 @@ package decode
 import (
 	"encoding/base64"
 	"fmt"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -84,11 +85,26 @@ func RecordToMap(fr *flow.Record) config.GenericMap {
 	var interfaces []string
 	var directions []int
 	if len(fr.DupList) != 0 {
-		for _, m := range fr.DupList {
-			for key, value := range m {
-				interfaces = append(interfaces, key)
-				directions = append(directions, int(flow.Direction(value)))
-			}
+		ifBytes := make([]uint64, 0, len(fr.DupList))
+		ifPackets := make([]uint32, 0, len(fr.DupList))
+		var ifDropBytes []uint64
+		var ifDropPackets []uint32
+		hasDrops := false
+		for i := range fr.DupList {
+			dup := &fr.DupList[i]
+			interfaces = append(interfaces, dup.Interface)
+			directions = append(directions, int(flow.Direction(dup.Direction)))
+			ifBytes = append(ifBytes, dup.Bytes)
+			ifPackets = append(ifPackets, dup.Packets)
+			ifDropBytes = append(ifDropBytes, dup.PktDropBytes)
+			ifDropPackets = append(ifDropPackets, dup.PktDropPackets)
+			hasDrops = hasDrops || dup.PktDropPackets != 0
+		}
+		out["IfBytes"] = ifBytes
+		out["IfPackets"] = ifPackets
+		if hasDrops {
+			out["IfPktDropBytes"] = ifDropBytes
+			out["IfPktDropPackets"] = ifDropPackets
 		}
 	} else {
 		interfaces = append(interfaces, fr.Interface)
@@ -97,6 +113,25 @@ func RecordToMap(fr *flow.Record) config.GenericMap {
 	out["Interfaces"] = interfaces
 	out["IfDirections"] = directions
 
+	if fr.Id.Netns != 0 {
+		out["NetNS"] = fr.Id.Netns
+	}
+	if fr.NetNSName != "" {
+		out["NetNSName"] = fr.NetNSName
+	}
+	if fr.InterfaceLink != nil {
+		ifMAC := flow.MacAddr(fr.InterfaceLink.MAC)
+		out["IfType"] = fr.InterfaceLink.Type
+		out["IfMac"] = ifMAC.String()
+		out["IfMtu"] = fr.InterfaceLink.MTU
+		if fr.InterfaceLink.Master != "" {
+			out["IfMaster"] = fr.InterfaceLink.Master
+		}
+		if fr.InterfaceLink.PeerIfIndex != 0 {
+			out["IfPeerIndex"] = fr.InterfaceLink.PeerIfIndex
+		}
+	}
+
 	if fr.Id.EthProtocol == uint16(ethernet.EtherTypeIPv4) || fr.Id.EthProtocol == uint16(ethernet.EtherTypeIPv6) {
 		out["SrcAddr"] = flow.IP(fr.Id.SrcIp).String()
 		out["DstAddr"] = flow.IP(fr.Id.DstIp).String()
@@ -133,12 +168,51 @@ func RecordToMap(fr *flow.Record) config.GenericMap {
 		out["PktDropPackets"] = fr.Metrics.PktDrops.Packets
 		out["PktDropLatestFlags"] = fr.Metrics.PktDrops.LatestFlags
 		out["PktDropLatestState"] = TCPStateToStr(uint32(fr.Metrics.PktDrops.LatestState))
-		out["PktDropLatestDropCause"] = PktDropCauseToStr(fr.Metrics.PktDrops.LatestDropCause)
+		if fr.DropCause != "" {
+			out["PktDropLatestDropCause"] = fr.DropCause
+		} else {
+			out["PktDropLatestDropCause"] = PktDropCauseToStr(fr.Metrics.PktDrops.LatestDropCause)
+		}
+		if fr.Metrics.PktDrops.LatestDropLocation != 0 {
+			out["PktDropLatestLocation"] = PktDropLocationToStr(fr.Metrics.PktDrops.LatestDropLocation, fr.DropLocation)
+		}
+		if len(fr.DropStack) != 0 {
+			out["PktDropLatestStack"] = fr.DropStack
+		}
 	}
 
 	if fr.TimeFlowRtt != 0 {
 		out["TimeFlowRttNs"] = fr.TimeFlowRtt.Nanoseconds()
 	}
+
+	if fr.TransitLatency != 0 {
+		out["TransitLatencyNs"] = fr.TransitLatency.Nanoseconds()
+	}
+
+	if fr.Metrics.Qdisc.Packets != 0 {
+		out["QdiscLatencyNs"] = fr.QdiscLatency().Nanoseconds()
+		out["QdiscPackets"] = fr.Metrics.Qdisc.Packets
+	}
+	if fr.Metrics.Qdisc.DropPackets != 0 {
+		out["QdiscDropBytes"] = fr.Metrics.Qdisc.DropBytes
+		out["QdiscDropPackets"] = fr.Metrics.Qdisc.DropPackets
+	}
+	if kind := fr.QdiscKind(); kind != "" {
+		out["QdiscKind"] = kind
+	}
+
+	if fr.Reverse != nil {
+		out["ReverseBytes"] = fr.Reverse.Bytes
+		out["ReversePackets"] = fr.Reverse.Packets
+		if fr.Id.TransportProtocol == syscall.IPPROTO_TCP {
+			out["ReverseFlags"] = fr.Reverse.Flags
+		}
+		if fr.Reverse.PktDropPackets != 0 {
+			out["ReversePktDropBytes"] = fr.Reverse.PktDropBytes
+			out["ReversePktDropPackets"] = fr.Reverse.PktDropPackets
+		}
+		out["BiflowDirection"] = fr.Reverse.BiflowDirection
+	}
 	return out
 }
 
@@ -237,10 +311,42 @@ func TCPStateToStr(state uint32) string {
 	return "TCP_INVALID_STATE"
 }
 
-// PktDropCauseToStr is based on kernel drop cause definition
+// PktDropLocationToStr returns the kernel function of a drop location, as resolved by the agent
+// from the kernel symbols, or the address of the drop location if it couldn't be resolved.
+func PktDropLocationToStr(location uint64, function string) string {
+	if function != "" {
+		return function
+	}
+	if location == 0 {
+		return ""
+	}
+	return fmt.Sprintf("0x%x", location)
+}
+
+// pktDropCauses holds the names of the drop causes of the running kernel, if they are known
+var pktDropCauses atomic.Pointer[map[uint32]string]
+
+// SetPktDropCauses sets the names of the drop causes of the running kernel, as read from its
+// BTF. They take precedence over the static names of PktDropCauseToStr.
+func SetPktDropCauses(names map[uint32]string) {
+	pktDropCauses.Store(&names)
+}
+
+// PktDropCauseToStr returns the name of a drop cause: from the drop causes of the running kernel
+// if they have been set with SetPktDropCauses, or otherwise from the kernel drop cause definition
+func PktDropCauseToStr(dropCause uint32) string {
+	if names := pktDropCauses.Load(); names != nil {
+		if name, ok := (*names)[dropCause]; ok {
+			return name
+		}
+	}
+	return staticPktDropCauseToStr(dropCause)
+}
+
+// staticPktDropCauseToStr is based on kernel drop cause definition
 // https://elixir.bootlin.com/linux/latest/source/include/net/dropreason.h#L88
 // nolint:cyclop
-func PktDropCauseToStr(dropCause uint32) string {
+func staticPktDropCauseToStr(dropCause uint32) string {
 	switch dropCause {
 	case skbDropReasonSubSysCore + 2:
 		return "SKB_DROP_REASON_NOT_SPECIFIED"
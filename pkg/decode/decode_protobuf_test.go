@@ -19,14 +19,20 @@ func TestPBFlowToMap(t *testing.T) {
 	var someDuration time.Duration = 10000000 // 10ms
 	flow := &pbflow.Record{
 		Interface: "eth0",
-		DupList: []*pbflow.DupMapEntry{
+		DupList: []*pbflow.DupEntry{
 			{
 				Interface: "5e6e92caa1d51cf",
 				Direction: pbflow.Direction_INGRESS,
+				Bytes:     456,
+				Packets:   123,
 			},
 			{
-				Interface: "eth0",
-				Direction: pbflow.Direction_EGRESS,
+				Interface:      "eth0",
+				Direction:      pbflow.Direction_EGRESS,
+				Bytes:          400,
+				Packets:        110,
+				PktDropBytes:   56,
+				PktDropPackets: 13,
 			},
 		},
 		EthProtocol:   2048,
@@ -63,11 +69,20 @@ func TestPBFlowToMap(t *testing.T) {
 		PktDropLatestFlags:     0x100,
 		PktDropLatestState:     1,
 		PktDropLatestDropCause: 4,
+		PktDropLatestLocation:  0xffffffff81a2b3c4,
+		PktDropLatestFunction:  "tcp_v4_rcv+0x8a",
+		PktDropLatestStack:     []string{"tcp_v4_rcv+0x8a", "ip_local_deliver_finish+0x7b"},
 		DnsLatency:             durationpb.New(someDuration),
 		DnsId:                  1,
 		DnsFlags:               0x80,
 		DnsErrno:               0,
 		TimeFlowRtt:            durationpb.New(someDuration),
+		TransitLatency:         durationpb.New(someDuration),
+		QdiscTime:              durationpb.New(3 * time.Millisecond),
+		QdiscPackets:           3,
+		QdiscDropBytes:         3000,
+		QdiscDropPackets:       2,
+		QdiscKind:              "fq_codel",
 	}
 
 	out := PBFlowToMap(flow)
@@ -75,6 +90,10 @@ func TestPBFlowToMap(t *testing.T) {
 	delete(out, "TimeReceived")
 	assert.Equal(t, config.GenericMap{
 		"IfDirections":           []int{0, 1},
+		"IfBytes":                []uint64{456, 400},
+		"IfPackets":              []uint32{123, 110},
+		"IfPktDropBytes":         []uint64{0, 56},
+		"IfPktDropPackets":       []uint32{0, 13},
 		"Bytes":                  uint64(456),
 		"SrcAddr":                "1.2.3.4",
 		"DstAddr":                "5.6.7.8",
@@ -97,12 +116,53 @@ func TestPBFlowToMap(t *testing.T) {
 		"PktDropLatestFlags":     uint16(0x100),
 		"PktDropLatestState":     "TCP_ESTABLISHED",
 		"PktDropLatestDropCause": "SKB_DROP_REASON_PKT_TOO_SMALL",
+		"PktDropLatestLocation":  "tcp_v4_rcv+0x8a",
+		"PktDropLatestStack":     []string{"tcp_v4_rcv+0x8a", "ip_local_deliver_finish+0x7b"},
 		"DnsLatencyMs":           someDuration.Milliseconds(),
 		"DnsId":                  uint16(1),
 		"DnsFlags":               uint16(0x80),
 		"DnsFlagsResponseCode":   "NoError",
 		"DnsErrno":               uint32(0),
 		"TimeFlowRttNs":          someDuration.Nanoseconds(),
+		"TransitLatencyNs":       someDuration.Nanoseconds(),
+		"QdiscLatencyNs":         time.Millisecond.Nanoseconds(),
+		"QdiscPackets":           uint32(3),
+		"QdiscDropBytes":         uint64(3000),
+		"QdiscDropPackets":       uint32(2),
+		"QdiscKind":              "fq_codel",
 	}, out)
 
 }
+
+func TestPktDropLocationToStr(t *testing.T) {
+	assert.Equal(t, "tcp_v4_rcv+0x8a", PktDropLocationToStr(0xffffffff81a2b08a, "tcp_v4_rcv+0x8a"))
+	assert.Equal(t, "0xffffffff81a2b08a", PktDropLocationToStr(0xffffffff81a2b08a, ""))
+	assert.Empty(t, PktDropLocationToStr(0, ""))
+}
+
+func TestPktDropCauseToStr(t *testing.T) {
+	defer SetPktDropCauses(nil)
+	assert.Equal(t, "SKB_DROP_REASON_NO_SOCKET", PktDropCauseToStr(3))
+	assert.Equal(t, "OVS_DROP_LAST_ACTION", PktDropCauseToStr(3<<16+1))
+
+	// the drop causes of the running kernel take precedence over the default ones
+	SetPktDropCauses(map[uint32]string{3: "SKB_DROP_REASON_NO_SOCKET", 90: "SKB_DROP_REASON_NEW_CAUSE"})
+	assert.Equal(t, "SKB_DROP_REASON_NEW_CAUSE", PktDropCauseToStr(90))
+	assert.Equal(t, "SKB_DROP_REASON_NO_SOCKET", PktDropCauseToStr(3))
+	assert.Equal(t, "OVS_DROP_LAST_ACTION", PktDropCauseToStr(3<<16+1))
+}
+
+func TestPBFlowToMap_DropCauseName(t *testing.T) {
+	// the drop cause name resolved by the agent is used, whatever the kernel of the decoder
+	out := PBFlowToMap(&pbflow.Record{
+		Network:                    &pbflow.Network{SrcAddr: &pbflow.IP{}, DstAddr: &pbflow.IP{}},
+		DataLink:                   &pbflow.DataLink{},
+		Transport:                  &pbflow.Transport{},
+		TimeFlowStart:              timestamppb.Now(),
+		TimeFlowEnd:                timestamppb.Now(),
+		PktDropPackets:             1,
+		PktDropLatestDropCause:     90,
+		PktDropLatestDropCauseName: "SKB_DROP_REASON_NEW_CAUSE",
+	})
+	assert.Equal(t, "SKB_DROP_REASON_NEW_CAUSE", out["PktDropLatestDropCause"])
+}
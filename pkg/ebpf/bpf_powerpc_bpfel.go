@@ -79,6 +79,7 @@ type BpfFlowIdT struct {
 	IcmpType          uint8
 	IcmpCode          uint8
 	IfIndex           uint32
+	Netns             uint32
 }
 
 type BpfFlowMetrics BpfFlowMetricsT
@@ -94,6 +95,7 @@ type BpfFlowMetricsT struct {
 	PktDrops        BpfPktDropsT
 	DnsRecord       BpfDnsRecordT
 	FlowRtt         uint64
+	Qdisc           BpfQdiscT
 }
 
 type BpfFlowRecordT struct {
@@ -111,12 +113,27 @@ const (
 	BpfGlobalCountersKeyTMAX_DROPPED_FLOWS_KEY     BpfGlobalCountersKeyT = 4
 )
 
+type BpfIfaceProfileKeyT struct {
+	IfIndex uint32
+	Netns   uint32
+}
+
 type BpfPktDropsT struct {
-	Packets         uint32
-	Bytes           uint64
-	LatestFlags     uint16
-	LatestState     uint8
-	LatestDropCause uint32
+	Packets            uint32
+	Bytes              uint64
+	LatestFlags        uint16
+	LatestState        uint8
+	LatestDropCause    uint32
+	LatestDropLocation uint64
+	LatestDropStackId  uint32
+}
+
+type BpfQdiscT struct {
+	Time        uint64
+	Packets     uint32
+	DropPackets uint32
+	DropBytes   uint64
+	LatestKind  [16]uint8
 }
 
 // LoadBpf returns the embedded CollectionSpec for Bpf.
@@ -161,6 +178,9 @@ type BpfSpecs struct {
 // It can be passed ebpf.CollectionSpec.Assign.
 type BpfProgramSpecs struct {
 	KfreeSkb            *ebpf.ProgramSpec `ebpf:"kfree_skb"`
+	KfreeSkbKprobe      *ebpf.ProgramSpec `ebpf:"kfree_skb_kprobe"`
+	QdiscEnqueue        *ebpf.ProgramSpec `ebpf:"qdisc_enqueue"`
+	QdiscXmit           *ebpf.ProgramSpec `ebpf:"qdisc_xmit"`
 	TcEgressFlowParse   *ebpf.ProgramSpec `ebpf:"tc_egress_flow_parse"`
 	TcEgressPcaParse    *ebpf.ProgramSpec `ebpf:"tc_egress_pca_parse"`
 	TcIngressFlowParse  *ebpf.ProgramSpec `ebpf:"tc_ingress_flow_parse"`
@@ -180,9 +200,12 @@ type BpfMapSpecs struct {
 	AggregatedFlows *ebpf.MapSpec `ebpf:"aggregated_flows"`
 	DirectFlows     *ebpf.MapSpec `ebpf:"direct_flows"`
 	DnsFlows        *ebpf.MapSpec `ebpf:"dns_flows"`
+	DropStacks      *ebpf.MapSpec `ebpf:"drop_stacks"`
 	FilterMap       *ebpf.MapSpec `ebpf:"filter_map"`
 	GlobalCounters  *ebpf.MapSpec `ebpf:"global_counters"`
+	IfaceProfiles   *ebpf.MapSpec `ebpf:"iface_profiles"`
 	PacketRecord    *ebpf.MapSpec `ebpf:"packet_record"`
+	QdiscSkbs       *ebpf.MapSpec `ebpf:"qdisc_skbs"`
 }
 
 // BpfObjects contains all objects after they have been loaded into the kernel.
@@ -207,9 +230,12 @@ type BpfMaps struct {
 	AggregatedFlows *ebpf.Map `ebpf:"aggregated_flows"`
 	DirectFlows     *ebpf.Map `ebpf:"direct_flows"`
 	DnsFlows        *ebpf.Map `ebpf:"dns_flows"`
+	DropStacks      *ebpf.Map `ebpf:"drop_stacks"`
 	FilterMap       *ebpf.Map `ebpf:"filter_map"`
 	GlobalCounters  *ebpf.Map `ebpf:"global_counters"`
+	IfaceProfiles   *ebpf.Map `ebpf:"iface_profiles"`
 	PacketRecord    *ebpf.Map `ebpf:"packet_record"`
+	QdiscSkbs       *ebpf.Map `ebpf:"qdisc_skbs"`
 }
 
 func (m *BpfMaps) Close() error {
@@ -217,9 +243,12 @@ func (m *BpfMaps) Close() error {
 		m.AggregatedFlows,
 		m.DirectFlows,
 		m.DnsFlows,
+		m.DropStacks,
 		m.FilterMap,
 		m.GlobalCounters,
+		m.IfaceProfiles,
 		m.PacketRecord,
+		m.QdiscSkbs,
 	)
 }
 
@@ -228,6 +257,9 @@ func (m *BpfMaps) Close() error {
 // It can be passed to LoadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type BpfPrograms struct {
 	KfreeSkb            *ebpf.Program `ebpf:"kfree_skb"`
+	KfreeSkbKprobe      *ebpf.Program `ebpf:"kfree_skb_kprobe"`
+	QdiscEnqueue        *ebpf.Program `ebpf:"qdisc_enqueue"`
+	QdiscXmit           *ebpf.Program `ebpf:"qdisc_xmit"`
 	TcEgressFlowParse   *ebpf.Program `ebpf:"tc_egress_flow_parse"`
 	TcEgressPcaParse    *ebpf.Program `ebpf:"tc_egress_pca_parse"`
 	TcIngressFlowParse  *ebpf.Program `ebpf:"tc_ingress_flow_parse"`
@@ -243,6 +275,9 @@ type BpfPrograms struct {
 func (p *BpfPrograms) Close() error {
 	return _BpfClose(
 		p.KfreeSkb,
+		p.KfreeSkbKprobe,
+		p.QdiscEnqueue,
+		p.QdiscXmit,
 		p.TcEgressFlowParse,
 		p.TcEgressPcaParse,
 		p.TcIngressFlowParse,
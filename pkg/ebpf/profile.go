@@ -0,0 +1,69 @@
+package ebpf
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ifaces"
+
+	"github.com/cilium/ebpf"
+)
+
+// flags of the per-interface profiles, as defined in bpf/types.h
+const (
+	ifaceProfileNoDNS    = uint8(0x01)
+	ifaceProfileNoFilter = uint8(0x02)
+)
+
+// InterfaceProfile defines how an interface is traced: which hooks are attached, and which of
+// the optional features apply to its traffic. The optional features must also be enabled in the
+// fetcher configuration, as an interface profile can only disable them.
+type InterfaceProfile struct {
+	Ingress bool
+	Egress  bool
+	// DNSTracking enables the DNS tracking of the flows of the interface
+	DNSTracking bool
+	// FlowFilter applies the flow filter rule to the flows of the interface
+	FlowFilter bool
+	// PCA enables the packet capture of the interface
+	PCA bool
+}
+
+// disabledFeatures returns the flags of the optional features that the profile disables, among
+// the given globally enabled features
+func (p *InterfaceProfile) disabledFeatures(enabled uint8) uint8 {
+	var flags uint8
+	if !p.DNSTracking {
+		flags |= ifaceProfileNoDNS
+	}
+	if !p.FlowFilter {
+		flags |= ifaceProfileNoFilter
+	}
+	return flags & enabled
+}
+
+// programInterfaceProfile stores the optional features that an interface profile disables, for
+// the eBPF programs to skip them on that interface. The enabled flags are the globally enabled
+// features, as the eBPF programs don't look up the profiles of the disabled ones.
+func programInterfaceProfile(objects *BpfObjects, iface ifaces.Interface, profile *InterfaceProfile, enabled uint8) error {
+	if enabled == 0 {
+		return nil
+	}
+	ino, err := ifaces.NetNSInode(iface.NetNS)
+	if err != nil {
+		return fmt.Errorf("failed to get netns of interface %s: %w", iface.Name, err)
+	}
+	key := BpfIfaceProfileKeyT{IfIndex: uint32(iface.Index), Netns: ino}
+	flags := profile.disabledFeatures(enabled)
+	if flags == 0 {
+		// the interface might reuse the index of a removed interface with another profile
+		if err := objects.IfaceProfiles.Delete(key); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+			return fmt.Errorf("failed to delete profile of interface %s: %w", iface.Name, err)
+		}
+		return nil
+	}
+	if err := objects.IfaceProfiles.Update(key, flags, ebpf.UpdateAny); err != nil {
+		return fmt.Errorf("failed to update profile of interface %s: %w", iface.Name, err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package ebpf
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -27,20 +28,28 @@ import (
 )
 
 // $BPF_CLANG and $BPF_CFLAGS are set by the Makefile.
-//go:generate bpf2go -cc $BPF_CLANG -cflags $BPF_CFLAGS -target amd64,arm64,ppc64le,s390x -type flow_metrics_t -type flow_id_t -type flow_record_t -type pkt_drops_t -type dns_record_t -type global_counters_key_t -type direction_t -type filter_action_t Bpf ../../bpf/flows.c -- -I../../bpf/headers
+//go:generate bpf2go -cc $BPF_CLANG -cflags $BPF_CFLAGS -target amd64,arm64,ppc64le,s390x -type flow_metrics_t -type flow_id_t -type flow_record_t -type pkt_drops_t -type dns_record_t -type global_counters_key_t -type direction_t -type filter_action_t -type iface_profile_key_t -type qdisc_t Bpf ../../bpf/flows.c -- -I../../bpf/headers
 
 const (
 	qdiscType = "clsact"
 	// ebpf map names as defined in bpf/maps_definition.h
 	aggregatedFlowsMap = "aggregated_flows"
 	dnsLatencyMap      = "dns_flows"
+	dropStacksMap      = "drop_stacks"
+	qdiscSkbsMap       = "qdisc_skbs"
+	// maximum number of frames of the drop stacks, as defined in bpf/types.h
+	dropStackDepth = 20
 	// constants defined in flows.c as "volatile const"
 	constSampling            = "sampling"
 	constTraceMessages       = "trace_messages"
 	constEnableRtt           = "enable_rtt"
 	constEnableDNSTracking   = "enable_dns_tracking"
 	constEnableFlowFiltering = "enable_flows_filtering"
+	constEnablePktDropStacks = "enable_pkt_drop_stacks"
+	constEnableQdiscTracking = "enable_qdisc_tracking"
 	pktDropHook              = "kfree_skb"
+	qdiscEnqueueHook         = "qdisc_enqueue"
+	qdiscXmitHook            = "net_dev_start_xmit"
 	constPcaEnable           = "enable_pca"
 	pcaRecordsMap            = "packet_record"
 	tcEgressFilterName       = "tc/tc_egress_flow_parse"
@@ -61,23 +70,25 @@ type FlowFetcher struct {
 	ingressFilters           map[ifaces.Interface]*netlink.BpfFilter
 	ringbufReader            *ringbuf.Reader
 	cacheMaxSize             int
-	enableIngress            bool
-	enableEgress             bool
+	profileFeatures          uint8
 	pktDropsTracePoint       link.Link
+	pktDropsKprobeLink       link.Link
 	rttFentryLink            link.Link
 	rttKprobeLink            link.Link
+	qdiscEnqueueLink         link.Link
+	qdiscXmitLink            link.Link
 	egressTCXLink            map[ifaces.Interface]link.Link
 	ingressTCXLink           map[ifaces.Interface]link.Link
 	lookupAndDeleteSupported bool
 }
 
 type FlowFetcherConfig struct {
-	EnableIngress    bool
-	EnableEgress     bool
 	Debug            bool
 	Sampling         int
 	CacheMaxSize     int
 	PktDrops         bool
+	PktDropStacks    bool
+	QdiscTracking    bool
 	DNSTracker       bool
 	EnableRTT        bool
 	EnableFlowFilter bool
@@ -95,9 +106,14 @@ func NewFlowFetcher(cfg *FlowFetcherConfig) (*FlowFetcher, error) {
 	if err != nil {
 		return nil, fmt.Errorf("loading BPF data: %w", err)
 	}
+	if err := checkSpec(spec); err != nil {
+		return nil, err
+	}
 
 	// Resize maps according to user-provided configuration
-	spec.Maps[aggregatedFlowsMap].MaxEntries = uint32(cfg.CacheMaxSize)
+	if err := resizeMap(spec, aggregatedFlowsMap, uint32(cfg.CacheMaxSize)); err != nil {
+		return nil, err
+	}
 
 	traceMsgs := 0
 	if cfg.Debug {
@@ -115,7 +131,9 @@ func NewFlowFetcher(cfg *FlowFetcherConfig) (*FlowFetcher, error) {
 	}
 
 	if enableDNSTracking == 0 {
-		spec.Maps[dnsLatencyMap].MaxEntries = 1
+		if err := resizeMap(spec, dnsLatencyMap, 1); err != nil {
+			return nil, err
+		}
 	}
 
 	enableFlowFiltering := 0
@@ -123,12 +141,28 @@ func NewFlowFetcher(cfg *FlowFetcherConfig) (*FlowFetcher, error) {
 		enableFlowFiltering = 1
 	}
 
+	enablePktDropStacks := 0
+	if cfg.PktDrops && cfg.PktDropStacks {
+		enablePktDropStacks = 1
+	} else if err := resizeMap(spec, dropStacksMap, 1); err != nil {
+		return nil, err
+	}
+
+	enableQdiscTracking := 0
+	if cfg.QdiscTracking {
+		enableQdiscTracking = 1
+	} else if err := resizeMap(spec, qdiscSkbsMap, 1); err != nil {
+		return nil, err
+	}
+
 	if err := spec.RewriteConstants(map[string]interface{}{
 		constSampling:            uint32(cfg.Sampling),
 		constTraceMessages:       uint8(traceMsgs),
 		constEnableRtt:           uint8(enableRtt),
 		constEnableDNSTracking:   uint8(enableDNSTracking),
 		constEnableFlowFiltering: uint8(enableFlowFiltering),
+		constEnablePktDropStacks: uint8(enablePktDropStacks),
+		constEnableQdiscTracking: uint8(enableQdiscTracking),
 	}); err != nil {
 		return nil, fmt.Errorf("rewriting BPF constants definition: %w", err)
 	}
@@ -152,17 +186,28 @@ func NewFlowFetcher(cfg *FlowFetcherConfig) (*FlowFetcher, error) {
 	log.Debugf("Deleting specs for PCA")
 	// Deleting specs for PCA
 	// Always set pcaRecordsMap to the minimum in FlowFetcher - PCA and Flow Fetcher are mutually exclusive.
-	spec.Maps[pcaRecordsMap].MaxEntries = 1
+	if err := resizeMap(spec, pcaRecordsMap, 1); err != nil {
+		return nil, err
+	}
 
 	objects.TcxEgressPcaParse = nil
 	objects.TcIngressPcaParse = nil
 	delete(spec.Programs, constPcaEnable)
 
-	var pktDropsLink link.Link
-	if cfg.PktDrops && !oldKernel {
-		pktDropsLink, err = link.Tracepoint("skb", pktDropHook, objects.KfreeSkb, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to attach the BPF program to kfree_skb tracepoint: %w", err)
+	var pktDropsLink, pktDropsKprobeLink link.Link
+	if cfg.PktDrops {
+		if !oldKernel {
+			pktDropsLink, err = link.Tracepoint("skb", pktDropHook, objects.KfreeSkb, nil)
+			if err != nil {
+				log.Warningf("failed to attach the BPF program to kfree_skb tracepoint: %v fallback to use kprobe", err)
+			}
+		}
+		if pktDropsLink == nil {
+			// the kprobe reports the drops and their location, but not their reason
+			pktDropsKprobeLink, err = link.Kprobe(pktDropHook, objects.KfreeSkbKprobe, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to attach the BPF program to kfree_skb kprobe: %w", err)
+			}
 		}
 	}
 
@@ -181,6 +226,15 @@ func NewFlowFetcher(cfg *FlowFetcherConfig) (*FlowFetcher, error) {
 		}
 	}
 
+	var qdiscEnqueueLink, qdiscXmitLink link.Link
+	if cfg.QdiscTracking {
+		if oldKernel {
+			log.Warn("the qdisc tracking requires a kernel 5.16 or newer: disabling it")
+		} else {
+			qdiscEnqueueLink, qdiscXmitLink = attachQdiscTracepoints(&objects)
+		}
+	}
+
 	// read events from igress+egress ringbuffer
 	flows, err := ringbuf.NewReader(objects.DirectFlows)
 	if err != nil {
@@ -194,19 +248,72 @@ func NewFlowFetcher(cfg *FlowFetcherConfig) (*FlowFetcher, error) {
 		ingressFilters:           map[ifaces.Interface]*netlink.BpfFilter{},
 		qdiscs:                   map[ifaces.Interface]*netlink.GenericQdisc{},
 		cacheMaxSize:             cfg.CacheMaxSize,
-		enableIngress:            cfg.EnableIngress,
-		enableEgress:             cfg.EnableEgress,
+		profileFeatures:          profileFeatures(cfg),
 		pktDropsTracePoint:       pktDropsLink,
+		pktDropsKprobeLink:       pktDropsKprobeLink,
 		rttFentryLink:            rttFentryLink,
 		rttKprobeLink:            rttKprobeLink,
+		qdiscEnqueueLink:         qdiscEnqueueLink,
+		qdiscXmitLink:            qdiscXmitLink,
 		egressTCXLink:            map[ifaces.Interface]link.Link{},
 		ingressTCXLink:           map[ifaces.Interface]link.Link{},
 		lookupAndDeleteSupported: true, // this will be turned off later if found to be not supported
 	}, nil
 }
 
-func (m *FlowFetcher) AttachTCX(iface ifaces.Interface) error {
+// attachQdiscTracepoints attaches the qdisc tracking programs. The tracking is disabled when
+// any of the tracepoints can't be attached, as the qdisc_enqueue tracepoint only exists since
+// kernel 5.16.
+func attachQdiscTracepoints(objects *BpfObjects) (enqueueLink, xmitLink link.Link) {
+	enqueueLink, err := link.Tracepoint("qdisc", qdiscEnqueueHook, objects.QdiscEnqueue, nil)
+	if err != nil {
+		log.WithError(err).Warn("failed to attach the BPF program to qdisc_enqueue tracepoint: disabling the qdisc tracking")
+		return nil, nil
+	}
+	xmitLink, err = link.Tracepoint("net", qdiscXmitHook, objects.QdiscXmit, nil)
+	if err != nil {
+		log.WithError(err).Warn("failed to attach the BPF program to net_dev_start_xmit tracepoint: disabling the qdisc tracking")
+		if err := enqueueLink.Close(); err != nil {
+			log.WithError(err).Debug("can't detach the qdisc_enqueue tracepoint")
+		}
+		return nil, nil
+	}
+	return enqueueLink, xmitLink
+}
+
+// checkSpec verifies that the embedded BPF objects match the Go bindings: they must define all the
+// programs and maps of the bindings, and the flows map must have the same layout. Otherwise, the
+// objects would fail to load or the flows would be wrongly decoded, e.g. when the eBPF code has
+// been changed without regenerating the BPF objects.
+func checkSpec(spec *ebpf.CollectionSpec) error {
+	if err := spec.Assign(&BpfSpecs{}); err != nil {
+		return fmt.Errorf("the BPF objects don't match the Go bindings and must be regenerated"+
+			" with 'make docker-generate': %w", err)
+	}
+	flows := spec.Maps[aggregatedFlowsMap]
+	keySize, valueSize := binary.Size(BpfFlowId{}), binary.Size(BpfFlowMetrics{})
+	if flows.KeySize != uint32(keySize) || flows.ValueSize != uint32(valueSize) {
+		return fmt.Errorf("map %s has %d-byte keys and %d-byte values, but the Go bindings expect"+
+			" %d and %d bytes: the BPF objects must be regenerated with 'make docker-generate'",
+			aggregatedFlowsMap, flows.KeySize, flows.ValueSize, keySize, valueSize)
+	}
+	return nil
+}
+
+// resizeMap sets the maximum number of entries of a map of the BPF objects
+func resizeMap(spec *ebpf.CollectionSpec, name string, maxEntries uint32) error {
+	m, ok := spec.Maps[name]
+	if !ok {
+		return fmt.Errorf("map %s not found in the BPF objects", name)
+	}
+	m.MaxEntries = maxEntries
+	return nil
+}
+
+// AttachTCX attaches the flows programs to the TCX hooks of an interface, according to its profile
+func (m *FlowFetcher) AttachTCX(iface ifaces.Interface, profile *InterfaceProfile) error {
 	ilog := log.WithField("iface", iface)
+	m.programProfile(iface, profile)
 	if iface.NetNS != netns.None() {
 		originalNs, err := netns.Get()
 		if err != nil {
@@ -223,7 +330,7 @@ func (m *FlowFetcher) AttachTCX(iface ifaces.Interface) error {
 		}
 	}
 
-	if m.enableEgress {
+	if profile.Egress {
 		egrLink, err := link.AttachTCX(link.TCXOptions{
 			Program:   m.objects.BpfPrograms.TcxEgressFlowParse,
 			Attach:    ebpf.AttachTCXEgress,
@@ -241,7 +348,7 @@ func (m *FlowFetcher) AttachTCX(iface ifaces.Interface) error {
 		ilog.WithField("interface", iface.Name).Debug("successfully attach egressTCX hook")
 	}
 
-	if m.enableIngress {
+	if profile.Ingress {
 		ingLink, err := link.AttachTCX(link.TCXOptions{
 			Program:   m.objects.BpfPrograms.TcxIngressFlowParse,
 			Attach:    ebpf.AttachTCXIngress,
@@ -262,6 +369,26 @@ func (m *FlowFetcher) AttachTCX(iface ifaces.Interface) error {
 	return nil
 }
 
+// programProfile stores the features that the interface profile disables. On failure, all the
+// enabled features apply to the interface.
+func (m *FlowFetcher) programProfile(iface ifaces.Interface, profile *InterfaceProfile) {
+	if err := programInterfaceProfile(m.objects, iface, profile, m.profileFeatures); err != nil {
+		log.WithField("iface", iface).WithError(err).
+			Warn("can't program the interface profile. All the enabled features apply to the interface")
+	}
+}
+
+func profileFeatures(cfg *FlowFetcherConfig) uint8 {
+	var features uint8
+	if cfg.DNSTracker {
+		features |= ifaceProfileNoDNS
+	}
+	if cfg.EnableFlowFilter {
+		features |= ifaceProfileNoFilter
+	}
+	return features
+}
+
 func removeTCFilters(ifName string, tcDir uint32) error {
 	link, err := netlink.LinkByName(ifName)
 	if err != nil {
@@ -340,10 +467,11 @@ func (m *FlowFetcher) removePreviousFilters(iface ifaces.Interface) error {
 	return nil
 }
 
-// Register and links the eBPF fetcher into the system. The program should invoke Unregister
-// before exiting.
-func (m *FlowFetcher) Register(iface ifaces.Interface) error {
+// Register and links the eBPF fetcher into the system, according to the interface profile. The
+// program should invoke Unregister before exiting.
+func (m *FlowFetcher) Register(iface ifaces.Interface, profile *InterfaceProfile) error {
 	ilog := log.WithField("iface", iface)
+	m.programProfile(iface, profile)
 	handle, err := netlink.NewHandleAt(iface.NetNS)
 	if err != nil {
 		return fmt.Errorf("failed to create handle for netns (%s): %w", iface.NetNS.String(), err)
@@ -381,17 +509,17 @@ func (m *FlowFetcher) Register(iface ifaces.Interface) error {
 		return fmt.Errorf("failed to remove previous filters: %w", err)
 	}
 
-	if err := m.registerEgress(iface, ipvlan, handle); err != nil {
+	if err := m.registerEgress(iface, profile, ipvlan, handle); err != nil {
 		return err
 	}
 
-	return m.registerIngress(iface, ipvlan, handle)
+	return m.registerIngress(iface, profile, ipvlan, handle)
 }
 
-func (m *FlowFetcher) registerEgress(iface ifaces.Interface, ipvlan netlink.Link, handle *netlink.Handle) error {
+func (m *FlowFetcher) registerEgress(iface ifaces.Interface, profile *InterfaceProfile, ipvlan netlink.Link, handle *netlink.Handle) error {
 	ilog := log.WithField("iface", iface)
-	if !m.enableEgress {
-		ilog.Debug("ignoring egress traffic, according to user configuration")
+	if !profile.Egress {
+		ilog.Debug("ignoring egress traffic, according to the interface profile")
 		return nil
 	}
 	// Fetch events on egress
@@ -422,10 +550,10 @@ func (m *FlowFetcher) registerEgress(iface ifaces.Interface, ipvlan netlink.Link
 	return nil
 }
 
-func (m *FlowFetcher) registerIngress(iface ifaces.Interface, ipvlan netlink.Link, handle *netlink.Handle) error {
+func (m *FlowFetcher) registerIngress(iface ifaces.Interface, profile *InterfaceProfile, ipvlan netlink.Link, handle *netlink.Handle) error {
 	ilog := log.WithField("iface", iface)
-	if !m.enableIngress {
-		ilog.Debug("ignoring ingress traffic, according to user configuration")
+	if !profile.Ingress {
+		ilog.Debug("ignoring ingress traffic, according to the interface profile")
 		return nil
 	}
 	// Fetch events on ingress
@@ -470,6 +598,11 @@ func (m *FlowFetcher) Close() error {
 			errs = append(errs, err)
 		}
 	}
+	if m.pktDropsKprobeLink != nil {
+		if err := m.pktDropsKprobeLink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	if m.rttFentryLink != nil {
 		if err := m.rttFentryLink.Close(); err != nil {
 			errs = append(errs, err)
@@ -480,6 +613,16 @@ func (m *FlowFetcher) Close() error {
 			errs = append(errs, err)
 		}
 	}
+	if m.qdiscEnqueueLink != nil {
+		if err := m.qdiscEnqueueLink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if m.qdiscXmitLink != nil {
+		if err := m.qdiscXmitLink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	// m.ringbufReader.Read is a blocking operation, so we need to close the ring buffer
 	// from another goroutine to avoid the system not being able to exit if there
 	// isn't traffic in a given interface
@@ -516,6 +659,12 @@ func (m *FlowFetcher) Close() error {
 		if err := m.objects.FilterMap.Close(); err != nil {
 			errs = append(errs, err)
 		}
+		if err := m.objects.DropStacks.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		if err := m.objects.QdiscSkbs.Close(); err != nil {
+			errs = append(errs, err)
+		}
 		if len(errs) == 0 {
 			m.objects = nil
 		}
@@ -669,6 +818,26 @@ func (m *FlowFetcher) ReadGlobalCounter(met *metrics.Metrics) {
 	}
 }
 
+// LookupDropStack returns the addresses of the frames of a packet drop stack, given the
+// LatestDropStackId of the drop (the id of the stack in the drop_stacks map, plus one).
+func (m *FlowFetcher) LookupDropStack(stackID uint32) ([]uint64, error) {
+	if stackID == 0 {
+		return nil, nil
+	}
+	var frames [dropStackDepth]uint64
+	if err := m.objects.DropStacks.Lookup(stackID-1, &frames); err != nil {
+		return nil, fmt.Errorf("looking up drop stack %d: %w", stackID-1, err)
+	}
+	stack := frames[:]
+	for i, addr := range frames {
+		if addr == 0 {
+			stack = frames[:i]
+			break
+		}
+	}
+	return stack, nil
+}
+
 // DeleteMapsStaleEntries Look for any stale entries in the features maps and delete them
 func (m *FlowFetcher) DeleteMapsStaleEntries(timeOut time.Duration) {
 	m.lookupAndDeleteDNSMap(timeOut)
@@ -704,7 +873,7 @@ func (m *FlowFetcher) lookupAndDeleteDNSMap(timeOut time.Duration) {
 func kernelSpecificLoadAndAssign(oldKernel bool, spec *ebpf.CollectionSpec) (BpfObjects, error) {
 	objects := BpfObjects{}
 
-	// For older kernel (< 5.14) kfree_sbk drop hook doesn't exists
+	// For older kernel (< 5.14) kfree_sbk drop hook doesn't provide the drop reason: the kprobe is used instead
 	if oldKernel {
 		// Here we define another structure similar to the bpf2go created one but w/o the hooks that does not exist in older kernel
 		// Note: if new hooks are added in the future we need to update the following structures manually
@@ -719,6 +888,7 @@ func kernelSpecificLoadAndAssign(oldKernel bool, spec *ebpf.CollectionSpec) (Bpf
 			TcxIngressPcaParse  *ebpf.Program `ebpf:"tcx_ingress_pca_parse"`
 			TCPRcvFentry        *ebpf.Program `ebpf:"tcp_rcv_fentry"`
 			TCPRcvKprobe        *ebpf.Program `ebpf:"tcp_rcv_kprobe"`
+			KfreeSkbKprobe      *ebpf.Program `ebpf:"kfree_skb_kprobe"`
 		}
 		type NewBpfObjects struct {
 			NewBpfPrograms
@@ -744,6 +914,9 @@ func kernelSpecificLoadAndAssign(oldKernel bool, spec *ebpf.CollectionSpec) (Bpf
 		objects.DnsFlows = newObjects.DnsFlows
 		objects.FilterMap = newObjects.FilterMap
 		objects.GlobalCounters = newObjects.GlobalCounters
+		objects.IfaceProfiles = newObjects.IfaceProfiles
+		objects.DropStacks = newObjects.DropStacks
+		objects.QdiscSkbs = newObjects.QdiscSkbs
 		objects.TcEgressFlowParse = newObjects.TcEgressFlowParse
 		objects.TcIngressFlowParse = newObjects.TcIngressFlowParse
 		objects.TcxEgressFlowParse = newObjects.TcxEgressFlowParse
@@ -754,6 +927,7 @@ func kernelSpecificLoadAndAssign(oldKernel bool, spec *ebpf.CollectionSpec) (Bpf
 		objects.TcxIngressPcaParse = newObjects.TcxIngressPcaParse
 		objects.TcpRcvFentry = newObjects.TCPRcvFentry
 		objects.TcpRcvKprobe = newObjects.TCPRcvKprobe
+		objects.KfreeSkbKprobe = newObjects.KfreeSkbKprobe
 		objects.KfreeSkb = nil
 	} else {
 		if err := spec.LoadAndAssign(&objects, nil); err != nil {
@@ -784,8 +958,6 @@ type PacketFetcher struct {
 	ingressFilters           map[ifaces.Interface]*netlink.BpfFilter
 	perfReader               *perf.Reader
 	cacheMaxSize             int
-	enableIngress            bool
-	enableEgress             bool
 	egressTCXLink            map[ifaces.Interface]link.Link
 	ingressTCXLink           map[ifaces.Interface]link.Link
 	lookupAndDeleteSupported bool
@@ -856,8 +1028,6 @@ func NewPacketFetcher(cfg *FlowFetcherConfig) (*PacketFetcher, error) {
 		ingressFilters:           map[ifaces.Interface]*netlink.BpfFilter{},
 		qdiscs:                   map[ifaces.Interface]*netlink.GenericQdisc{},
 		cacheMaxSize:             cfg.CacheMaxSize,
-		enableIngress:            cfg.EnableIngress,
-		enableEgress:             cfg.EnableEgress,
 		egressTCXLink:            map[ifaces.Interface]link.Link{},
 		ingressTCXLink:           map[ifaces.Interface]link.Link{},
 		lookupAndDeleteSupported: true, // this will be turned off later if found to be not supported
@@ -899,22 +1069,33 @@ func registerInterface(iface ifaces.Interface) (*netlink.GenericQdisc, netlink.L
 	return qdisc, ipvlan, nil
 }
 
-func (p *PacketFetcher) Register(iface ifaces.Interface) error {
-
+// Register attaches the PCA programs to the TC hooks of an interface, according to its profile.
+// Interfaces whose profile disables PCA are ignored.
+func (p *PacketFetcher) Register(iface ifaces.Interface, profile *InterfaceProfile) error {
+	if !profile.PCA {
+		plog.WithField("iface", iface).Debug("PCA disabled by the interface profile. Ignoring")
+		return nil
+	}
 	qdisc, ipvlan, err := registerInterface(iface)
 	if err != nil {
 		return err
 	}
 	p.qdiscs[iface] = qdisc
 
-	if err := p.registerEgress(iface, ipvlan); err != nil {
+	if err := p.registerEgress(iface, profile, ipvlan); err != nil {
 		return err
 	}
-	return p.registerIngress(iface, ipvlan)
+	return p.registerIngress(iface, profile, ipvlan)
 }
 
-func (p *PacketFetcher) AttachTCX(iface ifaces.Interface) error {
+// AttachTCX attaches the PCA programs to the TCX hooks of an interface, according to its
+// profile. Interfaces whose profile disables PCA are ignored.
+func (p *PacketFetcher) AttachTCX(iface ifaces.Interface, profile *InterfaceProfile) error {
 	ilog := log.WithField("iface", iface)
+	if !profile.PCA {
+		plog.WithField("iface", iface).Debug("PCA disabled by the interface profile. Ignoring")
+		return nil
+	}
 	if iface.NetNS != netns.None() {
 		originalNs, err := netns.Get()
 		if err != nil {
@@ -931,7 +1112,7 @@ func (p *PacketFetcher) AttachTCX(iface ifaces.Interface) error {
 		}
 	}
 
-	if p.enableEgress {
+	if profile.Egress {
 		egrLink, err := link.AttachTCX(link.TCXOptions{
 			Program:   p.objects.BpfPrograms.TcxEgressPcaParse,
 			Attach:    ebpf.AttachTCXEgress,
@@ -949,7 +1130,7 @@ func (p *PacketFetcher) AttachTCX(iface ifaces.Interface) error {
 		ilog.WithField("interface", iface.Name).Debug("successfully attach PCA egressTCX hook")
 	}
 
-	if p.enableIngress {
+	if profile.Ingress {
 		ingLink, err := link.AttachTCX(link.TCXOptions{
 			Program:   p.objects.BpfPrograms.TcxIngressPcaParse,
 			Attach:    ebpf.AttachTCXIngress,
@@ -999,7 +1180,11 @@ func fetchEgressEvents(iface ifaces.Interface, ipvlan netlink.Link, parser *ebpf
 
 }
 
-func (p *PacketFetcher) registerEgress(iface ifaces.Interface, ipvlan netlink.Link) error {
+func (p *PacketFetcher) registerEgress(iface ifaces.Interface, profile *InterfaceProfile, ipvlan netlink.Link) error {
+	if !profile.Egress {
+		plog.WithField("iface", iface).Debug("ignoring egress traffic, according to the interface profile")
+		return nil
+	}
 	egressFilter, err := fetchEgressEvents(iface, ipvlan, p.objects.TcEgressPcaParse, "tc_egress_pca_parse")
 	if err != nil {
 		return err
@@ -1038,7 +1223,11 @@ func fetchIngressEvents(iface ifaces.Interface, ipvlan netlink.Link, parser *ebp
 
 }
 
-func (p *PacketFetcher) registerIngress(iface ifaces.Interface, ipvlan netlink.Link) error {
+func (p *PacketFetcher) registerIngress(iface ifaces.Interface, profile *InterfaceProfile, ipvlan netlink.Link) error {
+	if !profile.Ingress {
+		plog.WithField("iface", iface).Debug("ignoring ingress traffic, according to the interface profile")
+		return nil
+	}
 	ingressFilter, err := fetchIngressEvents(iface, ipvlan, p.objects.TcIngressPcaParse, "tc_ingress_pca_parse")
 	if err != nil {
 		return err
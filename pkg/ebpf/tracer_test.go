@@ -0,0 +1,29 @@
+package ebpf
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSpec_MissingObjects(t *testing.T) {
+	// e.g. BPF objects that have not been regenerated after adding a map
+	spec := &ebpf.CollectionSpec{
+		Maps:     map[string]*ebpf.MapSpec{aggregatedFlowsMap: {Name: aggregatedFlowsMap}},
+		Programs: map[string]*ebpf.ProgramSpec{},
+	}
+	assert.Error(t, checkSpec(spec))
+}
+
+func TestResizeMap(t *testing.T) {
+	spec := &ebpf.CollectionSpec{
+		Maps: map[string]*ebpf.MapSpec{aggregatedFlowsMap: {Name: aggregatedFlowsMap, MaxEntries: 10}},
+	}
+	require.NoError(t, resizeMap(spec, aggregatedFlowsMap, 1000))
+	assert.EqualValues(t, 1000, spec.Maps[aggregatedFlowsMap].MaxEntries)
+
+	// a missing map is reported instead of panicking
+	assert.Error(t, resizeMap(spec, qdiscSkbsMap, 1))
+}
@@ -379,6 +379,132 @@ func TestConversions(t *testing.T) {
 				"TimeFlowRttNs":          someDuration.Nanoseconds(),
 			},
 		},
+		{
+			name: "Biflow record",
+			flow: &flow.Record{
+				RawRecord: flow.RawRecord{
+					Id: ebpf.BpfFlowId{
+						EthProtocol:       2048,
+						Direction:         flow.DirectionEgress,
+						SrcMac:            flow.MacAddr{0x04, 0x05, 0x06, 0x07, 0x08, 0x09},
+						DstMac:            flow.MacAddr{0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f},
+						SrcIp:             flow.IPAddr{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0x06, 0x07, 0x08, 0x09},
+						DstIp:             flow.IPAddr{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0x0a, 0x0b, 0x0c, 0x0d},
+						SrcPort:           23000,
+						DstPort:           443,
+						TransportProtocol: 6,
+					},
+					Metrics: ebpf.BpfFlowMetrics{
+						Bytes:   456,
+						Packets: 123,
+						Flags:   0x12,
+						Dscp:    64,
+					},
+				},
+				Reverse: &flow.ReverseMetrics{
+					Bytes:           789,
+					Packets:         100,
+					Flags:           0x110,
+					PktDropBytes:    60,
+					PktDropPackets:  1,
+					BiflowDirection: flow.BiflowInitiator,
+				},
+				Interface:     "eth0",
+				TimeFlowStart: someTime,
+				TimeFlowEnd:   someTime,
+				AgentIP:       net.IPv4(0x0a, 0x0b, 0x0c, 0x0d),
+			},
+			expected: &config.GenericMap{
+				"IfDirections":          []int{1},
+				"Bytes":                 456,
+				"SrcAddr":               "6.7.8.9",
+				"DstAddr":               "10.11.12.13",
+				"Dscp":                  64,
+				"DstMac":                "0A:0B:0C:0D:0E:0F",
+				"SrcMac":                "04:05:06:07:08:09",
+				"Etype":                 2048,
+				"Packets":               123,
+				"Proto":                 6,
+				"SrcPort":               23000,
+				"DstPort":               443,
+				"Flags":                 0x12,
+				"TimeFlowStartMs":       someTime.UnixMilli(),
+				"TimeFlowEndMs":         someTime.UnixMilli(),
+				"Interfaces":            []string{"eth0"},
+				"AgentIP":               "10.11.12.13",
+				"DnsErrno":              0,
+				"ReverseBytes":          789,
+				"ReversePackets":        100,
+				"ReverseFlags":          0x110,
+				"ReversePktDropBytes":   60,
+				"ReversePktDropPackets": 1,
+				"BiflowDirection":       1,
+			},
+		},
+		{
+			name: "Interface metadata record",
+			flow: &flow.Record{
+				RawRecord: flow.RawRecord{
+					Id: ebpf.BpfFlowId{
+						EthProtocol:       2048,
+						Direction:         flow.DirectionIngress,
+						SrcMac:            flow.MacAddr{0x04, 0x05, 0x06, 0x07, 0x08, 0x09},
+						DstMac:            flow.MacAddr{0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f},
+						SrcIp:             flow.IPAddr{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0x06, 0x07, 0x08, 0x09},
+						DstIp:             flow.IPAddr{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0x0a, 0x0b, 0x0c, 0x0d},
+						SrcPort:           23000,
+						DstPort:           443,
+						TransportProtocol: 6,
+						IfIndex:           2,
+						Netns:             4026532500,
+					},
+					Metrics: ebpf.BpfFlowMetrics{
+						Bytes:   456,
+						Packets: 123,
+						Flags:   0x10,
+					},
+				},
+				Interface: "eth0",
+				NetNSName: "cni-7e3b",
+				InterfaceLink: &flow.InterfaceLink{
+					Type:        "veth",
+					MAC:         flow.MacAddr{0x0a, 0x58, 0x0a, 0x80, 0x00, 0x01},
+					MTU:         1400,
+					Master:      "br-int",
+					PeerIfIndex: 17,
+				},
+				TimeFlowStart: someTime,
+				TimeFlowEnd:   someTime,
+				AgentIP:       net.IPv4(0x0a, 0x0b, 0x0c, 0x0d),
+			},
+			expected: &config.GenericMap{
+				"IfDirections":    []int{0},
+				"Bytes":           456,
+				"SrcAddr":         "6.7.8.9",
+				"DstAddr":         "10.11.12.13",
+				"Dscp":            0,
+				"DstMac":          "0A:0B:0C:0D:0E:0F",
+				"SrcMac":          "04:05:06:07:08:09",
+				"Etype":           2048,
+				"Packets":         123,
+				"Proto":           6,
+				"SrcPort":         23000,
+				"DstPort":         443,
+				"Flags":           0x10,
+				"TimeFlowStartMs": someTime.UnixMilli(),
+				"TimeFlowEndMs":   someTime.UnixMilli(),
+				"Interfaces":      []string{"eth0"},
+				"NetNS":           4026532500,
+				"NetNSName":       "cni-7e3b",
+				"IfType":          "veth",
+				"IfMac":           "0A:58:0A:80:00:01",
+				"IfMtu":           1400,
+				"IfMaster":        "br-int",
+				"IfPeerIndex":     17,
+				"AgentIP":         "10.11.12.13",
+				"DnsErrno":        0,
+			},
+		},
 		{
 			name: "Multiple interfaces record",
 			flow: &flow.Record{
@@ -404,9 +530,9 @@ func TestConversions(t *testing.T) {
 						},
 					},
 				},
-				DupList: []map[string]uint8{
-					{"5e6e92caa1d51cf": 0},
-					{"eth0": 1},
+				DupList: []flow.DupEntry{
+					{Interface: "5e6e92caa1d51cf", Direction: 0, Bytes: 64, Packets: 1},
+					{Interface: "eth0", Direction: 1, Bytes: 64, Packets: 1},
 				},
 				TimeFlowStart: someTime,
 				TimeFlowEnd:   someTime,
@@ -414,6 +540,8 @@ func TestConversions(t *testing.T) {
 			},
 			expected: &config.GenericMap{
 				"IfDirections":    []int{0, 1},
+				"IfBytes":         []uint64{64, 64},
+				"IfPackets":       []uint32{1, 1},
 				"Bytes":           64,
 				"SrcAddr":         "6.7.8.9",
 				"DstAddr":         "10.11.12.13",
@@ -467,7 +595,7 @@ func TestConversions(t *testing.T) {
 func normalizeMap(m config.GenericMap) error {
 	for k, v := range m {
 		switch v := v.(type) {
-		case bool, string, int64, []string, []int:
+		case bool, string, int64, []string, []int, []uint32, []uint64:
 			continue
 		default:
 			conv, err := utils.ConvertToUint32(v)
@@ -0,0 +1,139 @@
+package exporter
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mariomac/guara/pkg/test"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ebpf"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	grpc "github.com/netobserv/netobserv-ebpf-agent/pkg/grpc/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/pbflow"
+	test2 "github.com/netobserv/netobserv-ebpf-agent/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGRPCProto_RoundRobin(t *testing.T) {
+	targets, outs := startCollectors(t, 2)
+	opts := fastRetries
+	opts.Balancing = GRPCBalanceRoundRobin
+	exporter, err := StartGRPCProtoPool(grpc.PoolConfig{Targets: targets}, 1, metrics.NewMetrics(&metrics.Settings{}), opts)
+	require.NoError(t, err)
+
+	flows := make(chan []*flow.Record, 10)
+	flows <- []*flow.Record{recordBetween("10.0.0.1", "10.0.0.2"), recordBetween("10.0.0.1", "10.0.0.2")}
+	flows <- []*flow.Record{recordBetween("10.0.0.1", "10.0.0.2"), recordBetween("10.0.0.1", "10.0.0.2")}
+	go exporter.ExportFlows(flows)
+
+	// each single-flow message is sent to a different collector
+	for i := 0; i < 2; i++ {
+		for _, out := range outs {
+			rs := test2.ReceiveTimeout(t, out, timeout)
+			assert.Len(t, rs.Entries, 1)
+		}
+	}
+	close(flows)
+}
+
+func TestGRPCProto_ConversationHash(t *testing.T) {
+	targets, outs := startCollectors(t, 3)
+	opts := fastRetries
+	opts.Balancing = GRPCBalanceConversation
+	exporter, err := StartGRPCProtoPool(grpc.PoolConfig{Targets: targets}, 1000, metrics.NewMetrics(&metrics.Settings{}), opts)
+	require.NoError(t, err)
+
+	const conversations = 30
+	var records []*flow.Record
+	for i := 0; i < conversations; i++ {
+		records = append(records,
+			recordBetween(fmt.Sprintf("10.0.0.%d", i), "10.1.0.1"),
+			recordBetween("10.1.0.1", fmt.Sprintf("10.0.0.%d", i)))
+	}
+	flows := make(chan []*flow.Record, 10)
+	flows <- records
+	flows <- records
+	go exporter.ExportFlows(flows)
+
+	// both directions of a conversation are always submitted to the same collector
+	collectorOf := map[string]int{}
+	received := 0
+	for received < 4*conversations {
+		for i, out := range outs {
+			select {
+			case rs := <-out:
+				for _, r := range rs.Entries {
+					key := conversationKey(r)
+					if c, ok := collectorOf[key]; ok {
+						assert.Equal(t, c, i, "conversation %s submitted to different collectors", key)
+					}
+					collectorOf[key] = i
+					received++
+				}
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+	assert.Len(t, collectorOf, conversations)
+	close(flows)
+}
+
+func TestGRPCProto_Failover(t *testing.T) {
+	targets, outs := startCollectors(t, 1)
+	// add a collector that is not listening
+	port, err := test.FreeTCPPort()
+	require.NoError(t, err)
+	targets = append(targets, fmt.Sprintf("127.0.0.1:%d", port))
+
+	exporter, err := StartGRPCProtoPool(grpc.PoolConfig{Targets: targets}, 1, metrics.NewMetrics(&metrics.Settings{}), fastRetries)
+	require.NoError(t, err)
+
+	flows := make(chan []*flow.Record, 10)
+	for i := 1; i <= 4; i++ {
+		flows <- []*flow.Record{{RawRecord: flow.RawRecord{Metrics: ebpf.BpfFlowMetrics{Bytes: uint64(i)}}}}
+	}
+	go exporter.ExportFlows(flows)
+
+	// all the flows are received by the healthy collector
+	received := map[uint64]struct{}{}
+	for i := 0; i < 4; i++ {
+		rs := test2.ReceiveTimeout(t, outs[0], timeout)
+		require.Len(t, rs.Entries, 1)
+		received[rs.Entries[0].Bytes] = struct{}{}
+	}
+	assert.Len(t, received, 4)
+	close(flows)
+}
+
+func startCollectors(t *testing.T, n int) ([]string, []chan *pbflow.Records) {
+	t.Helper()
+	var targets []string
+	var outs []chan *pbflow.Records
+	for i := 0; i < n; i++ {
+		port, err := test.FreeTCPPort()
+		require.NoError(t, err)
+		out := make(chan *pbflow.Records, 100)
+		coll, err := grpc.StartCollector(port, out)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = coll.Close() })
+		targets = append(targets, fmt.Sprintf("127.0.0.1:%d", port))
+		outs = append(outs, out)
+	}
+	return targets, outs
+}
+
+func recordBetween(src, dst string) *flow.Record {
+	r := &flow.Record{RawRecord: flow.RawRecord{Id: ebpf.BpfFlowId{EthProtocol: 0x0800}}}
+	copy(r.Id.SrcIp[:], net.ParseIP(src).To16())
+	copy(r.Id.DstIp[:], net.ParseIP(dst).To16())
+	return r
+}
+
+func conversationKey(r *pbflow.Record) string {
+	src := pbflow.PBToFlow(r).Id.SrcIp
+	dst := pbflow.PBToFlow(r).Id.DstIp
+	return fmt.Sprint(getFlowKey(&flow.Record{RawRecord: flow.RawRecord{Id: ebpf.BpfFlowId{SrcIp: src, DstIp: dst}}}))
+}
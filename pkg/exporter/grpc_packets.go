@@ -35,8 +35,8 @@ func writeGRPCPacket(time time.Time, data []byte, conn *grpc.ClientConnection) e
 	return err
 }
 
-func StartGRPCPacketSend(hostIP string, hostPort int) (*GRPCPacketProto, error) {
-	clientConn, err := grpc.ConnectClient(hostIP, hostPort)
+func StartGRPCPacketSend(hostIP string, hostPort int, options ...grpc.ClientOption) (*GRPCPacketProto, error) {
+	clientConn, err := grpc.ConnectClient(hostIP, hostPort, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -2,6 +2,9 @@ package exporter
 
 import (
 	"context"
+	"errors"
+	"hash/fnv"
+	"time"
 
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
 	grpc "github.com/netobserv/netobserv-ebpf-agent/pkg/grpc/flow"
@@ -11,61 +14,304 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 var glog = logrus.WithField("component", "exporter/GRPCProto")
 
 const componentGRPC = "grpc"
 
+// Strategies to balance the flows across the collector endpoints
+const (
+	// GRPCBalanceRoundRobin submits each message to the next healthy endpoint
+	GRPCBalanceRoundRobin = "round-robin"
+	// GRPCBalanceConversation submits all the flows between the same pair of IPs to the same
+	// endpoint, as long as it is healthy
+	GRPCBalanceConversation = "conversation-hash"
+)
+
 // GRPCProto flow exporter. Its ExportFlows method accepts slices of *flow.Record
 // by its input channel, converts them to *pbflow.Records instances, and submits
-// them to the collector.
+// them to the collector endpoints.
 type GRPCProto struct {
-	hostIP     string
-	hostPort   int
-	clientConn *grpc.ClientConnection
+	pool *grpc.ClientPool
 	// maxFlowsPerMessage limits the maximum number of flows per GRPC message.
 	// If a message contains more flows than this number, the GRPC message will be split into
 	// multiple messages.
 	maxFlowsPerMessage int
-	metrics            *metrics.Metrics
-	batchCounter       prometheus.Counter
+	sendOptions        GRPCSendOptions
+	// senders by endpoint address
+	senders map[string]*grpcSender
+	// nextEndpoint is the index of the next endpoint in round-robin balancing
+	nextEndpoint int
+	metrics      *metrics.Metrics
+	batchCounter prometheus.Counter
 }
 
-func StartGRPCProto(hostIP string, hostPort int, maxFlowsPerMessage int, m *metrics.Metrics) (*GRPCProto, error) {
-	clientConn, err := grpc.ConnectClient(hostIP, hostPort)
+type grpcSender struct {
+	endpoint *grpc.Endpoint
+	// streamer is nil if the unary Send RPC is used
+	streamer *flowStreamer
+}
+
+// GRPCSendOptions configures how the flow batches are delivered to the collector
+type GRPCSendOptions struct {
+	// Timeout for each unary Send invocation or, in streaming mode, for waiting an acknowledgement
+	// from the collector. Zero means no timeout.
+	Timeout time.Duration
+	// MaxRetries is the number of times a failed submission is retried before discarding the flows
+	MaxRetries int
+	// InitialBackoff is the time to wait before the first retry. It is doubled on each retry.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum time to wait between retries
+	MaxBackoff time.Duration
+	// Streaming submits the flows through the Stream RPC, with acknowledgements and flow control.
+	// If the collector does not implement it, the exporter falls back to the unary Send RPC.
+	Streaming bool
+	// Balancing strategy across the collector endpoints: GRPCBalanceRoundRobin (default) or
+	// GRPCBalanceConversation
+	Balancing string
+}
+
+// StartGRPCProto starts a GRPCProto exporter that submits the flows to a single collector
+func StartGRPCProto(hostIP string, hostPort int, maxFlowsPerMessage int, m *metrics.Metrics, sendOptions GRPCSendOptions, options ...grpc.ClientOption) (*GRPCProto, error) {
+	return StartGRPCProtoPool(grpc.PoolConfig{
+		Targets: []string{utils.GetSocket(hostIP, hostPort)},
+		ClientOptions: func(string) []grpc.ClientOption {
+			return options
+		},
+	}, maxFlowsPerMessage, m, sendOptions)
+}
+
+// StartGRPCProtoPool starts a GRPCProto exporter that balances the flows across the endpoints of
+// the provided pool configuration. If the submission to an endpoint fails, it is marked as
+// unhealthy and the flows are submitted to the next healthy endpoint.
+func StartGRPCProtoPool(poolConfig grpc.PoolConfig, maxFlowsPerMessage int, m *metrics.Metrics, sendOptions GRPCSendOptions) (*GRPCProto, error) {
+	pool, err := grpc.NewClientPool(poolConfig)
 	if err != nil {
 		return nil, err
 	}
 	return &GRPCProto{
-		hostIP:             hostIP,
-		hostPort:           hostPort,
-		clientConn:         clientConn,
+		pool:               pool,
 		maxFlowsPerMessage: maxFlowsPerMessage,
+		sendOptions:        sendOptions,
+		senders:            map[string]*grpcSender{},
 		metrics:            m,
 		batchCounter:       m.CreateBatchCounter(componentGRPC),
 	}, nil
 }
 
+func (g *GRPCProto) backoff() wait.Backoff {
+	return wait.Backoff{
+		Duration: g.sendOptions.InitialBackoff,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    g.sendOptions.MaxRetries,
+		Cap:      g.sendOptions.MaxBackoff,
+	}
+}
+
 // ExportFlows accepts slices of *flow.Record by its input channel, converts them
 // to *pbflow.Records instances, and submits them to the collector.
 func (g *GRPCProto) ExportFlows(input <-chan []*flow.Record) {
-	socket := utils.GetSocket(g.hostIP, g.hostPort)
-	log := glog.WithField("collector", socket)
 	for inputRecords := range input {
 		g.metrics.EvictionCounter.WithSource(componentGRPC).Inc()
-		for _, pbRecords := range pbflow.FlowsToPB(inputRecords, g.maxFlowsPerMessage) {
-			log.Debugf("sending %d records", len(pbRecords.Entries))
-			if _, err := g.clientConn.Client().Send(context.TODO(), pbRecords); err != nil {
+		g.syncSenders()
+		g.export(inputRecords)
+	}
+	for _, s := range g.senders {
+		if s.streamer == nil {
+			continue
+		}
+		if discarded := countFlows(s.streamer.Close()); discarded > 0 {
+			glog.WithFields(logrus.Fields{"collector": s.endpoint.Address, "flows": discarded}).
+				Warn("closing flow stream without acknowledgement of all the flows")
+			g.metrics.DroppedFlowsCounter.WithSourceAndReason(componentGRPC, "unacknowledged").Add(float64(discarded))
+		}
+	}
+	if err := g.pool.Close(); err != nil {
+		glog.WithError(err).Warn("couldn't close flow export client")
+		g.metrics.Errors.WithErrorName(componentGRPC, "CannotCloseClient").Inc()
+	}
+}
+
+func (g *GRPCProto) export(records []*flow.Record) {
+	endpoints := g.pool.Healthy()
+	if len(endpoints) == 0 {
+		// keep trying with all the endpoints rather than discarding the flows
+		endpoints = g.pool.Endpoints()
+	}
+	if g.sendOptions.Balancing == GRPCBalanceConversation && len(endpoints) > 1 {
+		groups := make([][]*flow.Record, len(endpoints))
+		for _, record := range records {
+			i := endpointForKey(endpoints, getFlowKey(record))
+			groups[i] = append(groups[i], record)
+		}
+		for i, group := range groups {
+			for _, pbRecords := range pbflow.FlowsToPB(group, g.maxFlowsPerMessage) {
+				g.sendWithFailover(endpoints, i, pbRecords)
+				g.countBatch(pbRecords)
+			}
+		}
+		return
+	}
+	for _, pbRecords := range pbflow.FlowsToPB(records, g.maxFlowsPerMessage) {
+		g.sendWithFailover(endpoints, g.roundRobin(len(endpoints)), pbRecords)
+		g.countBatch(pbRecords)
+	}
+}
+
+func (g *GRPCProto) roundRobin(endpoints int) int {
+	g.nextEndpoint = (g.nextEndpoint + 1) % endpoints
+	return g.nextEndpoint
+}
+
+func (g *GRPCProto) countBatch(pbRecords *pbflow.Records) {
+	g.batchCounter.Inc()
+	g.metrics.EvictedFlowsCounter.WithSource(componentGRPC).Add(float64(len(pbRecords.Entries)))
+}
+
+// endpointForKey returns the index of the endpoint for a given conversation key, using
+// rendezvous hashing, so only the conversations of an endpoint are moved when it is removed.
+func endpointForKey(endpoints []*grpc.Endpoint, key []byte) int {
+	selected, maxScore := 0, uint64(0)
+	for i, e := range endpoints {
+		h := fnv.New64a()
+		_, _ = h.Write(key)
+		_, _ = h.Write([]byte(e.Address))
+		if score := h.Sum64(); score > maxScore {
+			selected, maxScore = i, score
+		}
+	}
+	return selected
+}
+
+// sendWithFailover submits the records to the endpoint at the given index. If it fails, the
+// endpoint is marked as unhealthy and the records, as well as any other unacknowledged record, are
+// submitted to the next endpoints.
+func (g *GRPCProto) sendWithFailover(endpoints []*grpc.Endpoint, first int, pbRecords *pbflow.Records) {
+	batches := []*pbflow.Records{pbRecords}
+	for n := 0; n < len(endpoints) && len(batches) > 0; n++ {
+		endpoint := endpoints[(first+n)%len(endpoints)]
+		log := glog.WithField("collector", endpoint.Address)
+		var failed []*pbflow.Records
+		for i, batch := range batches {
+			log.Debugf("sending %d records", len(batch.Entries))
+			discarded, err := g.sendTo(log, endpoint, batch)
+			if err != nil {
 				g.metrics.Errors.WithErrorName(componentGRPC, "CannotWriteMessage").Inc()
 				log.WithError(err).Error("couldn't send flow records to collector")
+				g.pool.MarkUnhealthy(endpoint)
+				failed = append(discarded, batches[i+1:]...)
+				break
 			}
-			g.batchCounter.Inc()
-			g.metrics.EvictedFlowsCounter.WithSource(componentGRPC).Add(float64(len(pbRecords.Entries)))
 		}
+		batches = failed
 	}
-	if err := g.clientConn.Close(); err != nil {
-		log.WithError(err).Warn("couldn't close flow export client")
-		g.metrics.Errors.WithErrorName(componentGRPC, "CannotCloseClient").Inc()
+	if dropped := countFlows(batches); dropped > 0 {
+		g.metrics.DroppedFlowsCounter.WithSourceAndReason(componentGRPC, "send-failure").Add(float64(dropped))
+	}
+}
+
+// sendTo submits the records to the given endpoint. On error, it returns the discarded batches,
+// including the submitted records.
+func (g *GRPCProto) sendTo(log *logrus.Entry, endpoint *grpc.Endpoint, pbRecords *pbflow.Records) ([]*pbflow.Records, error) {
+	s := g.senderFor(endpoint)
+	if s.streamer == nil {
+		if err := g.sendUnary(log, endpoint.Client(), pbRecords); err != nil {
+			return []*pbflow.Records{pbRecords}, err
+		}
+		return nil, nil
+	}
+	discarded, err := s.streamer.Send(pbRecords)
+	if err == nil || !errors.Is(err, errStreamUnimplemented) {
+		return discarded, err
+	}
+	log.WithError(err).Warn("falling back to unary gRPC flows submission")
+	s.streamer = nil
+	// the discarded batches include the current one
+	for i, batch := range discarded {
+		if err := g.sendUnary(log, endpoint.Client(), batch); err != nil {
+			return discarded[i:], err
+		}
+	}
+	return nil, nil
+}
+
+func (g *GRPCProto) senderFor(endpoint *grpc.Endpoint) *grpcSender {
+	s, ok := g.senders[endpoint.Address]
+	if !ok {
+		s = &grpcSender{endpoint: endpoint}
+		if g.sendOptions.Streaming {
+			s.streamer = newFlowStreamer(endpoint.Client(), g.sendOptions.Timeout, g.sendOptions.MaxRetries, g.backoff())
+		}
+		g.senders[endpoint.Address] = s
+	}
+	return s
+}
+
+// syncSenders removes the senders of the endpoints that have been removed from the pool,
+// submitting their unacknowledged flows to the remaining endpoints
+func (g *GRPCProto) syncSenders() {
+	current := map[*grpc.Endpoint]struct{}{}
+	for _, e := range g.pool.Endpoints() {
+		current[e] = struct{}{}
+	}
+	var orphans []*pbflow.Records
+	for address, s := range g.senders {
+		if _, ok := current[s.endpoint]; ok {
+			continue
+		}
+		delete(g.senders, address)
+		if s.streamer != nil {
+			orphans = append(orphans, s.streamer.Abort()...)
+		}
+	}
+	if len(orphans) == 0 {
+		return
+	}
+	endpoints := g.pool.Healthy()
+	if len(endpoints) == 0 {
+		endpoints = g.pool.Endpoints()
+	}
+	for _, batch := range orphans {
+		g.sendWithFailover(endpoints, g.roundRobin(len(endpoints)), batch)
+	}
+}
+
+// sendUnary submits the records through the unary Send RPC, retrying transient errors
+func (g *GRPCProto) sendUnary(log *logrus.Entry, client pbflow.CollectorClient, pbRecords *pbflow.Records) error {
+	backoff := g.backoff()
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		if g.sendOptions.Timeout > 0 {
+			ctx, cancel = context.WithTimeout(context.Background(), g.sendOptions.Timeout)
+		}
+		_, err := client.Send(ctx, pbRecords)
+		cancel()
+		if err == nil || attempt >= g.sendOptions.MaxRetries || !isRetryable(err) {
+			return err
+		}
+		log.WithError(err).WithField("attempt", attempt+1).Debug("couldn't send flow records. Retrying")
+		time.Sleep(backoff.Step())
+	}
+}
+
+func isRetryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+func countFlows(batches []*pbflow.Records) int {
+	flows := 0
+	for _, batch := range batches {
+		flows += len(batch.Entries)
 	}
+	return flows
 }
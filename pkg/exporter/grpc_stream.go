@@ -0,0 +1,239 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/pbflow"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var errStreamUnimplemented = errors.New("collector does not implement the Stream RPC")
+
+// flowStreamer submits flow batches through the pbflow.Collector Stream RPC. It keeps the
+// batches that haven't been acknowledged yet, so they can be submitted again if the stream is
+// broken and needs to be re-opened. It only sends batches while it has credits granted by the
+// collector, so a collector that is not able to keep up will slow down the export.
+// It is not safe for concurrent access.
+type flowStreamer struct {
+	client     pbflow.CollectorClient
+	timeout    time.Duration
+	maxRetries int
+	backoff    wait.Backoff
+
+	stream  pbflow.Collector_StreamClient
+	cancel  context.CancelFunc
+	replies chan *pbflow.StreamReply
+	// recvErr is set before closing the replies channel
+	recvErr error
+	credits uint32
+
+	sequence uint64
+	// pending batches, ordered by sequence, that haven't been acknowledged
+	pending []*pbflow.RecordsBatch
+	// number of pending batches that have been sent through the current stream
+	sent int
+}
+
+func newFlowStreamer(client pbflow.CollectorClient, timeout time.Duration, maxRetries int, backoff wait.Backoff) *flowStreamer {
+	return &flowStreamer{
+		client:     client,
+		timeout:    timeout,
+		maxRetries: maxRetries,
+		backoff:    backoff,
+	}
+}
+
+// Send submits the records and returns once they have been sent through the stream. The records
+// might not have been acknowledged yet. If the stream can't be (re)established after the
+// configured retries, the pending batches are discarded and returned with the error.
+func (s *flowStreamer) Send(records *pbflow.Records) ([]*pbflow.Records, error) {
+	s.sequence++
+	s.pending = append(s.pending, &pbflow.RecordsBatch{Sequence: s.sequence, Records: records})
+	if err := s.withRetries(s.sendPending); err != nil {
+		return s.discardPending(), err
+	}
+	return nil, nil
+}
+
+// Close waits for the acknowledgement of the pending batches, submitting them again if the
+// stream is broken, and closes the stream. It returns the unacknowledged batches.
+func (s *flowStreamer) Close() []*pbflow.Records {
+	if len(s.pending) > 0 {
+		if err := s.withRetries(s.sendAndWaitAcks); err != nil {
+			glog.WithError(err).Debug("error waiting for the pending acknowledgements")
+		}
+	}
+	if s.stream != nil {
+		if err := s.stream.CloseSend(); err != nil {
+			glog.WithError(err).Debug("error closing the flow stream")
+		}
+	}
+	s.closeStream()
+	return s.discardPending()
+}
+
+// Abort closes the stream without waiting for acknowledgements, and returns the unacknowledged
+// batches.
+func (s *flowStreamer) Abort() []*pbflow.Records {
+	s.closeStream()
+	return s.discardPending()
+}
+
+// withRetries invokes the passed function, re-opening the stream with exponential backoff
+// after each failure, until it succeeds or the maximum number of retries is reached.
+func (s *flowStreamer) withRetries(fn func() error) error {
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		s.closeStream()
+		if errors.Is(err, errStreamUnimplemented) || attempt >= s.maxRetries {
+			return err
+		}
+		glog.WithError(err).WithField("attempt", attempt+1).Debug("flow stream failed. Retrying")
+		time.Sleep(backoff.Step())
+	}
+}
+
+func (s *flowStreamer) sendAndWaitAcks() error {
+	if err := s.sendPending(); err != nil {
+		return err
+	}
+	for len(s.pending) > 0 {
+		if err := s.waitReply(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *flowStreamer) sendPending() error {
+	if s.stream == nil {
+		if err := s.openStream(); err != nil {
+			return err
+		}
+	}
+	for s.sent < len(s.pending) {
+		for s.credits == 0 {
+			if err := s.waitReply(); err != nil {
+				return err
+			}
+		}
+		if err := s.stream.Send(s.pending[s.sent]); err != nil {
+			return fmt.Errorf("sending batch %d: %w", s.pending[s.sent].Sequence, err)
+		}
+		s.sent++
+		s.credits--
+	}
+	// process, without blocking, any acknowledgement that might have been already received
+	for {
+		select {
+		case reply, ok := <-s.replies:
+			if !ok {
+				return s.recvErr
+			}
+			s.handleReply(reply)
+		default:
+			return nil
+		}
+	}
+}
+
+func (s *flowStreamer) openStream() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := s.client.Stream(ctx)
+	if err != nil {
+		cancel()
+		return streamError(err)
+	}
+	s.stream = stream
+	s.cancel = cancel
+	s.credits = 0
+	s.sent = 0
+	replies := make(chan *pbflow.StreamReply, 1)
+	s.replies = replies
+	go func() {
+		defer close(replies)
+		for {
+			reply, err := stream.Recv()
+			if err != nil {
+				s.recvErr = streamError(err)
+				return
+			}
+			replies <- reply
+		}
+	}()
+	return nil
+}
+
+func (s *flowStreamer) closeStream() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	if s.replies != nil {
+		// wait for the receiving goroutine to finish
+		for range s.replies {
+		}
+	}
+	s.stream, s.cancel, s.replies = nil, nil, nil
+	s.credits, s.sent = 0, 0
+}
+
+// waitReply blocks until a reply from the collector is received and processed
+func (s *flowStreamer) waitReply() error {
+	var timeout <-chan time.Time
+	if s.timeout > 0 {
+		timer := time.NewTimer(s.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case reply, ok := <-s.replies:
+		if !ok {
+			return s.recvErr
+		}
+		s.handleReply(reply)
+		return nil
+	case <-timeout:
+		return fmt.Errorf("no reply from the collector after %s", s.timeout)
+	}
+}
+
+func (s *flowStreamer) handleReply(reply *pbflow.StreamReply) {
+	s.credits += reply.Credits
+	acked := 0
+	for acked < len(s.pending) && s.pending[acked].Sequence <= reply.AckSequence {
+		acked++
+	}
+	if acked > 0 {
+		s.pending = s.pending[acked:]
+		s.sent -= acked
+		if s.sent < 0 {
+			s.sent = 0
+		}
+	}
+}
+
+func (s *flowStreamer) discardPending() []*pbflow.Records {
+	discarded := make([]*pbflow.Records, 0, len(s.pending))
+	for _, batch := range s.pending {
+		discarded = append(discarded, batch.Records)
+	}
+	s.pending = nil
+	return discarded
+}
+
+func streamError(err error) error {
+	if status.Code(err) == codes.Unimplemented {
+		return fmt.Errorf("%w: %s", errStreamUnimplemented, err.Error())
+	}
+	return err
+}
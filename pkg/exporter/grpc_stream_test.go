@@ -0,0 +1,185 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mariomac/guara/pkg/test"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ebpf"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	grpc "github.com/netobserv/netobserv-ebpf-agent/pkg/grpc/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/pbflow"
+	test2 "github.com/netobserv/netobserv-ebpf-agent/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var fastRetries = GRPCSendOptions{
+	Timeout:        timeout,
+	MaxRetries:     3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     10 * time.Millisecond,
+}
+
+func TestGRPCProto_Streaming_Backpressure(t *testing.T) {
+	port, err := test.FreeTCPPort()
+	require.NoError(t, err)
+	// unbuffered channel: the collector won't accept more flows until they are read
+	serverOut := make(chan *pbflow.Records)
+	coll, err := grpc.StartCollector(port, serverOut, grpc.WithStreamCredits(2))
+	require.NoError(t, err)
+	defer coll.Close()
+
+	opts := fastRetries
+	opts.Streaming = true
+	exporter, err := StartGRPCProto("127.0.0.1", port, 1000, metrics.NewMetrics(&metrics.Settings{}), opts)
+	require.NoError(t, err)
+
+	flows := make(chan []*flow.Record, 10)
+	for i := 1; i <= 6; i++ {
+		flows <- []*flow.Record{{RawRecord: flow.RawRecord{Metrics: ebpf.BpfFlowMetrics{Bytes: uint64(i)}}}}
+	}
+	go exporter.ExportFlows(flows)
+
+	// the exporter must stop reading flows after it runs out of credits: the first batch is
+	// blocked in the collector, the second one is in flight, and the third one waits for credits
+	time.Sleep(200 * time.Millisecond)
+	assert.Len(t, flows, 3)
+
+	for i := 1; i <= 6; i++ {
+		rs := test2.ReceiveTimeout(t, serverOut, timeout)
+		require.Len(t, rs.Entries, 1)
+		assert.EqualValues(t, i, rs.Entries[0].Bytes)
+	}
+	close(flows)
+}
+
+func TestGRPCProto_Streaming_Reconnect(t *testing.T) {
+	serverOut := make(chan *pbflow.Records, 10)
+	collector := &flakyCollector{out: serverOut, streamFailures: 1}
+	port := startTestCollector(t, collector)
+
+	opts := fastRetries
+	opts.Streaming = true
+	exporter, err := StartGRPCProto("127.0.0.1", port, 1000, metrics.NewMetrics(&metrics.Settings{}), opts)
+	require.NoError(t, err)
+
+	flows := make(chan []*flow.Record, 10)
+	flows <- []*flow.Record{{RawRecord: flow.RawRecord{Metrics: ebpf.BpfFlowMetrics{Bytes: 123}}}}
+	close(flows)
+	// the first stream breaks before acknowledging the batch, so it is submitted again
+	exporter.ExportFlows(flows)
+
+	rs := test2.ReceiveTimeout(t, serverOut, timeout)
+	require.Len(t, rs.Entries, 1)
+	assert.EqualValues(t, 123, rs.Entries[0].Bytes)
+	assert.EqualValues(t, 2, collector.streams.Load())
+}
+
+func TestGRPCProto_Streaming_FallbackToUnary(t *testing.T) {
+	serverOut := make(chan *pbflow.Records, 10)
+	port := startTestCollector(t, &unaryCollector{out: serverOut})
+
+	opts := fastRetries
+	opts.Streaming = true
+	exporter, err := StartGRPCProto("127.0.0.1", port, 1000, metrics.NewMetrics(&metrics.Settings{}), opts)
+	require.NoError(t, err)
+
+	flows := make(chan []*flow.Record, 10)
+	flows <- []*flow.Record{{RawRecord: flow.RawRecord{Metrics: ebpf.BpfFlowMetrics{Bytes: 1}}}}
+	flows <- []*flow.Record{{RawRecord: flow.RawRecord{Metrics: ebpf.BpfFlowMetrics{Bytes: 2}}}}
+	go exporter.ExportFlows(flows)
+
+	for i := 1; i <= 2; i++ {
+		rs := test2.ReceiveTimeout(t, serverOut, timeout)
+		require.Len(t, rs.Entries, 1)
+		assert.EqualValues(t, i, rs.Entries[0].Bytes)
+	}
+	close(flows)
+}
+
+func TestGRPCProto_Unary_Retries(t *testing.T) {
+	serverOut := make(chan *pbflow.Records, 10)
+	collector := &unaryCollector{out: serverOut, failures: 2}
+	port := startTestCollector(t, collector)
+
+	exporter, err := StartGRPCProto("127.0.0.1", port, 1000, metrics.NewMetrics(&metrics.Settings{}), fastRetries)
+	require.NoError(t, err)
+
+	flows := make(chan []*flow.Record, 10)
+	flows <- []*flow.Record{{RawRecord: flow.RawRecord{Metrics: ebpf.BpfFlowMetrics{Bytes: 456}}}}
+	go exporter.ExportFlows(flows)
+
+	rs := test2.ReceiveTimeout(t, serverOut, timeout)
+	require.Len(t, rs.Entries, 1)
+	assert.EqualValues(t, 456, rs.Entries[0].Bytes)
+	assert.EqualValues(t, 3, collector.calls.Load())
+	close(flows)
+}
+
+func startTestCollector(t *testing.T, collector pbflow.CollectorServer) int {
+	t.Helper()
+	port, err := test.FreeTCPPort()
+	require.NoError(t, err)
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	require.NoError(t, err)
+	server := ggrpc.NewServer()
+	pbflow.RegisterCollectorServer(server, collector)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+	return port
+}
+
+// unaryCollector does not implement the Stream RPC, and fails the first Send invocations
+type unaryCollector struct {
+	pbflow.UnimplementedCollectorServer
+	out      chan<- *pbflow.Records
+	failures int32
+	calls    atomic.Int32
+}
+
+func (c *unaryCollector) Send(_ context.Context, records *pbflow.Records) (*pbflow.CollectorReply, error) {
+	if c.calls.Add(1) <= c.failures {
+		return nil, status.Error(codes.Unavailable, "try again later")
+	}
+	c.out <- records
+	return &pbflow.CollectorReply{}, nil
+}
+
+// flakyCollector breaks the first streams after receiving a batch, without acknowledging it
+type flakyCollector struct {
+	pbflow.UnimplementedCollectorServer
+	out            chan<- *pbflow.Records
+	streamFailures int32
+	streams        atomic.Int32
+}
+
+func (c *flakyCollector) Stream(stream pbflow.Collector_StreamServer) error {
+	broken := c.streams.Add(1) <= c.streamFailures
+	if err := stream.Send(&pbflow.StreamReply{Credits: 4}); err != nil {
+		return err
+	}
+	for {
+		batch, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		if broken {
+			return status.Error(codes.Unavailable, "connection reset")
+		}
+		c.out <- batch.Records
+		if err := stream.Send(&pbflow.StreamReply{AckSequence: batch.Sequence, Credits: 1}); err != nil {
+			return err
+		}
+	}
+}
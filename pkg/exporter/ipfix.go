@@ -1,33 +1,168 @@
 package exporter
 
 import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
 	"net"
+	"strings"
+	"time"
 
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/utils"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"github.com/vmware/go-ipfix/pkg/entities"
-	ipfixExporter "github.com/vmware/go-ipfix/pkg/exporter"
 	"github.com/vmware/go-ipfix/pkg/registry"
 )
 
 var ilog = logrus.WithField("component", "exporter/IPFIXProto")
 
-// TODO: encode also the equivalent of the Protobuf's AgentIP field in a format that is binary-
-// compatible with OVN-K.
+// ianaRecordElements are the IANA information elements that are common to the IPv4 and IPv6
+// templates
+var ianaRecordElements = []string{
+	"octetDeltaCount",
+	"tcpControlBits",
+	"flowStartSeconds",
+	"flowStartMilliseconds",
+	"flowEndSeconds",
+	"flowEndMilliseconds",
+	"packetDeltaCount",
+	"interfaceName",
+	"ipClassOfService",
+	"exporterIPv4Address",
+	"exporterIPv6Address",
+	"droppedOctetDeltaCount",
+	"droppedPacketDeltaCount",
+}
+
+const (
+	componentIPFIX = "ipfix"
+
+	ipfixVersion              = 10
+	ipfixMessageHeaderLen     = 16
+	ipfixSetHeaderLen         = 4
+	ipfixTemplateSetID        = 2
+	ipfixOptionsTemplateSetID = 3
+	ipfixObservationDomainID  = 1
+	ipfixTemplateIDv4         = 256
+	ipfixTemplateIDv6         = 257
+	ipfixTemplateIDStats      = 258
+	ipfixTemplateIDBiflowV4   = 259
+	ipfixTemplateIDBiflowV6   = 260
+	// ipfixRandomSampling is the samplingAlgorithm value for random packet sampling
+	ipfixRandomSampling = 2
+)
+
+// ipfixV4Elements and ipfixV6Elements are the IANA information elements that are specific to the
+// IPv4 and IPv6 templates, respectively
+var ipfixV4Elements = []string{
+	"ethernetType",
+	"flowDirection",
+	"sourceMacAddress",
+	"destinationMacAddress",
+	"sourceIPv4Address",
+	"destinationIPv4Address",
+	"protocolIdentifier",
+	"sourceTransportPort",
+	"destinationTransportPort",
+	"icmpTypeIPv4",
+	"icmpCodeIPv4",
+}
+
+var ipfixV6Elements = []string{
+	"ethernetType",
+	"flowDirection",
+	"sourceMacAddress",
+	"destinationMacAddress",
+	"sourceIPv6Address",
+	"destinationIPv6Address",
+	"nextHeaderIPv6",
+	"sourceTransportPort",
+	"destinationTransportPort",
+	"icmpTypeIPv6",
+	"icmpCodeIPv6",
+}
+
+// ipfixReverseElements are the RFC 5103 reverse information elements of the biflow templates
+var ipfixReverseElements = []string{
+	"reverseOctetDeltaCount",
+	"reversePacketDeltaCount",
+	"reverseTcpControlBits",
+	"reverseDroppedOctetDeltaCount",
+	"reverseDroppedPacketDeltaCount",
+}
+
+// ipfixStatsElements are the fields of the options template for the exporting process
+// statistics. The first one is the scope.
+var ipfixStatsElements = []string{
+	"observationDomainId",
+	"exportedMessageTotalCount",
+	"exportedFlowRecordTotalCount",
+	"notSentFlowTotalCount",
+	"samplingInterval",
+	"samplingAlgorithm",
+}
 
+// IPFIXConfig configures the IPFIX exporter
+type IPFIXConfig struct {
+	TargetHost string
+	TargetPort int
+	// Transport protocol: tcp or udp
+	Transport string
+	// TemplateRefresh is the period to send again the templates over UDP, so collectors that
+	// restart or lose packets can decode the data records (RFC 7011, section 8.4). It is ignored
+	// over TCP, where the templates are sent once per connection.
+	TemplateRefresh time.Duration
+	// StatsInterval is the period to export the exporting process statistics, as options data
+	// records. Zero disables them.
+	StatsInterval time.Duration
+	// Sampling is the packet sampling interval. 0 or 1 means no sampling.
+	Sampling int
+	// MaxMessageSize is the maximum size of the IPFIX messages. The flows are batched in data sets
+	// up to that size.
+	MaxMessageSize int
+	// Biflow adds the IPv4 and IPv6 bidirectional flow templates, with the RFC 5103 reverse
+	// information elements, for the flows that have been stitched with their reverse flows
+	Biflow bool
+}
+
+// IPFIX exporter, over TCP or UDP. Each message contains a single set, and each template set a
+// single template, so the collectors that only decode the first set or template of a message can
+// still decode all the flows.
 type IPFIX struct {
-	hostIP       string
-	hostPort     int
-	exporter     *ipfixExporter.ExportingProcess
-	templateIDv4 uint16
-	templateIDv6 uint16
-	entitiesV4   []entities.InfoElementWithValue
-	entitiesV6   []entities.InfoElementWithValue
+	cfg    IPFIXConfig
+	writer io.WriteCloser
+	// refreshTemplates is true if the templates must be sent periodically, as over UDP
+	refreshTemplates bool
+	now              func() time.Time
+
+	entitiesV4       []entities.InfoElementWithValue
+	entitiesV6       []entities.InfoElementWithValue
+	entitiesBiflowV4 []entities.InfoElementWithValue
+	entitiesBiflowV6 []entities.InfoElementWithValue
+	entitiesStats    []entities.InfoElementWithValue
+	// encoded template sets, each one with a single template
+	templates [][]byte
+
+	// sequence is the number of data records sent, as reported in the message header
+	sequence         uint32
+	exportedMessages uint64
+	exportedFlows    uint64
+	notSentFlows     uint64
+
+	metrics      *metrics.Metrics
+	batchCounter prometheus.Counter
 }
 
 func addElementToTemplate(log *logrus.Entry, elementName string, value []byte, elements *[]entities.InfoElementWithValue) error {
-	element, err := registry.GetInfoElement(elementName, registry.IANAEnterpriseID)
+	return addEnterpriseElementToTemplate(log, registry.IANAEnterpriseID, elementName, value, elements)
+}
+
+func addEnterpriseElementToTemplate(log *logrus.Entry, enterpriseID uint32, elementName string, value []byte, elements *[]entities.InfoElementWithValue) error {
+	element, err := registry.GetInfoElement(elementName, enterpriseID)
 	if err != nil {
 		log.WithError(err).Errorf("Did not find the element with name %s", elementName)
 		return err
@@ -42,223 +177,208 @@ func addElementToTemplate(log *logrus.Entry, elementName string, value []byte, e
 }
 
 func AddRecordValuesToTemplate(log *logrus.Entry, elements *[]entities.InfoElementWithValue) error {
-	err := addElementToTemplate(log, "octetDeltaCount", nil, elements)
-	if err != nil {
-		return err
-	}
-	err = addElementToTemplate(log, "tcpControlBits", nil, elements)
-	if err != nil {
-		return err
-	}
-	err = addElementToTemplate(log, "flowStartSeconds", nil, elements)
-	if err != nil {
-		return err
-	}
-	err = addElementToTemplate(log, "flowStartMilliseconds", nil, elements)
-	if err != nil {
-		return err
-	}
-	err = addElementToTemplate(log, "flowEndSeconds", nil, elements)
-	if err != nil {
-		return err
-	}
-	err = addElementToTemplate(log, "flowEndMilliseconds", nil, elements)
-	if err != nil {
-		return err
-	}
-	err = addElementToTemplate(log, "packetDeltaCount", nil, elements)
-	if err != nil {
-		return err
+	for _, name := range ianaRecordElements {
+		if err := addElementToTemplate(log, name, nil, elements); err != nil {
+			return err
+		}
 	}
-	err = addElementToTemplate(log, "interfaceName", nil, elements)
-	if err != nil {
-		return err
+	for _, ie := range netObservElements {
+		if err := addEnterpriseElementToTemplate(log, NetObservEnterpriseID, ie.Name, nil, elements); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func SendTemplateRecordv4(log *logrus.Entry, exporter *ipfixExporter.ExportingProcess) (uint16, []entities.InfoElementWithValue, error) {
-	templateID := exporter.NewTemplateID()
-	templateSet := entities.NewSet(false)
-	err := templateSet.PrepareSet(entities.Template, templateID)
-	if err != nil {
-		return 0, nil, err
+// addBiflowValuesToTemplate adds the RFC 5103 reverse information elements and the biflow
+// direction
+func addBiflowValuesToTemplate(log *logrus.Entry, elements *[]entities.InfoElementWithValue) error {
+	for _, name := range ipfixReverseElements {
+		if err := addEnterpriseElementToTemplate(log, registry.IANAReversedEnterpriseID, name, nil, elements); err != nil {
+			return err
+		}
 	}
-	elements := make([]entities.InfoElementWithValue, 0)
+	return addElementToTemplate(log, "biflowDirection", nil, elements)
+}
 
-	err = addElementToTemplate(log, "ethernetType", nil, &elements)
-	if err != nil {
-		return 0, nil, err
-	}
-	err = addElementToTemplate(log, "flowDirection", nil, &elements)
-	if err != nil {
-		return 0, nil, err
-	}
-	err = addElementToTemplate(log, "sourceMacAddress", nil, &elements)
-	if err != nil {
-		return 0, nil, err
-	}
-	err = addElementToTemplate(log, "destinationMacAddress", nil, &elements)
-	if err != nil {
-		return 0, nil, err
-	}
-	err = addElementToTemplate(log, "sourceIPv4Address", nil, &elements)
-	if err != nil {
-		return 0, nil, err
-	}
-	err = addElementToTemplate(log, "destinationIPv4Address", nil, &elements)
-	if err != nil {
-		return 0, nil, err
-	}
-	err = addElementToTemplate(log, "protocolIdentifier", nil, &elements)
-	if err != nil {
-		return 0, nil, err
-	}
-	err = addElementToTemplate(log, "sourceTransportPort", nil, &elements)
-	if err != nil {
-		return 0, nil, err
-	}
-	err = addElementToTemplate(log, "destinationTransportPort", nil, &elements)
-	if err != nil {
-		return 0, nil, err
-	}
-	err = addElementToTemplate(log, "icmpTypeIPv4", nil, &elements)
-	if err != nil {
-		return 0, nil, err
-	}
-	err = addElementToTemplate(log, "icmpCodeIPv4", nil, &elements)
-	if err != nil {
-		return 0, nil, err
-	}
-	err = AddRecordValuesToTemplate(log, &elements)
-	if err != nil {
-		return 0, nil, err
+func templateElements(log *logrus.Entry, names []string) ([]entities.InfoElementWithValue, error) {
+	elements := make([]entities.InfoElementWithValue, 0)
+	for _, name := range names {
+		if err := addElementToTemplate(log, name, nil, &elements); err != nil {
+			return nil, err
+		}
 	}
-	err = templateSet.AddRecord(elements, templateID)
-	if err != nil {
-		return 0, nil, err
+	return elements, nil
+}
+
+// encodeTemplate returns the template record of the given elements
+func encodeTemplate(templateID uint16, elements []entities.InfoElementWithValue) ([]byte, error) {
+	record := entities.NewTemplateRecord(templateID, len(elements), false)
+	if err := record.PrepareRecord(); err != nil {
+		return nil, err
 	}
-	_, err = exporter.SendSet(templateSet)
-	if err != nil {
-		return 0, nil, err
+	for _, element := range elements {
+		if err := record.AddInfoElement(element); err != nil {
+			return nil, err
+		}
 	}
+	return record.GetBuffer(), nil
+}
 
-	return templateID, elements, nil
+// encodeOptionsTemplate returns the options template record of the given elements, where the
+// first scopeCount elements are the scope fields. Only IANA elements are supported.
+func encodeOptionsTemplate(templateID uint16, scopeCount int, elements []entities.InfoElementWithValue) []byte {
+	be := binary.BigEndian
+	buf := make([]byte, 0, 6+4*len(elements))
+	buf = be.AppendUint16(buf, templateID)
+	buf = be.AppendUint16(buf, uint16(len(elements)))
+	buf = be.AppendUint16(buf, uint16(scopeCount))
+	for _, element := range elements {
+		ie := element.GetInfoElement()
+		buf = be.AppendUint16(buf, ie.ElementId)
+		buf = be.AppendUint16(buf, ie.Len)
+	}
+	return buf
 }
 
-func SendTemplateRecordv6(log *logrus.Entry, exporter *ipfixExporter.ExportingProcess) (uint16, []entities.InfoElementWithValue, error) {
-	templateID := exporter.NewTemplateID()
-	templateSet := entities.NewSet(false)
-	err := templateSet.PrepareSet(entities.Template, templateID)
-	if err != nil {
-		return 0, nil, err
+// encodeDataRecord returns the data record with the current values of the elements
+func encodeDataRecord(templateID uint16, elements []entities.InfoElementWithValue) ([]byte, error) {
+	record := entities.NewDataRecord(templateID, len(elements), 0, false)
+	for _, element := range elements {
+		if err := record.AddInfoElement(element); err != nil {
+			return nil, err
+		}
 	}
-	elements := make([]entities.InfoElementWithValue, 0)
+	// the values are encoded now, before the elements are overridden by the next flows
+	return record.GetBuffer(), nil
+}
 
-	err = addElementToTemplate(log, "ethernetType", nil, &elements)
-	if err != nil {
-		return 0, nil, err
-	}
-	err = addElementToTemplate(log, "flowDirection", nil, &elements)
+// encodeSet returns a set with the given ID and records
+func encodeSet(setID uint16, records ...[]byte) []byte {
+	length := ipfixSetHeaderLen
+	for _, r := range records {
+		length += len(r)
+	}
+	buf := make([]byte, 0, length)
+	buf = binary.BigEndian.AppendUint16(buf, setID)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(length))
+	for _, r := range records {
+		buf = append(buf, r...)
+	}
+	return buf
+}
+
+// StartIPFIXExporter connects to the collector and sends the templates
+func StartIPFIXExporter(cfg *IPFIXConfig, m *metrics.Metrics) (*IPFIX, error) {
+	socket := utils.GetSocket(cfg.TargetHost, cfg.TargetPort)
+	log := ilog.WithField("collector", socket)
+
+	conn, err := net.Dial(cfg.Transport, socket)
 	if err != nil {
-		return 0, nil, err
+		return nil, fmt.Errorf("connecting to IPFIX collector %s: %w", socket, err)
 	}
-	err = addElementToTemplate(log, "sourceMacAddress", nil, &elements)
+	log.Infof("Created exporter connecting to collector with address: %s", socket)
+	ipf, err := newIPFIX(cfg, conn, cfg.Transport == "udp", m)
 	if err != nil {
-		return 0, nil, err
+		_ = conn.Close()
+		return nil, err
 	}
-	err = addElementToTemplate(log, "destinationMacAddress", nil, &elements)
-	if err != nil {
-		return 0, nil, err
+	return ipf, nil
+}
+
+// newIPFIX creates an IPFIX exporter that writes the messages into the passed writer, and sends
+// the templates
+func newIPFIX(cfg *IPFIXConfig, writer io.WriteCloser, refreshTemplates bool, m *metrics.Metrics) (*IPFIX, error) {
+	if err := LoadIPFIXRegistry(); err != nil {
+		return nil, fmt.Errorf("loading IPFIX registry: %w", err)
+	}
+	ipf := &IPFIX{
+		cfg:              *cfg,
+		writer:           writer,
+		refreshTemplates: refreshTemplates,
+		now:              time.Now,
+		metrics:          m,
+		batchCounter:     m.CreateBatchCounter(componentIPFIX),
+	}
+	// the message length field is 16 bits long
+	if ipf.cfg.MaxMessageSize > math.MaxUint16 {
+		return nil, fmt.Errorf("IPFIX maximum message size is too big: %d. It must be at most %d",
+			ipf.cfg.MaxMessageSize, math.MaxUint16)
+	}
+	var err error
+	if ipf.entitiesV4, err = templateElements(ilog, ipfixV4Elements); err != nil {
+		return nil, err
 	}
-	err = addElementToTemplate(log, "sourceIPv6Address", nil, &elements)
-	if err != nil {
-		return 0, nil, err
+	if err = AddRecordValuesToTemplate(ilog, &ipf.entitiesV4); err != nil {
+		return nil, err
 	}
-	err = addElementToTemplate(log, "destinationIPv6Address", nil, &elements)
-	if err != nil {
-		return 0, nil, err
+	if ipf.entitiesV6, err = templateElements(ilog, ipfixV6Elements); err != nil {
+		return nil, err
 	}
-	err = addElementToTemplate(log, "nextHeaderIPv6", nil, &elements)
-	if err != nil {
-		return 0, nil, err
+	if err = AddRecordValuesToTemplate(ilog, &ipf.entitiesV6); err != nil {
+		return nil, err
 	}
-	err = addElementToTemplate(log, "sourceTransportPort", nil, &elements)
-	if err != nil {
-		return 0, nil, err
+	if ipf.entitiesStats, err = templateElements(ilog, ipfixStatsElements); err != nil {
+		return nil, err
 	}
-	err = addElementToTemplate(log, "destinationTransportPort", nil, &elements)
+	templateV4, err := encodeTemplate(ipfixTemplateIDv4, ipf.entitiesV4)
 	if err != nil {
-		return 0, nil, err
+		return nil, fmt.Errorf("encoding IPv4 template: %w", err)
 	}
-	err = addElementToTemplate(log, "icmpTypeIPv6", nil, &elements)
+	templateV6, err := encodeTemplate(ipfixTemplateIDv6, ipf.entitiesV6)
 	if err != nil {
-		return 0, nil, err
+		return nil, fmt.Errorf("encoding IPv6 template: %w", err)
 	}
-	err = addElementToTemplate(log, "icmpCodeIPv6", nil, &elements)
-	if err != nil {
-		return 0, nil, err
+	ipf.templates = [][]byte{
+		encodeSet(ipfixTemplateSetID, templateV4),
+		encodeSet(ipfixTemplateSetID, templateV6),
 	}
-	err = AddRecordValuesToTemplate(log, &elements)
-	if err != nil {
-		return 0, nil, err
+	if ipf.cfg.Biflow {
+		if err := ipf.addBiflowTemplates(); err != nil {
+			return nil, err
+		}
 	}
-
-	err = templateSet.AddRecord(elements, templateID)
-	if err != nil {
-		return 0, nil, err
+	if ipf.cfg.StatsInterval > 0 {
+		ipf.templates = append(ipf.templates, encodeSet(ipfixOptionsTemplateSetID,
+			encodeOptionsTemplate(ipfixTemplateIDStats, 1, ipf.entitiesStats)))
 	}
-	_, err = exporter.SendSet(templateSet)
-	if err != nil {
-		return 0, nil, err
+	// each template set is sent in its own message
+	for _, set := range ipf.templates {
+		if ipfixMessageHeaderLen+len(set) > ipf.cfg.MaxMessageSize {
+			return nil, fmt.Errorf("IPFIX maximum message size is too small: %d. It must be at least %d"+
+				" to fit the templates", ipf.cfg.MaxMessageSize, ipfixMessageHeaderLen+len(set))
+		}
 	}
 
-	return templateID, elements, nil
+	if err := ipf.sendTemplates(); err != nil {
+		return nil, fmt.Errorf("sending IPFIX templates: %w", err)
+	}
+	return ipf, nil
 }
 
-// Sends out Template record to the IPFIX collector
-func StartIPFIXExporter(hostIP string, hostPort int, transportProto string) (*IPFIX, error) {
-	socket := utils.GetSocket(hostIP, hostPort)
-	log := ilog.WithField("collector", socket)
-
-	registry.LoadRegistry()
-	// Create exporter using local server info
-	input := ipfixExporter.ExporterInput{
-		CollectorAddress:    socket,
-		CollectorProtocol:   transportProto,
-		ObservationDomainID: 1,
-		TempRefTimeout:      1,
+// addBiflowTemplates adds the biflow templates, which extend the IPv4 and IPv6 templates with the
+// reverse information elements
+func (ipf *IPFIX) addBiflowTemplates() error {
+	ipf.entitiesBiflowV4 = append([]entities.InfoElementWithValue{}, ipf.entitiesV4...)
+	if err := addBiflowValuesToTemplate(ilog, &ipf.entitiesBiflowV4); err != nil {
+		return err
 	}
-	exporter, err := ipfixExporter.InitExportingProcess(input)
-	if err != nil {
-		log.Fatalf("Got error when connecting to local server %s: %v", socket, err)
-		return nil, err
+	ipf.entitiesBiflowV6 = append([]entities.InfoElementWithValue{}, ipf.entitiesV6...)
+	if err := addBiflowValuesToTemplate(ilog, &ipf.entitiesBiflowV6); err != nil {
+		return err
 	}
-	log.Infof("Created exporter connecting to local server with address: %s", socket)
-
-	templateIDv4, entitiesV4, err := SendTemplateRecordv4(log, exporter)
+	templateV4, err := encodeTemplate(ipfixTemplateIDBiflowV4, ipf.entitiesBiflowV4)
 	if err != nil {
-		log.WithError(err).Error("Failed in send IPFIX template v4 record")
-		return nil, err
+		return fmt.Errorf("encoding IPv4 biflow template: %w", err)
 	}
-
-	templateIDv6, entitiesV6, err := SendTemplateRecordv6(log, exporter)
+	templateV6, err := encodeTemplate(ipfixTemplateIDBiflowV6, ipf.entitiesBiflowV6)
 	if err != nil {
-		log.WithError(err).Error("Failed in send IPFIX template v6 record")
-		return nil, err
+		return fmt.Errorf("encoding IPv6 biflow template: %w", err)
 	}
-	log.Infof("entities v4 %+v", entitiesV4)
-	log.Infof("entities v6 %+v", entitiesV6)
-
-	return &IPFIX{
-		hostIP:       hostIP,
-		hostPort:     hostPort,
-		exporter:     exporter,
-		templateIDv4: templateIDv4,
-		templateIDv6: templateIDv6,
-		entitiesV4:   entitiesV4,
-		entitiesV6:   entitiesV6,
-	}, nil
+	ipf.templates = append(ipf.templates,
+		encodeSet(ipfixTemplateSetID, templateV4),
+		encodeSet(ipfixTemplateSetID, templateV6))
+	return nil
 }
 
 func setIPv4Address(ieValPtr *entities.InfoElementWithValue, ipAddress net.IP) {
@@ -269,6 +389,37 @@ func setIPv4Address(ieValPtr *entities.InfoElementWithValue, ipAddress net.IP) {
 		ieVal.SetIPAddressValue(ipAddress)
 	}
 }
+
+func setIPv6Address(ieValPtr *entities.InfoElementWithValue, ipAddress net.IP) {
+	ieVal := *ieValPtr
+	if ipAddress == nil {
+		ieVal.SetIPAddressValue(net.IPv6zero)
+	} else {
+		ieVal.SetIPAddressValue(ipAddress.To16())
+	}
+}
+
+// agentIPs returns the agent IP as IPv4 or IPv6 address. The address of the other family is nil.
+func agentIPs(record *flow.Record) (net.IP, net.IP) {
+	if ip4 := record.AgentIP.To4(); ip4 != nil {
+		return ip4, nil
+	}
+	return nil, record.AgentIP
+}
+
+// duplicateInterfaces encodes the interfaces where the flow has been observed as a
+// comma-separated list of interface:direction pairs
+func duplicateInterfaces(record *flow.Record) string {
+	var sb strings.Builder
+	for i := range record.DupList {
+		if sb.Len() > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, "%s:%d", record.DupList[i].Interface, record.DupList[i].Direction)
+	}
+	return sb.String()
+}
+
 func setIERecordValue(record *flow.Record, ieValPtr *entities.InfoElementWithValue) {
 	ieVal := *ieValPtr
 	switch ieVal.GetName() {
@@ -288,6 +439,72 @@ func setIERecordValue(record *flow.Record, ieValPtr *entities.InfoElementWithVal
 		ieVal.SetUnsigned64Value(uint64(record.Metrics.Packets))
 	case "interfaceName":
 		ieVal.SetStringValue(record.Interface)
+	case "ipClassOfService":
+		ieVal.SetUnsigned8Value(record.Metrics.Dscp << 2)
+	case "exporterIPv4Address":
+		ip4, _ := agentIPs(record)
+		setIPv4Address(ieValPtr, ip4)
+	case "exporterIPv6Address":
+		_, ip6 := agentIPs(record)
+		setIPv6Address(ieValPtr, ip6)
+	case "droppedOctetDeltaCount":
+		ieVal.SetUnsigned64Value(record.Metrics.PktDrops.Bytes)
+	case "droppedPacketDeltaCount":
+		ieVal.SetUnsigned64Value(uint64(record.Metrics.PktDrops.Packets))
+	}
+}
+
+// setIEEnterpriseValue sets the values of the NetObserv-specific information elements
+func setIEEnterpriseValue(record *flow.Record, ieValPtr *entities.InfoElementWithValue) {
+	ieVal := *ieValPtr
+	if ieVal.GetInfoElement().EnterpriseId != NetObservEnterpriseID {
+		return
+	}
+	switch ieVal.GetName() {
+	case "pktDropLatestState":
+		ieVal.SetUnsigned8Value(record.Metrics.PktDrops.LatestState)
+	case "pktDropLatestFlags":
+		ieVal.SetUnsigned16Value(record.Metrics.PktDrops.LatestFlags)
+	case "pktDropLatestDropCause":
+		ieVal.SetUnsigned32Value(record.Metrics.PktDrops.LatestDropCause)
+	case "dnsId":
+		ieVal.SetUnsigned16Value(record.Metrics.DnsRecord.Id)
+	case "dnsFlags":
+		ieVal.SetUnsigned16Value(record.Metrics.DnsRecord.Flags)
+	case "dnsErrno":
+		ieVal.SetUnsigned8Value(record.Metrics.DnsRecord.Errno)
+	case "dnsLatencyNanoseconds":
+		ieVal.SetUnsigned64Value(uint64(record.DNSLatency.Nanoseconds()))
+	case "flowRttNanoseconds":
+		ieVal.SetUnsigned64Value(uint64(record.TimeFlowRtt.Nanoseconds()))
+	case "duplicate":
+		ieVal.SetBooleanValue(record.Duplicate)
+	case "duplicateInterfaces":
+		ieVal.SetStringValue(duplicateInterfaces(record))
+	}
+}
+
+// setIEReverseValue sets the values of the RFC 5103 reverse information elements and the biflow
+// direction
+func setIEReverseValue(record *flow.Record, ieValPtr *entities.InfoElementWithValue) {
+	ieVal := *ieValPtr
+	reverse := record.Reverse
+	if reverse == nil {
+		reverse = &flow.ReverseMetrics{}
+	}
+	switch ieVal.GetName() {
+	case "reverseOctetDeltaCount":
+		ieVal.SetUnsigned64Value(reverse.Bytes)
+	case "reversePacketDeltaCount":
+		ieVal.SetUnsigned64Value(uint64(reverse.Packets))
+	case "reverseTcpControlBits":
+		ieVal.SetUnsigned16Value(reverse.Flags)
+	case "reverseDroppedOctetDeltaCount":
+		ieVal.SetUnsigned64Value(reverse.PktDropBytes)
+	case "reverseDroppedPacketDeltaCount":
+		ieVal.SetUnsigned64Value(uint64(reverse.PktDropPackets))
+	case "biflowDirection":
+		ieVal.SetUnsigned8Value(reverse.BiflowDirection)
 	}
 }
 func setIEValue(record *flow.Record, ieValPtr *entities.InfoElementWithValue) {
@@ -325,59 +542,164 @@ func setEntities(record *flow.Record, elements *[]entities.InfoElementWithValue)
 	for _, ieVal := range *elements {
 		setIEValue(record, &ieVal)
 		setIERecordValue(record, &ieVal)
+		setIEEnterpriseValue(record, &ieVal)
+		setIEReverseValue(record, &ieVal)
 	}
 }
-func (ipf *IPFIX) sendDataRecord(_ *logrus.Entry, record *flow.Record, v6 bool) error {
-	dataSet := entities.NewSet(false)
-	var templateID uint16
-	if v6 {
-		templateID = ipf.templateIDv6
-		setEntities(record, &ipf.entitiesV6)
-	} else {
-		templateID = ipf.templateIDv4
-		setEntities(record, &ipf.entitiesV4)
+func (ipf *IPFIX) sendTemplates() error {
+	for _, set := range ipf.templates {
+		if err := ipf.writeMessage(set, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ipf *IPFIX) sendStats() {
+	values := []uint64{
+		ipfixObservationDomainID,
+		// this message is included in the count
+		ipf.exportedMessages + 1,
+		ipf.exportedFlows,
+		ipf.notSentFlows,
+		uint64(max(ipf.cfg.Sampling, 1)),
+		ipfixRandomSampling,
+	}
+	for i, ieVal := range ipf.entitiesStats {
+		switch ieVal.GetDataType() {
+		case entities.Unsigned8:
+			ieVal.SetUnsigned8Value(uint8(values[i]))
+		case entities.Unsigned32:
+			ieVal.SetUnsigned32Value(uint32(values[i]))
+		default:
+			ieVal.SetUnsigned64Value(values[i])
+		}
+	}
+	record, err := encodeDataRecord(ipfixTemplateIDStats, ipf.entitiesStats)
+	if err == nil {
+		err = ipf.writeMessage(encodeSet(ipfixTemplateIDStats, record), 1)
 	}
-	err := dataSet.PrepareSet(entities.Data, templateID)
 	if err != nil {
-		return err
+		ilog.WithError(err).Warn("couldn't send IPFIX exporting process statistics")
 	}
-	if v6 {
-		err = dataSet.AddRecord(ipf.entitiesV6, templateID)
-		if err != nil {
-			return err
+}
+
+// sendFlows encodes the flows as data records of the given template, and sends them batched in as
+// few messages as possible
+func (ipf *IPFIX) sendFlows(templateID uint16, elements []entities.InfoElementWithValue, records []*flow.Record) {
+	var batch [][]byte
+	batchLen := ipfixMessageHeaderLen + ipfixSetHeaderLen
+	flush := func() {
+		if len(batch) == 0 {
+			return
 		}
-	} else {
-		err = dataSet.AddRecord(ipf.entitiesV4, templateID)
+		if err := ipf.writeMessage(encodeSet(templateID, batch...), len(batch)); err != nil {
+			ilog.WithError(err).Error("Failed in send IPFIX data records")
+			ipf.metrics.Errors.WithErrorName(componentIPFIX, "CannotWriteMessage").Inc()
+			ipf.notSentFlows += uint64(len(batch))
+		} else {
+			ipf.exportedFlows += uint64(len(batch))
+		}
+		batch, batchLen = nil, ipfixMessageHeaderLen+ipfixSetHeaderLen
+	}
+	for _, record := range records {
+		setEntities(record, &elements)
+		data, err := encodeDataRecord(templateID, elements)
 		if err != nil {
-			return err
+			ilog.WithError(err).Error("Failed in encoding IPFIX data record")
+			ipf.notSentFlows++
+			continue
+		}
+		if batchLen+len(data) > ipf.cfg.MaxMessageSize {
+			flush()
 		}
+		batch = append(batch, data)
+		batchLen += len(data)
 	}
-	_, err = ipf.exporter.SendSet(dataSet)
-	if err != nil {
+	flush()
+}
+
+// writeMessage writes an IPFIX message with the given set, which contains the given number of
+// data records
+func (ipf *IPFIX) writeMessage(set []byte, dataRecords int) error {
+	be := binary.BigEndian
+	msg := make([]byte, 0, ipfixMessageHeaderLen+len(set))
+	msg = be.AppendUint16(msg, ipfixVersion)
+	msg = be.AppendUint16(msg, uint16(ipfixMessageHeaderLen+len(set)))
+	msg = be.AppendUint32(msg, uint32(ipf.now().Unix()))
+	msg = be.AppendUint32(msg, ipf.sequence)
+	msg = be.AppendUint32(msg, ipfixObservationDomainID)
+	msg = append(msg, set...)
+	if _, err := ipf.writer.Write(msg); err != nil {
 		return err
 	}
+	ipf.sequence += uint32(dataRecords)
+	ipf.exportedMessages++
+	ipf.batchCounter.Inc()
 	return nil
 }
 
 // ExportFlows accepts slices of *flow.Record by its input channel, converts them
 // to IPFIX Records, and submits them to the collector.
 func (ipf *IPFIX) ExportFlows(input <-chan []*flow.Record) {
-	socket := utils.GetSocket(ipf.hostIP, ipf.hostPort)
-	log := ilog.WithField("collector", socket)
-	for inputRecords := range input {
-		for _, record := range inputRecords {
-			if record.Id.EthProtocol == flow.IPv6Type {
-				err := ipf.sendDataRecord(log, record, true)
-				if err != nil {
-					log.WithError(err).Error("Failed in send IPFIX data record")
-				}
-			} else {
-				err := ipf.sendDataRecord(log, record, false)
-				if err != nil {
-					log.WithError(err).Error("Failed in send IPFIX data record")
-				}
+	var refreshTicks, statsTicks <-chan time.Time
+	if ipf.refreshTemplates && ipf.cfg.TemplateRefresh > 0 {
+		ticker := time.NewTicker(ipf.cfg.TemplateRefresh)
+		defer ticker.Stop()
+		refreshTicks = ticker.C
+	}
+	if ipf.cfg.StatsInterval > 0 {
+		ticker := time.NewTicker(ipf.cfg.StatsInterval)
+		defer ticker.Stop()
+		statsTicks = ticker.C
+	}
+	for {
+		select {
+		case inputRecords, ok := <-input:
+			if !ok {
+				ipf.close()
+				return
+			}
+			ipf.exportFlows(inputRecords)
+		case <-refreshTicks:
+			if err := ipf.sendTemplates(); err != nil {
+				ilog.WithError(err).Warn("couldn't send again the IPFIX templates")
 			}
+		case <-statsTicks:
+			ipf.sendStats()
 		}
 	}
-	ipf.exporter.CloseConnToCollector()
+}
+
+func (ipf *IPFIX) exportFlows(records []*flow.Record) {
+	ipf.metrics.EvictionCounter.WithSource(componentIPFIX).Inc()
+	var v4, v6, biflowV4, biflowV6 []*flow.Record
+	for _, record := range records {
+		biflow := ipf.cfg.Biflow && record.Reverse != nil
+		switch {
+		case record.Id.EthProtocol == flow.IPv6Type && biflow:
+			biflowV6 = append(biflowV6, record)
+		case record.Id.EthProtocol == flow.IPv6Type:
+			v6 = append(v6, record)
+		case biflow:
+			biflowV4 = append(biflowV4, record)
+		default:
+			v4 = append(v4, record)
+		}
+	}
+	ipf.sendFlows(ipfixTemplateIDv4, ipf.entitiesV4, v4)
+	ipf.sendFlows(ipfixTemplateIDv6, ipf.entitiesV6, v6)
+	ipf.sendFlows(ipfixTemplateIDBiflowV4, ipf.entitiesBiflowV4, biflowV4)
+	ipf.sendFlows(ipfixTemplateIDBiflowV6, ipf.entitiesBiflowV6, biflowV6)
+	ipf.metrics.EvictedFlowsCounter.WithSource(componentIPFIX).Add(float64(len(records)))
+}
+
+func (ipf *IPFIX) close() {
+	if ipf.cfg.StatsInterval > 0 {
+		ipf.sendStats()
+	}
+	if err := ipf.writer.Close(); err != nil {
+		ilog.WithError(err).Warn("couldn't close IPFIX exporter")
+		ipf.metrics.Errors.WithErrorName(componentIPFIX, "CannotCloseClient").Inc()
+	}
 }
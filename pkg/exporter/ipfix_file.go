@@ -0,0 +1,23 @@
+package exporter
+
+import (
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+)
+
+// StartIPFIXFileExporter creates an IPFIX exporter that writes the flows into rotating files of
+// the configured directory, following the IPFIX file format (RFC 5655): each file is a sequence of
+// IPFIX messages that starts with the templates, so it can be decoded on its own.
+func StartIPFIXFileExporter(cfg *IPFIXConfig, fileCfg *RotatingFileConfig, m *metrics.Metrics) (*IPFIX, error) {
+	writer, err := newRotatingFile(fileCfg, "ipfix-", ".ipfix")
+	if err != nil {
+		return nil, err
+	}
+	ipf, err := newIPFIX(cfg, writer, false, m)
+	if err != nil {
+		_ = writer.Close()
+		return nil, err
+	}
+	writer.onNewFile = ipf.sendTemplates
+	ilog.WithField("directory", fileCfg.Directory).Info("Created exporter writing to IPFIX files")
+	return ipf, nil
+}
@@ -0,0 +1,109 @@
+package exporter
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startTestIPFIXFile(t *testing.T, fileCfg RotatingFileConfig) *IPFIX {
+	t.Helper()
+	fileCfg.Directory = t.TempDir()
+	ipfix, err := StartIPFIXFileExporter(&IPFIXConfig{MaxMessageSize: 1400}, &fileCfg, metrics.NewMetrics(&metrics.Settings{}))
+	require.NoError(t, err)
+	return ipfix
+}
+
+// readIPFIXFiles returns the IPFIX files of the directory, sorted by name, as the list of the
+// set IDs of their messages
+func readIPFIXFiles(t *testing.T, dir string) (names []string, sets [][]uint16) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		file, err := os.Open(filepath.Join(dir, name))
+		require.NoError(t, err)
+		var reader io.Reader = file
+		if strings.HasSuffix(name, ".gz") {
+			reader, err = gzip.NewReader(file)
+			require.NoError(t, err)
+		}
+		content, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		file.Close()
+
+		var ids []uint16
+		for len(content) > 0 {
+			require.GreaterOrEqual(t, len(content), ipfixMessageHeaderLen+ipfixSetHeaderLen)
+			require.EqualValues(t, ipfixVersion, binary.BigEndian.Uint16(content[0:]))
+			msgLen := binary.BigEndian.Uint16(content[2:])
+			ids = append(ids, binary.BigEndian.Uint16(content[ipfixMessageHeaderLen:]))
+			content = content[msgLen:]
+		}
+		sets = append(sets, ids)
+	}
+	return names, sets
+}
+
+func TestIPFIXFile_SizeRotationCompressionAndRetention(t *testing.T) {
+	ipfix := startTestIPFIXFile(t, RotatingFileConfig{MaxSize: 1200, Compress: true, MaxFiles: 3})
+	dir := ipfix.writer.(*rotatingFile).cfg.Directory
+
+	flows := make(chan []*flow.Record, 10)
+	for i := 0; i < 10; i++ {
+		// each batch is sent in a message that takes most of a file
+		var records []*flow.Record
+		for j := 0; j < 5; j++ {
+			records = append(records, netflowTestRecord(time.Now(), "10.0.0.1", "10.0.0.2"))
+		}
+		flows <- records
+	}
+	close(flows)
+	ipfix.ExportFlows(flows)
+
+	names, sets := readIPFIXFiles(t, dir)
+	require.Len(t, names, 3, "only the newest files are kept")
+	for i, name := range names {
+		assert.True(t, strings.HasPrefix(name, "ipfix-"), name)
+		assert.True(t, strings.HasSuffix(name, ".ipfix.gz"), "file is compressed: %s", name)
+		// every file starts with the templates, so it can be decoded on its own
+		assert.Equal(t, []uint16{ipfixTemplateSetID, ipfixTemplateSetID, ipfixTemplateIDv4}, sets[i])
+	}
+}
+
+func TestIPFIXFile_TimeRotation(t *testing.T) {
+	ipfix := startTestIPFIXFile(t, RotatingFileConfig{RotationInterval: time.Hour})
+	writer := ipfix.writer.(*rotatingFile)
+	clock := time.Now()
+	writer.now = func() time.Time { return clock }
+
+	record := netflowTestRecord(time.Now(), "10.0.0.1", "10.0.0.2")
+	ipfix.exportFlows([]*flow.Record{record})
+	ipfix.exportFlows([]*flow.Record{record})
+	clock = clock.Add(time.Hour)
+	ipfix.exportFlows([]*flow.Record{record})
+	ipfix.close()
+
+	names, sets := readIPFIXFiles(t, writer.cfg.Directory)
+	require.Len(t, names, 2)
+	for _, name := range names {
+		assert.True(t, strings.HasSuffix(name, ".ipfix"), "file is not compressed: %s", name)
+	}
+	assert.Equal(t, []uint16{ipfixTemplateSetID, ipfixTemplateSetID, ipfixTemplateIDv4, ipfixTemplateIDv4}, sets[0])
+	assert.Equal(t, []uint16{ipfixTemplateSetID, ipfixTemplateSetID, ipfixTemplateIDv4}, sets[1])
+}
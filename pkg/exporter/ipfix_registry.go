@@ -0,0 +1,43 @@
+package exporter
+
+import (
+	"github.com/vmware/go-ipfix/pkg/entities"
+	"github.com/vmware/go-ipfix/pkg/registry"
+)
+
+// NetObservEnterpriseID is the private enterprise number of the NetObserv-specific IPFIX
+// information elements, for the flow data that has no equivalent in the IANA registry. It is the
+// Red Hat, Inc. enterprise number.
+const NetObservEnterpriseID uint32 = 2312
+
+// netObservElements is the registry of NetObserv-specific information elements. Element IDs must
+// never be changed or reused, as collectors rely on them to decode the exported data.
+var netObservElements = []*entities.InfoElement{
+	entities.NewInfoElement("pktDropLatestState", 1, entities.Unsigned8, NetObservEnterpriseID, 1),
+	entities.NewInfoElement("pktDropLatestFlags", 2, entities.Unsigned16, NetObservEnterpriseID, 2),
+	entities.NewInfoElement("pktDropLatestDropCause", 3, entities.Unsigned32, NetObservEnterpriseID, 4),
+	entities.NewInfoElement("dnsId", 4, entities.Unsigned16, NetObservEnterpriseID, 2),
+	entities.NewInfoElement("dnsFlags", 5, entities.Unsigned16, NetObservEnterpriseID, 2),
+	entities.NewInfoElement("dnsErrno", 6, entities.Unsigned8, NetObservEnterpriseID, 1),
+	entities.NewInfoElement("dnsLatencyNanoseconds", 7, entities.Unsigned64, NetObservEnterpriseID, 8),
+	entities.NewInfoElement("flowRttNanoseconds", 8, entities.Unsigned64, NetObservEnterpriseID, 8),
+	entities.NewInfoElement("duplicate", 9, entities.Boolean, NetObservEnterpriseID, 1),
+	// duplicateInterfaces lists the interfaces where the flow has been observed, as a
+	// comma-separated list of interface:direction pairs
+	entities.NewInfoElement("duplicateInterfaces", 10, entities.String, NetObservEnterpriseID, entities.VariableLength),
+}
+
+// LoadIPFIXRegistry loads the IANA information elements, as well as the NetObserv-specific ones.
+// IPFIX collectors based on the go-ipfix library must invoke it to decode the exported flows.
+func LoadIPFIXRegistry() error {
+	registry.LoadRegistry()
+	if err := registry.InitNewRegistry(NetObservEnterpriseID); err != nil {
+		return err
+	}
+	for _, ie := range netObservElements {
+		if err := registry.PutInfoElement(*ie, NetObservEnterpriseID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
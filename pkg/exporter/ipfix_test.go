@@ -0,0 +1,341 @@
+package exporter
+
+import (
+	"encoding/binary"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ebpf"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	ipfixCollector "github.com/vmware/go-ipfix/pkg/collector"
+	"github.com/vmware/go-ipfix/pkg/entities"
+)
+
+func startTestIPFIX(t *testing.T, port int, cfg IPFIXConfig) *IPFIX {
+	t.Helper()
+	cfg.TargetHost = "127.0.0.1"
+	cfg.TargetPort = port
+	if cfg.Transport == "" {
+		cfg.Transport = "tcp"
+	}
+	if cfg.MaxMessageSize == 0 {
+		cfg.MaxMessageSize = 1400
+	}
+	ipfix, err := StartIPFIXExporter(&cfg, metrics.NewMetrics(&metrics.Settings{}))
+	require.NoError(t, err)
+	return ipfix
+}
+
+func startIPFIXCollector(t *testing.T) (*ipfixCollector.CollectingProcess, int) {
+	t.Helper()
+	require.NoError(t, LoadIPFIXRegistry())
+	cp, err := ipfixCollector.InitCollectingProcess(ipfixCollector.CollectorInput{
+		Address:       "127.0.0.1:0",
+		Protocol:      "tcp",
+		MaxBufferSize: 65535,
+	})
+	require.NoError(t, err)
+	go cp.Start()
+	t.Cleanup(cp.Stop)
+	require.Eventually(t, func() bool { return cp.GetAddress() != nil }, timeout, 10*time.Millisecond)
+	return cp, cp.GetAddress().(*net.TCPAddr).Port
+}
+
+// receiveIPFIXData returns the elements of the next data record received by the collector, by name
+func receiveIPFIXData(t *testing.T, cp *ipfixCollector.CollectingProcess) map[string]entities.InfoElementWithValue {
+	t.Helper()
+	for {
+		select {
+		case msg := <-cp.GetMsgChan():
+			set := msg.GetSet()
+			if set.GetSetType() != entities.Data {
+				continue
+			}
+			elements := map[string]entities.InfoElementWithValue{}
+			for _, ie := range set.GetRecords()[0].GetOrderedElementList() {
+				elements[ie.GetName()] = ie
+			}
+			return elements
+		case <-time.After(timeout):
+			require.Fail(t, "timeout while waiting for an IPFIX data record")
+		}
+	}
+}
+
+func TestIPFIX_AllElements(t *testing.T) {
+	cp, port := startIPFIXCollector(t)
+	ipfix := startTestIPFIX(t, port, IPFIXConfig{})
+
+	start := time.Now().Add(-time.Second).Truncate(time.Millisecond)
+	record := &flow.Record{
+		RawRecord: flow.RawRecord{
+			Id: ebpf.BpfFlowId{
+				EthProtocol:       ipv4Type,
+				Direction:         flow.DirectionEgress,
+				SrcMac:            [6]uint8{1, 2, 3, 4, 5, 6},
+				DstMac:            [6]uint8{6, 5, 4, 3, 2, 1},
+				SrcIp:             flow.IPAddrFromNetIP(net.ParseIP("10.0.0.1")),
+				DstIp:             flow.IPAddrFromNetIP(net.ParseIP("10.0.0.2")),
+				TransportProtocol: 17,
+				SrcPort:           53,
+				DstPort:           34567,
+			},
+			Metrics: ebpf.BpfFlowMetrics{
+				Packets: 3,
+				Bytes:   456,
+				Flags:   0x10,
+				Dscp:    10,
+				PktDrops: ebpf.BpfPktDropsT{
+					Packets:         2,
+					Bytes:           100,
+					LatestFlags:     0x4,
+					LatestState:     7,
+					LatestDropCause: 5,
+				},
+				DnsRecord: ebpf.BpfDnsRecordT{Id: 77, Flags: 0x8180, Errno: 0},
+			},
+		},
+		TimeFlowStart: start,
+		TimeFlowEnd:   start.Add(500 * time.Millisecond),
+		DNSLatency:    3 * time.Millisecond,
+		TimeFlowRtt:   250 * time.Microsecond,
+		Interface:     "eth0",
+		Duplicate:     true,
+		AgentIP:       net.ParseIP("192.168.1.10"),
+		DupList:       []flow.DupEntry{{Interface: "eth0", Direction: 1}, {Interface: "br-ex", Direction: 0}},
+	}
+	flows := make(chan []*flow.Record, 1)
+	flows <- []*flow.Record{record}
+	close(flows)
+	go ipfix.ExportFlows(flows)
+
+	elements := receiveIPFIXData(t, cp)
+	assert.EqualValues(t, ipv4Type, elements["ethernetType"].GetUnsigned16Value())
+	assert.EqualValues(t, 1, elements["flowDirection"].GetUnsigned8Value())
+	assert.Equal(t, "01:02:03:04:05:06", elements["sourceMacAddress"].GetMacAddressValue().String())
+	assert.Equal(t, "10.0.0.1", elements["sourceIPv4Address"].GetIPAddressValue().String())
+	assert.Equal(t, "10.0.0.2", elements["destinationIPv4Address"].GetIPAddressValue().String())
+	assert.EqualValues(t, 17, elements["protocolIdentifier"].GetUnsigned8Value())
+	assert.EqualValues(t, 53, elements["sourceTransportPort"].GetUnsigned16Value())
+	assert.EqualValues(t, 34567, elements["destinationTransportPort"].GetUnsigned16Value())
+	assert.EqualValues(t, 456, elements["octetDeltaCount"].GetUnsigned64Value())
+	assert.EqualValues(t, 3, elements["packetDeltaCount"].GetUnsigned64Value())
+	assert.EqualValues(t, 0x10, elements["tcpControlBits"].GetUnsigned16Value())
+	assert.EqualValues(t, start.UnixMilli(), elements["flowStartMilliseconds"].GetUnsigned64Value())
+	assert.EqualValues(t, start.Add(500*time.Millisecond).UnixMilli(), elements["flowEndMilliseconds"].GetUnsigned64Value())
+	assert.Equal(t, "eth0", elements["interfaceName"].GetStringValue())
+
+	// new IANA elements
+	assert.EqualValues(t, 40, elements["ipClassOfService"].GetUnsigned8Value())
+	assert.Equal(t, "192.168.1.10", elements["exporterIPv4Address"].GetIPAddressValue().String())
+	assert.Equal(t, "::", elements["exporterIPv6Address"].GetIPAddressValue().String())
+	assert.EqualValues(t, 100, elements["droppedOctetDeltaCount"].GetUnsigned64Value())
+	assert.EqualValues(t, 2, elements["droppedPacketDeltaCount"].GetUnsigned64Value())
+
+	// NetObserv-specific elements
+	for _, ie := range netObservElements {
+		require.Contains(t, elements, ie.Name)
+		assert.Equal(t, NetObservEnterpriseID, elements[ie.Name].GetInfoElement().EnterpriseId)
+	}
+	assert.EqualValues(t, 7, elements["pktDropLatestState"].GetUnsigned8Value())
+	assert.EqualValues(t, 0x4, elements["pktDropLatestFlags"].GetUnsigned16Value())
+	assert.EqualValues(t, 5, elements["pktDropLatestDropCause"].GetUnsigned32Value())
+	assert.EqualValues(t, 77, elements["dnsId"].GetUnsigned16Value())
+	assert.EqualValues(t, 0x8180, elements["dnsFlags"].GetUnsigned16Value())
+	assert.EqualValues(t, 0, elements["dnsErrno"].GetUnsigned8Value())
+	assert.EqualValues(t, 3_000_000, elements["dnsLatencyNanoseconds"].GetUnsigned64Value())
+	assert.EqualValues(t, 250_000, elements["flowRttNanoseconds"].GetUnsigned64Value())
+	assert.True(t, elements["duplicate"].GetBooleanValue())
+	assert.Equal(t, "eth0:1,br-ex:0", elements["duplicateInterfaces"].GetStringValue())
+}
+
+func TestIPFIX_IPv6AgentIP(t *testing.T) {
+	cp, port := startIPFIXCollector(t)
+	ipfix := startTestIPFIX(t, port, IPFIXConfig{})
+
+	record := &flow.Record{
+		RawRecord: flow.RawRecord{Id: ebpf.BpfFlowId{
+			EthProtocol: flow.IPv6Type,
+			SrcIp:       flow.IPAddrFromNetIP(net.ParseIP("2001:db8::1")),
+			DstIp:       flow.IPAddrFromNetIP(net.ParseIP("2001:db8::2")),
+		}},
+		AgentIP: net.ParseIP("2001:db8::10"),
+	}
+	flows := make(chan []*flow.Record, 1)
+	flows <- []*flow.Record{record}
+	close(flows)
+	go ipfix.ExportFlows(flows)
+
+	elements := receiveIPFIXData(t, cp)
+	assert.Equal(t, "2001:db8::1", elements["sourceIPv6Address"].GetIPAddressValue().String())
+	assert.Equal(t, "0.0.0.0", elements["exporterIPv4Address"].GetIPAddressValue().String())
+	assert.Equal(t, "2001:db8::10", elements["exporterIPv6Address"].GetIPAddressValue().String())
+	assert.False(t, elements["duplicate"].GetBooleanValue())
+	assert.Empty(t, elements["duplicateInterfaces"].GetStringValue())
+}
+
+func TestIPFIX_Biflow(t *testing.T) {
+	cp, port := startIPFIXCollector(t)
+	ipfix := startTestIPFIX(t, port, IPFIXConfig{Biflow: true})
+
+	uniflow := netflowTestRecord(time.Now(), "10.0.0.1", "10.0.0.2")
+	biflow := netflowTestRecord(time.Now(), "2001:db8::1", "2001:db8::2")
+	biflow.Reverse = &flow.ReverseMetrics{
+		Bytes:           789,
+		Packets:         5,
+		Flags:           0x110,
+		PktDropBytes:    60,
+		PktDropPackets:  1,
+		BiflowDirection: flow.BiflowInitiator,
+	}
+	flows := make(chan []*flow.Record, 1)
+	flows <- []*flow.Record{biflow, uniflow}
+	close(flows)
+	go ipfix.ExportFlows(flows)
+
+	// the unidirectional flows keep being sent with the IPv4 and IPv6 templates
+	elements := receiveIPFIXData(t, cp)
+	assert.Equal(t, "10.0.0.1", elements["sourceIPv4Address"].GetIPAddressValue().String())
+	assert.NotContains(t, elements, "reverseOctetDeltaCount")
+
+	elements = receiveIPFIXData(t, cp)
+	assert.Equal(t, "2001:db8::1", elements["sourceIPv6Address"].GetIPAddressValue().String())
+	assert.EqualValues(t, uint64(1<<33), elements["octetDeltaCount"].GetUnsigned64Value())
+	assert.EqualValues(t, 12, elements["packetDeltaCount"].GetUnsigned64Value())
+	assert.EqualValues(t, 789, elements["reverseOctetDeltaCount"].GetUnsigned64Value())
+	assert.EqualValues(t, 5, elements["reversePacketDeltaCount"].GetUnsigned64Value())
+	assert.EqualValues(t, 0x110, elements["reverseTcpControlBits"].GetUnsigned16Value())
+	assert.EqualValues(t, 60, elements["reverseDroppedOctetDeltaCount"].GetUnsigned64Value())
+	assert.EqualValues(t, 1, elements["reverseDroppedPacketDeltaCount"].GetUnsigned64Value())
+	assert.EqualValues(t, flow.BiflowInitiator, elements["biflowDirection"].GetUnsigned8Value())
+}
+
+func TestIPFIX_BatchedDataSets(t *testing.T) {
+	cp, port := startIPFIXCollector(t)
+	ipfix := startTestIPFIX(t, port, IPFIXConfig{MaxMessageSize: 1000})
+
+	var records []*flow.Record
+	for i := 0; i < 50; i++ {
+		records = append(records, netflowTestRecord(time.Now(), "10.0.0.1", "10.0.0.2"))
+	}
+	flows := make(chan []*flow.Record, 1)
+	flows <- records
+	close(flows)
+	go ipfix.ExportFlows(flows)
+
+	received, messages := 0, 0
+	for received < len(records) {
+		select {
+		case msg := <-cp.GetMsgChan():
+			set := msg.GetSet()
+			if set.GetSetType() != entities.Data {
+				continue
+			}
+			assert.LessOrEqual(t, msg.GetMessageLen(), uint16(1000))
+			messages++
+			assert.EqualValues(t, received, msg.GetSequenceNum(), "data records sent before the message")
+			received += int(set.GetNumberOfRecords())
+		case <-time.After(timeout):
+			require.Fail(t, "timeout while waiting for IPFIX data records")
+		}
+	}
+	assert.Equal(t, len(records), received)
+	// each record takes 140 bytes, so 7 of them fit in each message
+	assert.Equal(t, 8, messages)
+}
+
+// readIPFIXSet reads an IPFIX message from the UDP connection, and returns the ID and content of
+// its set
+func readIPFIXSet(t *testing.T, conn *net.UDPConn) (uint16, []byte) {
+	t.Helper()
+	msg := readUDP(t, conn)
+	be := binary.BigEndian
+	require.EqualValues(t, 10, be.Uint16(msg[0:]))
+	require.EqualValues(t, len(msg), be.Uint16(msg[2:]))
+	set := msg[ipfixMessageHeaderLen:]
+	require.EqualValues(t, len(set), be.Uint16(set[2:]))
+	return be.Uint16(set[0:]), set[ipfixSetHeaderLen:]
+}
+
+func TestIPFIX_InvalidMaxMessageSize(t *testing.T) {
+	for _, size := range []int{math.MaxUint16 + 1, 100} {
+		_, err := StartIPFIXExporter(&IPFIXConfig{
+			TargetHost:     "127.0.0.1",
+			TargetPort:     9999,
+			Transport:      "udp",
+			StatsInterval:  time.Hour,
+			MaxMessageSize: size,
+			Biflow:         true,
+		}, metrics.NewMetrics(&metrics.Settings{}))
+		assert.Errorf(t, err, "size %d should be rejected", size)
+	}
+}
+
+func TestIPFIX_UDPTemplateRefreshAndStats(t *testing.T) {
+	collector, port := listenUDP(t)
+	ipfix := startTestIPFIX(t, port, IPFIXConfig{
+		Transport:       "udp",
+		TemplateRefresh: 50 * time.Millisecond,
+		StatsInterval:   time.Hour,
+		Sampling:        50,
+	})
+	be := binary.BigEndian
+
+	// templates and options templates are sent on startup
+	setID, template := readIPFIXSet(t, collector)
+	assert.EqualValues(t, ipfixTemplateSetID, setID)
+	assert.EqualValues(t, ipfixTemplateIDv4, be.Uint16(template[0:]))
+	setID, template = readIPFIXSet(t, collector)
+	assert.EqualValues(t, ipfixTemplateSetID, setID)
+	assert.EqualValues(t, ipfixTemplateIDv6, be.Uint16(template[0:]))
+	setID, options := readIPFIXSet(t, collector)
+	require.EqualValues(t, ipfixOptionsTemplateSetID, setID)
+	assert.EqualValues(t, ipfixTemplateIDStats, be.Uint16(options[0:]))
+	assert.EqualValues(t, len(ipfixStatsElements), be.Uint16(options[2:]), "field count")
+	assert.EqualValues(t, 1, be.Uint16(options[4:]), "scope field count")
+	assert.EqualValues(t, 149, be.Uint16(options[6:]), "observationDomainId scope")
+
+	flows := make(chan []*flow.Record, 1)
+	done := make(chan struct{})
+	go func() {
+		ipfix.ExportFlows(flows)
+		close(done)
+	}()
+
+	// templates are sent again periodically
+	for _, expected := range []int{ipfixTemplateSetID, ipfixTemplateSetID, ipfixOptionsTemplateSetID} {
+		setID, _ = readIPFIXSet(t, collector)
+		assert.EqualValues(t, expected, setID)
+	}
+
+	flows <- []*flow.Record{
+		netflowTestRecord(time.Now(), "10.0.0.1", "10.0.0.2"),
+		netflowTestRecord(time.Now(), "2001:db8::1", "2001:db8::2"),
+	}
+	close(flows)
+	<-done
+
+	// data sets and, on close, the statistics
+	var stats []byte
+	dataSets := 0
+	for stats == nil {
+		switch setID, content := readIPFIXSet(t, collector); setID {
+		case ipfixTemplateIDv4, ipfixTemplateIDv6:
+			dataSets++
+		case ipfixTemplateIDStats:
+			stats = content
+		}
+	}
+	assert.Equal(t, 2, dataSets)
+	assert.EqualValues(t, ipfixObservationDomainID, be.Uint32(stats[0:]))
+	assert.GreaterOrEqual(t, be.Uint64(stats[4:]), uint64(9), "exported messages")
+	assert.EqualValues(t, 2, be.Uint64(stats[12:]), "exported flows")
+	assert.EqualValues(t, 0, be.Uint64(stats[20:]), "not sent flows")
+	assert.EqualValues(t, 50, be.Uint32(stats[28:]), "sampling interval")
+	assert.EqualValues(t, ipfixRandomSampling, stats[32], "sampling algorithm")
+}
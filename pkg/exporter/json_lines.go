@@ -0,0 +1,120 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/decode"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+var jlog = logrus.WithField("component", "exporter/JSONLines")
+
+const componentJSONLines = "jsonlines"
+
+// JSONLinesConfig configures the JSON Lines exporter
+type JSONLinesConfig struct {
+	// Pretty indents the JSON objects over several lines, which is easier to read but isn't valid
+	// JSON Lines
+	Pretty bool
+	// Fields to export. Empty exports all the fields.
+	Fields []string
+}
+
+// JSONLines exporter writes each flow as a JSON object in a line, with the same field names as the
+// flows decoded by Flowlogs-Pipeline
+type JSONLines struct {
+	cfg     JSONLinesConfig
+	writer  io.WriteCloser
+	fields  map[string]struct{}
+	metrics *metrics.Metrics
+}
+
+// nopCloser writer, so closing the exporter doesn't close the standard output
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// StartJSONLinesStdoutExporter creates a JSON Lines exporter that writes the flows to the standard
+// output
+func StartJSONLinesStdoutExporter(cfg *JSONLinesConfig, m *metrics.Metrics) *JSONLines {
+	jlog.Info("Created exporter writing JSON lines to the standard output")
+	return newJSONLines(cfg, nopCloser{Writer: os.Stdout}, m)
+}
+
+// StartJSONLinesFileExporter creates a JSON Lines exporter that writes the flows into rotating
+// files of the configured directory
+func StartJSONLinesFileExporter(cfg *JSONLinesConfig, fileCfg *RotatingFileConfig, m *metrics.Metrics) (*JSONLines, error) {
+	writer, err := newRotatingFile(fileCfg, "flows-", ".jsonl")
+	if err != nil {
+		return nil, err
+	}
+	jlog.WithField("directory", fileCfg.Directory).Info("Created exporter writing to JSON lines files")
+	return newJSONLines(cfg, writer, m), nil
+}
+
+func newJSONLines(cfg *JSONLinesConfig, writer io.WriteCloser, m *metrics.Metrics) *JSONLines {
+	jl := &JSONLines{cfg: *cfg, writer: writer, metrics: m}
+	if len(cfg.Fields) > 0 {
+		jl.fields = make(map[string]struct{}, len(cfg.Fields))
+		for _, field := range cfg.Fields {
+			jl.fields[field] = struct{}{}
+		}
+	}
+	return jl
+}
+
+// ExportFlows accepts slices of *flow.Record by its input channel, and writes each batch of flows
+// at once, so a batch is never split across files
+func (jl *JSONLines) ExportFlows(input <-chan []*flow.Record) {
+	for records := range input {
+		jl.writeFlows(records)
+	}
+	if err := jl.writer.Close(); err != nil {
+		jlog.WithError(err).Warn("couldn't close JSON lines exporter")
+		jl.metrics.Errors.WithErrorName(componentJSONLines, "CannotCloseClient").Inc()
+	}
+}
+
+func (jl *JSONLines) writeFlows(records []*flow.Record) {
+	var buf bytes.Buffer
+	for _, record := range records {
+		line, err := jl.encode(record)
+		if err != nil {
+			jlog.WithError(err).Debug("can't encode JSON flow. Ignoring")
+			jl.metrics.Errors.WithErrorName(componentJSONLines, "CannotEncodeMessage").Inc()
+			continue
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if _, err := jl.writer.Write(buf.Bytes()); err != nil {
+		jlog.WithError(err).Error("can't write JSON lines")
+		jl.metrics.Errors.WithErrorName(componentJSONLines, "CannotWriteMessage").Inc()
+	}
+	jl.metrics.EvictionCounter.WithSource(componentJSONLines).Inc()
+	jl.metrics.EvictedFlowsCounter.WithSource(componentJSONLines).Add(float64(len(records)))
+}
+
+func (jl *JSONLines) encode(record *flow.Record) ([]byte, error) {
+	out := decode.RecordToMap(record)
+	if jl.fields != nil {
+		for field := range out {
+			if _, ok := jl.fields[field]; !ok {
+				delete(out, field)
+			}
+		}
+	}
+	if jl.cfg.Pretty {
+		return json.MarshalIndent(out, "", "  ")
+	}
+	return json.Marshal(out)
+}
@@ -0,0 +1,95 @@
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportJSONLines(jl *JSONLines, batches ...[]*flow.Record) {
+	flows := make(chan []*flow.Record, len(batches))
+	for _, batch := range batches {
+		flows <- batch
+	}
+	close(flows)
+	jl.ExportFlows(flows)
+}
+
+func TestJSONLines_AllFields(t *testing.T) {
+	var out bytes.Buffer
+	jl := newJSONLines(&JSONLinesConfig{}, nopCloser{Writer: &out}, metrics.NewMetrics(&metrics.Settings{}))
+	record := netflowTestRecord(time.Now(), "10.0.0.1", "10.0.0.2")
+	record.AgentIP = []byte{192, 168, 1, 10}
+	record.Interface = "eth0"
+	exportJSONLines(jl, []*flow.Record{record, netflowTestRecord(time.Now(), "2001:db8::1", "2001:db8::2")})
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &fields))
+	assert.Equal(t, "10.0.0.1", fields["SrcAddr"])
+	assert.Equal(t, "10.0.0.2", fields["DstAddr"])
+	assert.EqualValues(t, 34567, fields["SrcPort"])
+	assert.EqualValues(t, 443, fields["DstPort"])
+	assert.EqualValues(t, 6, fields["Proto"])
+	assert.EqualValues(t, 12, fields["Packets"])
+	assert.EqualValues(t, uint64(1<<33), fields["Bytes"])
+	assert.Equal(t, "192.168.1.10", fields["AgentIP"])
+	assert.Equal(t, []interface{}{"eth0"}, fields["Interfaces"])
+	assert.Contains(t, fields, "TimeFlowStartMs")
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &fields))
+	assert.Equal(t, "2001:db8::1", fields["SrcAddr"])
+}
+
+func TestJSONLines_PrettySelectedFields(t *testing.T) {
+	var out bytes.Buffer
+	jl := newJSONLines(&JSONLinesConfig{Pretty: true, Fields: []string{"SrcAddr", "Bytes", "Unknown"}},
+		nopCloser{Writer: &out}, metrics.NewMetrics(&metrics.Settings{}))
+	exportJSONLines(jl, []*flow.Record{netflowTestRecord(time.Now(), "10.0.0.1", "10.0.0.2")})
+
+	assert.Equal(t, "{\n  \"Bytes\": 8589934592,\n  \"SrcAddr\": \"10.0.0.1\"\n}\n", out.String())
+}
+
+func TestJSONLines_Files(t *testing.T) {
+	dir := t.TempDir()
+	jl, err := StartJSONLinesFileExporter(&JSONLinesConfig{Fields: []string{"SrcAddr"}},
+		&RotatingFileConfig{Directory: dir, MaxSize: 50}, metrics.NewMetrics(&metrics.Settings{}))
+	require.NoError(t, err)
+	v4 := netflowTestRecord(time.Now(), "10.0.0.1", "10.0.0.2")
+	v6 := netflowTestRecord(time.Now(), "2001:db8::1", "2001:db8::2")
+	// the second batch doesn't fit in the first file
+	exportJSONLines(jl, []*flow.Record{v4, v4}, []*flow.Record{v6})
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	var lines []string
+	for _, entry := range entries {
+		assert.True(t, strings.HasPrefix(entry.Name(), "flows-"), entry.Name())
+		assert.True(t, strings.HasSuffix(entry.Name(), ".jsonl"), entry.Name())
+		file, err := os.Open(filepath.Join(dir, entry.Name()))
+		require.NoError(t, err)
+		scanner := bufio.NewScanner(file)
+		var fileLines []string
+		for scanner.Scan() {
+			fileLines = append(fileLines, scanner.Text())
+		}
+		file.Close()
+		lines = append(lines, strings.Join(fileLines, "|"))
+	}
+	assert.Equal(t, []string{
+		`{"SrcAddr":"10.0.0.1"}|{"SrcAddr":"10.0.0.1"}`,
+		`{"SrcAddr":"2001:db8::1"}`,
+	}, lines)
+}
@@ -2,6 +2,10 @@ package exporter
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
@@ -16,15 +20,96 @@ var klog = logrus.WithField("component", "exporter/KafkaProto")
 
 const componentKafka = "kafka"
 
+// kafkaSchemaVersion is reported in the schema-version header, and must be increased on any
+// breaking change of the message formats
+const kafkaSchemaVersion = "1"
+
+// KafkaFormat is the encoding of the Kafka messages
+type KafkaFormat string
+
+const (
+	// KafkaFormatProtobuf sends each flow as a protobuf pbflow.Record, as expected by the
+	// Flowlogs-Pipeline collector
+	KafkaFormatProtobuf KafkaFormat = "protobuf"
+	// KafkaFormatJSON sends each flow as a JSONRecord
+	KafkaFormatJSON KafkaFormat = "json"
+	// KafkaFormatProtobufBatch sends the flows of each batch, for each topic, as a protobuf
+	// pbflow.Records
+	KafkaFormatProtobufBatch KafkaFormat = "protobuf-batch"
+)
+
+// KafkaKey selects the partition key of the Kafka messages
+type KafkaKey string
+
+const (
+	// KafkaKeyConversation keys the messages by the sorted IP pair, so both directions of a
+	// conversation go to the same partition
+	KafkaKeyConversation KafkaKey = "conversation"
+	// KafkaKeySourceIP keys the messages by the source IP
+	KafkaKeySourceIP KafkaKey = "source-ip"
+	// KafkaKeyAgentIP keys the messages by the IP of the agent that traced the flows
+	KafkaKeyAgentIP KafkaKey = "agent-ip"
+	// KafkaKeyNone doesn't key the messages, so they are balanced across the partitions
+	KafkaKeyNone KafkaKey = "none"
+)
+
+// KafkaRoute sends the flows that match a rule to a given topic. Accepted rules are: drops (flows
+// with dropped packets), dns (flows with DNS tracking information) and rtt (flows with a measured
+// TCP round-trip time).
+type KafkaRoute struct {
+	Rule  string
+	Topic string
+	match func(*flow.Record) bool
+}
+
+var kafkaRouteRules = map[string]func(*flow.Record) bool{
+	"drops": func(r *flow.Record) bool {
+		return r.Metrics.PktDrops.Packets > 0 || r.Metrics.PktDrops.LatestDropCause != 0
+	},
+	"dns": func(r *flow.Record) bool { return r.Metrics.DnsRecord.Id != 0 },
+	"rtt": func(r *flow.Record) bool { return r.TimeFlowRtt != 0 },
+}
+
+// ParseKafkaRoutes parses a list of rule=topic routes
+func ParseKafkaRoutes(routes []string) ([]KafkaRoute, error) {
+	parsed := make([]KafkaRoute, 0, len(routes))
+	for _, route := range routes {
+		rule, topic, ok := strings.Cut(strings.TrimSpace(route), "=")
+		if !ok || topic == "" {
+			return nil, fmt.Errorf("wrong Kafka route %q. Expected format: rule=topic", route)
+		}
+		match, ok := kafkaRouteRules[rule]
+		if !ok {
+			return nil, fmt.Errorf("unknown Kafka route rule %q. Accepted rules are: drops, dns, rtt", rule)
+		}
+		parsed = append(parsed, KafkaRoute{Rule: rule, Topic: topic, match: match})
+	}
+	return parsed, nil
+}
+
 type kafkaWriter interface {
 	WriteMessages(ctx context.Context, msgs ...kafkago.Message) error
 }
 
-// KafkaProto exports flows over Kafka, encoded as a protobuf that is understandable by the
-// Flowlogs-Pipeline collector
+// KafkaProto exports flows over Kafka, encoded by default as a protobuf that is understandable by
+// the Flowlogs-Pipeline collector
 type KafkaProto struct {
 	Writer  kafkaWriter
 	Metrics *metrics.Metrics
+	// Format of the messages. Defaults to KafkaFormatProtobuf.
+	Format KafkaFormat
+	// Key of the messages. Defaults to KafkaKeyConversation. When the format is
+	// KafkaFormatProtobufBatch, only KafkaKeyAgentIP keys the messages.
+	Key KafkaKey
+	// Headers adds the agent-ip, schema-version and sampling headers to each message
+	Headers  bool
+	Sampling int
+	// MessageMaxFlows is the maximum number of flows of each KafkaFormatProtobufBatch message
+	MessageMaxFlows int
+	// Routes send the flows to the topic of the first matching route, or to DefaultTopic if none
+	// matches. The Writer must not have a topic when routes are defined.
+	Routes       []KafkaRoute
+	DefaultTopic string
 }
 
 func (kp *KafkaProto) ExportFlows(input <-chan []*flow.Record) {
@@ -34,6 +119,7 @@ func (kp *KafkaProto) ExportFlows(input <-chan []*flow.Record) {
 	}
 }
 
+// getFlowKey returns the conversation key of the flow
 func getFlowKey(record *flow.Record) []byte {
 	// We are sorting IP address so flows from on ip to a second IP get the same key whatever the direction is
 	for k := range record.Id.SrcIp {
@@ -46,17 +132,114 @@ func getFlowKey(record *flow.Record) []byte {
 	return append(record.Id.SrcIp[:], record.Id.DstIp[:]...)
 }
 
-func (kp *KafkaProto) batchAndSubmit(records []*flow.Record) {
-	klog.Debugf("sending %d records", len(records))
+func (kp *KafkaProto) key(record *flow.Record) []byte {
+	switch kp.Key {
+	case KafkaKeySourceIP:
+		return record.Id.SrcIp[:]
+	case KafkaKeyAgentIP:
+		return record.AgentIP
+	case KafkaKeyNone:
+		return nil
+	default:
+		return getFlowKey(record)
+	}
+}
+
+// topic returns the topic of the flow, or an empty string if the Writer topic must be used
+func (kp *KafkaProto) topic(record *flow.Record) string {
+	if len(kp.Routes) == 0 {
+		return ""
+	}
+	for i := range kp.Routes {
+		if kp.Routes[i].match(record) {
+			return kp.Routes[i].Topic
+		}
+	}
+	return kp.DefaultTopic
+}
+
+func (kp *KafkaProto) headers(record *flow.Record) []kafkago.Header {
+	if !kp.Headers {
+		return nil
+	}
+	return []kafkago.Header{
+		{Key: "agent-ip", Value: []byte(record.AgentIP.String())},
+		{Key: "schema-version", Value: []byte(kafkaSchemaVersion)},
+		{Key: "sampling", Value: []byte(strconv.Itoa(kp.Sampling))},
+	}
+}
+
+func (kp *KafkaProto) encode(record *flow.Record) ([]byte, error) {
+	if kp.Format == KafkaFormatJSON {
+		return json.Marshal(&JSONRecord{
+			Record:          record,
+			TimeFlowStart:   record.TimeFlowStart.Unix(),
+			TimeFlowEnd:     record.TimeFlowEnd.Unix(),
+			TimeFlowStartMs: record.TimeFlowStart.UnixMilli(),
+			TimeFlowEndMs:   record.TimeFlowEnd.UnixMilli(),
+		})
+	}
+	return proto.Marshal(pbflow.FlowToPB(record))
+}
+
+// messages returns a message for each flow
+func (kp *KafkaProto) messages(records []*flow.Record) []kafkago.Message {
 	msgs := make([]kafkago.Message, 0, len(records))
 	for _, record := range records {
-		pbBytes, err := proto.Marshal(pbflow.FlowToPB(record))
+		value, err := kp.encode(record)
 		if err != nil {
-			klog.WithError(err).Debug("can't encode protobuf message. Ignoring")
+			klog.WithError(err).Debug("can't encode message. Ignoring")
 			kp.Metrics.Errors.WithErrorName(componentKafka, "CannotEncodeMessage").Inc()
 			continue
 		}
-		msgs = append(msgs, kafkago.Message{Value: pbBytes, Key: getFlowKey(record)})
+		msgs = append(msgs, kafkago.Message{
+			Topic:   kp.topic(record),
+			Key:     kp.key(record),
+			Value:   value,
+			Headers: kp.headers(record),
+		})
+	}
+	return msgs
+}
+
+// batchMessages returns, for each topic, messages with up to MessageMaxFlows flows
+func (kp *KafkaProto) batchMessages(records []*flow.Record) []kafkago.Message {
+	var topics []string
+	byTopic := map[string][]*flow.Record{}
+	for _, record := range records {
+		topic := kp.topic(record)
+		if _, ok := byTopic[topic]; !ok {
+			topics = append(topics, topic)
+		}
+		byTopic[topic] = append(byTopic[topic], record)
+	}
+	var msgs []kafkago.Message
+	for _, topic := range topics {
+		for _, batch := range pbflow.FlowsToPB(byTopic[topic], max(kp.MessageMaxFlows, 1)) {
+			value, err := proto.Marshal(batch)
+			if err != nil {
+				klog.WithError(err).Debug("can't encode protobuf message. Ignoring")
+				kp.Metrics.Errors.WithErrorName(componentKafka, "CannotEncodeMessage").Inc()
+				continue
+			}
+			first := byTopic[topic][0]
+			msg := kafkago.Message{Topic: topic, Value: value, Headers: kp.headers(first)}
+			if kp.Key == KafkaKeyAgentIP {
+				msg.Key = first.AgentIP
+			}
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+func (kp *KafkaProto) batchAndSubmit(records []*flow.Record) {
+	klog.Debugf("sending %d records", len(records))
+	var msgs []kafkago.Message
+	if kp.Format == KafkaFormatProtobufBatch {
+		msgs = kp.batchMessages(records)
+	} else {
+		msgs = kp.messages(records)
 	}
 
 	if err := kp.Writer.WriteMessages(context.TODO(), msgs...); err != nil {
@@ -67,6 +250,8 @@ func (kp *KafkaProto) batchAndSubmit(records []*flow.Record) {
 	kp.Metrics.EvictedFlowsCounter.WithSource(componentKafka).Add(float64(len(records)))
 }
 
+// JSONRecord is the JSON representation of a flow, with its start and end times as Unix
+// timestamps in seconds and milliseconds
 type JSONRecord struct {
 	*flow.Record
 	TimeFlowStart   int64
@@ -2,6 +2,7 @@ package exporter
 
 import (
 	"context"
+	"encoding/json"
 	"net"
 	"testing"
 	"time"
@@ -105,6 +106,89 @@ func TestIdenticalKeys(t *testing.T) {
 
 }
 
+func TestJSONFormatSourceKeyAndHeaders(t *testing.T) {
+	wc := writerCapturer{}
+	kj := KafkaProto{
+		Writer:   &wc,
+		Metrics:  metrics.NewMetrics(&metrics.Settings{}),
+		Format:   KafkaFormatJSON,
+		Key:      KafkaKeySourceIP,
+		Headers:  true,
+		Sampling: 50,
+	}
+	record := flow.Record{AgentIP: net.ParseIP("10.9.8.7")}
+	record.Id.SrcIp = flow.IPAddrFromNetIP(net.ParseIP("192.1.2.3"))
+	record.Id.DstIp = flow.IPAddrFromNetIP(net.ParseIP("127.3.2.1"))
+	record.TimeFlowStart = time.UnixMilli(1700000000123)
+	record.Metrics.Bytes = 789
+	input := make(chan []*flow.Record, 1)
+	input <- []*flow.Record{&record}
+	close(input)
+	kj.ExportFlows(input)
+
+	require.Len(t, wc.messages, 1)
+	msg := wc.messages[0]
+	assert.Empty(t, msg.Topic, "the writer topic is used")
+	assert.Equal(t, ByteArrayFromNetIP(net.ParseIP("192.1.2.3")), msg.Key)
+	assert.Equal(t, []kafkago.Header{
+		{Key: "agent-ip", Value: []byte("10.9.8.7")},
+		{Key: "schema-version", Value: []byte("1")},
+		{Key: "sampling", Value: []byte("50")},
+	}, msg.Headers)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(msg.Value, &decoded))
+	assert.EqualValues(t, 1700000000, decoded["TimeFlowStart"])
+	assert.EqualValues(t, 1700000000123, decoded["TimeFlowStartMs"])
+	assert.Equal(t, "10.9.8.7", decoded["AgentIP"])
+	assert.EqualValues(t, 789, decoded["Metrics"].(map[string]interface{})["Bytes"])
+}
+
+func TestProtobufBatchRouting(t *testing.T) {
+	routes, err := ParseKafkaRoutes([]string{"drops=network-drops", "dns=network-dns"})
+	require.NoError(t, err)
+	wc := writerCapturer{}
+	kj := KafkaProto{
+		Writer:          &wc,
+		Metrics:         metrics.NewMetrics(&metrics.Settings{}),
+		Format:          KafkaFormatProtobufBatch,
+		Key:             KafkaKeyAgentIP,
+		MessageMaxFlows: 2,
+		Routes:          routes,
+		DefaultTopic:    "network-flows",
+	}
+	agentIP := net.ParseIP("10.9.8.7")
+	plain := &flow.Record{AgentIP: agentIP}
+	drop := &flow.Record{AgentIP: agentIP}
+	drop.Metrics.PktDrops.Packets = 3
+	drop.Metrics.DnsRecord.Id = 1
+	dns := &flow.Record{AgentIP: agentIP}
+	dns.Metrics.DnsRecord.Id = 1
+	input := make(chan []*flow.Record, 1)
+	input <- []*flow.Record{plain, drop, plain, dns, plain}
+	close(input)
+	kj.ExportFlows(input)
+
+	var topics []string
+	var sizes []int
+	for _, msg := range wc.messages {
+		assert.Equal(t, []byte(agentIP), msg.Key)
+		assert.Empty(t, msg.Headers)
+		var records pbflow.Records
+		require.NoError(t, proto.Unmarshal(msg.Value, &records))
+		topics = append(topics, msg.Topic)
+		sizes = append(sizes, len(records.Entries))
+	}
+	assert.Equal(t, []string{"network-flows", "network-flows", "network-drops", "network-dns"}, topics)
+	assert.Equal(t, []int{2, 1, 1, 1}, sizes)
+}
+
+func TestParseKafkaRoutes_Errors(t *testing.T) {
+	_, err := ParseKafkaRoutes([]string{"drops"})
+	assert.Error(t, err)
+	_, err = ParseKafkaRoutes([]string{"unknown=topic"})
+	assert.Error(t, err)
+}
+
 type writerCapturer struct {
 	messages []kafkago.Message
 }
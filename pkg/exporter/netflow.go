@@ -0,0 +1,392 @@
+package exporter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/utils"
+
+	"github.com/gavv/monotime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var nlog = logrus.WithField("component", "exporter/NetFlow")
+
+const (
+	componentNetFlow = "netflow"
+	// netflowMaxPacketSize is the maximum size of the UDP payload, so the packets are not
+	// fragmented in the usual network MTUs
+	netflowMaxPacketSize = 1400
+
+	netflowV5HeaderLen = 24
+	netflowV5RecordLen = 48
+	// netflowV5MaxRecords is the maximum number of records per packet, according to the v5 format
+	netflowV5MaxRecords = 30
+
+	netflowV9HeaderLen   = 20
+	netflowV9TemplateIDs = 256
+	netflowV9TemplateV4  = netflowV9TemplateIDs
+	netflowV9TemplateV6  = netflowV9TemplateIDs + 1
+)
+
+// NetFlowConfig configures the NetFlow exporter
+type NetFlowConfig struct {
+	// Version of the NetFlow protocol: 5 or 9
+	Version    int
+	TargetHost string
+	TargetPort int
+	// SourceIP, if set, is used as the source address of the NetFlow packets. If it can't be
+	// bound, the default source address is used.
+	SourceIP net.IP
+	// SourceID is the engine ID in v5, where it must fit in 8 bits, and the source ID in v9
+	SourceID uint32
+	// Sampling is the packet sampling interval. 0 or 1 means no sampling.
+	Sampling int
+	// TemplateRefresh is the period to send again the v9 templates
+	TemplateRefresh time.Duration
+}
+
+// NetFlow flow exporter for the NetFlow v5 and v9 protocols, over UDP. The timestamps of the
+// flows are expressed in milliseconds since the system boot, as the sysUptime header field.
+// NetFlow v5 only supports IPv4 flows, and counters larger than 32 bits are truncated to the
+// maximum value.
+type NetFlow struct {
+	cfg      NetFlowConfig
+	conn     net.Conn
+	bootTime time.Time
+	now      func() time.Time
+	// sequence is the number of exported flows in v5, and of exported packets in v9
+	sequence      uint32
+	lastTemplates time.Time
+	metrics       *metrics.Metrics
+	batchCounter  prometheus.Counter
+}
+
+func StartNetFlow(cfg *NetFlowConfig, m *metrics.Metrics) (*NetFlow, error) {
+	if cfg.Version != 5 && cfg.Version != 9 {
+		return nil, fmt.Errorf("unsupported NetFlow version %d", cfg.Version)
+	}
+	if cfg.Version == 5 && cfg.SourceID > math.MaxUint8 {
+		return nil, fmt.Errorf("NetFlow v5 engine ID %d out of range: must be at most %d",
+			cfg.SourceID, math.MaxUint8)
+	}
+	socket := utils.GetSocket(cfg.TargetHost, cfg.TargetPort)
+	raddr, err := net.ResolveUDPAddr("udp", socket)
+	if err != nil {
+		return nil, fmt.Errorf("resolving NetFlow collector address: %w", err)
+	}
+	var conn net.Conn
+	if cfg.SourceIP != nil {
+		if conn, err = net.DialUDP("udp", &net.UDPAddr{IP: cfg.SourceIP}, raddr); err != nil {
+			nlog.WithError(err).WithField("source", cfg.SourceIP).
+				Warn("can't use the agent IP as the NetFlow source address. Using the default one")
+		}
+	}
+	if conn == nil {
+		if conn, err = net.DialUDP("udp", nil, raddr); err != nil {
+			return nil, fmt.Errorf("connecting to NetFlow collector: %w", err)
+		}
+	}
+	now := time.Now()
+	return &NetFlow{
+		cfg:          *cfg,
+		conn:         conn,
+		bootTime:     now.Add(-monotime.Now()),
+		now:          time.Now,
+		metrics:      m,
+		batchCounter: m.CreateBatchCounter(componentNetFlow),
+	}, nil
+}
+
+// ExportFlows accepts slices of *flow.Record by its input channel, encodes them in as many
+// NetFlow packets as needed and submits them to the collector.
+func (n *NetFlow) ExportFlows(input <-chan []*flow.Record) {
+	nlog.WithField("collector", n.conn.RemoteAddr()).Infof("starting NetFlow v%d exporter", n.cfg.Version)
+	for records := range input {
+		n.metrics.EvictionCounter.WithSource(componentNetFlow).Inc()
+		var packets [][]byte
+		if n.cfg.Version == 5 {
+			packets = n.encodeV5(records)
+		} else {
+			packets = n.encodeV9(records)
+		}
+		for _, packet := range packets {
+			if _, err := n.conn.Write(packet); err != nil {
+				nlog.WithError(err).Error("couldn't send NetFlow packet")
+				n.metrics.Errors.WithErrorName(componentNetFlow, "CannotWriteMessage").Inc()
+				continue
+			}
+			n.batchCounter.Inc()
+		}
+		n.metrics.EvictedFlowsCounter.WithSource(componentNetFlow).Add(float64(len(records)))
+	}
+	if err := n.conn.Close(); err != nil {
+		nlog.WithError(err).Warn("couldn't close NetFlow connection")
+		n.metrics.Errors.WithErrorName(componentNetFlow, "CannotCloseClient").Inc()
+	}
+}
+
+// uptime returns the milliseconds between the system boot and the given time. As defined by
+// NetFlow, it wraps around after ~49.7 days.
+func (n *NetFlow) uptime(t time.Time) uint32 {
+	ms := t.Sub(n.bootTime).Milliseconds()
+	if ms < 0 {
+		return 0
+	}
+	return uint32(ms)
+}
+
+func (n *NetFlow) encodeV5(records []*flow.Record) [][]byte {
+	var ipv4 []*flow.Record
+	for _, r := range records {
+		if r.Id.EthProtocol == ipv4Type {
+			ipv4 = append(ipv4, r)
+		}
+	}
+	if skipped := len(records) - len(ipv4); skipped > 0 {
+		nlog.WithField("flows", skipped).Debug("NetFlow v5 does not support non-IPv4 flows. Ignoring them")
+		n.metrics.DroppedFlowsCounter.WithSourceAndReason(componentNetFlow, "unsupported-protocol").Add(float64(skipped))
+	}
+	var packets [][]byte
+	for len(ipv4) > 0 {
+		chunk := ipv4[:min(len(ipv4), netflowV5MaxRecords)]
+		ipv4 = ipv4[len(chunk):]
+		packets = append(packets, n.encodeV5Packet(chunk))
+	}
+	return packets
+}
+
+func (n *NetFlow) encodeV5Packet(records []*flow.Record) []byte {
+	now := n.now()
+	buf := make([]byte, netflowV5HeaderLen, netflowV5HeaderLen+len(records)*netflowV5RecordLen)
+	be := binary.BigEndian
+	be.PutUint16(buf[0:], 5)
+	be.PutUint16(buf[2:], uint16(len(records)))
+	be.PutUint32(buf[4:], n.uptime(now))
+	be.PutUint32(buf[8:], uint32(now.Unix()))
+	be.PutUint32(buf[12:], uint32(now.Nanosecond()))
+	be.PutUint32(buf[16:], n.sequence)
+	buf[20] = 0 // engine type
+	buf[21] = uint8(n.cfg.SourceID)
+	be.PutUint16(buf[22:], samplingInterval(n.cfg.Sampling))
+	n.sequence += uint32(len(records))
+
+	for _, r := range records {
+		rec := make([]byte, netflowV5RecordLen)
+		copy(rec[0:4], r.Id.SrcIp[12:16])
+		copy(rec[4:8], r.Id.DstIp[12:16])
+		// next hop (8:12) is unknown
+		input, output := interfaces(r)
+		be.PutUint16(rec[12:], uint16(input))
+		be.PutUint16(rec[14:], uint16(output))
+		be.PutUint32(rec[16:], r.Metrics.Packets)
+		be.PutUint32(rec[20:], clampUint32(r.Metrics.Bytes))
+		be.PutUint32(rec[24:], n.uptime(r.TimeFlowStart))
+		be.PutUint32(rec[28:], n.uptime(r.TimeFlowEnd))
+		be.PutUint16(rec[32:], r.Id.SrcPort)
+		be.PutUint16(rec[34:], r.Id.DstPort)
+		rec[37] = uint8(r.Metrics.Flags)
+		rec[38] = r.Id.TransportProtocol
+		rec[39] = r.Metrics.Dscp << 2
+		// AS numbers, masks and paddings are left to zero
+		buf = append(buf, rec...)
+	}
+	return buf
+}
+
+// netflowV9Field is a field of a NetFlow v9 template
+type netflowV9Field struct {
+	fieldType uint16
+	length    uint16
+	encode    func(n *NetFlow, r *flow.Record, dst []byte)
+}
+
+var netflowV9CommonFields = []netflowV9Field{
+	{1, 8, func(_ *NetFlow, r *flow.Record, dst []byte) { binary.BigEndian.PutUint64(dst, r.Metrics.Bytes) }},
+	{2, 8, func(_ *NetFlow, r *flow.Record, dst []byte) {
+		binary.BigEndian.PutUint64(dst, uint64(r.Metrics.Packets))
+	}},
+	{4, 1, func(_ *NetFlow, r *flow.Record, dst []byte) { dst[0] = r.Id.TransportProtocol }},
+	{5, 1, func(_ *NetFlow, r *flow.Record, dst []byte) { dst[0] = r.Metrics.Dscp << 2 }},
+	{6, 1, func(_ *NetFlow, r *flow.Record, dst []byte) { dst[0] = uint8(r.Metrics.Flags) }},
+	{7, 2, func(_ *NetFlow, r *flow.Record, dst []byte) { binary.BigEndian.PutUint16(dst, r.Id.SrcPort) }},
+	{11, 2, func(_ *NetFlow, r *flow.Record, dst []byte) { binary.BigEndian.PutUint16(dst, r.Id.DstPort) }},
+	{10, 4, func(_ *NetFlow, r *flow.Record, dst []byte) {
+		input, _ := interfaces(r)
+		binary.BigEndian.PutUint32(dst, input)
+	}},
+	{14, 4, func(_ *NetFlow, r *flow.Record, dst []byte) {
+		_, output := interfaces(r)
+		binary.BigEndian.PutUint32(dst, output)
+	}},
+	{22, 4, func(n *NetFlow, r *flow.Record, dst []byte) {
+		binary.BigEndian.PutUint32(dst, n.uptime(r.TimeFlowStart))
+	}},
+	{21, 4, func(n *NetFlow, r *flow.Record, dst []byte) { binary.BigEndian.PutUint32(dst, n.uptime(r.TimeFlowEnd)) }},
+	{32, 2, func(_ *NetFlow, r *flow.Record, dst []byte) {
+		binary.BigEndian.PutUint16(dst, uint16(r.Id.IcmpType)<<8|uint16(r.Id.IcmpCode))
+	}},
+	{56, 6, func(_ *NetFlow, r *flow.Record, dst []byte) { copy(dst, r.Id.SrcMac[:]) }},
+	{80, 6, func(_ *NetFlow, r *flow.Record, dst []byte) { copy(dst, r.Id.DstMac[:]) }},
+	{61, 1, func(_ *NetFlow, r *flow.Record, dst []byte) { dst[0] = r.Id.Direction }},
+	{34, 4, func(n *NetFlow, _ *flow.Record, dst []byte) {
+		binary.BigEndian.PutUint32(dst, uint32(max(n.cfg.Sampling, 1)))
+	}},
+}
+
+var netflowV9FieldsV4 = append([]netflowV9Field{
+	{8, 4, func(_ *NetFlow, r *flow.Record, dst []byte) { copy(dst, r.Id.SrcIp[12:16]) }},
+	{12, 4, func(_ *NetFlow, r *flow.Record, dst []byte) { copy(dst, r.Id.DstIp[12:16]) }},
+}, netflowV9CommonFields...)
+
+var netflowV9FieldsV6 = append([]netflowV9Field{
+	{27, 16, func(_ *NetFlow, r *flow.Record, dst []byte) { copy(dst, r.Id.SrcIp[:]) }},
+	{28, 16, func(_ *NetFlow, r *flow.Record, dst []byte) { copy(dst, r.Id.DstIp[:]) }},
+}, netflowV9CommonFields...)
+
+func netflowV9RecordLen(fields []netflowV9Field) int {
+	length := 0
+	for _, f := range fields {
+		length += int(f.length)
+	}
+	return length
+}
+
+// v9Packet accumulates the flowsets of a NetFlow v9 packet
+type v9Packet struct {
+	buf   []byte
+	count uint16
+	// start of the data flowset being written, if any
+	setStart int
+	setID    uint16
+}
+
+func (n *NetFlow) encodeV9(records []*flow.Record) [][]byte {
+	var packets [][]byte
+	var p *v9Packet
+	flush := func() {
+		if p != nil && p.count > 0 {
+			packets = append(packets, n.finishV9Packet(p))
+		}
+		p = nil
+	}
+	sendTemplates := n.lastTemplates.IsZero() || n.now().Sub(n.lastTemplates) >= n.cfg.TemplateRefresh
+	for _, r := range records {
+		var templateID uint16
+		var fields []netflowV9Field
+		switch r.Id.EthProtocol {
+		case ipv4Type:
+			templateID, fields = netflowV9TemplateV4, netflowV9FieldsV4
+		case flow.IPv6Type:
+			templateID, fields = netflowV9TemplateV6, netflowV9FieldsV6
+		default:
+			n.metrics.DroppedFlowsCounter.WithSourceAndReason(componentNetFlow, "unsupported-protocol").Inc()
+			continue
+		}
+		recordLen := netflowV9RecordLen(fields)
+		if p != nil && len(p.buf)+recordLen+4+3 > netflowMaxPacketSize {
+			flush()
+		}
+		if p == nil {
+			p = &v9Packet{buf: make([]byte, netflowV9HeaderLen, netflowMaxPacketSize)}
+			if sendTemplates {
+				n.appendV9Templates(p)
+				n.lastTemplates = n.now()
+				sendTemplates = false
+			}
+		}
+		if p.setID != templateID {
+			closeV9DataSet(p)
+			p.setStart, p.setID = len(p.buf), templateID
+			p.buf = binary.BigEndian.AppendUint16(p.buf, templateID)
+			p.buf = binary.BigEndian.AppendUint16(p.buf, 0) // length, set when the flowset is closed
+		}
+		rec := make([]byte, recordLen)
+		offset := 0
+		for _, f := range fields {
+			f.encode(n, r, rec[offset:offset+int(f.length)])
+			offset += int(f.length)
+		}
+		p.buf = append(p.buf, rec...)
+		p.count++
+	}
+	flush()
+	return packets
+}
+
+func (n *NetFlow) appendV9Templates(p *v9Packet) {
+	be := binary.BigEndian
+	start := len(p.buf)
+	p.buf = be.AppendUint16(p.buf, 0) // template flowset ID
+	p.buf = be.AppendUint16(p.buf, 0) // length, set below
+	for _, t := range []struct {
+		id     uint16
+		fields []netflowV9Field
+	}{{netflowV9TemplateV4, netflowV9FieldsV4}, {netflowV9TemplateV6, netflowV9FieldsV6}} {
+		p.buf = be.AppendUint16(p.buf, t.id)
+		p.buf = be.AppendUint16(p.buf, uint16(len(t.fields)))
+		for _, f := range t.fields {
+			p.buf = be.AppendUint16(p.buf, f.fieldType)
+			p.buf = be.AppendUint16(p.buf, f.length)
+		}
+		p.count++
+	}
+	be.PutUint16(p.buf[start+2:], uint16(len(p.buf)-start))
+}
+
+// closeV9DataSet pads the current data flowset to a 32-bit boundary and sets its length
+func closeV9DataSet(p *v9Packet) {
+	if p.setID == 0 {
+		return
+	}
+	for (len(p.buf)-p.setStart)%4 != 0 {
+		p.buf = append(p.buf, 0)
+	}
+	binary.BigEndian.PutUint16(p.buf[p.setStart+2:], uint16(len(p.buf)-p.setStart))
+	p.setID = 0
+}
+
+func (n *NetFlow) finishV9Packet(p *v9Packet) []byte {
+	closeV9DataSet(p)
+	now := n.now()
+	be := binary.BigEndian
+	be.PutUint16(p.buf[0:], 9)
+	be.PutUint16(p.buf[2:], p.count)
+	be.PutUint32(p.buf[4:], n.uptime(now))
+	be.PutUint32(p.buf[8:], uint32(now.Unix()))
+	be.PutUint32(p.buf[12:], n.sequence)
+	be.PutUint32(p.buf[16:], n.cfg.SourceID)
+	n.sequence++
+	return p.buf
+}
+
+// interfaces returns the input and output interface indices of the flow, according to its
+// direction
+func interfaces(r *flow.Record) (input, output uint32) {
+	if r.Id.Direction == flow.DirectionEgress {
+		return 0, r.Id.IfIndex
+	}
+	return r.Id.IfIndex, 0
+}
+
+// samplingInterval encodes the v5 sampling interval field: 2 bits for the sampling mode (1 for
+// packet interval sampling) and 14 bits for the interval
+func samplingInterval(sampling int) uint16 {
+	if sampling <= 1 {
+		return 0
+	}
+	return 1<<14 | uint16(min(sampling, 1<<14-1))
+}
+
+func clampUint32(v uint64) uint32 {
+	if v > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(v)
+}
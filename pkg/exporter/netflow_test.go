@@ -0,0 +1,247 @@
+package exporter
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ebpf"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetFlowV5(t *testing.T) {
+	collector, port := listenUDP(t)
+	exporter, err := StartNetFlow(&NetFlowConfig{
+		Version:    5,
+		TargetHost: "127.0.0.1",
+		TargetPort: port,
+		SourceIP:   net.ParseIP("127.0.0.1"),
+		SourceID:   7,
+		Sampling:   50,
+	}, metrics.NewMetrics(&metrics.Settings{}))
+	require.NoError(t, err)
+	bootTime := exporter.bootTime
+
+	flows := make(chan []*flow.Record, 10)
+	var records []*flow.Record
+	for i := 0; i < 35; i++ {
+		records = append(records, netflowTestRecord(bootTime, "10.0.0.1", "10.0.0.2"))
+	}
+	// IPv6 flows are ignored
+	records = append(records, netflowTestRecord(bootTime, "2001:db8::1", "2001:db8::2"))
+	flows <- records
+	close(flows)
+	exporter.ExportFlows(flows)
+
+	be := binary.BigEndian
+	// 35 IPv4 flows are split in packets of 30 records at most
+	packet := readUDP(t, collector)
+	require.Len(t, packet, netflowV5HeaderLen+30*netflowV5RecordLen)
+	assert.EqualValues(t, 5, be.Uint16(packet[0:]))
+	assert.EqualValues(t, 30, be.Uint16(packet[2:]))
+	assert.EqualValues(t, 0, be.Uint32(packet[16:]), "flow sequence")
+	assert.EqualValues(t, 7, packet[21], "engine id")
+	assert.EqualValues(t, 1<<14|50, be.Uint16(packet[22:]), "sampling interval")
+
+	rec := packet[netflowV5HeaderLen:]
+	assert.Equal(t, net.ParseIP("10.0.0.1").To4(), net.IP(rec[0:4]))
+	assert.Equal(t, net.ParseIP("10.0.0.2").To4(), net.IP(rec[4:8]))
+	assert.EqualValues(t, 3, be.Uint16(rec[12:]), "input interface")
+	assert.EqualValues(t, 0, be.Uint16(rec[14:]), "output interface")
+	assert.EqualValues(t, 12, be.Uint32(rec[16:]), "packets")
+	assert.EqualValues(t, uint32(0xFFFFFFFF), be.Uint32(rec[20:]), "bytes are capped to 32 bits")
+	assert.EqualValues(t, 10_000, be.Uint32(rec[24:]), "first switched")
+	assert.EqualValues(t, 12_500, be.Uint32(rec[28:]), "last switched")
+	assert.EqualValues(t, 34567, be.Uint16(rec[32:]))
+	assert.EqualValues(t, 443, be.Uint16(rec[34:]))
+	assert.EqualValues(t, 0x12, rec[37], "tcp flags")
+	assert.EqualValues(t, 6, rec[38], "protocol")
+	assert.EqualValues(t, 10<<2, rec[39], "tos")
+
+	packet = readUDP(t, collector)
+	assert.EqualValues(t, 5, be.Uint16(packet[2:]))
+	assert.EqualValues(t, 30, be.Uint32(packet[16:]), "flow sequence")
+}
+
+func TestNetFlowV5_InvalidEngineID(t *testing.T) {
+	_, port := listenUDP(t)
+	cfg := NetFlowConfig{Version: 5, TargetHost: "127.0.0.1", TargetPort: port, SourceID: 256}
+	_, err := StartNetFlow(&cfg, metrics.NewMetrics(&metrics.Settings{}))
+	require.Error(t, err)
+
+	// v9 source IDs are 32-bit
+	cfg.Version = 9
+	exporter, err := StartNetFlow(&cfg, metrics.NewMetrics(&metrics.Settings{}))
+	require.NoError(t, err)
+	require.NoError(t, exporter.conn.Close())
+}
+
+func TestNetFlowV9(t *testing.T) {
+	collector, port := listenUDP(t)
+	exporter, err := StartNetFlow(&NetFlowConfig{
+		Version:         9,
+		TargetHost:      "127.0.0.1",
+		TargetPort:      port,
+		SourceID:        1234,
+		TemplateRefresh: time.Minute,
+	}, metrics.NewMetrics(&metrics.Settings{}))
+	require.NoError(t, err)
+	bootTime := exporter.bootTime
+	now := time.Now()
+	exporter.now = func() time.Time { return now }
+
+	flows := make(chan []*flow.Record, 10)
+	flows <- []*flow.Record{
+		netflowTestRecord(bootTime, "10.0.0.1", "10.0.0.2"),
+		netflowTestRecord(bootTime, "2001:db8::1", "2001:db8::2"),
+	}
+	flows <- []*flow.Record{netflowTestRecord(bootTime, "10.0.0.3", "10.0.0.4")}
+	go exporter.ExportFlows(flows)
+
+	// the first packet contains the templates and the data of both address families
+	packet := readUDP(t, collector)
+	templates, data := decodeNetFlowV9(t, packet, nil)
+	assert.EqualValues(t, 4, binary.BigEndian.Uint16(packet[2:]), "records count")
+	assert.EqualValues(t, 0, binary.BigEndian.Uint32(packet[12:]), "sequence")
+	assert.EqualValues(t, 1234, binary.BigEndian.Uint32(packet[16:]), "source id")
+	require.Contains(t, templates, uint16(netflowV9TemplateV4))
+	require.Contains(t, templates, uint16(netflowV9TemplateV6))
+	require.Len(t, data, 2)
+	assert.Equal(t, net.ParseIP("10.0.0.1").To4(), net.IP(data[0][8]))
+	assert.Equal(t, net.ParseIP("2001:db8::2"), net.IP(data[1][28]))
+	assert.EqualValues(t, uint64(1)<<33, binary.BigEndian.Uint64(data[0][1]), "bytes")
+	assert.EqualValues(t, 12, binary.BigEndian.Uint64(data[0][2]), "packets")
+	assert.EqualValues(t, 10_000, binary.BigEndian.Uint32(data[0][22]), "first switched")
+	assert.EqualValues(t, 12_500, binary.BigEndian.Uint32(data[0][21]), "last switched")
+	assert.EqualValues(t, 3, binary.BigEndian.Uint32(data[0][10]), "input interface")
+
+	// the second packet doesn't contain templates, so it is decoded with the previous ones
+	packet = readUDP(t, collector)
+	newTemplates, data := decodeNetFlowV9(t, packet, templates)
+	assert.Len(t, newTemplates, 2)
+	assert.EqualValues(t, 1, binary.BigEndian.Uint16(packet[2:]), "records count")
+	assert.EqualValues(t, 1, binary.BigEndian.Uint32(packet[12:]), "sequence")
+	require.Len(t, data, 1)
+	assert.Equal(t, net.ParseIP("10.0.0.3").To4(), net.IP(data[0][8]))
+	close(flows)
+}
+
+func TestNetFlowV9_TemplateRefresh(t *testing.T) {
+	exporter := &NetFlow{
+		cfg:      NetFlowConfig{Version: 9, TemplateRefresh: time.Minute},
+		bootTime: time.Now().Add(-time.Hour),
+		metrics:  metrics.NewMetrics(&metrics.Settings{}),
+	}
+	now := time.Now()
+	exporter.now = func() time.Time { return now }
+	records := []*flow.Record{netflowTestRecord(exporter.bootTime, "10.0.0.1", "10.0.0.2")}
+
+	hasTemplates := func(packets [][]byte) bool {
+		require.Len(t, packets, 1)
+		// the first flowset ID is 0 for templates
+		return binary.BigEndian.Uint16(packets[0][netflowV9HeaderLen:]) == 0
+	}
+	assert.True(t, hasTemplates(exporter.encodeV9(records)))
+	now = now.Add(30 * time.Second)
+	assert.False(t, hasTemplates(exporter.encodeV9(records)))
+	now = now.Add(31 * time.Second)
+	assert.True(t, hasTemplates(exporter.encodeV9(records)))
+	assert.False(t, hasTemplates(exporter.encodeV9(records)))
+}
+
+func netflowTestRecord(bootTime time.Time, src, dst string) *flow.Record {
+	r := &flow.Record{
+		RawRecord: flow.RawRecord{
+			Id: ebpf.BpfFlowId{
+				TransportProtocol: 6,
+				SrcPort:           34567,
+				DstPort:           443,
+				IfIndex:           3,
+				Direction:         flow.DirectionIngress,
+			},
+			Metrics: ebpf.BpfFlowMetrics{
+				Packets: 12,
+				Bytes:   1 << 33,
+				Flags:   0x12,
+				Dscp:    10,
+			},
+		},
+		TimeFlowStart: bootTime.Add(10 * time.Second),
+		TimeFlowEnd:   bootTime.Add(12500 * time.Millisecond),
+	}
+	ip := net.ParseIP(src)
+	if ip.To4() != nil {
+		r.Id.EthProtocol = ipv4Type
+	} else {
+		r.Id.EthProtocol = flow.IPv6Type
+	}
+	copy(r.Id.SrcIp[:], ip.To16())
+	copy(r.Id.DstIp[:], net.ParseIP(dst).To16())
+	return r
+}
+
+// decodeNetFlowV9 returns the templates, as field type -> length lists, and the data records, as
+// field type -> value maps
+func decodeNetFlowV9(t *testing.T, packet []byte, templates map[uint16][][2]uint16) (map[uint16][][2]uint16, []map[uint16][]byte) {
+	t.Helper()
+	be := binary.BigEndian
+	require.EqualValues(t, 9, be.Uint16(packet[0:]))
+	if templates == nil {
+		templates = map[uint16][][2]uint16{}
+	}
+	var data []map[uint16][]byte
+	for set := packet[netflowV9HeaderLen:]; len(set) > 0; {
+		setID, setLen := be.Uint16(set[0:]), int(be.Uint16(set[2:]))
+		require.Zero(t, setLen%4, "flowsets must be 32-bit aligned")
+		body := set[4:setLen]
+		if setID == 0 {
+			for len(body) > 0 {
+				id, count := be.Uint16(body[0:]), int(be.Uint16(body[2:]))
+				var fields [][2]uint16
+				for i := 0; i < count; i++ {
+					fields = append(fields, [2]uint16{be.Uint16(body[4+4*i:]), be.Uint16(body[6+4*i:])})
+				}
+				templates[id] = fields
+				body = body[4+4*count:]
+			}
+		} else {
+			fields, ok := templates[setID]
+			require.True(t, ok, "unknown template %d", setID)
+			recordLen := 0
+			for _, f := range fields {
+				recordLen += int(f[1])
+			}
+			for len(body) >= recordLen {
+				record := map[uint16][]byte{}
+				for _, f := range fields {
+					record[f[0]] = body[:f[1]]
+					body = body[f[1]:]
+				}
+				data = append(data, record)
+			}
+		}
+		set = set[setLen:]
+	}
+	return templates, data
+}
+
+func listenUDP(t *testing.T) (*net.UDPConn, int) {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func readUDP(t *testing.T, conn *net.UDPConn) []byte {
+	t.Helper()
+	buf := make([]byte, 65535)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(timeout)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	return buf[:n]
+}
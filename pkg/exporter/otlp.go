@@ -0,0 +1,428 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/decode"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+var olog = logrus.WithField("component", "exporter/OTLP")
+
+const (
+	componentOTLP = "otlp"
+	otlpScopeName = "github.com/netobserv/netobserv-ebpf-agent"
+)
+
+// Transport protocols of the OTLP exporter
+const (
+	OTLPProtocolGRPC = "grpc"
+	OTLPProtocolHTTP = "http/protobuf"
+)
+
+// OTLPConfig configures the OTLP flows exporter
+type OTLPConfig struct {
+	// Endpoint of the OpenTelemetry collector, in host:port format
+	Endpoint string
+	// Protocol is OTLPProtocolGRPC or OTLPProtocolHTTP
+	Protocol string
+	// HTTPPath is the path of the logs endpoint, for the HTTP protocol
+	HTTPPath string
+	// Headers to be sent with each export request
+	Headers map[string]string
+	// Compression of the export requests: gzip or none
+	Compression string
+	// TLSConfig enables TLS if not nil
+	TLSConfig *tls.Config
+	// Timeout of each export request
+	Timeout time.Duration
+	// BatchMaxRecords is the maximum number of log records per export request
+	BatchMaxRecords int
+	// BatchTimeout is the maximum time that the flows are buffered before being exported, while
+	// the batch doesn't reach BatchMaxRecords
+	BatchTimeout time.Duration
+}
+
+// otlpLogsClient abstracts the OTLP transport protocol
+type otlpLogsClient interface {
+	export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) error
+	close() error
+}
+
+// OTLP flow exporter. It sends each flow as an OpenTelemetry log record, whose attributes follow
+// the network, source and destination semantic conventions.
+type OTLP struct {
+	cfg          OTLPConfig
+	client       otlpLogsClient
+	resource     *resourcepb.Resource
+	metrics      *metrics.Metrics
+	batchCounter prometheus.Counter
+}
+
+func StartOTLP(cfg *OTLPConfig, m *metrics.Metrics) (*OTLP, error) {
+	var client otlpLogsClient
+	var err error
+	switch cfg.Protocol {
+	case OTLPProtocolGRPC:
+		client, err = newOTLPGRPCClient(cfg)
+	case OTLPProtocolHTTP:
+		client = newOTLPHTTPClient(cfg)
+	default:
+		err = fmt.Errorf("wrong OTLP protocol %s", cfg.Protocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Compression != "gzip" && cfg.Compression != "none" {
+		return nil, fmt.Errorf("wrong OTLP compression %s", cfg.Compression)
+	}
+	resourceAttrs := []*commonpb.KeyValue{stringAttr("service.name", "netobserv-ebpf-agent")}
+	if hostname, err := os.Hostname(); err == nil {
+		resourceAttrs = append(resourceAttrs, stringAttr("host.name", hostname))
+	}
+	return &OTLP{
+		cfg:          *cfg,
+		client:       client,
+		resource:     &resourcepb.Resource{Attributes: resourceAttrs},
+		metrics:      m,
+		batchCounter: m.CreateBatchCounter(componentOTLP),
+	}, nil
+}
+
+// ExportFlows accepts slices of *flow.Record by its input channel, and accumulates them until
+// the maximum batch size or the batch timeout is reached, to submit them to the collector.
+func (o *OTLP) ExportFlows(input <-chan []*flow.Record) {
+	olog.WithField("endpoint", o.cfg.Endpoint).Info("starting OTLP exporter")
+	var ticks <-chan time.Time
+	if o.cfg.BatchTimeout > 0 {
+		ticker := time.NewTicker(o.cfg.BatchTimeout)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	var batch []*logspb.LogRecord
+	for {
+		select {
+		case records, ok := <-input:
+			if !ok {
+				o.submit(batch)
+				if err := o.client.close(); err != nil {
+					olog.WithError(err).Warn("couldn't close OTLP client")
+					o.metrics.Errors.WithErrorName(componentOTLP, "CannotCloseClient").Inc()
+				}
+				return
+			}
+			o.metrics.EvictionCounter.WithSource(componentOTLP).Inc()
+			for _, record := range records {
+				batch = append(batch, flowToLogRecord(record))
+				if o.cfg.BatchMaxRecords > 0 && len(batch) >= o.cfg.BatchMaxRecords {
+					o.submit(batch)
+					batch = nil
+				}
+			}
+			if ticks == nil {
+				o.submit(batch)
+				batch = nil
+			}
+		case <-ticks:
+			o.submit(batch)
+			batch = nil
+		}
+	}
+}
+
+func (o *OTLP) submit(batch []*logspb.LogRecord) {
+	if len(batch) == 0 {
+		return
+	}
+	req := &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: o.resource,
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope:      &commonpb.InstrumentationScope{Name: otlpScopeName},
+				LogRecords: batch,
+			}},
+		}},
+	}
+	ctx := context.Background()
+	if o.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.cfg.Timeout)
+		defer cancel()
+	}
+	olog.Debugf("sending %d records", len(batch))
+	if err := o.client.export(ctx, req); err != nil {
+		olog.WithError(err).Error("couldn't send flow records to OTLP collector")
+		o.metrics.Errors.WithErrorName(componentOTLP, "CannotWriteMessage").Inc()
+		o.metrics.DroppedFlowsCounter.WithSourceAndReason(componentOTLP, "send-failure").Add(float64(len(batch)))
+		return
+	}
+	o.batchCounter.Inc()
+	o.metrics.EvictedFlowsCounter.WithSource(componentOTLP).Add(float64(len(batch)))
+}
+
+func flowToLogRecord(fr *flow.Record) *logspb.LogRecord {
+	srcMAC := flow.MacAddr(fr.Id.SrcMac)
+	dstMAC := flow.MacAddr(fr.Id.DstMac)
+	attrs := []*commonpb.KeyValue{
+		stringAttr("network.interface.name", fr.Interface),
+		stringAttr("network.io.direction", ioDirection(fr.Id.Direction)),
+		stringAttr("source.mac", srcMAC.String()),
+		stringAttr("destination.mac", dstMAC.String()),
+		intAttr("network.bytes", int64(fr.Metrics.Bytes)),
+		intAttr("network.packets", int64(fr.Metrics.Packets)),
+		intAttr("netobserv.flow.start", fr.TimeFlowStart.UnixNano()),
+		intAttr("netobserv.flow.end", fr.TimeFlowEnd.UnixNano()),
+		stringAttr("netobserv.agent.ip", fr.AgentIP.String()),
+	}
+	if fr.Duplicate {
+		attrs = append(attrs, boolAttr("netobserv.flow.duplicate", true))
+	}
+	switch fr.Id.EthProtocol {
+	case flow.IPv6Type:
+		attrs = append(attrs, stringAttr("network.type", "ipv6"))
+	case ipv4Type:
+		attrs = append(attrs, stringAttr("network.type", "ipv4"))
+	}
+	if fr.Id.EthProtocol == flow.IPv6Type || fr.Id.EthProtocol == ipv4Type {
+		attrs = append(attrs,
+			stringAttr("source.address", flow.IP(fr.Id.SrcIp).String()),
+			stringAttr("destination.address", flow.IP(fr.Id.DstIp).String()),
+			stringAttr("network.transport", transportName(fr.Id.TransportProtocol)),
+			intAttr("network.iana_number", int64(fr.Id.TransportProtocol)),
+			intAttr("netobserv.flow.dscp", int64(fr.Metrics.Dscp)),
+		)
+		switch fr.Id.TransportProtocol {
+		case syscall.IPPROTO_ICMP, syscall.IPPROTO_ICMPV6:
+			attrs = append(attrs,
+				intAttr("netobserv.icmp.type", int64(fr.Id.IcmpType)),
+				intAttr("netobserv.icmp.code", int64(fr.Id.IcmpCode)))
+		case syscall.IPPROTO_TCP, syscall.IPPROTO_UDP, syscall.IPPROTO_SCTP:
+			attrs = append(attrs,
+				intAttr("source.port", int64(fr.Id.SrcPort)),
+				intAttr("destination.port", int64(fr.Id.DstPort)))
+			if fr.Id.TransportProtocol == syscall.IPPROTO_TCP {
+				attrs = append(attrs, intAttr("netobserv.tcp.flags", int64(fr.Metrics.Flags)))
+			}
+		}
+	}
+	if fr.Metrics.DnsRecord.Id != 0 {
+		attrs = append(attrs,
+			intAttr("netobserv.dns.id", int64(fr.Metrics.DnsRecord.Id)),
+			intAttr("netobserv.dns.flags", int64(fr.Metrics.DnsRecord.Flags)),
+			stringAttr("netobserv.dns.response_code", decode.DNSRcodeToStr(uint32(fr.Metrics.DnsRecord.Flags)&0xF)))
+		if fr.Metrics.DnsRecord.Latency != 0 {
+			attrs = append(attrs, intAttr("netobserv.dns.latency_ms", fr.DNSLatency.Milliseconds()))
+		}
+	} else if fr.Metrics.DnsRecord.Errno != 0 {
+		attrs = append(attrs, intAttr("netobserv.dns.errno", int64(fr.Metrics.DnsRecord.Errno)))
+	}
+	if fr.Metrics.PktDrops.LatestDropCause != 0 {
+		attrs = append(attrs,
+			intAttr("netobserv.drops.bytes", int64(fr.Metrics.PktDrops.Bytes)),
+			intAttr("netobserv.drops.packets", int64(fr.Metrics.PktDrops.Packets)),
+			intAttr("netobserv.drops.latest_flags", int64(fr.Metrics.PktDrops.LatestFlags)),
+			stringAttr("netobserv.drops.latest_state", decode.TCPStateToStr(uint32(fr.Metrics.PktDrops.LatestState))),
+			stringAttr("netobserv.drops.latest_cause", decode.PktDropCauseToStr(fr.Metrics.PktDrops.LatestDropCause)))
+		if fr.Metrics.PktDrops.LatestDropLocation != 0 {
+			attrs = append(attrs, stringAttr("netobserv.drops.latest_location",
+				decode.PktDropLocationToStr(fr.Metrics.PktDrops.LatestDropLocation, fr.DropLocation)))
+		}
+	}
+	if fr.TimeFlowRtt != 0 {
+		attrs = append(attrs, intAttr("netobserv.flow.rtt_ns", fr.TimeFlowRtt.Nanoseconds()))
+	}
+	if fr.TransitLatency != 0 {
+		attrs = append(attrs, intAttr("netobserv.flow.transit_latency_ns", fr.TransitLatency.Nanoseconds()))
+	}
+	if fr.Metrics.Qdisc.Packets != 0 {
+		attrs = append(attrs, intAttr("netobserv.qdisc.latency_ns", fr.QdiscLatency().Nanoseconds()))
+	}
+	if fr.Metrics.Qdisc.DropPackets != 0 {
+		attrs = append(attrs,
+			intAttr("netobserv.qdisc.drop_bytes", int64(fr.Metrics.Qdisc.DropBytes)),
+			intAttr("netobserv.qdisc.drop_packets", int64(fr.Metrics.Qdisc.DropPackets)))
+	}
+	if kind := fr.QdiscKind(); kind != "" {
+		attrs = append(attrs, stringAttr("netobserv.qdisc.kind", kind))
+	}
+	return &logspb.LogRecord{
+		TimeUnixNano:         uint64(fr.TimeFlowEnd.UnixNano()),
+		ObservedTimeUnixNano: uint64(time.Now().UnixNano()),
+		SeverityNumber:       logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+		SeverityText:         "INFO",
+		Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "network flow"}},
+		Attributes:           attrs,
+	}
+}
+
+// ipv4Type value as defined in IEEE 802
+const ipv4Type = 0x0800
+
+func ioDirection(direction uint8) string {
+	if direction == flow.DirectionEgress {
+		return "transmit"
+	}
+	return "receive"
+}
+
+func transportName(proto uint8) string {
+	switch proto {
+	case syscall.IPPROTO_TCP:
+		return "tcp"
+	case syscall.IPPROTO_UDP:
+		return "udp"
+	case syscall.IPPROTO_SCTP:
+		return "sctp"
+	case syscall.IPPROTO_ICMP:
+		return "icmp"
+	case syscall.IPPROTO_ICMPV6:
+		return "ipv6-icmp"
+	default:
+		return strconv.Itoa(int(proto))
+	}
+}
+
+func stringAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func intAttr(key string, value int64) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: value}}}
+}
+
+func boolAttr(key string, value bool) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: value}}}
+}
+
+type otlpGRPCClient struct {
+	conn     *grpc.ClientConn
+	client   collogspb.LogsServiceClient
+	headers  metadata.MD
+	callOpts []grpc.CallOption
+}
+
+func newOTLPGRPCClient(cfg *OTLPConfig) (*otlpGRPCClient, error) {
+	creds := insecure.NewCredentials()
+	if cfg.TLSConfig != nil {
+		creds = credentials.NewTLS(cfg.TLSConfig)
+	}
+	conn, err := grpc.NewClient(cfg.Endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	c := &otlpGRPCClient{
+		conn:    conn,
+		client:  collogspb.NewLogsServiceClient(conn),
+		headers: metadata.New(cfg.Headers),
+	}
+	if cfg.Compression == "gzip" {
+		c.callOpts = append(c.callOpts, grpc.UseCompressor(grpcgzip.Name))
+	}
+	return c, nil
+}
+
+func (c *otlpGRPCClient) export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) error {
+	resp, err := c.client.Export(metadata.NewOutgoingContext(ctx, c.headers), req, c.callOpts...)
+	if err != nil {
+		return err
+	}
+	if ps := resp.GetPartialSuccess(); ps != nil && ps.RejectedLogRecords > 0 {
+		olog.WithField("rejected", ps.RejectedLogRecords).Warn(ps.ErrorMessage)
+	}
+	return nil
+}
+
+func (c *otlpGRPCClient) close() error {
+	return c.conn.Close()
+}
+
+type otlpHTTPClient struct {
+	url         string
+	headers     map[string]string
+	compression string
+	client      *http.Client
+}
+
+func newOTLPHTTPClient(cfg *OTLPConfig) *otlpHTTPClient {
+	scheme := "http"
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.TLSConfig != nil {
+		scheme = "https"
+		transport.TLSClientConfig = cfg.TLSConfig
+	}
+	return &otlpHTTPClient{
+		url:         scheme + "://" + cfg.Endpoint + cfg.HTTPPath,
+		headers:     cfg.Headers,
+		compression: cfg.Compression,
+		client:      &http.Client{Transport: transport},
+	}
+}
+
+func (c *otlpHTTPClient) export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) error {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("encoding OTLP request: %w", err)
+	}
+	if c.compression == "gzip" {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(body); err != nil {
+			return fmt.Errorf("compressing OTLP request: %w", err)
+		}
+		if err := gz.Close(); err != nil {
+			return fmt.Errorf("compressing OTLP request: %w", err)
+		}
+		body = buf.Bytes()
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	if c.compression == "gzip" {
+		httpReq.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range c.headers {
+		httpReq.Header.Set(k, v)
+	}
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// the body must be read to reuse the connection
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("OTLP collector returned %s: %s", resp.Status, respBody)
+	}
+	return nil
+}
+
+func (c *otlpHTTPClient) close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,189 @@
+package exporter
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mariomac/guara/pkg/test"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ebpf"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+	test2 "github.com/netobserv/netobserv-ebpf-agent/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOTLP_GRPC(t *testing.T) {
+	port, err := test.FreeTCPPort()
+	require.NoError(t, err)
+	lis, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	received := make(chan *collogspb.ExportLogsServiceRequest, 10)
+	headers := make(chan metadata.MD, 10)
+	server := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(server, &fakeLogsService{received: received, headers: headers})
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	defer server.Stop()
+
+	exporter, err := StartOTLP(&OTLPConfig{
+		Endpoint:        fmt.Sprintf("127.0.0.1:%d", port),
+		Protocol:        OTLPProtocolGRPC,
+		Headers:         map[string]string{"authorization": "Bearer token"},
+		Compression:     "gzip",
+		Timeout:         timeout,
+		BatchMaxRecords: 2,
+		BatchTimeout:    time.Hour,
+	}, metrics.NewMetrics(&metrics.Settings{}))
+	require.NoError(t, err)
+
+	flows := make(chan []*flow.Record, 10)
+	flows <- []*flow.Record{testOTLPRecord(), testOTLPRecord(), testOTLPRecord()}
+	go exporter.ExportFlows(flows)
+
+	// batches are split by the maximum number of records
+	req := test2.ReceiveTimeout(t, received, timeout)
+	require.Len(t, req.ResourceLogs, 1)
+	require.Len(t, req.ResourceLogs[0].ScopeLogs, 1)
+	records := req.ResourceLogs[0].ScopeLogs[0].LogRecords
+	require.Len(t, records, 2)
+	assertOTLPAttributes(t, records[0].Attributes)
+	assert.Equal(t, []string{"Bearer token"}, test2.ReceiveTimeout(t, headers, timeout).Get("authorization"))
+
+	// the remaining records are flushed when the exporter is closed
+	close(flows)
+	req = test2.ReceiveTimeout(t, received, timeout)
+	assert.Len(t, req.ResourceLogs[0].ScopeLogs[0].LogRecords, 1)
+}
+
+func TestOTLP_HTTP(t *testing.T) {
+	received := make(chan *collogspb.ExportLogsServiceRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/v1/logs", req.URL.Path)
+		assert.Equal(t, "application/x-protobuf", req.Header.Get("Content-Type"))
+		assert.Equal(t, "gzip", req.Header.Get("Content-Encoding"))
+		gz, err := gzip.NewReader(req.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(gz)
+		require.NoError(t, err)
+		logs := &collogspb.ExportLogsServiceRequest{}
+		require.NoError(t, proto.Unmarshal(body, logs))
+		received <- logs
+	}))
+	defer server.Close()
+
+	exporter, err := StartOTLP(&OTLPConfig{
+		Endpoint:        strings.TrimPrefix(server.URL, "http://"),
+		Protocol:        OTLPProtocolHTTP,
+		HTTPPath:        "/v1/logs",
+		Compression:     "gzip",
+		Timeout:         timeout,
+		BatchMaxRecords: 1000,
+		BatchTimeout:    10 * time.Millisecond,
+	}, metrics.NewMetrics(&metrics.Settings{}))
+	require.NoError(t, err)
+
+	flows := make(chan []*flow.Record, 10)
+	flows <- []*flow.Record{testOTLPRecord()}
+	flows <- []*flow.Record{testOTLPRecord()}
+	go exporter.ExportFlows(flows)
+
+	// flows are accumulated until the batch timeout
+	req := test2.ReceiveTimeout(t, received, timeout)
+	records := req.ResourceLogs[0].ScopeLogs[0].LogRecords
+	require.Len(t, records, 2)
+	assertOTLPAttributes(t, records[1].Attributes)
+	assert.Equal(t, "service.name", req.ResourceLogs[0].Resource.Attributes[0].Key)
+	close(flows)
+}
+
+func testOTLPRecord() *flow.Record {
+	r := &flow.Record{
+		RawRecord: flow.RawRecord{
+			Id: ebpf.BpfFlowId{
+				EthProtocol:       flow.IPv6Type,
+				Direction:         flow.DirectionEgress,
+				TransportProtocol: 6,
+				SrcPort:           34567,
+				DstPort:           443,
+				SrcMac:            [6]uint8{0x11, 0x22, 0x33, 0x44, 0x55, 0x66},
+			},
+			Metrics: ebpf.BpfFlowMetrics{
+				Bytes:   1234,
+				Packets: 5,
+				Flags:   0x10,
+				PktDrops: ebpf.BpfPktDropsT{
+					Packets:         1,
+					Bytes:           100,
+					LatestDropCause: 2,
+				},
+			},
+		},
+		Interface:     "eth0",
+		TimeFlowStart: time.Unix(1000, 0),
+		TimeFlowEnd:   time.Unix(1001, 0),
+		AgentIP:       net.ParseIP("10.9.8.7"),
+		TimeFlowRtt:   20 * time.Millisecond,
+	}
+	copy(r.Id.SrcIp[:], net.ParseIP("2001:db8::1"))
+	copy(r.Id.DstIp[:], net.ParseIP("2001:db8::2"))
+	return r
+}
+
+func assertOTLPAttributes(t *testing.T, kvs []*commonpb.KeyValue) {
+	t.Helper()
+	attrs := map[string]interface{}{}
+	for _, kv := range kvs {
+		switch v := kv.Value.Value.(type) {
+		case *commonpb.AnyValue_StringValue:
+			attrs[kv.Key] = v.StringValue
+		case *commonpb.AnyValue_IntValue:
+			attrs[kv.Key] = v.IntValue
+		case *commonpb.AnyValue_BoolValue:
+			attrs[kv.Key] = v.BoolValue
+		}
+	}
+	assert.Equal(t, "2001:db8::1", attrs["source.address"])
+	assert.Equal(t, "2001:db8::2", attrs["destination.address"])
+	assert.EqualValues(t, 34567, attrs["source.port"])
+	assert.EqualValues(t, 443, attrs["destination.port"])
+	assert.Equal(t, "tcp", attrs["network.transport"])
+	assert.Equal(t, "ipv6", attrs["network.type"])
+	assert.Equal(t, "eth0", attrs["network.interface.name"])
+	assert.Equal(t, "transmit", attrs["network.io.direction"])
+	assert.Equal(t, "11:22:33:44:55:66", attrs["source.mac"])
+	assert.EqualValues(t, 1234, attrs["network.bytes"])
+	assert.EqualValues(t, 5, attrs["network.packets"])
+	assert.EqualValues(t, 0x10, attrs["netobserv.tcp.flags"])
+	assert.EqualValues(t, 100, attrs["netobserv.drops.bytes"])
+	assert.EqualValues(t, 20*time.Millisecond, attrs["netobserv.flow.rtt_ns"])
+	assert.Equal(t, "10.9.8.7", attrs["netobserv.agent.ip"])
+}
+
+type fakeLogsService struct {
+	collogspb.UnimplementedLogsServiceServer
+	received chan<- *collogspb.ExportLogsServiceRequest
+	headers  chan<- metadata.MD
+}
+
+func (f *fakeLogsService) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	f.headers <- md
+	f.received <- req
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
@@ -0,0 +1,164 @@
+package exporter
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/decode"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+var pflog = logrus.WithField("component", "exporter/PromFlows")
+
+const componentPromFlows = "prometheus"
+
+// Labels of the flow metrics
+const (
+	PromFlowsLabelSrcSubnet = "src_subnet"
+	PromFlowsLabelDstSubnet = "dst_subnet"
+	PromFlowsLabelProtocol  = "protocol"
+	PromFlowsLabelInterface = "interface"
+	PromFlowsLabelDirection = "direction"
+)
+
+// promFlowsOtherSubnet is the subnet label of the addresses that don't belong to any configured
+// CIDR
+const promFlowsOtherSubnet = "other"
+
+// PromFlowsConfig configures the metrics derived from the flows
+type PromFlowsConfig struct {
+	// Labels of the metrics. Fewer labels bound the cardinality of the metrics.
+	Labels []string
+	// CIDRs that the addresses are grouped by in the subnet labels. The most specific CIDR is
+	// used, or "other" if none matches. If empty, the addresses are grouped by their
+	// PrefixV4 or PrefixV6 first bits.
+	CIDRs []*net.IPNet
+	// PrefixV4 and PrefixV6 are the prefix lengths of the subnet labels, when no CIDRs are set
+	PrefixV4 int
+	PrefixV6 int
+}
+
+// PromFlows exporter derives Prometheus metrics from the flows, which are exposed by the agent
+// metrics server
+type PromFlows struct {
+	cfg         PromFlowsConfig
+	labelValues []func(*flow.Record) string
+	flows       *metrics.FlowMetrics
+	metrics     *metrics.Metrics
+}
+
+func StartPromFlows(cfg *PromFlowsConfig, m *metrics.Metrics) (*PromFlows, error) {
+	if cfg.PrefixV4 < 0 || cfg.PrefixV4 > 32 || cfg.PrefixV6 < 0 || cfg.PrefixV6 > 128 {
+		return nil, fmt.Errorf("wrong flow metrics subnet prefix lengths: /%d, /%d", cfg.PrefixV4, cfg.PrefixV6)
+	}
+	pf := &PromFlows{cfg: *cfg, metrics: m}
+	for _, label := range cfg.Labels {
+		switch label {
+		case PromFlowsLabelSrcSubnet:
+			pf.labelValues = append(pf.labelValues, func(r *flow.Record) string { return pf.subnet(r, r.Id.SrcIp) })
+		case PromFlowsLabelDstSubnet:
+			pf.labelValues = append(pf.labelValues, func(r *flow.Record) string { return pf.subnet(r, r.Id.DstIp) })
+		case PromFlowsLabelProtocol:
+			pf.labelValues = append(pf.labelValues, func(r *flow.Record) string {
+				if !isIP(r) {
+					return ""
+				}
+				return transportName(r.Id.TransportProtocol)
+			})
+		case PromFlowsLabelInterface:
+			pf.labelValues = append(pf.labelValues, func(r *flow.Record) string { return r.Interface })
+		case PromFlowsLabelDirection:
+			pf.labelValues = append(pf.labelValues, func(r *flow.Record) string {
+				if r.Id.Direction == flow.DirectionEgress {
+					return "egress"
+				}
+				return "ingress"
+			})
+		default:
+			return nil, fmt.Errorf("unknown flow metrics label %q. Accepted labels are: %s, %s, %s, %s, %s",
+				label, PromFlowsLabelSrcSubnet, PromFlowsLabelDstSubnet, PromFlowsLabelProtocol,
+				PromFlowsLabelInterface, PromFlowsLabelDirection)
+		}
+	}
+	pf.flows = m.CreateFlowMetrics(cfg.Labels)
+	return pf, nil
+}
+
+func isIP(r *flow.Record) bool {
+	return r.Id.EthProtocol == ipv4Type || r.Id.EthProtocol == flow.IPv6Type
+}
+
+func (pf *PromFlows) subnet(r *flow.Record, addr flow.IPAddr) string {
+	if !isIP(r) {
+		return ""
+	}
+	ip := flow.IP(addr)
+	if len(pf.cfg.CIDRs) > 0 {
+		var match *net.IPNet
+		matchLen := -1
+		for _, cidr := range pf.cfg.CIDRs {
+			if ones, _ := cidr.Mask.Size(); ones > matchLen && cidr.Contains(ip) {
+				match, matchLen = cidr, ones
+			}
+		}
+		if match == nil {
+			return promFlowsOtherSubnet
+		}
+		return match.String()
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		mask := net.CIDRMask(pf.cfg.PrefixV4, 32)
+		return (&net.IPNet{IP: ip4.Mask(mask), Mask: mask}).String()
+	}
+	mask := net.CIDRMask(pf.cfg.PrefixV6, 128)
+	return (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String()
+}
+
+// ExportFlows accepts slices of *flow.Record by its input channel, and updates the flow metrics
+func (pf *PromFlows) ExportFlows(input <-chan []*flow.Record) {
+	pflog.Info("starting Prometheus flow metrics exporter")
+	for records := range input {
+		pf.metrics.EvictionCounter.WithSource(componentPromFlows).Inc()
+		for _, record := range records {
+			pf.observe(record)
+		}
+		pf.metrics.EvictedFlowsCounter.WithSource(componentPromFlows).Add(float64(len(records)))
+	}
+}
+
+func (pf *PromFlows) observe(record *flow.Record) {
+	// the same flow observed from other interfaces would be counted several times
+	if record.Duplicate {
+		return
+	}
+	labels := make([]string, 0, len(pf.labelValues)+1)
+	for _, value := range pf.labelValues {
+		labels = append(labels, value(record))
+	}
+	pf.flows.Bytes.WithLabelValues(labels...).Add(float64(record.Metrics.Bytes))
+	pf.flows.Packets.WithLabelValues(labels...).Add(float64(record.Metrics.Packets))
+	if record.Metrics.DnsRecord.Latency != 0 {
+		pf.flows.DNSLatency.WithLabelValues(labels...).Observe(record.DNSLatency.Seconds())
+	}
+	if record.TimeFlowRtt != 0 {
+		pf.flows.RTT.WithLabelValues(labels...).Observe(record.TimeFlowRtt.Seconds())
+	}
+	if record.TransitLatency != 0 {
+		pf.flows.TransitLatency.WithLabelValues(labels...).Observe(record.TransitLatency.Seconds())
+	}
+	if record.Metrics.PktDrops.Packets != 0 {
+		dropLabels := append(labels, decode.PktDropCauseToStr(record.Metrics.PktDrops.LatestDropCause))
+		pf.flows.DropBytes.WithLabelValues(dropLabels...).Add(float64(record.Metrics.PktDrops.Bytes))
+		pf.flows.DropPackets.WithLabelValues(dropLabels...).Add(float64(record.Metrics.PktDrops.Packets))
+	}
+	if record.Metrics.Qdisc.Packets != 0 {
+		pf.flows.QdiscLatency.WithLabelValues(labels...).Observe(record.QdiscLatency().Seconds())
+	}
+	if record.Metrics.Qdisc.DropPackets != 0 {
+		qdiscDropLabels := append(labels, record.QdiscKind())
+		pf.flows.QdiscDropBytes.WithLabelValues(qdiscDropLabels...).Add(float64(record.Metrics.Qdisc.DropBytes))
+		pf.flows.QdiscDropPackets.WithLabelValues(qdiscDropLabels...).Add(float64(record.Metrics.Qdisc.DropPackets))
+	}
+}
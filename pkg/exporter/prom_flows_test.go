@@ -0,0 +1,103 @@
+package exporter
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ebpf"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func metricValue(t *testing.T, metric prometheus.Metric) *dto.Metric {
+	t.Helper()
+	out := &dto.Metric{}
+	require.NoError(t, metric.Write(out))
+	return out
+}
+
+func exportPromFlows(pf *PromFlows, records ...*flow.Record) {
+	flows := make(chan []*flow.Record, 1)
+	flows <- records
+	close(flows)
+	pf.ExportFlows(flows)
+}
+
+func TestPromFlows_Metrics(t *testing.T) {
+	pf, err := StartPromFlows(&PromFlowsConfig{
+		Labels:   []string{PromFlowsLabelSrcSubnet, PromFlowsLabelDstSubnet, PromFlowsLabelProtocol, PromFlowsLabelInterface, PromFlowsLabelDirection},
+		PrefixV4: 16,
+		PrefixV6: 48,
+	}, metrics.NewMetrics(&metrics.Settings{Prefix: "test_prom_flows_"}))
+	require.NoError(t, err)
+
+	v4 := netflowTestRecord(time.Now(), "10.1.2.3", "10.2.3.4")
+	v4.Interface = "eth0"
+	v4.TimeFlowRtt = 20 * time.Millisecond
+	v4.Metrics.PktDrops.Packets = 2
+	v4.Metrics.PktDrops.Bytes = 120
+	v4.Metrics.PktDrops.LatestDropCause = 2
+	sameSubnets := netflowTestRecord(time.Now(), "10.1.200.1", "10.2.0.1")
+	sameSubnets.Interface = "eth0"
+	duplicate := netflowTestRecord(time.Now(), "10.1.2.3", "10.2.3.4")
+	duplicate.Interface = "eth0"
+	duplicate.Duplicate = true
+	v6 := netflowTestRecord(time.Now(), "2001:db8:1:2::1", "2001:db8:2::1")
+	v6.Interface = "eth1"
+	v6.Id.Direction = flow.DirectionEgress
+	v6.Id.TransportProtocol = 17
+	v6.Metrics.DnsRecord.Latency = 1
+	v6.DNSLatency = 3 * time.Millisecond
+	v6.Metrics.Qdisc = ebpf.BpfQdiscT{
+		Time: uint64(4 * time.Millisecond), Packets: 2, DropPackets: 3, DropBytes: 4500,
+		LatestKind: [16]uint8{'h', 't', 'b'},
+	}
+	exportPromFlows(pf, v4, sameSubnets, duplicate, v6)
+
+	v4Labels := []string{"10.1.0.0/16", "10.2.0.0/16", "tcp", "eth0", "ingress"}
+	assert.EqualValues(t, 2*(1<<33), metricValue(t, pf.flows.Bytes.WithLabelValues(v4Labels...)).Counter.GetValue(),
+		"duplicates are ignored")
+	assert.EqualValues(t, 24, metricValue(t, pf.flows.Packets.WithLabelValues(v4Labels...)).Counter.GetValue())
+	dropLabels := append(v4Labels, "SKB_DROP_REASON_NOT_SPECIFIED")
+	assert.EqualValues(t, 120, metricValue(t, pf.flows.DropBytes.WithLabelValues(dropLabels...)).Counter.GetValue())
+	assert.EqualValues(t, 2, metricValue(t, pf.flows.DropPackets.WithLabelValues(dropLabels...)).Counter.GetValue())
+	rtt := metricValue(t, pf.flows.RTT.WithLabelValues(v4Labels...).(prometheus.Metric)).Histogram
+	assert.EqualValues(t, 1, rtt.GetSampleCount())
+	assert.InDelta(t, 0.02, rtt.GetSampleSum(), 1e-9)
+
+	v6Labels := []string{"2001:db8:1::/48", "2001:db8:2::/48", "udp", "eth1", "egress"}
+	assert.EqualValues(t, 1<<33, metricValue(t, pf.flows.Bytes.WithLabelValues(v6Labels...)).Counter.GetValue())
+	dns := metricValue(t, pf.flows.DNSLatency.WithLabelValues(v6Labels...).(prometheus.Metric)).Histogram
+	assert.EqualValues(t, 1, dns.GetSampleCount())
+	assert.InDelta(t, 0.003, dns.GetSampleSum(), 1e-9)
+	qdisc := metricValue(t, pf.flows.QdiscLatency.WithLabelValues(v6Labels...).(prometheus.Metric)).Histogram
+	assert.EqualValues(t, 1, qdisc.GetSampleCount())
+	assert.InDelta(t, 0.002, qdisc.GetSampleSum(), 1e-9)
+	qdiscDropLabels := append(v6Labels, "htb")
+	assert.EqualValues(t, 4500, metricValue(t, pf.flows.QdiscDropBytes.WithLabelValues(qdiscDropLabels...)).Counter.GetValue())
+	assert.EqualValues(t, 3, metricValue(t, pf.flows.QdiscDropPackets.WithLabelValues(qdiscDropLabels...)).Counter.GetValue())
+}
+
+func TestPromFlows_CIDRs(t *testing.T) {
+	_, wide, _ := net.ParseCIDR("10.0.0.0/8")
+	_, narrow, _ := net.ParseCIDR("10.1.0.0/16")
+	pf, err := StartPromFlows(&PromFlowsConfig{
+		Labels: []string{PromFlowsLabelSrcSubnet, PromFlowsLabelDstSubnet},
+		CIDRs:  []*net.IPNet{wide, narrow},
+	}, metrics.NewMetrics(&metrics.Settings{Prefix: "test_prom_flows_cidrs_"}))
+	require.NoError(t, err)
+	exportPromFlows(pf, netflowTestRecord(time.Now(), "10.1.2.3", "10.2.3.4"), netflowTestRecord(time.Now(), "10.1.2.3", "192.168.0.1"))
+
+	assert.EqualValues(t, 12, metricValue(t, pf.flows.Packets.WithLabelValues("10.1.0.0/16", "10.0.0.0/8")).Counter.GetValue())
+	assert.EqualValues(t, 12, metricValue(t, pf.flows.Packets.WithLabelValues("10.1.0.0/16", "other")).Counter.GetValue())
+}
+
+func TestPromFlows_WrongLabel(t *testing.T) {
+	_, err := StartPromFlows(&PromFlowsConfig{Labels: []string{"pod"}}, metrics.NewMetrics(&metrics.Settings{Prefix: "test_prom_flows_wrong_"}))
+	assert.Error(t, err)
+}
@@ -0,0 +1,207 @@
+package exporter
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var rflog = logrus.WithField("component", "exporter/RotatingFile")
+
+// rotatingFileTimeLayout names the files after their creation time, so sorting the file names
+// sorts them from the oldest to the newest
+const rotatingFileTimeLayout = "20060102T150405.000000000Z"
+
+// RotatingFileConfig configures the rotation and retention of the files written by the file
+// exporters
+type RotatingFileConfig struct {
+	// Directory where the files are written
+	Directory string
+	// MaxSize is the size, in bytes, after which the current file is rotated. Zero disables the
+	// size-based rotation.
+	MaxSize int64
+	// RotationInterval is the maximum age of the current file before it is rotated. Zero disables
+	// the time-based rotation.
+	RotationInterval time.Duration
+	// Compress the rotated files with gzip
+	Compress bool
+	// MaxFiles is the maximum number of files kept in the directory, including the current one.
+	// The oldest files are removed first. Zero keeps all the files.
+	MaxFiles int
+}
+
+// rotatingFile writes into files that are rotated by size or age, then optionally compressed and
+// removed once there are too many of them. Each write goes entirely into a single file.
+type rotatingFile struct {
+	cfg       RotatingFileConfig
+	prefix    string
+	extension string
+	now       func() time.Time
+
+	file    *os.File
+	size    int64
+	created time.Time
+	// onNewFile is invoked after a new file is created, e.g. to write headers at its start. The
+	// files are not rotated while it is running.
+	onNewFile func() error
+	inNewFile bool
+
+	// the rotated files are compressed and removed in background
+	cleanup sync.Mutex
+	pending sync.WaitGroup
+}
+
+// newRotatingFile returns a writer into files named <prefix><creation time><extension>, in the
+// configured directory. The first file is created by the first write.
+func newRotatingFile(cfg *RotatingFileConfig, prefix, extension string) (*rotatingFile, error) {
+	if err := os.MkdirAll(cfg.Directory, 0o750); err != nil {
+		return nil, fmt.Errorf("creating directory %s: %w", cfg.Directory, err)
+	}
+	return &rotatingFile{cfg: *cfg, prefix: prefix, extension: extension, now: time.Now}, nil
+}
+
+func (w *rotatingFile) Write(msg []byte) (int, error) {
+	if w.file != nil && !w.inNewFile && w.mustRotate(len(msg)) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	if w.file == nil {
+		if err := w.create(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(msg)
+	w.size += int64(n)
+	return n, err
+}
+
+// mustRotate returns whether the current file must be rotated before writing a message of the
+// given length. A file always contains at least a message, even if it is bigger than MaxSize.
+func (w *rotatingFile) mustRotate(msgLen int) bool {
+	if w.cfg.MaxSize > 0 && w.size > 0 && w.size+int64(msgLen) > w.cfg.MaxSize {
+		return true
+	}
+	return w.cfg.RotationInterval > 0 && w.now().Sub(w.created) >= w.cfg.RotationInterval
+}
+
+func (w *rotatingFile) create() error {
+	w.created = w.now()
+	name := filepath.Join(w.cfg.Directory, w.prefix+w.created.UTC().Format(rotatingFileTimeLayout)+w.extension)
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+	w.file, w.size = file, 0
+	if w.cfg.MaxFiles > 0 {
+		w.background(w.removeOldFiles)
+	}
+	if w.onNewFile != nil {
+		w.inNewFile = true
+		defer func() { w.inNewFile = false }()
+		if err := w.onNewFile(); err != nil {
+			return fmt.Errorf("writing headers into file %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// rotate closes the current file. The next file is created by the next write.
+func (w *rotatingFile) rotate() error {
+	name := w.file.Name()
+	err := w.file.Close()
+	w.file = nil
+	if err != nil {
+		return fmt.Errorf("closing file %s: %w", name, err)
+	}
+	if w.cfg.Compress {
+		w.background(func() {
+			if err := gzipFile(name); err != nil {
+				rflog.WithError(err).WithField("file", name).Warn("couldn't compress file")
+			}
+		})
+	}
+	return nil
+}
+
+// background runs a compression or retention task without blocking the export. The tasks are run
+// one at a time, so a file isn't removed while it is compressed.
+func (w *rotatingFile) background(task func()) {
+	w.pending.Add(1)
+	go func() {
+		defer w.pending.Done()
+		w.cleanup.Lock()
+		defer w.cleanup.Unlock()
+		task()
+	}()
+}
+
+// gzipFile compresses the file into a new file with the .gz extension, and removes the original
+func gzipFile(name string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(name+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// removeOldFiles removes the oldest files of the directory with the writer prefix and extension, so there are at most MaxFiles
+func (w *rotatingFile) removeOldFiles() {
+	entries, err := os.ReadDir(w.cfg.Directory)
+	if err != nil {
+		rflog.WithError(err).Warn("couldn't list files")
+		return
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, w.prefix) &&
+			(strings.HasSuffix(name, w.extension) || strings.HasSuffix(name, w.extension+".gz")) {
+			files = append(files, name)
+		}
+	}
+	if len(files) <= w.cfg.MaxFiles {
+		return
+	}
+	sort.Strings(files)
+	for _, name := range files[:len(files)-w.cfg.MaxFiles] {
+		if err := os.Remove(filepath.Join(w.cfg.Directory, name)); err != nil {
+			rflog.WithError(err).WithField("file", name).Warn("couldn't remove old file")
+		}
+	}
+}
+
+// Close the current file, and waits for the rotated files to be compressed
+func (w *rotatingFile) Close() error {
+	var err error
+	if w.file != nil {
+		err = w.rotate()
+	}
+	w.pending.Wait()
+	return err
+}
@@ -0,0 +1,562 @@
+package exporter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ifaces"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/utils"
+
+	"github.com/gavv/monotime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+var slog = logrus.WithField("component", "exporter/SFlow")
+
+const (
+	componentSFlow = "sflow"
+	// sflowMaxDatagramSize is the maximum size of the UDP payload, so the datagrams are not
+	// fragmented in the usual network MTUs
+	sflowMaxDatagramSize = 1400
+
+	sflowVersion = 5
+
+	// sample formats, with the standard enterprise (0)
+	sflowFlowSample    = 1
+	sflowCounterSample = 2
+
+	// flow record formats
+	sflowRawPacketHeader = 1
+	sflowSampledEthernet = 2
+	sflowSampledIPv4     = 3
+	sflowSampledIPv6     = 4
+
+	// counter record formats
+	sflowGenericInterfaceCounters = 1
+
+	sflowHeaderProtocolEthernet = 1
+
+	// IANA ifType of the interfaces
+	sflowIfTypeOther         = 1
+	sflowIfTypeEthernet      = 6
+	sflowIfTypePropVirtual   = 53
+	sflowIfTypeTunnel        = 131
+	sflowIfTypeL2VLAN        = 135
+	sflowIfTypeIEEE8023adLag = 161
+	sflowIfTypeBridge        = 209
+
+	// sflowNetNSDir is where the named network namespaces, whose interfaces are reported in the
+	// counter samples, are mounted
+	sflowNetNSDir = "/var/run/netns"
+
+	// sflowUnknownCounter is the value of the counters that are not available
+	sflowUnknownCounter = math.MaxUint32
+)
+
+// SFlowConfig configures the sFlow exporter
+type SFlowConfig struct {
+	TargetHost string
+	TargetPort int
+	// AgentIP is the agent address reported in each datagram
+	AgentIP net.IP
+	// SubAgentID distinguishes several sFlow agents running with the same agent address. It
+	// identifies the agent's network namespace. The other namespaces are reported as sub-agents
+	// whose ID is their inode number, as the interface indexes are only unique in a namespace.
+	SubAgentID uint32
+	// Sampling is the packet sampling interval. 0 or 1 means no sampling.
+	Sampling int
+	// CountersInterval is the period to send the interface counter samples. Zero disables them.
+	CountersInterval time.Duration
+	// MaxHeaderSize is the maximum number of bytes of each sampled packet header
+	MaxHeaderSize int
+}
+
+// sflowInterface holds the statistics of a network interface, as reported in the sFlow generic
+// interface counters
+type sflowInterface struct {
+	// netns is the inode number of the network namespace of the interface
+	netns   uint32
+	index   uint32
+	ifType  uint32
+	adminUp bool
+	operUp  bool
+	promisc bool
+	stats   netlink.LinkStatistics
+}
+
+// sflowSource identifies a data source: an interface of a sub-agent
+type sflowSource struct {
+	subAgentID uint32
+	ifIndex    uint32
+}
+
+// sflowSample is an encoded flow or counter sample, to be sent in a datagram of its sub-agent
+type sflowSample struct {
+	subAgentID uint32
+	data       []byte
+}
+
+// SFlow exporter for the sFlow v5 protocol, over UDP. It submits a flow sample for each flow
+// record or captured packet, and periodically a counter sample for each network interface of
+// the host.
+// As each flow record aggregates many sampled packets, its flow sample reports a sampling rate
+// multiplied by the number of packets of the flow, and the average packet length, so the
+// collectors estimate the right number of packets and bytes.
+type SFlow struct {
+	cfg      SFlowConfig
+	conn     net.Conn
+	bootTime time.Time
+	now      func() time.Time
+	// hostNetNS is the inode number of the agent's network namespace
+	hostNetNS uint32
+	// interfaces returns the statistics of the network interfaces of the host
+	interfaces func() ([]sflowInterface, error)
+
+	// per-sub-agent datagram sequences
+	datagramSequence map[uint32]uint32
+	// per-source sequences and sample pools
+	flowSequence    map[sflowSource]uint32
+	samplePool      map[sflowSource]uint32
+	counterSequence map[sflowSource]uint32
+
+	metrics      *metrics.Metrics
+	batchCounter prometheus.Counter
+}
+
+func StartSFlow(cfg *SFlowConfig, m *metrics.Metrics) (*SFlow, error) {
+	hostNetNS, err := ifaces.NetNSInode(netns.None())
+	if err != nil {
+		return nil, fmt.Errorf("getting the agent network namespace: %w", err)
+	}
+	socket := utils.GetSocket(cfg.TargetHost, cfg.TargetPort)
+	raddr, err := net.ResolveUDPAddr("udp", socket)
+	if err != nil {
+		return nil, fmt.Errorf("resolving sFlow collector address: %w", err)
+	}
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to sFlow collector: %w", err)
+	}
+	now := time.Now()
+	return &SFlow{
+		cfg:              *cfg,
+		conn:             conn,
+		bootTime:         now.Add(-monotime.Now()),
+		now:              time.Now,
+		hostNetNS:        hostNetNS,
+		interfaces:       hostInterfaces,
+		datagramSequence: map[uint32]uint32{},
+		flowSequence:     map[sflowSource]uint32{},
+		samplePool:       map[sflowSource]uint32{},
+		counterSequence:  map[sflowSource]uint32{},
+		metrics:          m,
+		batchCounter:     m.CreateBatchCounter(componentSFlow),
+	}, nil
+}
+
+// ExportFlows accepts slices of *flow.Record by its input channel, and submits a flow sample
+// for each of them.
+func (s *SFlow) ExportFlows(input <-chan []*flow.Record) {
+	slog.WithField("collector", s.conn.RemoteAddr()).Info("starting sFlow exporter for flows")
+	exportSFlow(s, input, func(records []*flow.Record) {
+		s.metrics.EvictionCounter.WithSource(componentSFlow).Inc()
+		samples := make([]sflowSample, 0, len(records))
+		for _, r := range records {
+			if sample, ok := s.flowRecordSample(r); ok {
+				samples = append(samples, sample)
+			}
+		}
+		s.send(samples)
+		s.metrics.EvictedFlowsCounter.WithSource(componentSFlow).Add(float64(len(records)))
+	})
+}
+
+// ExportPackets accepts slices of *flow.PacketRecord by its input channel, and submits a flow
+// sample with the header of each packet.
+func (s *SFlow) ExportPackets(input <-chan []*flow.PacketRecord) {
+	slog.WithField("collector", s.conn.RemoteAddr()).Info("starting sFlow exporter for packets")
+	exportSFlow(s, input, func(packets []*flow.PacketRecord) {
+		samples := make([]sflowSample, 0, len(packets))
+		for _, p := range packets {
+			if len(p.Stream) != 0 {
+				samples = append(samples, s.packetSample(p))
+			}
+		}
+		s.send(samples)
+		s.metrics.EvictedPacketsCounter.WithSource(componentSFlow).Add(float64(len(samples)))
+	})
+}
+
+// exportSFlow invokes the export function for each input slice, and sends the counter samples
+// periodically, until the input channel is closed
+func exportSFlow[T any](s *SFlow, input <-chan []T, export func([]T)) {
+	var counterTicks <-chan time.Time
+	if s.cfg.CountersInterval > 0 {
+		ticker := time.NewTicker(s.cfg.CountersInterval)
+		defer ticker.Stop()
+		counterTicks = ticker.C
+	}
+	for {
+		select {
+		case items, ok := <-input:
+			if !ok {
+				if err := s.conn.Close(); err != nil {
+					slog.WithError(err).Warn("couldn't close sFlow connection")
+					s.metrics.Errors.WithErrorName(componentSFlow, "CannotCloseClient").Inc()
+				}
+				return
+			}
+			export(items)
+		case <-counterTicks:
+			s.sendCounters()
+		}
+	}
+}
+
+func (s *SFlow) sendCounters() {
+	ifaces, err := s.interfaces()
+	if err != nil {
+		slog.WithError(err).Warn("can't get the interface statistics. Skipping counter samples")
+		return
+	}
+	samples := make([]sflowSample, 0, len(ifaces))
+	for i := range ifaces {
+		samples = append(samples, s.counterSample(&ifaces[i]))
+	}
+	s.send(samples)
+}
+
+// subAgentID returns the sub-agent that reports the interfaces of a network namespace: the
+// configured one for the agent's namespace, or the namespace inode number for the others
+func (s *SFlow) subAgentID(netnsIno uint32) uint32 {
+	if netnsIno == 0 || netnsIno == s.hostNetNS {
+		return s.cfg.SubAgentID
+	}
+	return netnsIno
+}
+
+// send groups the samples by sub-agent, and submits them to the collector
+func (s *SFlow) send(samples []sflowSample) {
+	bySubAgent := map[uint32][][]byte{}
+	var subAgents []uint32
+	for _, sample := range samples {
+		if _, ok := bySubAgent[sample.subAgentID]; !ok {
+			subAgents = append(subAgents, sample.subAgentID)
+		}
+		bySubAgent[sample.subAgentID] = append(bySubAgent[sample.subAgentID], sample.data)
+	}
+	for _, subAgentID := range subAgents {
+		s.sendSubAgent(subAgentID, bySubAgent[subAgentID])
+	}
+}
+
+// sendSubAgent packs the samples of a sub-agent in as many datagrams as needed and submits them
+// to the collector
+func (s *SFlow) sendSubAgent(subAgentID uint32, samples [][]byte) {
+	for len(samples) > 0 {
+		datagram := s.datagramHeader(subAgentID)
+		count := 0
+		for _, sample := range samples {
+			if count > 0 && len(datagram)+len(sample) > sflowMaxDatagramSize {
+				break
+			}
+			datagram = append(datagram, sample...)
+			count++
+		}
+		samples = samples[count:]
+		binary.BigEndian.PutUint32(datagram[s.samplesCountOffset():], uint32(count))
+		if _, err := s.conn.Write(datagram); err != nil {
+			slog.WithError(err).Error("couldn't send sFlow datagram")
+			s.metrics.Errors.WithErrorName(componentSFlow, "CannotWriteMessage").Inc()
+			continue
+		}
+		s.batchCounter.Inc()
+	}
+}
+
+func (s *SFlow) agentAddress() (uint32, net.IP) {
+	if ip4 := s.cfg.AgentIP.To4(); ip4 != nil {
+		return 1, ip4
+	}
+	if s.cfg.AgentIP != nil {
+		return 2, s.cfg.AgentIP.To16()
+	}
+	return 1, net.IPv4zero.To4()
+}
+
+// samplesCountOffset is the position, in the datagram header, of the number of samples
+func (s *SFlow) samplesCountOffset() int {
+	_, ip := s.agentAddress()
+	return 4*5 + len(ip)
+}
+
+func (s *SFlow) datagramHeader(subAgentID uint32) []byte {
+	addressType, ip := s.agentAddress()
+	s.datagramSequence[subAgentID]++
+	be := binary.BigEndian
+	buf := make([]byte, 0, sflowMaxDatagramSize)
+	buf = be.AppendUint32(buf, sflowVersion)
+	buf = be.AppendUint32(buf, addressType)
+	buf = append(buf, ip...)
+	buf = be.AppendUint32(buf, subAgentID)
+	buf = be.AppendUint32(buf, s.datagramSequence[subAgentID])
+	buf = be.AppendUint32(buf, s.uptime())
+	buf = be.AppendUint32(buf, 0) // number of samples, set when the datagram is complete
+	return buf
+}
+
+// uptime returns the milliseconds since the system boot. It wraps around after ~49.7 days.
+func (s *SFlow) uptime() uint32 {
+	return uint32(s.now().Sub(s.bootTime).Milliseconds())
+}
+
+func (s *SFlow) flowRecordSample(r *flow.Record) (sflowSample, bool) {
+	var ipRecord []byte
+	switch r.Id.EthProtocol {
+	case ipv4Type:
+		ipRecord = sflowRecord(sflowSampledIPv4, sflowSampledIPRecord(r, r.Id.SrcIp[12:16], r.Id.DstIp[12:16]))
+	case flow.IPv6Type:
+		ipRecord = sflowRecord(sflowSampledIPv6, sflowSampledIPRecord(r, r.Id.SrcIp[:], r.Id.DstIp[:]))
+	default:
+		s.metrics.DroppedFlowsCounter.WithSourceAndReason(componentSFlow, "unsupported-protocol").Inc()
+		return sflowSample{}, false
+	}
+	rate := clampUint32(uint64(max(s.cfg.Sampling, 1)) * uint64(max(r.Metrics.Packets, 1)))
+	input, output := interfaces(r)
+	source := sflowSource{subAgentID: s.subAgentID(r.Id.Netns), ifIndex: r.Id.IfIndex}
+	return s.flowSample(source, rate, input, output,
+		sflowRecord(sflowSampledEthernet, sflowSampledEthernetRecord(r)), ipRecord), true
+}
+
+func (s *SFlow) packetSample(p *flow.PacketRecord) sflowSample {
+	header := p.Stream[:min(len(p.Stream), max(s.cfg.MaxHeaderSize, 0))]
+	be := binary.BigEndian
+	buf := make([]byte, 0, 16+len(header)+3)
+	buf = be.AppendUint32(buf, sflowHeaderProtocolEthernet)
+	buf = be.AppendUint32(buf, uint32(len(p.Stream))) // frame length
+	buf = be.AppendUint32(buf, 0)                     // bytes stripped from the frame
+	buf = be.AppendUint32(buf, uint32(len(header)))
+	buf = appendOpaque(buf, header)
+	// the packet capture does not tell whether the packets are received or sent by the interface
+	source := sflowSource{subAgentID: s.subAgentID(p.Netns), ifIndex: p.IfIndex}
+	return s.flowSample(source, uint32(max(s.cfg.Sampling, 1)), 0, 0, sflowRecord(sflowRawPacketHeader, buf))
+}
+
+// flowSample encodes a flow sample with the given flow records, for the interface as source
+func (s *SFlow) flowSample(source sflowSource, rate, input, output uint32, records ...[]byte) sflowSample {
+	s.flowSequence[source]++
+	s.samplePool[source] += rate
+	be := binary.BigEndian
+	buf := make([]byte, 0, 32)
+	buf = be.AppendUint32(buf, s.flowSequence[source])
+	buf = be.AppendUint32(buf, source.ifIndex) // source ID, with type 0 (ifIndex)
+	buf = be.AppendUint32(buf, rate)
+	buf = be.AppendUint32(buf, s.samplePool[source])
+	buf = be.AppendUint32(buf, 0) // drops
+	buf = be.AppendUint32(buf, input)
+	buf = be.AppendUint32(buf, output)
+	buf = be.AppendUint32(buf, uint32(len(records)))
+	for _, record := range records {
+		buf = append(buf, record...)
+	}
+	return sflowSample{subAgentID: source.subAgentID, data: sflowRecord(sflowFlowSample, buf)}
+}
+
+func (s *SFlow) counterSample(iface *sflowInterface) sflowSample {
+	source := sflowSource{subAgentID: s.subAgentID(iface.netns), ifIndex: iface.index}
+	s.counterSequence[source]++
+	be := binary.BigEndian
+	buf := make([]byte, 0, 12)
+	buf = be.AppendUint32(buf, s.counterSequence[source])
+	buf = be.AppendUint32(buf, iface.index) // source ID, with type 0 (ifIndex)
+	buf = be.AppendUint32(buf, 1)           // number of counter records
+	buf = append(buf, sflowRecord(sflowGenericInterfaceCounters, sflowGenericCounters(iface))...)
+	return sflowSample{subAgentID: source.subAgentID, data: sflowRecord(sflowCounterSample, buf)}
+}
+
+func sflowGenericCounters(iface *sflowInterface) []byte {
+	st := &iface.stats
+	be := binary.BigEndian
+	buf := make([]byte, 0, 88)
+	buf = be.AppendUint32(buf, iface.index)
+	buf = be.AppendUint32(buf, iface.ifType)
+	buf = be.AppendUint64(buf, 0) // speed is unknown
+	buf = be.AppendUint32(buf, 0) // direction is unknown
+	var status uint32
+	if iface.adminUp {
+		status |= 1
+	}
+	if iface.operUp {
+		status |= 2
+	}
+	buf = be.AppendUint32(buf, status)
+	buf = be.AppendUint64(buf, st.RxBytes)
+	buf = be.AppendUint32(buf, clampUint32(st.RxPackets-min(st.Multicast, st.RxPackets)))
+	buf = be.AppendUint32(buf, clampUint32(st.Multicast))
+	buf = be.AppendUint32(buf, sflowUnknownCounter) // input broadcast packets
+	buf = be.AppendUint32(buf, clampUint32(st.RxDropped))
+	buf = be.AppendUint32(buf, clampUint32(st.RxErrors))
+	buf = be.AppendUint32(buf, sflowUnknownCounter) // input unknown protocols
+	buf = be.AppendUint64(buf, st.TxBytes)
+	buf = be.AppendUint32(buf, clampUint32(st.TxPackets))
+	buf = be.AppendUint32(buf, sflowUnknownCounter) // output multicast packets
+	buf = be.AppendUint32(buf, sflowUnknownCounter) // output broadcast packets
+	buf = be.AppendUint32(buf, clampUint32(st.TxDropped))
+	buf = be.AppendUint32(buf, clampUint32(st.TxErrors))
+	var promisc uint32
+	if iface.promisc {
+		promisc = 1
+	}
+	return be.AppendUint32(buf, promisc)
+}
+
+func sflowSampledEthernetRecord(r *flow.Record) []byte {
+	be := binary.BigEndian
+	buf := make([]byte, 0, 24)
+	buf = be.AppendUint32(buf, averagePacketLength(r))
+	buf = appendOpaque(buf, r.Id.SrcMac[:])
+	buf = appendOpaque(buf, r.Id.DstMac[:])
+	return be.AppendUint32(buf, uint32(r.Id.EthProtocol))
+}
+
+// sflowSampledIPRecord encodes the sampled IPv4 or IPv6 flow records, which only differ in the
+// length of the addresses
+func sflowSampledIPRecord(r *flow.Record, src, dst []byte) []byte {
+	be := binary.BigEndian
+	buf := make([]byte, 0, 24+len(src)+len(dst))
+	buf = be.AppendUint32(buf, averagePacketLength(r))
+	buf = be.AppendUint32(buf, uint32(r.Id.TransportProtocol))
+	buf = append(buf, src...)
+	buf = append(buf, dst...)
+	buf = be.AppendUint32(buf, uint32(r.Id.SrcPort))
+	buf = be.AppendUint32(buf, uint32(r.Id.DstPort))
+	buf = be.AppendUint32(buf, uint32(r.Metrics.Flags))
+	// type of service in IPv4, and traffic class in IPv6
+	return be.AppendUint32(buf, uint32(r.Metrics.Dscp)<<2)
+}
+
+func averagePacketLength(r *flow.Record) uint32 {
+	if r.Metrics.Packets == 0 {
+		return 0
+	}
+	return clampUint32(r.Metrics.Bytes / uint64(r.Metrics.Packets))
+}
+
+// sflowRecord prepends the format and length to the data of a sample or record
+func sflowRecord(format uint32, data []byte) []byte {
+	buf := make([]byte, 0, 8+len(data))
+	buf = binary.BigEndian.AppendUint32(buf, format)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(data)))
+	return append(buf, data...)
+}
+
+// appendOpaque appends the data padded to a 32-bit boundary, as XDR opaque values
+func appendOpaque(buf, data []byte) []byte {
+	buf = append(buf, data...)
+	for i := len(data); i%4 != 0; i++ {
+		buf = append(buf, 0)
+	}
+	return buf
+}
+
+// hostInterfaces returns the statistics of the non-loopback interfaces of the agent's network
+// namespace and of the named network namespaces
+func hostInterfaces() ([]sflowInterface, error) {
+	hostNetNS, err := ifaces.NetNSInode(netns.None())
+	if err != nil {
+		return nil, fmt.Errorf("getting the agent network namespace: %w", err)
+	}
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("listing network interfaces: %w", err)
+	}
+	result := appendSFlowInterfaces(nil, hostNetNS, links)
+	files, err := os.ReadDir(sflowNetNSDir)
+	if err != nil {
+		// there are no named namespaces, or they are not visible from the agent
+		slog.WithError(err).Debug("can't list the network namespaces")
+		return result, nil
+	}
+	for _, f := range files {
+		netnsIno, links, err := netnsLinks(f.Name())
+		if err != nil {
+			slog.WithError(err).WithField("netns", f.Name()).Debug("can't list the network interfaces")
+			continue
+		}
+		if netnsIno != hostNetNS {
+			result = appendSFlowInterfaces(result, netnsIno, links)
+		}
+	}
+	return result, nil
+}
+
+// netnsLinks returns the inode number of a named network namespace and its links
+func netnsLinks(name string) (uint32, []netlink.Link, error) {
+	nsh, err := netns.GetFromName(name)
+	if err != nil {
+		return 0, nil, fmt.Errorf("opening network namespace: %w", err)
+	}
+	defer nsh.Close()
+	netnsIno, err := ifaces.NetNSInode(nsh)
+	if err != nil {
+		return 0, nil, fmt.Errorf("getting network namespace inode: %w", err)
+	}
+	handle, err := netlink.NewHandleAt(nsh)
+	if err != nil {
+		return 0, nil, fmt.Errorf("creating netlink handle: %w", err)
+	}
+	defer handle.Delete()
+	links, err := handle.LinkList()
+	if err != nil {
+		return 0, nil, fmt.Errorf("listing network interfaces: %w", err)
+	}
+	return netnsIno, links, nil
+}
+
+func appendSFlowInterfaces(result []sflowInterface, netnsIno uint32, links []netlink.Link) []sflowInterface {
+	for _, link := range links {
+		attrs := link.Attrs()
+		if attrs.Flags&net.FlagLoopback != 0 || attrs.Statistics == nil {
+			continue
+		}
+		result = append(result, sflowInterface{
+			netns:   netnsIno,
+			index:   uint32(attrs.Index),
+			ifType:  sflowIfType(link.Type()),
+			adminUp: attrs.Flags&net.FlagUp != 0,
+			operUp:  attrs.OperState == netlink.OperUp,
+			promisc: attrs.Promisc != 0,
+			stats:   *attrs.Statistics,
+		})
+	}
+	return result
+}
+
+// sflowIfType maps a netlink link type to the IANA ifType of the interface
+func sflowIfType(linkType string) uint32 {
+	switch linkType {
+	case "device":
+		return sflowIfTypeEthernet
+	case "bridge", "openvswitch":
+		return sflowIfTypeBridge
+	case "bond":
+		return sflowIfTypeIEEE8023adLag
+	case "vlan":
+		return sflowIfTypeL2VLAN
+	case "vxlan", "geneve", "gre", "gretap", "ip6gre", "ip6gretap", "ipip", "ip6tnl", "sit", "vti", "wireguard":
+		return sflowIfTypeTunnel
+	case "veth", "macvlan", "macvtap", "ipvlan", "ipvtap", "dummy", "tuntap", "netkit":
+		return sflowIfTypePropVirtual
+	default:
+		return sflowIfTypeOther
+	}
+}
@@ -0,0 +1,245 @@
+package exporter
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+)
+
+// sflowV4HeaderLen is the length of the datagram header with an IPv4 agent address
+const sflowV4HeaderLen = 28
+
+func startTestSFlow(t *testing.T, cfg SFlowConfig) (*SFlow, *net.UDPConn) {
+	t.Helper()
+	collector, port := listenUDP(t)
+	cfg.TargetHost = "127.0.0.1"
+	cfg.TargetPort = port
+	exporter, err := StartSFlow(&cfg, metrics.NewMetrics(&metrics.Settings{}))
+	require.NoError(t, err)
+	return exporter, collector
+}
+
+func TestSFlow_FlowSamples(t *testing.T) {
+	exporter, collector := startTestSFlow(t, SFlowConfig{
+		AgentIP:    net.ParseIP("192.168.1.10"),
+		SubAgentID: 3,
+		Sampling:   50,
+	})
+	bootTime := exporter.bootTime
+	v4 := netflowTestRecord(bootTime, "10.0.0.1", "10.0.0.2")
+	v6 := netflowTestRecord(bootTime, "2001:db8::1", "2001:db8::2")
+	v6.Metrics.Bytes = 1200
+
+	flows := make(chan []*flow.Record, 10)
+	flows <- []*flow.Record{v4, v6}
+	close(flows)
+	exporter.ExportFlows(flows)
+
+	be := binary.BigEndian
+	datagram := readUDP(t, collector)
+	assert.EqualValues(t, 5, be.Uint32(datagram[0:]), "version")
+	assert.EqualValues(t, 1, be.Uint32(datagram[4:]), "agent address type")
+	assert.Equal(t, net.ParseIP("192.168.1.10").To4(), net.IP(datagram[8:12]))
+	assert.EqualValues(t, 3, be.Uint32(datagram[12:]), "sub agent ID")
+	assert.EqualValues(t, 1, be.Uint32(datagram[16:]), "datagram sequence")
+	assert.EqualValues(t, 2, be.Uint32(datagram[24:]), "number of samples")
+
+	// IPv4 flow sample
+	sample := datagram[sflowV4HeaderLen:]
+	assert.EqualValues(t, sflowFlowSample, be.Uint32(sample[0:]))
+	sampleLen := be.Uint32(sample[4:])
+	assert.EqualValues(t, 32+(8+24)+(8+32), sampleLen)
+	assert.EqualValues(t, 1, be.Uint32(sample[8:]), "sample sequence")
+	assert.EqualValues(t, 3, be.Uint32(sample[12:]), "source ID")
+	assert.EqualValues(t, 50*12, be.Uint32(sample[16:]), "sampling rate by the flow packets")
+	assert.EqualValues(t, 50*12, be.Uint32(sample[20:]), "sample pool")
+	assert.EqualValues(t, 3, be.Uint32(sample[28:]), "input interface")
+	assert.EqualValues(t, 0, be.Uint32(sample[32:]), "output interface")
+	assert.EqualValues(t, 2, be.Uint32(sample[36:]), "number of records")
+
+	ethernet := sample[40:]
+	assert.EqualValues(t, sflowSampledEthernet, be.Uint32(ethernet[0:]))
+	assert.EqualValues(t, 24, be.Uint32(ethernet[4:]))
+	assert.EqualValues(t, (1<<33)/12, be.Uint32(ethernet[8:]), "average packet length")
+	assert.EqualValues(t, ipv4Type, be.Uint32(ethernet[28:]))
+
+	ipv4 := ethernet[32:]
+	assert.EqualValues(t, sflowSampledIPv4, be.Uint32(ipv4[0:]))
+	assert.EqualValues(t, 32, be.Uint32(ipv4[4:]))
+	assert.EqualValues(t, 6, be.Uint32(ipv4[12:]), "protocol")
+	assert.Equal(t, net.ParseIP("10.0.0.1").To4(), net.IP(ipv4[16:20]))
+	assert.Equal(t, net.ParseIP("10.0.0.2").To4(), net.IP(ipv4[20:24]))
+	assert.EqualValues(t, 34567, be.Uint32(ipv4[24:]))
+	assert.EqualValues(t, 443, be.Uint32(ipv4[28:]))
+	assert.EqualValues(t, 0x12, be.Uint32(ipv4[32:]), "tcp flags")
+	assert.EqualValues(t, 40, be.Uint32(ipv4[36:]), "type of service")
+
+	// IPv6 flow sample, from the same source
+	sample = sample[8+sampleLen:]
+	assert.EqualValues(t, 2, be.Uint32(sample[8:]), "sample sequence")
+	assert.EqualValues(t, 50*12*2, be.Uint32(sample[20:]), "sample pool")
+	ipv6 := sample[40+32:]
+	assert.EqualValues(t, sflowSampledIPv6, be.Uint32(ipv6[0:]))
+	assert.EqualValues(t, 56, be.Uint32(ipv6[4:]))
+	assert.EqualValues(t, 100, be.Uint32(ipv6[8:]), "average packet length")
+	assert.Equal(t, net.ParseIP("2001:db8::1"), net.IP(ipv6[16:32]))
+	assert.Equal(t, net.ParseIP("2001:db8::2"), net.IP(ipv6[32:48]))
+}
+
+func TestSFlow_SplitDatagrams(t *testing.T) {
+	exporter, collector := startTestSFlow(t, SFlowConfig{AgentIP: net.ParseIP("2001:db8::10")})
+	var records []*flow.Record
+	for i := 0; i < 30; i++ {
+		records = append(records, netflowTestRecord(exporter.bootTime, "10.0.0.1", "10.0.0.2"))
+	}
+	flows := make(chan []*flow.Record, 10)
+	flows <- records
+	close(flows)
+	exporter.ExportFlows(flows)
+
+	be := binary.BigEndian
+	samples := 0
+	for sequence := 1; samples < len(records); sequence++ {
+		datagram := readUDP(t, collector)
+		require.LessOrEqual(t, len(datagram), sflowMaxDatagramSize)
+		assert.EqualValues(t, 2, be.Uint32(datagram[4:]), "agent address type")
+		assert.Equal(t, net.ParseIP("2001:db8::10"), net.IP(datagram[8:24]))
+		assert.EqualValues(t, sequence, be.Uint32(datagram[28:]), "datagram sequence")
+		samples += int(be.Uint32(datagram[36:]))
+	}
+	assert.Equal(t, len(records), samples)
+}
+
+func TestSFlow_PacketSamples(t *testing.T) {
+	exporter, collector := startTestSFlow(t, SFlowConfig{
+		AgentIP:       net.ParseIP("192.168.1.10"),
+		Sampling:      10,
+		MaxHeaderSize: 14,
+	})
+	frame := make([]byte, 100)
+	for i := range frame {
+		frame[i] = byte(i)
+	}
+	packets := make(chan []*flow.PacketRecord, 10)
+	packets <- []*flow.PacketRecord{{Stream: frame, Time: time.Now(), IfIndex: 4}, {}}
+	close(packets)
+	exporter.ExportPackets(packets)
+
+	be := binary.BigEndian
+	datagram := readUDP(t, collector)
+	assert.EqualValues(t, 1, be.Uint32(datagram[24:]), "empty packets are ignored")
+	sample := datagram[sflowV4HeaderLen:]
+	assert.EqualValues(t, sflowFlowSample, be.Uint32(sample[0:]))
+	assert.EqualValues(t, 4, be.Uint32(sample[12:]), "source ID")
+	assert.EqualValues(t, 10, be.Uint32(sample[16:]), "sampling rate")
+	assert.EqualValues(t, 1, be.Uint32(sample[36:]), "number of records")
+	header := sample[40:]
+	assert.EqualValues(t, sflowRawPacketHeader, be.Uint32(header[0:]))
+	assert.EqualValues(t, 16+16, be.Uint32(header[4:]), "header padded to 32 bits")
+	assert.EqualValues(t, 1, be.Uint32(header[8:]), "ethernet")
+	assert.EqualValues(t, 100, be.Uint32(header[12:]), "frame length")
+	assert.EqualValues(t, 14, be.Uint32(header[20:]), "header length")
+	assert.Equal(t, frame[:14], header[24:38])
+	assert.Equal(t, []byte{0, 0}, header[38:40])
+}
+
+func TestSFlow_CounterSamples(t *testing.T) {
+	exporter, collector := startTestSFlow(t, SFlowConfig{
+		AgentIP:          net.ParseIP("192.168.1.10"),
+		CountersInterval: 10 * time.Millisecond,
+	})
+	exporter.interfaces = func() ([]sflowInterface, error) {
+		return []sflowInterface{{
+			index:   5,
+			ifType:  sflowIfTypeBridge,
+			adminUp: true,
+			operUp:  true,
+			stats: netlink.LinkStatistics{
+				RxBytes: 1 << 40, RxPackets: 100, Multicast: 10, RxDropped: 2, RxErrors: 1,
+				TxBytes: 2000, TxPackets: 20, TxDropped: 3, TxErrors: 4,
+			},
+		}}, nil
+	}
+	flows := make(chan []*flow.Record)
+	done := make(chan struct{})
+	go func() {
+		exporter.ExportFlows(flows)
+		close(done)
+	}()
+
+	be := binary.BigEndian
+	datagram := readUDP(t, collector)
+	close(flows)
+	<-done
+
+	assert.EqualValues(t, 1, be.Uint32(datagram[24:]), "number of samples")
+	sample := datagram[sflowV4HeaderLen:]
+	assert.EqualValues(t, sflowCounterSample, be.Uint32(sample[0:]))
+	assert.EqualValues(t, 12+8+88, be.Uint32(sample[4:]))
+	assert.EqualValues(t, 1, be.Uint32(sample[8:]), "sample sequence")
+	assert.EqualValues(t, 5, be.Uint32(sample[12:]), "source ID")
+	assert.EqualValues(t, 1, be.Uint32(sample[16:]), "number of records")
+	counters := sample[20:]
+	assert.EqualValues(t, sflowGenericInterfaceCounters, be.Uint32(counters[0:]))
+	assert.EqualValues(t, 88, be.Uint32(counters[4:]))
+	c := counters[8:]
+	assert.EqualValues(t, 5, be.Uint32(c[0:]), "ifIndex")
+	assert.EqualValues(t, sflowIfTypeBridge, be.Uint32(c[4:]), "ifType")
+	assert.EqualValues(t, 3, be.Uint32(c[20:]), "admin and operational status up")
+	assert.EqualValues(t, uint64(1<<40), be.Uint64(c[24:]), "input octets")
+	assert.EqualValues(t, 90, be.Uint32(c[32:]), "input unicast packets")
+	assert.EqualValues(t, 10, be.Uint32(c[36:]), "input multicast packets")
+	assert.EqualValues(t, 2, be.Uint32(c[44:]), "input discards")
+	assert.EqualValues(t, 1, be.Uint32(c[48:]), "input errors")
+	assert.EqualValues(t, 2000, be.Uint64(c[56:]), "output octets")
+	assert.EqualValues(t, 20, be.Uint32(c[64:]), "output unicast packets")
+	assert.EqualValues(t, 3, be.Uint32(c[76:]), "output discards")
+	assert.EqualValues(t, 4, be.Uint32(c[80:]), "output errors")
+	assert.EqualValues(t, 0, be.Uint32(c[84:]), "promiscuous mode")
+}
+
+func TestSFlow_NetNSSubAgents(t *testing.T) {
+	exporter, collector := startTestSFlow(t, SFlowConfig{AgentIP: net.ParseIP("192.168.1.10"), SubAgentID: 3})
+	host := netflowTestRecord(exporter.bootTime, "10.0.0.1", "10.0.0.2")
+	host.Id.Netns = exporter.hostNetNS
+	// same interface index in another namespace
+	pod := netflowTestRecord(exporter.bootTime, "10.128.0.5", "10.0.0.2")
+	pod.Id.Netns = 4026532000
+	flows := make(chan []*flow.Record, 10)
+	flows <- []*flow.Record{host, pod, host}
+	close(flows)
+	exporter.ExportFlows(flows)
+
+	be := binary.BigEndian
+	datagram := readUDP(t, collector)
+	assert.EqualValues(t, 3, be.Uint32(datagram[12:]), "sub agent ID")
+	assert.EqualValues(t, 2, be.Uint32(datagram[24:]), "number of samples")
+	datagram = readUDP(t, collector)
+	assert.EqualValues(t, 4026532000, be.Uint32(datagram[12:]), "sub agent ID")
+	assert.EqualValues(t, 1, be.Uint32(datagram[16:]), "datagram sequence")
+	assert.EqualValues(t, 1, be.Uint32(datagram[24:]), "number of samples")
+	sample := datagram[sflowV4HeaderLen:]
+	assert.EqualValues(t, 1, be.Uint32(sample[8:]), "sample sequence")
+	assert.EqualValues(t, 3, be.Uint32(sample[12:]), "source ID")
+}
+
+func TestSFlow_IfType(t *testing.T) {
+	for linkType, ifType := range map[string]uint32{
+		"device": sflowIfTypeEthernet,
+		"bridge": sflowIfTypeBridge,
+		"bond":   sflowIfTypeIEEE8023adLag,
+		"vlan":   sflowIfTypeL2VLAN,
+		"geneve": sflowIfTypeTunnel,
+		"veth":   sflowIfTypePropVirtual,
+		"can":    sflowIfTypeOther,
+	} {
+		assert.Equal(t, ifType, sflowIfType(linkType), linkType)
+	}
+}
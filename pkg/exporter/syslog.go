@@ -0,0 +1,298 @@
+package exporter
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/decode"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/utils"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+var sylog = logrus.WithField("component", "exporter/Syslog")
+
+const (
+	componentSyslog = "syslog"
+	syslogAppName   = "netobserv-ebpf-agent"
+	syslogMsgID     = "flow"
+	// syslogSeverityInfo is the severity of all the flow messages
+	syslogSeverityInfo = 6
+)
+
+// Transport protocols of the syslog exporter
+const (
+	SyslogTransportUDP = "udp"
+	SyslogTransportTCP = "tcp"
+	SyslogTransportTLS = "tls"
+)
+
+// Renderings of the flows in the syslog messages
+const (
+	// SyslogFormatStructured renders each flow as RFC 5424 structured data, with the same field
+	// names as the flows decoded by Flowlogs-Pipeline
+	SyslogFormatStructured = "structured"
+	// SyslogFormatCEF renders each flow as an ArcSight Common Event Format message
+	SyslogFormatCEF = "cef"
+)
+
+// syslogSDID is the structured data ID of the flows, under the NetObserv private enterprise number
+var syslogSDID = "flow@" + strconv.FormatUint(uint64(NetObservEnterpriseID), 10)
+
+// SyslogConfig configures the syslog exporter
+type SyslogConfig struct {
+	TargetHost string
+	TargetPort int
+	// Transport is SyslogTransportUDP, SyslogTransportTCP or SyslogTransportTLS. TCP and TLS
+	// messages are framed by octet counting (RFC 6587 and RFC 5425).
+	Transport string
+	// TLSConfig of the TLS transport
+	TLSConfig *tls.Config
+	// Format is SyslogFormatStructured or SyslogFormatCEF
+	Format string
+	// Facility of the messages, from 0 to 23
+	Facility int
+	// RateLimit is the maximum number of messages per second. The flows beyond it are dropped.
+	// Zero disables the rate limiting.
+	RateLimit float64
+	// RateBurst is the maximum number of messages sent at once, above the rate limit
+	RateBurst int
+}
+
+// Syslog exporter sends each flow as an RFC 5424 syslog message
+type Syslog struct {
+	cfg      SyslogConfig
+	hostname string
+	procID   string
+	dial     func() (net.Conn, error)
+	conn     net.Conn
+	limiter  *rate.Limiter
+
+	metrics      *metrics.Metrics
+	batchCounter prometheus.Counter
+}
+
+func StartSyslog(cfg *SyslogConfig, m *metrics.Metrics) (*Syslog, error) {
+	socket := utils.GetSocket(cfg.TargetHost, cfg.TargetPort)
+	s := &Syslog{
+		cfg:          *cfg,
+		hostname:     "-",
+		procID:       strconv.Itoa(os.Getpid()),
+		metrics:      m,
+		batchCounter: m.CreateBatchCounter(componentSyslog),
+	}
+	switch cfg.Transport {
+	case SyslogTransportUDP, SyslogTransportTCP:
+		s.dial = func() (net.Conn, error) { return net.Dial(cfg.Transport, socket) }
+	case SyslogTransportTLS:
+		s.dial = func() (net.Conn, error) { return tls.Dial("tcp", socket, cfg.TLSConfig) }
+	default:
+		return nil, fmt.Errorf("wrong syslog transport %s", cfg.Transport)
+	}
+	if cfg.Format != SyslogFormatStructured && cfg.Format != SyslogFormatCEF {
+		return nil, fmt.Errorf("wrong syslog format %s", cfg.Format)
+	}
+	if cfg.Facility < 0 || cfg.Facility > 23 {
+		return nil, fmt.Errorf("wrong syslog facility %d", cfg.Facility)
+	}
+	if cfg.RateLimit > 0 {
+		s.limiter = rate.NewLimiter(rate.Limit(cfg.RateLimit), max(cfg.RateBurst, 1))
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		s.hostname = hostname
+	}
+	conn, err := s.dial()
+	if err != nil {
+		return nil, fmt.Errorf("connecting to syslog server %s: %w", socket, err)
+	}
+	s.conn = conn
+	sylog.WithField("server", socket).Info("Created exporter connecting to syslog server")
+	return s, nil
+}
+
+// ExportFlows accepts slices of *flow.Record by its input channel, and sends each flow as a
+// syslog message, as long as the rate limit allows it
+func (s *Syslog) ExportFlows(input <-chan []*flow.Record) {
+	for records := range input {
+		s.metrics.EvictionCounter.WithSource(componentSyslog).Inc()
+		s.sendFlows(records)
+		s.metrics.EvictedFlowsCounter.WithSource(componentSyslog).Add(float64(len(records)))
+	}
+	if s.conn != nil {
+		if err := s.conn.Close(); err != nil {
+			sylog.WithError(err).Warn("couldn't close syslog connection")
+			s.metrics.Errors.WithErrorName(componentSyslog, "CannotCloseClient").Inc()
+		}
+	}
+}
+
+func (s *Syslog) sendFlows(records []*flow.Record) {
+	now := time.Now()
+	var buf bytes.Buffer
+	for i, record := range records {
+		if s.limiter != nil && !s.limiter.AllowN(now, 1) {
+			s.metrics.DroppedFlowsCounter.WithSourceAndReason(componentSyslog, "rate-limited").
+				Add(float64(len(records) - i))
+			break
+		}
+		msg := s.message(record, now)
+		if s.cfg.Transport == SyslogTransportUDP {
+			// each UDP datagram contains a single message
+			s.write(msg)
+			continue
+		}
+		buf.WriteString(strconv.Itoa(len(msg)))
+		buf.WriteByte(' ')
+		buf.Write(msg)
+	}
+	if buf.Len() > 0 {
+		s.write(buf.Bytes())
+	}
+}
+
+// write sends the data, reconnecting first if the previous write failed
+func (s *Syslog) write(data []byte) {
+	if s.conn == nil {
+		conn, err := s.dial()
+		if err != nil {
+			sylog.WithError(err).Error("can't reconnect to syslog server")
+			s.metrics.Errors.WithErrorName(componentSyslog, "CannotConnect").Inc()
+			return
+		}
+		s.conn = conn
+	}
+	if _, err := s.conn.Write(data); err != nil {
+		sylog.WithError(err).Error("can't write syslog messages")
+		s.metrics.Errors.WithErrorName(componentSyslog, "CannotWriteMessage").Inc()
+		_ = s.conn.Close()
+		s.conn = nil
+		return
+	}
+	s.batchCounter.Inc()
+}
+
+// message returns the RFC 5424 message of the flow:
+// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+func (s *Syslog) message(record *flow.Record, now time.Time) []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<%d>1 %s %s %s %s %s ",
+		s.cfg.Facility*8+syslogSeverityInfo, now.UTC().Format(time.RFC3339Nano),
+		s.hostname, syslogAppName, s.procID, syslogMsgID)
+	if s.cfg.Format == SyslogFormatCEF {
+		sb.WriteString("- ")
+		sb.WriteString(cefMessage(record))
+	} else {
+		sb.WriteString(structuredData(record))
+	}
+	return []byte(sb.String())
+}
+
+// structuredData renders the flow as an RFC 5424 SD-ELEMENT, whose parameters are sorted by name
+func structuredData(record *flow.Record) string {
+	fields := decode.RecordToMap(record)
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	sb.WriteByte('[')
+	sb.WriteString(syslogSDID)
+	for _, name := range names {
+		sb.WriteByte(' ')
+		sb.WriteString(name)
+		sb.WriteString(`="`)
+		sdEscaper.WriteString(&sb, sdValue(fields[name]))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// sdEscaper escapes the characters that are not allowed unescaped in SD-PARAM values
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// sdValue renders the slices as comma-separated values
+func sdValue(value interface{}) string {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice {
+		return fmt.Sprint(value)
+	}
+	items := make([]string, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		items = append(items, fmt.Sprint(v.Index(i).Interface()))
+	}
+	return strings.Join(items, ",")
+}
+
+// cefEscaper escapes the characters that are not allowed unescaped in CEF extension values
+var cefEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+
+// cefMessage renders the flow as an ArcSight Common Event Format message:
+// CEF:Version|Device Vendor|Device Product|Device Version|Signature ID|Name|Severity|Extension
+func cefMessage(record *flow.Record) string {
+	var ext []string
+	add := func(key string, value interface{}) {
+		ext = append(ext, key+"="+cefEscaper.Replace(fmt.Sprint(value)))
+	}
+	add("rt", record.TimeFlowEnd.UnixMilli())
+	add("start", record.TimeFlowStart.UnixMilli())
+	add("end", record.TimeFlowEnd.UnixMilli())
+	if record.AgentIP != nil {
+		add("dvc", record.AgentIP)
+	}
+	srcMAC, dstMAC := flow.MacAddr(record.Id.SrcMac), flow.MacAddr(record.Id.DstMac)
+	add("smac", srcMAC.String())
+	add("dmac", dstMAC.String())
+	if record.Id.EthProtocol == ipv4Type || record.Id.EthProtocol == flow.IPv6Type {
+		add("src", flow.IP(record.Id.SrcIp))
+		add("dst", flow.IP(record.Id.DstIp))
+		add("proto", strings.ToUpper(transportName(record.Id.TransportProtocol)))
+		if record.Id.SrcPort != 0 || record.Id.DstPort != 0 {
+			add("spt", record.Id.SrcPort)
+			add("dpt", record.Id.DstPort)
+		}
+	}
+	if record.Id.Direction == flow.DirectionEgress {
+		add("deviceDirection", 1)
+		add("deviceOutboundInterface", record.Interface)
+	} else {
+		add("deviceDirection", 0)
+		add("deviceInboundInterface", record.Interface)
+	}
+	add("out", record.Metrics.Bytes)
+	add("cnt", record.Metrics.Packets)
+	if record.Metrics.PktDrops.LatestDropCause != 0 {
+		add("cs1Label", "pktDropLatestDropCause")
+		add("cs1", decode.PktDropCauseToStr(record.Metrics.PktDrops.LatestDropCause))
+		add("cn1Label", "pktDropPackets")
+		add("cn1", record.Metrics.PktDrops.Packets)
+		if record.Metrics.PktDrops.LatestDropLocation != 0 {
+			add("cs3Label", "pktDropLatestLocation")
+			add("cs3", decode.PktDropLocationToStr(record.Metrics.PktDrops.LatestDropLocation, record.DropLocation))
+		}
+	}
+	if record.Metrics.DnsRecord.Id != 0 {
+		add("cs2Label", "dnsFlagsResponseCode")
+		add("cs2", decode.DNSRcodeToStr(uint32(record.Metrics.DnsRecord.Flags)&0xF))
+		add("cn2Label", "dnsLatencyMs")
+		add("cn2", record.DNSLatency.Milliseconds())
+	}
+	if record.TimeFlowRtt != 0 {
+		add("cn3Label", "timeFlowRttNs")
+		add("cn3", record.TimeFlowRtt.Nanoseconds())
+	}
+	return "CEF:0|NetObserv|" + syslogAppName + "|1|flow|Network flow|1|" + strings.Join(ext, " ")
+}
@@ -0,0 +1,159 @@
+package exporter
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/test"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var syslogHeader = regexp.MustCompile(`^<134>1 \S+Z \S+ netobserv-ebpf-agent \d+ flow `)
+
+func syslogTestRecord() *flow.Record {
+	record := netflowTestRecord(time.Now(), "10.0.0.1", "10.0.0.2")
+	record.Interface = "eth0"
+	record.AgentIP = net.ParseIP("192.168.1.10")
+	return record
+}
+
+func exportSyslog(t *testing.T, s *Syslog, records ...*flow.Record) {
+	t.Helper()
+	flows := make(chan []*flow.Record, 1)
+	flows <- records
+	close(flows)
+	s.ExportFlows(flows)
+}
+
+// readOctetCounted reads a message framed by octet counting
+func readOctetCounted(t *testing.T, reader *bufio.Reader) string {
+	t.Helper()
+	length, err := reader.ReadString(' ')
+	require.NoError(t, err)
+	n, err := strconv.Atoi(length[:len(length)-1])
+	require.NoError(t, err)
+	msg := make([]byte, n)
+	_, err = io.ReadFull(reader, msg)
+	require.NoError(t, err)
+	return string(msg)
+}
+
+func TestSyslog_UDPStructuredData(t *testing.T) {
+	collector, port := listenUDP(t)
+	s, err := StartSyslog(&SyslogConfig{
+		TargetHost: "127.0.0.1",
+		TargetPort: port,
+		Transport:  SyslogTransportUDP,
+		Format:     SyslogFormatStructured,
+		Facility:   16,
+	}, metrics.NewMetrics(&metrics.Settings{}))
+	require.NoError(t, err)
+	record := syslogTestRecord()
+	record.Interface = `we"ird]`
+	exportSyslog(t, s, record, syslogTestRecord())
+
+	for i := 0; i < 2; i++ {
+		msg := string(readUDP(t, collector))
+		assert.Regexp(t, syslogHeader, msg)
+		assert.Contains(t, msg, ` [flow@2312 AgentIP="192.168.1.10" Bytes="8589934592" `)
+		assert.Contains(t, msg, ` DstAddr="10.0.0.2" `)
+		assert.Contains(t, msg, ` DstPort="443" `)
+		assert.Contains(t, msg, ` IfDirections="0" `)
+		assert.Contains(t, msg, ` SrcAddr="10.0.0.1" `)
+		assert.Contains(t, msg, ` SrcPort="34567" `)
+		if i == 0 {
+			assert.Contains(t, msg, ` Interfaces="we\"ird\]" `)
+		} else {
+			assert.Contains(t, msg, ` Interfaces="eth0" `)
+		}
+		assert.Regexp(t, `\]$`, msg)
+	}
+}
+
+func TestSyslog_TCPCEFWithRateLimit(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	m := metrics.NewMetrics(&metrics.Settings{})
+	s, err := StartSyslog(&SyslogConfig{
+		TargetHost: "127.0.0.1",
+		TargetPort: listener.Addr().(*net.TCPAddr).Port,
+		Transport:  SyslogTransportTCP,
+		Format:     SyslogFormatCEF,
+		Facility:   16,
+		RateLimit:  0.001,
+		RateBurst:  2,
+	}, m)
+	require.NoError(t, err)
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	drop := syslogTestRecord()
+	drop.Id.Direction = flow.DirectionEgress
+	drop.Metrics.PktDrops.Packets = 2
+	drop.Metrics.PktDrops.LatestDropCause = 2
+	exportSyslog(t, s, syslogTestRecord(), drop, syslogTestRecord(), syslogTestRecord())
+
+	reader := bufio.NewReader(conn)
+	msg := readOctetCounted(t, reader)
+	assert.Regexp(t, syslogHeader, msg)
+	assert.Contains(t, msg, " - CEF:0|NetObserv|netobserv-ebpf-agent|1|flow|Network flow|1|rt=")
+	assert.Contains(t, msg, " dvc=192.168.1.10 smac=00:00:00:00:00:00 dmac=00:00:00:00:00:00"+
+		" src=10.0.0.1 dst=10.0.0.2 proto=TCP spt=34567 dpt=443"+
+		" deviceDirection=0 deviceInboundInterface=eth0 out=8589934592 cnt=12")
+
+	msg = readOctetCounted(t, reader)
+	assert.Contains(t, msg, " deviceDirection=1 deviceOutboundInterface=eth0 ")
+	assert.Contains(t, msg, " cs1Label=pktDropLatestDropCause cs1=SKB_DROP_REASON_NOT_SPECIFIED cn1Label=pktDropPackets cn1=2")
+
+	// the two last flows exceed the rate limit, and the connection is closed after them
+	rest, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+}
+
+func TestSyslog_TLS(t *testing.T) {
+	certs := test.GenerateCerts(t, t.TempDir())
+	serverCerts, err := utils.NewCertReloader(certs.CACert, certs.ServerCert, certs.ServerKey)
+	require.NoError(t, err)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", serverCerts.ServerConfig())
+	require.NoError(t, err)
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		content, _ := io.ReadAll(conn)
+		received <- string(content)
+	}()
+
+	clientCerts, err := utils.NewCertReloader(certs.CACert, certs.ClientCert, certs.ClientKey)
+	require.NoError(t, err)
+	s, err := StartSyslog(&SyslogConfig{
+		TargetHost: "127.0.0.1",
+		TargetPort: listener.Addr().(*net.TCPAddr).Port,
+		Transport:  SyslogTransportTLS,
+		TLSConfig:  clientCerts.ClientConfig("127.0.0.1", false),
+		Format:     SyslogFormatStructured,
+		Facility:   16,
+	}, metrics.NewMetrics(&metrics.Settings{}))
+	require.NoError(t, err)
+	exportSyslog(t, s, syslogTestRecord())
+
+	content := test.ReceiveTimeout(t, received, timeout)
+	assert.Regexp(t, `^\d+ <134>1 .* \[flow@2312 AgentIP="192.168.1.10" .*\]$`, content)
+}
@@ -110,7 +110,6 @@ func TestEvict_MaxEntries(t *testing.T) {
 			},
 			TimeFlowStart: now.Add(-(1000 - 123) * time.Nanosecond),
 			TimeFlowEnd:   now.Add(-(1000 - 789) * time.Nanosecond),
-			DupList:       make([]map[string]uint8, 0),
 		},
 		k2: {
 			RawRecord: RawRecord{
@@ -121,7 +120,6 @@ func TestEvict_MaxEntries(t *testing.T) {
 			},
 			TimeFlowStart: now.Add(-(1000 - 456) * time.Nanosecond),
 			TimeFlowEnd:   now.Add(-(1000 - 456) * time.Nanosecond),
-			DupList:       make([]map[string]uint8, 0),
 		},
 	}, received)
 }
@@ -190,7 +188,6 @@ func TestEvict_Period(t *testing.T) {
 		},
 		TimeFlowStart: now.Add(-1000 + 123),
 		TimeFlowEnd:   now.Add(-1000 + 789),
-		DupList:       make([]map[string]uint8, 0),
 	}, *records[0])
 	records = receiveTimeout(t, evictor)
 	require.Len(t, records, 1)
@@ -207,7 +204,6 @@ func TestEvict_Period(t *testing.T) {
 		},
 		TimeFlowStart: now.Add(-1000 + 1123),
 		TimeFlowEnd:   now.Add(-1000 + 1456),
-		DupList:       make([]map[string]uint8, 0),
 	}, *records[0])
 
 	// no more flows are evicted
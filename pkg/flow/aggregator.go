@@ -0,0 +1,198 @@
+package flow
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ebpf"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+
+	"github.com/sirupsen/logrus"
+)
+
+var aglog = logrus.WithField("component", "flow/Aggregator")
+
+// Flow fields that the Aggregator can remove from the aggregation key
+const (
+	// AggregateIgnoreEphemeralPort removes the greater of the source and destination ports, if it
+	// belongs to the ephemeral ports range (see AggregatorConfig.EphemeralPortMin)
+	AggregateIgnoreEphemeralPort = "ephemeral_port"
+	AggregateIgnoreSrcPort       = "src_port"
+	AggregateIgnoreDstPort       = "dst_port"
+	AggregateIgnoreMACs          = "macs"
+	AggregateIgnoreInterface     = "interface"
+	AggregateIgnoreDirection     = "direction"
+)
+
+// AggregatorConfig configures the key and the window of the userspace aggregation
+type AggregatorConfig struct {
+	// Window is the period after which the aggregated flows are evicted
+	Window time.Duration
+	// MaxFlows is the number of aggregated flows that triggers an eviction before the end of
+	// the window
+	MaxFlows int
+	// Ignore is the list of AggregateIgnore* fields removed from the aggregation key
+	Ignore []string
+	// CIDRs that the source and destination addresses are collapsed into. The most specific CIDR
+	// is used. The addresses that don't belong to any CIDR are kept.
+	CIDRs []*net.IPNet
+	// EphemeralPortMin is the lowest port of the ephemeral ports range
+	EphemeralPortMin uint16
+}
+
+// aggregationKey distinguishes the duplicate flows, which must be aggregated separately so
+// they keep being excluded from any metrics' aggregation
+type aggregationKey struct {
+	id        ebpf.BpfFlowId
+	duplicate bool
+}
+
+// Aggregator re-aggregates the decorated flows over a coarser key than the eBPF flow ID, to
+// reduce the number of exported flows (e.g. the many short flows from a client that uses a
+// different ephemeral port for each connection).
+type Aggregator struct {
+	cfg     AggregatorConfig
+	ignore  map[string]bool
+	entries map[aggregationKey]*Record
+	metrics *metrics.Metrics
+}
+
+// NewAggregator creates a new Aggregator, or returns an error if the configuration contains
+// unknown fields
+func NewAggregator(cfg *AggregatorConfig, m *metrics.Metrics) (*Aggregator, error) {
+	if cfg.Window <= 0 {
+		return nil, fmt.Errorf("wrong aggregation window %s", cfg.Window)
+	}
+	ag := &Aggregator{
+		cfg:     *cfg,
+		ignore:  map[string]bool{},
+		entries: map[aggregationKey]*Record{},
+		metrics: m,
+	}
+	for _, field := range cfg.Ignore {
+		switch field {
+		case AggregateIgnoreEphemeralPort, AggregateIgnoreSrcPort, AggregateIgnoreDstPort,
+			AggregateIgnoreMACs, AggregateIgnoreInterface, AggregateIgnoreDirection:
+			ag.ignore[field] = true
+		default:
+			return nil, fmt.Errorf("unknown aggregation field %q. Accepted fields are: %s, %s, %s, %s, %s, %s",
+				field, AggregateIgnoreEphemeralPort, AggregateIgnoreSrcPort, AggregateIgnoreDstPort,
+				AggregateIgnoreMACs, AggregateIgnoreInterface, AggregateIgnoreDirection)
+		}
+	}
+	return ag, nil
+}
+
+// Aggregate reads the flows from the input channel and merges them by their aggregation key.
+// The aggregated flows are forwarded at the end of each window, when MaxFlows is reached, or
+// when the input channel is closed.
+func (ag *Aggregator) Aggregate(in <-chan []*Record, out chan<- []*Record) {
+	evictTick := time.NewTicker(ag.cfg.Window)
+	defer evictTick.Stop()
+	for {
+		select {
+		case <-evictTick.C:
+			if len(ag.entries) > 0 {
+				ag.evict(out, "timeout")
+			}
+		case records, ok := <-in:
+			if !ok {
+				aglog.Debug("input channel closed. Evicting entries")
+				if len(ag.entries) > 0 {
+					ag.evict(out, "closing")
+				}
+				return
+			}
+			for _, record := range records {
+				ag.add(record)
+				if ag.cfg.MaxFlows > 0 && len(ag.entries) >= ag.cfg.MaxFlows {
+					ag.evict(out, "full")
+					evictTick.Reset(ag.cfg.Window)
+				}
+			}
+		}
+		ag.metrics.BufferSizeGauge.WithBufferName("aggregator-entries").Set(float64(len(ag.entries)))
+	}
+}
+
+func (ag *Aggregator) add(record *Record) {
+	key := aggregationKey{id: ag.aggregatedID(&record.Id), duplicate: record.Duplicate}
+	stored, ok := ag.entries[key]
+	if !ok {
+		aggregated := copyRecord(record)
+		aggregated.Id = key.id
+		if key.id.IfIndex == 0 && record.Id.IfIndex != 0 {
+			aggregated.Interface = ""
+			aggregated.NetNSName = ""
+			aggregated.InterfaceLink = nil
+		}
+		ag.entries[key] = aggregated
+		return
+	}
+	accumulateRecord(stored, record)
+}
+
+// aggregatedID returns the flow ID without the ignored fields, and with the addresses collapsed
+// into their CIDRs
+func (ag *Aggregator) aggregatedID(id *ebpf.BpfFlowId) ebpf.BpfFlowId {
+	key := *id
+	if ag.ignore[AggregateIgnoreSrcPort] {
+		key.SrcPort = 0
+	}
+	if ag.ignore[AggregateIgnoreDstPort] {
+		key.DstPort = 0
+	}
+	if ag.ignore[AggregateIgnoreEphemeralPort] {
+		if key.SrcPort >= key.DstPort && key.SrcPort >= ag.cfg.EphemeralPortMin {
+			key.SrcPort = 0
+		} else if key.DstPort > key.SrcPort && key.DstPort >= ag.cfg.EphemeralPortMin {
+			key.DstPort = 0
+		}
+	}
+	if ag.ignore[AggregateIgnoreMACs] {
+		key.SrcMac = [MacLen]uint8{}
+		key.DstMac = [MacLen]uint8{}
+	}
+	if ag.ignore[AggregateIgnoreInterface] {
+		key.IfIndex = 0
+		key.Netns = 0
+	}
+	if ag.ignore[AggregateIgnoreDirection] {
+		key.Direction = 0
+	}
+	if len(ag.cfg.CIDRs) > 0 && key.EthProtocol != 0 {
+		key.SrcIp = ag.collapse(key.SrcIp)
+		key.DstIp = ag.collapse(key.DstIp)
+	}
+	return key
+}
+
+// collapse returns the network address of the most specific CIDR that contains the address, or
+// the address itself if no CIDR contains it
+func (ag *Aggregator) collapse(addr IPAddr) IPAddr {
+	ip := IP(addr)
+	var match *net.IPNet
+	matchLen := -1
+	for _, cidr := range ag.cfg.CIDRs {
+		if ones, _ := cidr.Mask.Size(); ones > matchLen && cidr.Contains(ip) {
+			match, matchLen = cidr, ones
+		}
+	}
+	if match == nil {
+		return addr
+	}
+	return IPAddrFromNetIP(ip.Mask(match.Mask).To16())
+}
+
+func (ag *Aggregator) evict(out chan<- []*Record, reason string) {
+	records := make([]*Record, 0, len(ag.entries))
+	for _, record := range ag.entries {
+		records = append(records, record)
+	}
+	ag.entries = map[aggregationKey]*Record{}
+	ag.metrics.EvictionCounter.WithSourceAndReason("aggregator", reason).Inc()
+	ag.metrics.EvictedFlowsCounter.WithSourceAndReason("aggregator", reason).Add(float64(len(records)))
+	aglog.WithField("numEntries", len(records)).Debug("records evicted from userspace aggregator")
+	out <- records
+}
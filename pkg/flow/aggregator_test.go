@@ -0,0 +1,150 @@
+package flow
+
+import (
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ebpf"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func aggregatorTestRecord(srcPort, dstPort uint16, start time.Time) *Record {
+	return &Record{RawRecord: RawRecord{Id: ebpf.BpfFlowId{
+		EthProtocol: 0x0800, SrcIp: srcAddr1, DstIp: dstAddr1, SrcPort: srcPort, DstPort: dstPort,
+		TransportProtocol: 6, SrcMac: MacAddr{0x1}, DstMac: MacAddr{0x2}, IfIndex: 1,
+	}, Metrics: ebpf.BpfFlowMetrics{
+		Packets: 2, Bytes: 100, Flags: 0x02,
+	}}, Interface: "eth0", TimeFlowStart: start, TimeFlowEnd: start.Add(time.Second)}
+}
+
+func aggregate(t *testing.T, ag *Aggregator, records ...*Record) []*Record {
+	t.Helper()
+	input := make(chan []*Record, 1)
+	output := make(chan []*Record, 10)
+	input <- records
+	close(input)
+	ag.Aggregate(input, output)
+	close(output)
+	var aggregated []*Record
+	for evicted := range output {
+		aggregated = append(aggregated, evicted...)
+	}
+	sort.Slice(aggregated, func(i, j int) bool {
+		return aggregated[i].Metrics.Packets > aggregated[j].Metrics.Packets
+	})
+	return aggregated
+}
+
+func TestAggregator_MergesMetrics(t *testing.T) {
+	ag, err := NewAggregator(&AggregatorConfig{
+		Window:           time.Hour,
+		Ignore:           []string{AggregateIgnoreEphemeralPort, AggregateIgnoreMACs},
+		EphemeralPortMin: 32768,
+	}, metrics.NewMetrics(&metrics.Settings{}))
+	require.NoError(t, err)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := aggregatorTestRecord(40000, 443, start)
+	first.Metrics.PktDrops = ebpf.BpfPktDropsT{Packets: 1, Bytes: 50, LatestDropCause: 2, LatestDropLocation: 0xffffffff81a2b3c4}
+	first.Metrics.DnsRecord = ebpf.BpfDnsRecordT{Id: 7, Flags: 0x8180, Latency: 10}
+	first.DNSLatency = 10
+	first.DupList = []DupEntry{{Interface: "eth0", Direction: 0, Bytes: 10, Packets: 1}}
+	second := aggregatorTestRecord(50000, 443, start.Add(-time.Second))
+	second.Metrics.Flags = 0x10
+	second.Metrics.FlowRtt = 30
+	second.TimeFlowRtt = 30
+	second.DupList = []DupEntry{{Interface: "eth0", Direction: 0, Bytes: 20, Packets: 2}, {Interface: "eth1", Direction: 1, Bytes: 20, Packets: 2}}
+	// the server side of the same conversation keeps its source port
+	reply := aggregatorTestRecord(443, 40000, start)
+	// duplicates are aggregated separately
+	duplicate := aggregatorTestRecord(40001, 443, start)
+	duplicate.Duplicate = true
+	duplicate.Metrics.Packets = 3
+	// ports below the ephemeral range are kept
+	other := aggregatorTestRecord(1234, 443, start)
+	other.Metrics.Packets = 1
+
+	aggregated := aggregate(t, ag, first, second, reply, duplicate, other)
+	require.Len(t, aggregated, 4)
+
+	merged := aggregated[0]
+	assert.Equal(t, uint16(0), merged.Id.SrcPort)
+	assert.Equal(t, uint16(443), merged.Id.DstPort)
+	assert.Equal(t, MacAddr{}, MacAddr(merged.Id.SrcMac))
+	assert.Equal(t, "eth0", merged.Interface)
+	assert.False(t, merged.Duplicate)
+	assert.EqualValues(t, 4, merged.Metrics.Packets)
+	assert.EqualValues(t, 200, merged.Metrics.Bytes)
+	assert.EqualValues(t, 0x12, merged.Metrics.Flags)
+	assert.Equal(t, ebpf.BpfPktDropsT{Packets: 1, Bytes: 50, LatestDropCause: 2, LatestDropLocation: 0xffffffff81a2b3c4}, merged.Metrics.PktDrops)
+	assert.Equal(t, ebpf.BpfDnsRecordT{Id: 7, Flags: 0x8180, Latency: 10}, merged.Metrics.DnsRecord)
+	assert.EqualValues(t, 10, merged.DNSLatency)
+	assert.EqualValues(t, 30, merged.Metrics.FlowRtt)
+	assert.EqualValues(t, 30, merged.TimeFlowRtt)
+	assert.Equal(t, start.Add(-time.Second), merged.TimeFlowStart)
+	assert.Equal(t, start.Add(time.Second), merged.TimeFlowEnd)
+	assert.Equal(t, []DupEntry{
+		{Interface: "eth0", Direction: 0, Bytes: 30, Packets: 3},
+		{Interface: "eth1", Direction: 1, Bytes: 20, Packets: 2},
+	}, merged.DupList)
+	// the input records are not modified
+	assert.EqualValues(t, 2, first.Metrics.Packets)
+	assert.Equal(t, []DupEntry{{Interface: "eth0", Direction: 0, Bytes: 10, Packets: 1}}, first.DupList)
+
+	assert.True(t, aggregated[1].Duplicate)
+	assert.Equal(t, uint16(0), aggregated[1].Id.SrcPort)
+	assert.Equal(t, uint16(443), aggregated[2].Id.SrcPort)
+	assert.Equal(t, uint16(0), aggregated[2].Id.DstPort)
+	assert.Equal(t, uint16(1234), aggregated[3].Id.SrcPort)
+}
+
+func TestAggregator_CIDRsAndInterface(t *testing.T) {
+	_, wide, _ := net.ParseCIDR("18.0.0.0/8")
+	_, narrow, _ := net.ParseCIDR("18.52.0.0/16")
+	ag, err := NewAggregator(&AggregatorConfig{
+		Window: time.Hour,
+		Ignore: []string{AggregateIgnoreSrcPort, AggregateIgnoreInterface, AggregateIgnoreDirection},
+		CIDRs:  []*net.IPNet{wide, narrow},
+	}, metrics.NewMetrics(&metrics.Settings{}))
+	require.NoError(t, err)
+	egress := aggregatorTestRecord(1000, 443, time.Now())
+	egress.Id.Direction = DirectionEgress
+	egress.Id.IfIndex = 2
+	egress.Interface = "eth1"
+
+	aggregated := aggregate(t, ag, aggregatorTestRecord(2000, 443, time.Now()), egress)
+	require.Len(t, aggregated, 1)
+	assert.Equal(t, "18.52.0.0", IP(aggregated[0].Id.SrcIp).String())
+	assert.Equal(t, "67.33.0.255", IP(aggregated[0].Id.DstIp).String())
+	assert.Equal(t, uint32(0), aggregated[0].Id.IfIndex)
+	assert.Empty(t, aggregated[0].Interface)
+	assert.Equal(t, DirectionIngress, aggregated[0].Id.Direction)
+	assert.EqualValues(t, 4, aggregated[0].Metrics.Packets)
+}
+
+func TestAggregator_EvictsWhenFull(t *testing.T) {
+	ag, err := NewAggregator(&AggregatorConfig{Window: time.Hour, MaxFlows: 2}, metrics.NewMetrics(&metrics.Settings{}))
+	require.NoError(t, err)
+	input := make(chan []*Record, 1)
+	output := make(chan []*Record, 10)
+	go ag.Aggregate(input, output)
+
+	input <- []*Record{aggregatorTestRecord(1, 443, time.Now()), aggregatorTestRecord(1, 443, time.Now()),
+		aggregatorTestRecord(2, 443, time.Now()), aggregatorTestRecord(3, 443, time.Now())}
+	evicted := receiveTimeout(t, output)
+	assert.Len(t, evicted, 2)
+	requireNoEviction(t, output)
+	close(input)
+	assert.Len(t, receiveTimeout(t, output), 1)
+}
+
+func TestAggregator_WrongConfig(t *testing.T) {
+	_, err := NewAggregator(&AggregatorConfig{Window: time.Hour, Ignore: []string{"pod"}}, metrics.NewMetrics(&metrics.Settings{}))
+	assert.Error(t, err)
+	_, err = NewAggregator(&AggregatorConfig{}, metrics.NewMetrics(&metrics.Settings{}))
+	assert.Error(t, err)
+}
@@ -0,0 +1,270 @@
+package flow
+
+import (
+	"bytes"
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+
+	"github.com/sirupsen/logrus"
+)
+
+var bflog = logrus.WithField("component", "flow/BiflowStitcher")
+
+// ICMP echo types, whose requests and replies belong to the same bidirectional flow
+const (
+	icmpEchoReply     = 0
+	icmpEchoRequest   = 8
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+)
+
+// biflowEndpoint is an address and port of a flow
+type biflowEndpoint struct {
+	ip   IPAddr
+	port uint16
+}
+
+func (e *biflowEndpoint) less(o *biflowEndpoint) bool {
+	if c := bytes.Compare(e.ip[:], o.ip[:]); c != 0 {
+		return c < 0
+	}
+	return e.port < o.port
+}
+
+// biflowKey identifies the two directions of a flow observed from the same interface. The
+// endpoints are sorted, so both directions have the same key. MACs and direction are ignored,
+// as they are swapped or different in the reverse direction. The ICMP type and code are zeroed
+// for the echo requests and replies, so that they are matched.
+type biflowKey struct {
+	ethProtocol       uint16
+	transportProtocol uint8
+	icmpType          uint8
+	icmpCode          uint8
+	ifIndex           uint32
+	netns             uint32
+	low, high         biflowEndpoint
+	duplicate         bool
+}
+
+// biflowEntry holds the accumulated flows of both directions. The source of the forward flow
+// is the low endpoint of the key.
+type biflowEntry struct {
+	forward, reverse *Record
+}
+
+// BiflowStitcher matches the flows with their reverse flows (same interface, swapped source and
+// destination), and merges them into bidirectional flows, as described in RFC 5103. The flows
+// whose reverse flow is not observed during the same window are forwarded unmodified.
+type BiflowStitcher struct {
+	window   time.Duration
+	maxFlows int
+	entries  map[biflowKey]*biflowEntry
+	metrics  *metrics.Metrics
+}
+
+// NewBiflowStitcher creates a new BiflowStitcher that matches the flows received during the
+// same window, and evicts them earlier if maxFlows is reached
+func NewBiflowStitcher(window time.Duration, maxFlows int, m *metrics.Metrics) (*BiflowStitcher, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("wrong biflow window %s", window)
+	}
+	return &BiflowStitcher{
+		window:   window,
+		maxFlows: maxFlows,
+		entries:  map[biflowKey]*biflowEntry{},
+		metrics:  m,
+	}, nil
+}
+
+// Stitch reads the flows from the input channel and forwards the bidirectional flows at the end
+// of each window, when maxFlows is reached, or when the input channel is closed.
+func (bs *BiflowStitcher) Stitch(in <-chan []*Record, out chan<- []*Record) {
+	evictTick := time.NewTicker(bs.window)
+	defer evictTick.Stop()
+	for {
+		select {
+		case <-evictTick.C:
+			if len(bs.entries) > 0 {
+				bs.evict(out, "timeout")
+			}
+		case records, ok := <-in:
+			if !ok {
+				bflog.Debug("input channel closed. Evicting entries")
+				if len(bs.entries) > 0 {
+					bs.evict(out, "closing")
+				}
+				return
+			}
+			for _, record := range records {
+				bs.add(record)
+				if bs.maxFlows > 0 && len(bs.entries) >= bs.maxFlows {
+					bs.evict(out, "full")
+					evictTick.Reset(bs.window)
+				}
+			}
+		}
+		bs.metrics.BufferSizeGauge.WithBufferName("biflow-entries").Set(float64(len(bs.entries)))
+	}
+}
+
+func (bs *BiflowStitcher) add(record *Record) {
+	src := biflowEndpoint{ip: record.Id.SrcIp, port: record.Id.SrcPort}
+	dst := biflowEndpoint{ip: record.Id.DstIp, port: record.Id.DstPort}
+	key := biflowKey{
+		ethProtocol:       record.Id.EthProtocol,
+		transportProtocol: record.Id.TransportProtocol,
+		icmpType:          record.Id.IcmpType,
+		icmpCode:          record.Id.IcmpCode,
+		ifIndex:           record.Id.IfIndex,
+		netns:             record.Id.Netns,
+		low:               src,
+		high:              dst,
+		duplicate:         record.Duplicate,
+	}
+	reverse := dst.less(&src)
+	if reverse {
+		key.low, key.high = dst, src
+	}
+	if isICMPEcho(record) {
+		key.icmpType, key.icmpCode = 0, 0
+	}
+	entry, ok := bs.entries[key]
+	if !ok {
+		entry = &biflowEntry{}
+		bs.entries[key] = entry
+	}
+	side := &entry.forward
+	if reverse {
+		side = &entry.reverse
+	}
+	if *side == nil {
+		*side = copyRecord(record)
+	} else {
+		accumulateRecord(*side, record)
+	}
+}
+
+func (bs *BiflowStitcher) evict(out chan<- []*Record, reason string) {
+	records := make([]*Record, 0, len(bs.entries))
+	for _, entry := range bs.entries {
+		switch {
+		case entry.reverse == nil:
+			records = append(records, entry.forward)
+		case entry.forward == nil:
+			records = append(records, entry.reverse)
+		default:
+			records = append(records, stitch(entry.forward, entry.reverse))
+		}
+	}
+	bs.entries = map[biflowKey]*biflowEntry{}
+	bs.metrics.EvictionCounter.WithSourceAndReason("biflow", reason).Inc()
+	bs.metrics.EvictedFlowsCounter.WithSourceAndReason("biflow", reason).Add(float64(len(records)))
+	bflog.WithField("numEntries", len(records)).Debug("records evicted from biflow stitcher")
+	out <- records
+}
+
+// stitch merges two flows in opposite directions into a bidirectional flow, whose source is the
+// initiator of the connection
+func stitch(a, b *Record) *Record {
+	initiator, responder, direction := biflowInitiator(a, b)
+	biflow := initiator
+	biflow.Reverse = &ReverseMetrics{
+		Bytes:           responder.Metrics.Bytes,
+		Packets:         responder.Metrics.Packets,
+		Flags:           responder.Metrics.Flags,
+		PktDropBytes:    responder.Metrics.PktDrops.Bytes,
+		PktDropPackets:  responder.Metrics.PktDrops.Packets,
+		BiflowDirection: direction,
+	}
+	// only the egress packets are queued, so the qdisc statistics of both directions are summed
+	biflow.Metrics.Qdisc.Time += responder.Metrics.Qdisc.Time
+	biflow.Metrics.Qdisc.Packets += responder.Metrics.Qdisc.Packets
+	biflow.Metrics.Qdisc.DropPackets += responder.Metrics.Qdisc.DropPackets
+	biflow.Metrics.Qdisc.DropBytes += responder.Metrics.Qdisc.DropBytes
+	if biflow.Metrics.Qdisc.LatestKind[0] == 0 {
+		biflow.Metrics.Qdisc.LatestKind = responder.Metrics.Qdisc.LatestKind
+	}
+	if biflow.Metrics.PktDrops.Packets == 0 {
+		biflow.Metrics.PktDrops.LatestFlags = responder.Metrics.PktDrops.LatestFlags
+		biflow.Metrics.PktDrops.LatestState = responder.Metrics.PktDrops.LatestState
+		biflow.Metrics.PktDrops.LatestDropCause = responder.Metrics.PktDrops.LatestDropCause
+		biflow.Metrics.PktDrops.LatestDropLocation = responder.Metrics.PktDrops.LatestDropLocation
+		biflow.Metrics.PktDrops.LatestDropStackId = responder.Metrics.PktDrops.LatestDropStackId
+		biflow.DropCause = responder.DropCause
+		biflow.DropLocation = responder.DropLocation
+		biflow.DropStack = responder.DropStack
+	}
+	// DNS and RTT describe the whole connection, so they are taken from any direction
+	biflow.Metrics.DnsRecord.Flags |= responder.Metrics.DnsRecord.Flags
+	if responder.Metrics.DnsRecord.Id != 0 {
+		biflow.Metrics.DnsRecord.Id = responder.Metrics.DnsRecord.Id
+	}
+	if responder.Metrics.DnsRecord.Latency > biflow.Metrics.DnsRecord.Latency {
+		biflow.Metrics.DnsRecord.Latency = responder.Metrics.DnsRecord.Latency
+		biflow.DNSLatency = responder.DNSLatency
+	}
+	if responder.Metrics.DnsRecord.Errno != 0 {
+		biflow.Metrics.DnsRecord.Errno = responder.Metrics.DnsRecord.Errno
+	}
+	if responder.Metrics.FlowRtt > biflow.Metrics.FlowRtt {
+		biflow.Metrics.FlowRtt = responder.Metrics.FlowRtt
+		biflow.TimeFlowRtt = responder.TimeFlowRtt
+	}
+	if responder.TransitLatency > biflow.TransitLatency {
+		biflow.TransitLatency = responder.TransitLatency
+	}
+	if responder.TimeFlowStart.Before(biflow.TimeFlowStart) {
+		biflow.TimeFlowStart = responder.TimeFlowStart
+	}
+	if responder.TimeFlowEnd.After(biflow.TimeFlowEnd) {
+		biflow.TimeFlowEnd = responder.TimeFlowEnd
+	}
+	for _, dup := range responder.DupList {
+		biflow.DupList = mergeDupEntry(biflow.DupList, dup)
+	}
+	return biflow
+}
+
+// biflowInitiator returns the initiator and responder flows. The initiator is the only one that
+// sends a SYN without ACK, or the one that doesn't send a SYN-ACK, or the ICMP echo requests. If
+// the TCP flags and ICMP types don't tell it, the flow that started first is arbitrarily
+// considered as the initiator.
+func biflowInitiator(a, b *Record) (initiator, responder *Record, direction uint8) {
+	aSYN, bSYN := a.Metrics.Flags&TCPFlagSYN != 0, b.Metrics.Flags&TCPFlagSYN != 0
+	aSYNACK, bSYNACK := a.Metrics.Flags&TCPFlagSYNACK != 0, b.Metrics.Flags&TCPFlagSYNACK != 0
+	aEchoRequest, bEchoRequest := isICMPEchoRequest(a), isICMPEchoRequest(b)
+	switch {
+	case aSYN && !bSYN, bSYNACK && !aSYNACK, aEchoRequest && !bEchoRequest:
+		return a, b, BiflowInitiator
+	case bSYN && !aSYN, aSYNACK && !bSYNACK, bEchoRequest && !aEchoRequest:
+		return b, a, BiflowInitiator
+	case b.TimeFlowStart.Before(a.TimeFlowStart):
+		return b, a, BiflowArbitrary
+	default:
+		return a, b, BiflowArbitrary
+	}
+}
+
+// isICMPEcho tells whether a flow is made of ICMP or ICMPv6 echo requests or replies
+func isICMPEcho(r *Record) bool {
+	switch r.Id.TransportProtocol {
+	case syscall.IPPROTO_ICMP:
+		return r.Id.IcmpType == icmpEchoRequest || r.Id.IcmpType == icmpEchoReply
+	case syscall.IPPROTO_ICMPV6:
+		return r.Id.IcmpType == icmpv6EchoRequest || r.Id.IcmpType == icmpv6EchoReply
+	}
+	return false
+}
+
+func isICMPEchoRequest(r *Record) bool {
+	switch r.Id.TransportProtocol {
+	case syscall.IPPROTO_ICMP:
+		return r.Id.IcmpType == icmpEchoRequest
+	case syscall.IPPROTO_ICMPV6:
+		return r.Id.IcmpType == icmpv6EchoRequest
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package flow
+
+import (
+	"sort"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/ebpf"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func biflowTestRecord(src, dst IPAddr, srcPort, dstPort uint16, flags uint16, start time.Time) *Record {
+	return &Record{RawRecord: RawRecord{Id: ebpf.BpfFlowId{
+		EthProtocol: 0x0800, SrcIp: src, DstIp: dst, SrcPort: srcPort, DstPort: dstPort,
+		TransportProtocol: 6, IfIndex: 1,
+	}, Metrics: ebpf.BpfFlowMetrics{
+		Packets: 2, Bytes: 100, Flags: flags,
+	}}, Interface: "eth0", TimeFlowStart: start, TimeFlowEnd: start.Add(time.Second)}
+}
+
+func stitchBiflows(t *testing.T, records ...*Record) []*Record {
+	t.Helper()
+	bs, err := NewBiflowStitcher(time.Hour, 0, metrics.NewMetrics(&metrics.Settings{}))
+	require.NoError(t, err)
+	input := make(chan []*Record, 1)
+	output := make(chan []*Record, 10)
+	input <- records
+	close(input)
+	bs.Stitch(input, output)
+	close(output)
+	var stitched []*Record
+	for evicted := range output {
+		stitched = append(stitched, evicted...)
+	}
+	sort.Slice(stitched, func(i, j int) bool {
+		return stitched[i].Id.SrcPort < stitched[j].Id.SrcPort
+	})
+	return stitched
+}
+
+func TestBiflow_InitiatorFromSYN(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// the response is observed first, but the request carries the SYN
+	response := biflowTestRecord(dstAddr1, srcAddr1, 443, 40000, TCPFlagSYNACK|0x10, start.Add(-time.Second))
+	response.Metrics.Bytes = 5000
+	response.Metrics.Packets = 4
+	response.Metrics.PktDrops = ebpf.BpfPktDropsT{Packets: 1, Bytes: 60, LatestDropCause: 2}
+	response.Metrics.DnsRecord = ebpf.BpfDnsRecordT{Id: 7, Latency: 10}
+	response.DNSLatency = 10
+	response.Metrics.FlowRtt = 30
+	response.TimeFlowRtt = 30
+	request := biflowTestRecord(srcAddr1, dstAddr1, 40000, 443, TCPFlagSYN|0x10, start)
+	more := biflowTestRecord(srcAddr1, dstAddr1, 40000, 443, 0x10, start.Add(time.Second))
+	// other flows are not matched
+	unrelated := biflowTestRecord(srcAddr1, dstAddr1, 40001, 443, TCPFlagSYN, start)
+	duplicate := biflowTestRecord(dstAddr1, srcAddr1, 443, 40000, 0x10, start)
+	duplicate.Duplicate = true
+
+	stitched := stitchBiflows(t, response, request, unrelated, more, duplicate)
+	require.Len(t, stitched, 3)
+	assert.Equal(t, duplicate, stitched[0])
+	assert.Equal(t, unrelated, stitched[2])
+
+	biflow := stitched[1]
+	assert.Equal(t, srcAddr1, IPAddr(biflow.Id.SrcIp))
+	assert.Equal(t, uint16(40000), biflow.Id.SrcPort)
+	assert.Equal(t, uint16(443), biflow.Id.DstPort)
+	assert.EqualValues(t, 4, biflow.Metrics.Packets)
+	assert.EqualValues(t, 200, biflow.Metrics.Bytes)
+	assert.Equal(t, TCPFlagSYN|0x10, biflow.Metrics.Flags)
+	assert.Equal(t, &ReverseMetrics{
+		Bytes:           5000,
+		Packets:         4,
+		Flags:           TCPFlagSYNACK | 0x10,
+		PktDropBytes:    60,
+		PktDropPackets:  1,
+		BiflowDirection: BiflowInitiator,
+	}, biflow.Reverse)
+	assert.EqualValues(t, 2, biflow.Metrics.PktDrops.LatestDropCause)
+	assert.Zero(t, biflow.Metrics.PktDrops.Packets, "the initiator didn't drop packets")
+	assert.EqualValues(t, 7, biflow.Metrics.DnsRecord.Id)
+	assert.EqualValues(t, 10, biflow.DNSLatency)
+	assert.EqualValues(t, 30, biflow.TimeFlowRtt)
+	assert.Equal(t, start.Add(-time.Second), biflow.TimeFlowStart)
+	assert.Equal(t, start.Add(2*time.Second), biflow.TimeFlowEnd)
+	// the input records are not modified
+	assert.Nil(t, request.Reverse)
+	assert.EqualValues(t, 2, request.Metrics.Packets)
+}
+
+func TestBiflow_ArbitraryInitiator(t *testing.T) {
+	start := time.Now()
+	// UDP flows don't have flags, so the first flow is considered as the initiator
+	query := biflowTestRecord(dstAddr1, srcAddr1, 53, 40000, 0, start)
+	query.Id.TransportProtocol = 17
+	query.Id.Direction = DirectionEgress
+	answer := biflowTestRecord(srcAddr1, dstAddr1, 40000, 53, 0, start.Add(time.Millisecond))
+	answer.Id.TransportProtocol = 17
+	answer.Id.Direction = DirectionIngress
+	answer.Metrics.Bytes = 300
+
+	stitched := stitchBiflows(t, answer, query)
+	require.Len(t, stitched, 1)
+	assert.Equal(t, dstAddr1, IPAddr(stitched[0].Id.SrcIp))
+	assert.Equal(t, DirectionEgress, stitched[0].Id.Direction)
+	assert.EqualValues(t, 100, stitched[0].Metrics.Bytes)
+	assert.EqualValues(t, 300, stitched[0].Reverse.Bytes)
+	assert.Equal(t, BiflowArbitrary, stitched[0].Reverse.BiflowDirection)
+}
+
+func TestBiflow_DifferentInterfaces(t *testing.T) {
+	start := time.Now()
+	request := biflowTestRecord(srcAddr1, dstAddr1, 40000, 443, TCPFlagSYN, start)
+	response := biflowTestRecord(dstAddr1, srcAddr1, 443, 40000, TCPFlagSYNACK, start)
+	response.Id.IfIndex = 2
+
+	stitched := stitchBiflows(t, request, response)
+	require.Len(t, stitched, 2)
+	assert.Nil(t, stitched[0].Reverse)
+	assert.Nil(t, stitched[1].Reverse)
+}
+
+func TestBiflow_ICMPEcho(t *testing.T) {
+	start := time.Now()
+	reply := biflowTestRecord(dstAddr1, srcAddr1, 0, 0, 0, start.Add(-time.Millisecond))
+	reply.Id.TransportProtocol = syscall.IPPROTO_ICMP
+	reply.Metrics.Qdisc = ebpf.BpfQdiscT{Time: 3000, Packets: 2, LatestKind: [16]uint8{'h', 't', 'b'}}
+	request := biflowTestRecord(srcAddr1, dstAddr1, 0, 0, 0, start)
+	request.Id.TransportProtocol = syscall.IPPROTO_ICMP
+	request.Id.IcmpType = icmpEchoRequest
+	request.Metrics.Qdisc = ebpf.BpfQdiscT{Time: 1000, Packets: 1, DropPackets: 1, DropBytes: 100}
+	// other ICMP messages are not matched
+	unreachable := biflowTestRecord(dstAddr1, srcAddr1, 0, 0, 0, start)
+	unreachable.Id.TransportProtocol = syscall.IPPROTO_ICMP
+	unreachable.Id.IcmpType = 3
+	unreachable.Id.IcmpCode = 1
+
+	stitched := stitchBiflows(t, reply, request, unreachable)
+	require.Len(t, stitched, 2)
+	sort.Slice(stitched, func(i, j int) bool {
+		return stitched[i].Id.IcmpType < stitched[j].Id.IcmpType
+	})
+	assert.Equal(t, unreachable, stitched[0])
+
+	biflow := stitched[1]
+	assert.Equal(t, srcAddr1, IPAddr(biflow.Id.SrcIp))
+	assert.EqualValues(t, icmpEchoRequest, biflow.Id.IcmpType)
+	assert.Equal(t, BiflowInitiator, biflow.Reverse.BiflowDirection)
+	assert.EqualValues(t, 100, biflow.Reverse.Bytes)
+	// the qdisc statistics of both directions are summed
+	assert.Equal(t, ebpf.BpfQdiscT{
+		Time: 4000, Packets: 3, DropPackets: 1, DropBytes: 100, LatestKind: [16]uint8{'h', 't', 'b'},
+	}, biflow.Metrics.Qdisc)
+}
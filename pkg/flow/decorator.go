@@ -4,16 +4,54 @@ import (
 	"net"
 )
 
-type InterfaceNamer func(ifIndex int) string
+// InterfaceNamer returns the name of an interface given the inode of its network namespace and
+// its index. A zero netns refers to the agent's network namespace.
+type InterfaceNamer func(netns uint32, ifIndex int) string
+
+// NetNSNamer returns the name of a network namespace given its inode, or an empty string if the
+// namespace has no name (e.g. the agent's network namespace).
+type NetNSNamer func(netns uint32) string
+
+// InterfaceLinker returns the link attributes of an interface given the inode of its network
+// namespace and its index, or nil if they are unknown.
+type InterfaceLinker func(netns uint32, ifIndex int) *InterfaceLink
+
+// InterfaceRanker returns the deduplication preference of an interface given the inode of its
+// network namespace and its index. The interfaces with lower rank are preferred.
+type InterfaceRanker func(netns uint32, ifIndex int) int
+
+// DropLocator returns the kernel function that dropped a packet and, if the stack id is not
+// zero, the kernel functions of the stack of the drop, given the drop location address and the
+// LatestDropStackId of the drop.
+type DropLocator func(location uint64, stackID uint32) (function string, stack []string)
+
+// DropCauseNamer returns the name of a drop cause in the kernel of the agent
+type DropCauseNamer func(cause uint32) string
 
 // Decorate adds to the flows extra metadata fields that are not directly fetched by eBPF:
-// - The interface name (corresponding to the interface index in the flow).
+// - The interface name (corresponding to the network namespace and interface index in the flow).
+// - The network namespace name, if it is named in /var/run/netns.
+// - The link attributes of the interface, if the linker is not nil.
+// - The name of the cause of the latest drop, if the drop cause namer is not nil.
+// - The kernel function and stack of the latest drop, if the drop locator is not nil.
 // - The IP address of the agent host.
-func Decorate(agentIP net.IP, ifaceNamer InterfaceNamer) func(in <-chan []*Record, out chan<- []*Record) {
+func Decorate(agentIP net.IP, ifaceNamer InterfaceNamer, netnsNamer NetNSNamer, linker InterfaceLinker,
+	dropCauseNamer DropCauseNamer, dropLocator DropLocator) func(in <-chan []*Record, out chan<- []*Record) {
 	return func(in <-chan []*Record, out chan<- []*Record) {
 		for flows := range in {
 			for _, flow := range flows {
-				flow.Interface = ifaceNamer(int(flow.Id.IfIndex))
+				flow.Interface = ifaceNamer(flow.Id.Netns, int(flow.Id.IfIndex))
+				flow.NetNSName = netnsNamer(flow.Id.Netns)
+				if linker != nil {
+					flow.InterfaceLink = linker(flow.Id.Netns, int(flow.Id.IfIndex))
+				}
+				if dropCauseNamer != nil && flow.Metrics.PktDrops.LatestDropCause != 0 {
+					flow.DropCause = dropCauseNamer(flow.Metrics.PktDrops.LatestDropCause)
+				}
+				if dropLocator != nil && flow.Metrics.PktDrops.LatestDropLocation != 0 {
+					flow.DropLocation, flow.DropStack = dropLocator(
+						flow.Metrics.PktDrops.LatestDropLocation, flow.Metrics.PktDrops.LatestDropStackId)
+				}
 				flow.AgentIP = agentIP
 			}
 			out <- flows
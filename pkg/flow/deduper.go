@@ -2,7 +2,6 @@ package flow
 
 import (
 	"container/list"
-	"reflect"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -19,6 +18,11 @@ var timeNow = time.Now
 // It is not safe for concurrent access.
 type deduperCache struct {
 	expire time.Duration
+	// ranker tells which interface is preferred to report a flow. If nil, the first interface
+	// reporting a flow is kept.
+	ranker InterfaceRanker
+	// batch is the sequence number of the records batch being deduplicated
+	batch uint64
 	// key: ebpf.BpfFlowId with the interface and MACs erased, to detect duplicates
 	// value: listElement pointing to a struct entry
 	ifaces map[ebpf.BpfFlowId]*list.Element
@@ -30,24 +34,88 @@ type entry struct {
 	key        *ebpf.BpfFlowId
 	dnsRecord  *ebpf.BpfDnsRecordT
 	flowRTT    *uint64
+	qdisc      *ebpf.BpfQdiscT
 	ifIndex    uint32
+	netns      uint32
+	rank       int
 	expiryTime time.Time
-	dupList    *[]map[string]uint8
+	// record is the last forwarded record of the flow, from the batch recordBatch
+	record      *Record
+	recordBatch uint64
+	transit     transitTimes
+	// dupList holds the views of the flow from each interface during the batch dupBatch
+	dupList  []DupEntry
+	dupBatch uint64
+}
+
+// transitTimes holds the earliest and latest timestamps of the first and last packets of a flow,
+// among the interfaces that observed it during the same batch
+type transitTimes struct {
+	batch                 uint64
+	ifIndex               uint32
+	netns                 uint32
+	multiIface            bool
+	firstStart, lastStart time.Time
+	firstEnd, lastEnd     time.Time
+}
+
+// observe records the timestamps of a flow observed from an interface, and returns whether the
+// flow was observed from different interfaces during the batch
+func (t *transitTimes) observe(r *Record, batch uint64) bool {
+	if t.batch != batch {
+		*t = transitTimes{
+			batch:      batch,
+			ifIndex:    r.Id.IfIndex,
+			netns:      r.Id.Netns,
+			firstStart: r.TimeFlowStart,
+			lastStart:  r.TimeFlowStart,
+			firstEnd:   r.TimeFlowEnd,
+			lastEnd:    r.TimeFlowEnd,
+		}
+		return false
+	}
+	if r.Id.IfIndex != t.ifIndex || r.Id.Netns != t.netns {
+		t.multiIface = true
+	}
+	if r.TimeFlowStart.Before(t.firstStart) {
+		t.firstStart = r.TimeFlowStart
+	}
+	if r.TimeFlowStart.After(t.lastStart) {
+		t.lastStart = r.TimeFlowStart
+	}
+	if r.TimeFlowEnd.Before(t.firstEnd) {
+		t.firstEnd = r.TimeFlowEnd
+	}
+	if r.TimeFlowEnd.After(t.lastEnd) {
+		t.lastEnd = r.TimeFlowEnd
+	}
+	return t.multiIface
+}
+
+// latency is the longest time that the first or the last packet took to be observed by all the
+// interfaces
+func (t *transitTimes) latency() time.Duration {
+	return max(t.lastStart.Sub(t.firstStart), t.lastEnd.Sub(t.firstEnd))
 }
 
 // Dedupe receives flows and filters these belonging to duplicate interfaces. It will forward
 // the flows from the first interface coming to it, until that flow expires in the cache
-// (no activity for it during the expiration time)
+// (no activity for it during the expiration time), or until the flow is observed from an
+// interface with a lower rank, if a ranker is provided.
 // The justMark argument tells that the deduper should not drop the duplicate flows but
 // set their Duplicate field.
-func Dedupe(expireTime time.Duration, justMark, mergeDup bool, ifaceNamer InterfaceNamer, m *metrics.Metrics) func(in <-chan []*Record, out chan<- []*Record) {
+// The flows observed from different interfaces within the same batch have their TransitLatency
+// set, as the time the packets took to cross these interfaces.
+func Dedupe(expireTime time.Duration, justMark, mergeDup bool, ifaceNamer InterfaceNamer, ranker InterfaceRanker, m *metrics.Metrics) func(in <-chan []*Record, out chan<- []*Record) {
 	cache := &deduperCache{
 		expire:  expireTime,
+		ranker:  ranker,
 		entries: list.New(),
 		ifaces:  map[ebpf.BpfFlowId]*list.Element{},
 	}
 	return func(in <-chan []*Record, out chan<- []*Record) {
 		for records := range in {
+			cache.batch++
 			cache.removeExpired()
 			fwd := make([]*Record, 0, len(records))
 			for _, record := range records {
@@ -66,10 +134,10 @@ func Dedupe(expireTime time.Duration, justMark, mergeDup bool, ifaceNamer Interf
 
 // checkDupe check current record if its already available nad if not added to fwd records list
 func (c *deduperCache) checkDupe(r *Record, justMark, mergeDup bool, fwd *[]*Record, ifaceNamer InterfaceNamer) {
-	mergeEntry := make(map[string]uint8)
 	rk := r.Id
 	// zeroes fields from key that should be ignored from the flow comparison
 	rk.IfIndex = 0
+	rk.Netns = 0
 	rk.SrcMac = [MacLen]uint8{0, 0, 0, 0, 0, 0}
 	rk.DstMac = [MacLen]uint8{0, 0, 0, 0, 0, 0}
 	rk.Direction = 0
@@ -93,55 +161,120 @@ func (c *deduperCache) checkDupe(r *Record, justMark, mergeDup bool, fwd *[]*Rec
 		if r.Metrics.FlowRtt != 0 && *fEntry.flowRTT == 0 {
 			*fEntry.flowRTT = r.Metrics.FlowRtt
 		}
-		if fEntry.ifIndex != r.Id.IfIndex {
+		// the qdisc is only seen from the egress interface, which might not be the reported one
+		if hasQdisc(&r.Metrics.Qdisc) && !hasQdisc(fEntry.qdisc) {
+			*fEntry.qdisc = r.Metrics.Qdisc
+		}
+		if fEntry.ifIndex != r.Id.IfIndex || fEntry.netns != r.Id.Netns {
+			if c.ranker != nil && c.rank(r) < fEntry.rank {
+				c.takeOver(fEntry, r, justMark, mergeDup, fwd, ifaceNamer)
+				return
+			}
+			c.observe(fEntry, r, mergeDup, ifaceNamer)
 			if justMark {
 				r.Duplicate = true
 				*fwd = append(*fwd, r)
 			}
-			if mergeDup {
-				ifName := ifaceNamer(int(r.Id.IfIndex))
-				mergeEntry[ifName] = r.Id.Direction
-				if dupEntryNew(*fEntry.dupList, mergeEntry) {
-					*fEntry.dupList = append(*fEntry.dupList, mergeEntry)
-					dlog.Debugf("merge list entries dump:")
-					for _, entry := range *fEntry.dupList {
-						for k, v := range entry {
-							dlog.Debugf("interface %s dir %d", k, v)
-						}
-					}
-				}
-			}
 			return
 		}
+		fEntry.record, fEntry.recordBatch = r, c.batch
+		c.observe(fEntry, r, mergeDup, ifaceNamer)
 		*fwd = append(*fwd, r)
 		return
 	}
 	// The flow has not been accounted previously (or was forgotten after expiration)
 	// so we register it for that concrete interface
 	e := entry{
-		key:        &rk,
-		dnsRecord:  &r.Metrics.DnsRecord,
-		flowRTT:    &r.Metrics.FlowRtt,
-		ifIndex:    r.Id.IfIndex,
-		expiryTime: timeNow().Add(c.expire),
+		key:         &rk,
+		dnsRecord:   &r.Metrics.DnsRecord,
+		flowRTT:     &r.Metrics.FlowRtt,
+		qdisc:       &r.Metrics.Qdisc,
+		ifIndex:     r.Id.IfIndex,
+		netns:       r.Id.Netns,
+		expiryTime:  timeNow().Add(c.expire),
+		record:      r,
+		recordBatch: c.batch,
 	}
-	if mergeDup {
-		ifName := ifaceNamer(int(r.Id.IfIndex))
-		mergeEntry[ifName] = r.Id.Direction
-		r.DupList = append(r.DupList, mergeEntry)
-		e.dupList = &r.DupList
+	if c.ranker != nil {
+		e.rank = c.rank(r)
 	}
+	c.observe(&e, r, mergeDup, ifaceNamer)
 	c.ifaces[rk] = c.entries.PushFront(&e)
 	*fwd = append(*fwd, r)
 }
 
-func dupEntryNew(dupList []map[string]uint8, mergeEntry map[string]uint8) bool {
-	for _, entry := range dupList {
-		if reflect.DeepEqual(entry, mergeEntry) {
-			return false
+func hasQdisc(q *ebpf.BpfQdiscT) bool {
+	return q.Packets != 0 || q.DropPackets != 0
+}
+
+func (c *deduperCache) rank(r *Record) int {
+	return c.ranker(r.Id.Netns, int(r.Id.IfIndex))
+}
+
+// takeOver registers the interface of the record as the interface reporting the flow, as it is
+// preferred to the interface that reported it until now. If the record of the previous interface
+// has not been forwarded yet, it is dropped or marked as duplicate.
+func (c *deduperCache) takeOver(e *entry, r *Record, justMark, mergeDup bool, fwd *[]*Record, ifaceNamer InterfaceNamer) {
+	previous := e.record
+	if previous != nil && e.recordBatch == c.batch {
+		// the duplicate list is moved to the new record
+		previous.DupList = nil
+		if justMark {
+			previous.Duplicate = true
+		} else {
+			for i, f := range *fwd {
+				if f == previous {
+					*fwd = append((*fwd)[:i], (*fwd)[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	// keep the enrichment that the previous interface might have provided
+	if r.Metrics.DnsRecord.Latency == 0 && e.dnsRecord.Latency != 0 {
+		r.Metrics.DnsRecord.Flags = e.dnsRecord.Flags
+		r.Metrics.DnsRecord.Id = e.dnsRecord.Id
+		r.Metrics.DnsRecord.Latency = e.dnsRecord.Latency
+		r.DNSLatency = time.Duration(e.dnsRecord.Latency)
+	}
+	if r.Metrics.FlowRtt == 0 && *e.flowRTT != 0 {
+		r.Metrics.FlowRtt = *e.flowRTT
+		r.TimeFlowRtt = time.Duration(*e.flowRTT)
+	}
+	if !hasQdisc(&r.Metrics.Qdisc) && hasQdisc(e.qdisc) {
+		r.Metrics.Qdisc = *e.qdisc
+	}
+	e.dnsRecord = &r.Metrics.DnsRecord
+	e.flowRTT = &r.Metrics.FlowRtt
+	e.qdisc = &r.Metrics.Qdisc
+	e.ifIndex = r.Id.IfIndex
+	e.netns = r.Id.Netns
+	e.rank = c.rank(r)
+	e.record, e.recordBatch = r, c.batch
+	c.observe(e, r, mergeDup, ifaceNamer)
+	*fwd = append(*fwd, r)
+}
+
+// observe adds the view of the flow from the interface of the record to the transit times and,
+// if mergeDup is set, to the duplicate list. Then it updates the record forwarded in the current
+// batch, if any.
+func (c *deduperCache) observe(e *entry, r *Record, mergeDup bool, ifaceNamer InterfaceNamer) {
+	multiIface := e.transit.observe(r, c.batch)
+	if mergeDup {
+		if e.dupBatch != c.batch {
+			e.dupList, e.dupBatch = nil, c.batch
 		}
+		e.dupList = mergeDupEntry(e.dupList, newDupEntry(r, ifaceNamer(r.Id.Netns, int(r.Id.IfIndex))))
+	}
+	if e.record == nil || e.recordBatch != c.batch {
+		return
+	}
+	if multiIface {
+		e.record.TransitLatency = e.transit.latency()
+	}
+	if mergeDup {
+		e.record.DupList = e.dupList
 	}
-	return true
 }
 
 func (c *deduperCache) removeExpired() {
@@ -153,6 +286,7 @@ func (c *deduperCache) removeExpired() {
 		c.entries.Remove(ele)
 		fEntry := ele.Value.(*entry)
 		fEntry.dupList = nil
+		fEntry.record = nil
 		delete(c.ifaces, *fEntry.key)
 		ele = c.entries.Back()
 	}
@@ -2,6 +2,7 @@ package flow
 
 import (
 	"net"
+	"strconv"
 	"testing"
 	"time"
 
@@ -71,7 +72,7 @@ func TestDedupe(t *testing.T) {
 	input := make(chan []*Record, 100)
 	output := make(chan []*Record, 100)
 
-	go Dedupe(time.Minute, false, false, interfaceNamer, metrics.NewMetrics(&metrics.Settings{}))(input, output)
+	go Dedupe(time.Minute, false, false, interfaceNamer, nil, metrics.NewMetrics(&metrics.Settings{}))(input, output)
 
 	input <- []*Record{
 		oneIf2,   // record 1 at interface 2: should be accepted
@@ -109,7 +110,7 @@ func TestDedupe_EvictFlows(t *testing.T) {
 	input := make(chan []*Record, 100)
 	output := make(chan []*Record, 100)
 
-	go Dedupe(15*time.Second, false, false, interfaceNamer, metrics.NewMetrics(&metrics.Settings{}))(input, output)
+	go Dedupe(15*time.Second, false, false, interfaceNamer, nil, metrics.NewMetrics(&metrics.Settings{}))(input, output)
 
 	// Should only accept records 1 and 2, at interface 1
 	input <- []*Record{oneIf1, twoIf1, oneIf2}
@@ -144,28 +145,140 @@ func TestDedupeMerge(t *testing.T) {
 	input := make(chan []*Record, 100)
 	output := make(chan []*Record, 100)
 
-	go Dedupe(time.Minute, false, true, interfaceNamer, metrics.NewMetrics(&metrics.Settings{}))(input, output)
+	namer := func(_ uint32, ifIndex int) string { return "if" + strconv.Itoa(ifIndex) }
+	go Dedupe(time.Minute, false, true, namer, nil, metrics.NewMetrics(&metrics.Settings{}))(input, output)
 
+	now := time.Now()
+	if2, if1 := dedupeTestRecord(2, now), dedupeTestRecord(1, now)
+	if1.Id.Direction = 1
+	if1.Metrics.Packets, if1.Metrics.Bytes = 1, 200
+	if1.Metrics.PktDrops = ebpf.BpfPktDropsT{Packets: 1, Bytes: 256}
 	input <- []*Record{
-		oneIf2, // record 1 at interface 2: should be accepted
-		oneIf1,
-	}
-	deduped := receiveTimeout(t, output)
-	assert.Equal(t, []*Record{oneIf2}, deduped)
-	assert.Equal(t, 2, len(oneIf2.DupList))
-
-	expectedMap := []map[string]uint8{
-		{
-			interfaceNamer(int(oneIf2.Id.IfIndex)): oneIf2.Id.Direction,
-		},
-		{
-			interfaceNamer(int(oneIf1.Id.IfIndex)): oneIf1.Id.Direction,
-		},
+		if2, // the flow at interface 2: should be accepted
+		if1,
 	}
+	assert.Equal(t, []*Record{if2}, receiveTimeout(t, output))
+	assert.Equal(t, []DupEntry{
+		{Interface: "if2", Direction: 0, Bytes: 456, Packets: 2},
+		{Interface: "if1", Direction: 1, Bytes: 200, Packets: 1, PktDropBytes: 256, PktDropPackets: 1},
+	}, if2.DupList)
+
+	// the next batch gets its own list, and the already forwarded record is not modified
+	next := dedupeTestRecord(2, now)
+	input <- []*Record{next}
+	assert.Equal(t, []*Record{next}, receiveTimeout(t, output))
+	assert.Equal(t, []DupEntry{{Interface: "if2", Direction: 0, Bytes: 456, Packets: 2}}, next.DupList)
+	assert.Len(t, if2.DupList, 2)
+}
+
+func TestDedupe_SameIndexInOtherNetNS(t *testing.T) {
+	input := make(chan []*Record, 100)
+	output := make(chan []*Record, 100)
+
+	go Dedupe(time.Minute, false, true, interfaceNamer, nil, metrics.NewMetrics(&metrics.Settings{}))(input, output)
 
-	for k, v := range oneIf2.DupList {
-		assert.Equal(t, expectedMap[k], v)
+	// the same flow observed from the eth0 interface of the host and of a container
+	host := &Record{RawRecord: RawRecord{Id: ebpf.BpfFlowId{
+		EthProtocol: 1, SrcPort: 633, DstPort: 456, IfIndex: 2, Netns: 4026531840,
+	}}}
+	container := &Record{RawRecord: RawRecord{Id: ebpf.BpfFlowId{
+		EthProtocol: 1, SrcPort: 633, DstPort: 456, IfIndex: 2, Netns: 4026532500,
+	}}}
+	input <- []*Record{host, container, host}
+	assert.Equal(t, []*Record{host, host}, receiveTimeout(t, output))
+}
+
+func dedupeTestRecord(ifIndex uint32, start time.Time) *Record {
+	return &Record{RawRecord: RawRecord{Id: ebpf.BpfFlowId{
+		EthProtocol: 1, SrcPort: 733, DstPort: 456, IfIndex: ifIndex,
+	}, Metrics: ebpf.BpfFlowMetrics{
+		Packets: 2, Bytes: 456,
+	}}, TimeFlowStart: start, TimeFlowEnd: start.Add(time.Second)}
+}
+
+func TestDedupe_Preference(t *testing.T) {
+	// the interface 1 is preferred to the others
+	ranker := func(_ uint32, ifIndex int) int {
+		if ifIndex == 1 {
+			return 0
+		}
+		return 1
 	}
+	input := make(chan []*Record, 100)
+	output := make(chan []*Record, 100)
+	namer := func(_ uint32, ifIndex int) string { return "if" + strconv.Itoa(ifIndex) }
+	go Dedupe(time.Minute, false, true, namer, ranker, metrics.NewMetrics(&metrics.Settings{}))(input, output)
+
+	now := time.Now()
+	other, preferred, another := dedupeTestRecord(2, now), dedupeTestRecord(1, now), dedupeTestRecord(3, now)
+	other.Metrics.FlowRtt = 100
+	other.TimeFlowRtt = 100
+	input <- []*Record{other, preferred, another}
+	// the preferred interface replaces the interface that reported the flow first
+	assert.Equal(t, []*Record{preferred}, receiveTimeout(t, output))
+	assert.EqualValues(t, 100, preferred.TimeFlowRtt, "the enrichment of the replaced interface is kept")
+	assert.Equal(t, []DupEntry{
+		{Interface: "if2", Bytes: 456, Packets: 2},
+		{Interface: "if1", Bytes: 456, Packets: 2},
+		{Interface: "if3", Bytes: 456, Packets: 2},
+	}, preferred.DupList)
+
+	// the preferred interface keeps reporting the flow in the next batches
+	other, preferred = dedupeTestRecord(2, now), dedupeTestRecord(1, now)
+	input <- []*Record{other, preferred}
+	assert.Equal(t, []*Record{preferred}, receiveTimeout(t, output))
+}
+
+func TestDedupe_PreferenceJustMark(t *testing.T) {
+	ranker := func(_ uint32, ifIndex int) int { return ifIndex }
+	input := make(chan []*Record, 100)
+	output := make(chan []*Record, 100)
+	go Dedupe(time.Minute, true, false, interfaceNamer, ranker, metrics.NewMetrics(&metrics.Settings{}))(input, output)
+
+	now := time.Now()
+	third, first, second := dedupeTestRecord(3, now), dedupeTestRecord(1, now), dedupeTestRecord(2, now)
+	input <- []*Record{third, first, second}
+	assert.Equal(t, []*Record{third, first, second}, receiveTimeout(t, output))
+	assert.True(t, third.Duplicate)
+	assert.False(t, first.Duplicate)
+	assert.True(t, second.Duplicate)
+}
+
+func TestDedupe_Qdisc(t *testing.T) {
+	input := make(chan []*Record, 100)
+	output := make(chan []*Record, 100)
+	go Dedupe(time.Minute, false, false, interfaceNamer, nil, metrics.NewMetrics(&metrics.Settings{}))(input, output)
+
+	// the qdisc is only seen by the egress interface, which is not the reported one
+	now := time.Now()
+	veth, egress := dedupeTestRecord(1, now), dedupeTestRecord(2, now)
+	egress.Metrics.Qdisc = ebpf.BpfQdiscT{Time: 3000, Packets: 2, LatestKind: [16]uint8{'h', 't', 'b'}}
+	input <- []*Record{veth, egress}
+	assert.Equal(t, []*Record{veth}, receiveTimeout(t, output))
+	assert.Equal(t, egress.Metrics.Qdisc, veth.Metrics.Qdisc)
+	assert.Equal(t, 1500*time.Nanosecond, veth.QdiscLatency())
+}
+
+func TestDedupe_TransitLatency(t *testing.T) {
+	input := make(chan []*Record, 100)
+	output := make(chan []*Record, 100)
+	go Dedupe(time.Minute, false, false, interfaceNamer, nil, metrics.NewMetrics(&metrics.Settings{}))(input, output)
+
+	now := time.Now()
+	// the flow crosses the interfaces 1, 2 and 3, in that order
+	if1, if2, if3 := dedupeTestRecord(1, now), dedupeTestRecord(2, now.Add(20*time.Microsecond)),
+		dedupeTestRecord(3, now.Add(50*time.Microsecond))
+	// the last packet took longer
+	if3.TimeFlowEnd = if3.TimeFlowEnd.Add(30 * time.Microsecond)
+	input <- []*Record{if2, if1, if3}
+	assert.Equal(t, []*Record{if2}, receiveTimeout(t, output))
+	assert.Equal(t, 80*time.Microsecond, if2.TransitLatency)
+
+	// a flow observed from a single interface has no transit latency
+	single := dedupeTestRecord(2, now)
+	input <- []*Record{single}
+	assert.Equal(t, []*Record{single}, receiveTimeout(t, output))
+	assert.Zero(t, single.TransitLatency)
 }
 
 type timerMock struct {
@@ -176,7 +289,7 @@ func (tm *timerMock) Now() time.Time {
 	return tm.now
 }
 
-func interfaceNamer(ifIndex int) string {
+func interfaceNamer(_ uint32, ifIndex int) string {
 	iface, err := net.InterfaceByIndex(ifIndex)
 	if err != nil {
 		return "unknown"
@@ -16,6 +16,10 @@ type RawByte byte
 type PacketRecord struct {
 	Stream []byte
 	Time   time.Time
+	// IfIndex and Netns identify the interface where the packet was captured, by its index and
+	// the inode number of its network namespace
+	IfIndex uint32
+	Netns   uint32
 }
 
 // NewPacketRecord contains packet bytes
@@ -38,13 +42,15 @@ func ReadRawPacket(reader io.Reader) (*PacketRecord, error) {
 	monotonicTimeNow := monotime.Now()
 	getLen := make([]byte, 4)
 	packetTimestamp := make([]byte, 8)
-	// Read IfIndex and discard it: To be used in other usecases
-	_ = binary.Read(reader, binary.LittleEndian, make([]byte, 4))
+	// Read IfIndex
+	_ = binary.Read(reader, binary.LittleEndian, &pr.IfIndex)
 	// Read Length of packet
 	_ = binary.Read(reader, binary.LittleEndian, getLen)
 	pr.Stream = make([]byte, binary.LittleEndian.Uint32(getLen))
 	// Read TimeStamp of packet
 	_ = binary.Read(reader, binary.LittleEndian, packetTimestamp)
+	// Read network namespace of the interface
+	_ = binary.Read(reader, binary.LittleEndian, &pr.Netns)
 	// The assumption is monotonic time should be as close to time recorded by ebpf.
 	// The difference is considered the delta time from current time.
 	tsDelta := time.Duration(uint64(monotonicTimeNow) - binary.LittleEndian.Uint64(packetTimestamp))
@@ -1,6 +1,7 @@
 package flow
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -17,6 +18,21 @@ const (
 )
 const MacLen = 6
 
+// Values according to field 239 (biflowDirection) in https://www.iana.org/assignments/ipfix/ipfix.xhtml
+const (
+	// BiflowArbitrary tells that the initiator of the biflow is unknown
+	BiflowArbitrary = uint8(0)
+	// BiflowInitiator tells that the source of the biflow is its initiator
+	BiflowInitiator = uint8(1)
+)
+
+// TCP flags, as set by the eBPF agent. The agent-specific SYN_ACK flag tells that both SYN and ACK
+// are set, so the SYN flag alone is only set by the initiator of a connection.
+const (
+	TCPFlagSYN    = uint16(0x02)
+	TCPFlagSYNACK = uint16(0x100)
+)
+
 // IPv4Type / IPv6Type value as defined in IEEE 802: https://www.iana.org/assignments/ieee-802-numbers/ieee-802-numbers.xhtml
 const IPv6Type = 0x86DD
 
@@ -41,6 +57,12 @@ type Record struct {
 	TimeFlowEnd   time.Time
 	DNSLatency    time.Duration
 	Interface     string
+	// NetNSName is the name of the network namespace of the interface (see Id.Netns for its
+	// inode), if it is named in /var/run/netns
+	NetNSName string `json:",omitempty"`
+	// InterfaceLink holds the link attributes of the interface, if the interface metadata is
+	// enabled and the attributes are known
+	InterfaceLink *InterfaceLink `json:",omitempty"`
 	// Duplicate tells whether this flow has another duplicate so it has to be excluded from
 	// any metrics' aggregation (e.g. bytes/second rates between two pods).
 	// The reason for this field is that the same flow can be observed from multiple interfaces,
@@ -53,7 +75,85 @@ type Record struct {
 	AgentIP net.IP
 	// Calculated RTT which is set when record is created by calling NewRecord
 	TimeFlowRtt time.Duration
-	DupList     []map[string]uint8
+	// TransitLatency is the time that the packets of the flow took to cross the node, between the
+	// first and the last interfaces that observed it. It is set by the deduper.
+	TransitLatency time.Duration `json:",omitempty"`
+	// DropCause is the name of LatestDropCause in the kernel of the agent. It is set by the decorator.
+	DropCause string `json:",omitempty"`
+	// DropLocation is the kernel function that dropped the latest packet of the flow, with the
+	// offset of the drop in the function (e.g. tcp_v4_rcv+0x8a). It is set by the decorator.
+	DropLocation string `json:",omitempty"`
+	// DropStack holds the kernel functions of the stack of the latest drop, from the innermost,
+	// when the drop stacks are enabled. It is set by the decorator.
+	DropStack []string `json:",omitempty"`
+	// DupList holds the views of the flow from each interface that observed it, when the deduper
+	// merges the duplicate flows
+	DupList []DupEntry `json:",omitempty"`
+	// Reverse holds the counters of the responder to initiator direction, when the flow has been
+	// stitched with its reverse flow (RFC 5103 bidirectional flow). The rest of the record
+	// describes the initiator to responder direction. It is nil for unidirectional flows.
+	Reverse *ReverseMetrics `json:",omitempty"`
+}
+
+// ReverseMetrics are the counters of the reverse direction of a bidirectional flow
+type ReverseMetrics struct {
+	Bytes          uint64
+	Packets        uint32
+	Flags          uint16
+	PktDropBytes   uint64
+	PktDropPackets uint32
+	// BiflowDirection is BiflowInitiator if the initiator could be inferred from the TCP flags,
+	// or BiflowArbitrary otherwise
+	BiflowDirection uint8
+}
+
+// DupEntry is the view of a flow from one of the interfaces that observed it, with the
+// counters of the flow in that interface and direction
+type DupEntry struct {
+	Interface      string
+	Direction      uint8
+	Bytes          uint64
+	Packets        uint32
+	PktDropBytes   uint64
+	PktDropPackets uint32
+}
+
+func newDupEntry(r *Record, ifName string) DupEntry {
+	return DupEntry{
+		Interface:      ifName,
+		Direction:      r.Id.Direction,
+		Bytes:          r.Metrics.Bytes,
+		Packets:        r.Metrics.Packets,
+		PktDropBytes:   r.Metrics.PktDrops.Bytes,
+		PktDropPackets: r.Metrics.PktDrops.Packets,
+	}
+}
+
+// mergeDupEntry adds the counters of a duplicate entry to the entry of the list with the same
+// interface and direction, or appends it to the list if there is none
+func mergeDupEntry(dupList []DupEntry, dup DupEntry) []DupEntry {
+	for i := range dupList {
+		if dupList[i].Interface == dup.Interface && dupList[i].Direction == dup.Direction {
+			dupList[i].Bytes += dup.Bytes
+			dupList[i].Packets += dup.Packets
+			dupList[i].PktDropBytes += dup.PktDropBytes
+			dupList[i].PktDropPackets += dup.PktDropPackets
+			return dupList
+		}
+	}
+	return append(dupList, dup)
+}
+
+// InterfaceLink holds the link attributes of the interface of a flow
+type InterfaceLink struct {
+	// Type is the netlink link type (e.g. device, veth, bridge, bond, vxlan, geneve)
+	Type string
+	MAC  MacAddr
+	MTU  uint32
+	// Master is the name of the master device (e.g. a bridge or a bond), if any
+	Master string `json:",omitempty"`
+	// PeerIfIndex is the index of the peer of a veth interface, in the namespace of the peer
+	PeerIfIndex uint32 `json:",omitempty"`
 }
 
 func NewRecord(
@@ -79,7 +179,6 @@ func NewRecord(
 	if metrics.DnsRecord.Latency != 0 {
 		record.DNSLatency = time.Duration(metrics.DnsRecord.Latency)
 	}
-	record.DupList = make([]map[string]uint8, 0)
 	return &record
 }
 
@@ -101,6 +200,12 @@ func Accumulate(r *ebpf.BpfFlowMetrics, src *ebpf.BpfFlowMetrics) {
 	if src.PktDrops.LatestDropCause != 0 {
 		r.PktDrops.LatestDropCause = src.PktDrops.LatestDropCause
 	}
+	if src.PktDrops.LatestDropLocation != 0 {
+		r.PktDrops.LatestDropLocation = src.PktDrops.LatestDropLocation
+	}
+	if src.PktDrops.LatestDropStackId != 0 {
+		r.PktDrops.LatestDropStackId = src.PktDrops.LatestDropStackId
+	}
 	// Accumulate DNS
 	r.DnsRecord.Flags |= src.DnsRecord.Flags
 	if src.DnsRecord.Id != 0 {
@@ -113,6 +218,14 @@ func Accumulate(r *ebpf.BpfFlowMetrics, src *ebpf.BpfFlowMetrics) {
 	if r.FlowRtt < src.FlowRtt {
 		r.FlowRtt = src.FlowRtt
 	}
+	// Accumulate qdisc statistics
+	r.Qdisc.Time += src.Qdisc.Time
+	r.Qdisc.Packets += src.Qdisc.Packets
+	r.Qdisc.DropPackets += src.Qdisc.DropPackets
+	r.Qdisc.DropBytes += src.Qdisc.DropBytes
+	if src.Qdisc.LatestKind[0] != 0 {
+		r.Qdisc.LatestKind = src.Qdisc.LatestKind
+	}
 	// Accumulate DSCP
 	if src.Dscp != 0 {
 		r.Dscp = src.Dscp
@@ -123,6 +236,80 @@ func Accumulate(r *ebpf.BpfFlowMetrics, src *ebpf.BpfFlowMetrics) {
 	}
 }
 
+// QdiscLatency returns the mean time that the dequeued packets of the flow waited in the egress
+// qdisc, or zero if it is unknown
+func (r *Record) QdiscLatency() time.Duration {
+	if r.Metrics.Qdisc.Packets == 0 {
+		return 0
+	}
+	return time.Duration(r.Metrics.Qdisc.Time / uint64(r.Metrics.Qdisc.Packets))
+}
+
+// QdiscKind returns the kind of the root egress qdisc of the flow (e.g. fq_codel or htb), or an
+// empty string if it is unknown
+func (r *Record) QdiscKind() string {
+	kind := r.Metrics.Qdisc.LatestKind[:]
+	if end := bytes.IndexByte(kind, 0); end >= 0 {
+		kind = kind[:end]
+	}
+	return string(kind)
+}
+
+// accumulateRecord merges the metrics of the src flow into the r flow, including the fields that
+// are computed in userspace
+func accumulateRecord(r *Record, src *Record) {
+	Accumulate(&r.Metrics, &src.Metrics)
+	if src.TimeFlowStart.Before(r.TimeFlowStart) {
+		r.TimeFlowStart = src.TimeFlowStart
+	}
+	if src.TimeFlowEnd.After(r.TimeFlowEnd) {
+		r.TimeFlowEnd = src.TimeFlowEnd
+	}
+	if src.DNSLatency > r.DNSLatency {
+		r.DNSLatency = src.DNSLatency
+	}
+	if src.TimeFlowRtt > r.TimeFlowRtt {
+		r.TimeFlowRtt = src.TimeFlowRtt
+	}
+	if src.TransitLatency > r.TransitLatency {
+		r.TransitLatency = src.TransitLatency
+	}
+	if src.DropCause != "" {
+		r.DropCause = src.DropCause
+	}
+	if src.DropLocation != "" {
+		r.DropLocation = src.DropLocation
+		r.DropStack = src.DropStack
+	}
+	for _, dup := range src.DupList {
+		r.DupList = mergeDupEntry(r.DupList, dup)
+	}
+	if src.Reverse != nil {
+		if r.Reverse == nil {
+			r.Reverse = &ReverseMetrics{}
+		}
+		r.Reverse.Bytes += src.Reverse.Bytes
+		r.Reverse.Packets += src.Reverse.Packets
+		r.Reverse.Flags |= src.Reverse.Flags
+		r.Reverse.PktDropBytes += src.Reverse.PktDropBytes
+		r.Reverse.PktDropPackets += src.Reverse.PktDropPackets
+		r.Reverse.BiflowDirection = max(r.Reverse.BiflowDirection, src.Reverse.BiflowDirection)
+	}
+}
+
+// copyRecord returns a copy of the record that can be accumulated without modifying the original
+func copyRecord(r *Record) *Record {
+	cp := *r
+	if r.DupList != nil {
+		cp.DupList = append([]DupEntry{}, r.DupList...)
+	}
+	if r.Reverse != nil {
+		reverse := *r.Reverse
+		cp.Reverse = &reverse
+	}
+	return &cp
+}
+
 // IP returns the net.IP equivalent object
 func IP(ia IPAddr) net.IP {
 	return ia[:]
@@ -3,6 +3,7 @@ package flow
 import (
 	"bytes"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -26,6 +27,7 @@ func TestRecordBinaryEncoding(t *testing.T) {
 		0x00,                   // icmp: u8 icmp_type
 		0x00,                   // icmp: u8 icmp_code
 		0x13, 0x14, 0x15, 0x16, // interface index
+		0x01, 0x02, 0x03, 0x04, // u32 netns
 		0x06, 0x07, 0x08, 0x09, // u32 packets
 		0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, // u64 bytes
 		0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, // u64 flow_start_time
@@ -39,6 +41,8 @@ func TestRecordBinaryEncoding(t *testing.T) {
 		0x1c, 0x1d, //flags
 		0x1e,          // state
 		0x11, 0, 0, 0, //case
+		0x20, 0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27, // u64 location
+		0x28, 0x29, 0x2a, 0x2b, // u32 stack id
 		// dns_record structure
 		01, 00, // id
 		0x80, 00, // flags
@@ -46,6 +50,12 @@ func TestRecordBinaryEncoding(t *testing.T) {
 		0x00, // errno
 		// u64 flow_rtt
 		0xad, 0xde, 0xef, 0xbe, 0xef, 0xbe, 0xad, 0xde,
+		// qdisc structure
+		0x30, 0x31, 0x32, 0x33, 0x34, 0x35, 0x36, 0x37, // u64 time
+		0x38, 0x39, 0x3a, 0x3b, // u32 packets
+		0x3c, 0x3d, 0x3e, 0x3f, // u32 drop_packets
+		0x40, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46, 0x47, // u64 drop_bytes
+		'f', 'q', '_', 'c', 'o', 'd', 'e', 'l', 0, 0, 0, 0, 0, 0, 0, 0, // u8[16] latest_kind
 	}))
 	require.NoError(t, err)
 
@@ -63,6 +73,7 @@ func TestRecordBinaryEncoding(t *testing.T) {
 			IcmpType:          0x00,
 			IcmpCode:          0x00,
 			IfIndex:           0x16151413,
+			Netns:             0x04030201,
 		},
 		Metrics: ebpf.BpfFlowMetrics{
 			Packets:         0x09080706,
@@ -73,11 +84,13 @@ func TestRecordBinaryEncoding(t *testing.T) {
 			Errno:           0x33,
 			Dscp:            0x60,
 			PktDrops: ebpf.BpfPktDropsT{
-				Packets:         0x13121110,
-				Bytes:           0x1b1a191817161514,
-				LatestFlags:     0x1d1c,
-				LatestState:     0x1e,
-				LatestDropCause: 0x11,
+				Packets:            0x13121110,
+				Bytes:              0x1b1a191817161514,
+				LatestFlags:        0x1d1c,
+				LatestState:        0x1e,
+				LatestDropCause:    0x11,
+				LatestDropLocation: 0x2726252423222120,
+				LatestDropStackId:  0x2b2a2928,
 			},
 			DnsRecord: ebpf.BpfDnsRecordT{
 				Id:      0x0001,
@@ -86,9 +99,38 @@ func TestRecordBinaryEncoding(t *testing.T) {
 				Errno:   0,
 			},
 			FlowRtt: 0xdeadbeefbeefdead,
+			Qdisc: ebpf.BpfQdiscT{
+				Time:        0x3736353433323130,
+				Packets:     0x3b3a3938,
+				DropPackets: 0x3f3e3d3c,
+				DropBytes:   0x4746454443424140,
+				LatestKind:  [16]uint8{'f', 'q', '_', 'c', 'o', 'd', 'e', 'l'},
+			},
 		},
 	}, *fr)
 	// assert that IP addresses are interpreted as IPv4 addresses
 	assert.Equal(t, "6.7.8.9", IP(fr.Id.SrcIp).String())
 	assert.Equal(t, "10.11.12.13", IP(fr.Id.DstIp).String())
+	assert.Equal(t, "fq_codel", (&Record{RawRecord: *fr}).QdiscKind())
+}
+
+func TestAccumulateQdisc(t *testing.T) {
+	r := Record{}
+	r.Metrics.Qdisc = ebpf.BpfQdiscT{Time: 3000, Packets: 2, LatestKind: [16]uint8{'h', 't', 'b'}}
+	src := Record{}
+	src.Metrics.Qdisc = ebpf.BpfQdiscT{Time: 5000, Packets: 2, DropPackets: 1, DropBytes: 1500}
+	accumulateRecord(&r, &src)
+
+	assert.Equal(t, ebpf.BpfQdiscT{
+		Time:        8000,
+		Packets:     4,
+		DropPackets: 1,
+		DropBytes:   1500,
+		LatestKind:  [16]uint8{'h', 't', 'b'},
+	}, r.Metrics.Qdisc)
+	assert.Equal(t, 2*time.Microsecond, r.QdiscLatency())
+	assert.Equal(t, "htb", r.QdiscKind())
+	// the flows without queued packets have no qdisc latency nor kind
+	assert.Zero(t, (&Record{}).QdiscLatency())
+	assert.Empty(t, src.QdiscKind())
 }
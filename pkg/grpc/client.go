@@ -0,0 +1,83 @@
+// Package grpcclient provides the connection setup shared by the gRPC flows and packets clients
+package grpcclient
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+// defaultMinConnectTimeout is the gRPC default, which must be set explicitly when overriding the
+// connection backoff
+const defaultMinConnectTimeout = 20 * time.Second
+
+type options struct {
+	tlsConfig        *tls.Config
+	keepaliveTime    time.Duration
+	keepaliveTimeout time.Duration
+	maxConnectDelay  time.Duration
+}
+
+// Option allows overriding the default configuration of the client connection.
+// Use them in the Dial function.
+type Option func(options *options)
+
+// WithTLSConfig enables TLS in the client connection. If the TLS configuration provides client
+// certificates, they are used for mutual TLS authentication.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(opts *options) {
+		opts.tlsConfig = tlsConfig
+	}
+}
+
+// WithKeepalive sends keepalive pings after the given time without activity in the connection, and
+// closes it if the ping is not acknowledged after the given timeout. Collectors might close the
+// connections that send pings more frequently than they allow.
+func WithKeepalive(keepaliveTime, keepaliveTimeout time.Duration) Option {
+	return func(opts *options) {
+		opts.keepaliveTime = keepaliveTime
+		opts.keepaliveTimeout = keepaliveTimeout
+	}
+}
+
+// WithMaxConnectDelay limits the exponential backoff between reconnection attempts to a broken
+// collector.
+func WithMaxConnectDelay(maxDelay time.Duration) Option {
+	return func(opts *options) {
+		opts.maxConnectDelay = maxDelay
+	}
+}
+
+// Dial creates a client connection to a collector. The connection is established lazily.
+func Dial(hostIP string, hostPort int, opts ...Option) (*grpc.ClientConn, error) {
+	copts := options{}
+	for _, opt := range opts {
+		opt(&copts)
+	}
+	creds := insecure.NewCredentials()
+	if copts.tlsConfig != nil {
+		creds = credentials.NewTLS(copts.tlsConfig)
+	}
+	dialOptions := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if copts.keepaliveTime > 0 {
+		dialOptions = append(dialOptions, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    copts.keepaliveTime,
+			Timeout: copts.keepaliveTimeout,
+		}))
+	}
+	if copts.maxConnectDelay > 0 {
+		connectBackoff := backoff.DefaultConfig
+		connectBackoff.MaxDelay = copts.maxConnectDelay
+		dialOptions = append(dialOptions, grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           connectBackoff,
+			MinConnectTimeout: defaultMinConnectTimeout,
+		}))
+	}
+	return grpc.NewClient(utils.GetSocket(hostIP, hostPort), dialOptions...)
+}
@@ -2,10 +2,9 @@
 package flowgrpc
 
 import (
+	grpcclient "github.com/netobserv/netobserv-ebpf-agent/pkg/grpc"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/pbflow"
-	"github.com/netobserv/netobserv-ebpf-agent/pkg/utils"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // ClientConnection wraps a gRPC+protobuf connection
@@ -14,11 +13,19 @@ type ClientConnection struct {
 	conn   *grpc.ClientConn
 }
 
-func ConnectClient(hostIP string, hostPort int) (*ClientConnection, error) {
-	// TODO: allow configuring some options (keepalive, backoff...)
-	socket := utils.GetSocket(hostIP, hostPort)
-	conn, err := grpc.NewClient(socket,
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
+// ClientOption allows overriding the default configuration of the ClientConnection instance.
+// Use them in the ConnectClient function.
+type ClientOption = grpcclient.Option
+
+// The client options are shared by the gRPC clients of the agent
+var (
+	WithClientTLSConfig = grpcclient.WithTLSConfig
+	WithKeepalive       = grpcclient.WithKeepalive
+	WithMaxConnectDelay = grpcclient.WithMaxConnectDelay
+)
+
+func ConnectClient(hostIP string, hostPort int, options ...ClientOption) (*ClientConnection, error) {
+	conn, err := grpcclient.Dial(hostIP, hostPort, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/mariomac/guara/pkg/test"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/pbflow"
+	test2 "github.com/netobserv/netobserv-ebpf-agent/pkg/test"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
@@ -126,6 +128,51 @@ func TestConstructorOptions(t *testing.T) {
 	}
 }
 
+func TestMutualTLSCommunication(t *testing.T) {
+	certs := test2.GenerateCerts(t, t.TempDir())
+	serverTLS, err := utils.NewCertReloader(certs.CACert, certs.ServerCert, certs.ServerKey)
+	require.NoError(t, err)
+	port, err := test.FreeTCPPort()
+	require.NoError(t, err)
+	serverOut := make(chan *pbflow.Records)
+	collector, err := StartCollector(port, serverOut, WithServerTLSConfig(serverTLS.ServerConfig()))
+	require.NoError(t, err)
+	defer collector.Close()
+
+	// client authenticating with a certificate signed by the server's trusted CA
+	clientTLS, err := utils.NewCertReloader(certs.CACert, certs.ClientCert, certs.ClientKey)
+	require.NoError(t, err)
+	cc, err := ConnectClient("127.0.0.1", port, WithClientTLSConfig(clientTLS.ClientConfig("127.0.0.1", false)))
+	require.NoError(t, err)
+	defer cc.Close()
+	go func() {
+		_, err := cc.Client().Send(context.Background(),
+			&pbflow.Records{Entries: []*pbflow.Record{{EthProtocol: 123, Bytes: 456}}})
+		assert.NoError(t, err)
+	}()
+	rs := test2.ReceiveTimeout(t, serverOut, timeout)
+	require.Len(t, rs.Entries, 1)
+	assert.EqualValues(t, 456, rs.Entries[0].Bytes)
+
+	// client without certificate must be rejected
+	noCertTLS, err := utils.NewCertReloader(certs.CACert, "", "")
+	require.NoError(t, err)
+	cc2, err := ConnectClient("127.0.0.1", port, WithClientTLSConfig(noCertTLS.ClientConfig("127.0.0.1", false)))
+	require.NoError(t, err)
+	defer cc2.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	_, err = cc2.Client().Send(ctx, &pbflow.Records{})
+	require.Error(t, err)
+
+	// plain-text client must be rejected
+	cc3, err := ConnectClient("127.0.0.1", port)
+	require.NoError(t, err)
+	defer cc3.Close()
+	_, err = cc3.Client().Send(ctx, &pbflow.Records{})
+	require.Error(t, err)
+}
+
 func BenchmarkIPv4GRPCCommunication(b *testing.B) {
 	port, err := test.FreeTCPPort()
 	require.NoError(b, err)
@@ -142,7 +189,7 @@ func BenchmarkIPv4GRPCCommunication(b *testing.B) {
 		EthProtocol: 2048,
 		Bytes:       456,
 		Flags:       1,
-		DupList: []*pbflow.DupMapEntry{
+		DupList: []*pbflow.DupEntry{
 			{
 				Interface: "eth0",
 				Direction: pbflow.Direction_EGRESS,
@@ -203,7 +250,7 @@ func BenchmarkIPv6GRPCCommunication(b *testing.B) {
 		EthProtocol: 2048,
 		Bytes:       456,
 		Flags:       1,
-		DupList: []*pbflow.DupMapEntry{
+		DupList: []*pbflow.DupEntry{
 			{
 				Interface: "eth0",
 				Direction: pbflow.Direction_EGRESS,
@@ -0,0 +1,293 @@
+package flowgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/pbflow"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/utils"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+var plog = logrus.WithField("component", "flowgrpc.ClientPool")
+
+// PoolConfig configures a ClientPool
+type PoolConfig struct {
+	// Targets of the collectors, in host:port format. Host names are resolved, and each resolved
+	// address is used as a separate endpoint.
+	Targets []string
+	// ResolveInterval is the period to resolve again the host names of the targets. Zero disables
+	// the periodic resolution.
+	ResolveInterval time.Duration
+	// HealthCheckInterval is the period to check the health of the endpoints. Zero disables
+	// the health checks, so the endpoints marked as unhealthy are never used again.
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout is the maximum time to wait for a health check response
+	HealthCheckTimeout time.Duration
+	// ClientOptions returns the options of the client connection for a given target host. It can
+	// be nil.
+	ClientOptions func(host string) []ClientOption
+}
+
+// Endpoint is a client connection to one of the resolved addresses of a ClientPool target
+type Endpoint struct {
+	// Address of the endpoint, in ip:port format
+	Address string
+	// Host of the target the endpoint has been resolved from
+	Host    string
+	conn    *ClientConnection
+	healthy atomic.Bool
+}
+
+func (e *Endpoint) Client() pbflow.CollectorClient {
+	return e.conn.Client()
+}
+
+func (e *Endpoint) Healthy() bool {
+	return e.healthy.Load()
+}
+
+// ClientPool keeps a client connection to each address that the configured targets resolve to,
+// and periodically checks their health. Endpoints that fail their health check, or that are
+// explicitly marked as unhealthy, are excluded from the Healthy list until they pass a health
+// check again.
+// A collector is considered healthy if it reports a SERVING status through the standard gRPC
+// health service, or if it is reachable but does not implement that service.
+type ClientPool struct {
+	cfg     PoolConfig
+	lookup  func(host string) ([]string, error)
+	connect func(hostIP string, hostPort int, options ...ClientOption) (*ClientConnection, error)
+
+	mutex sync.RWMutex
+	// endpoints, sorted by address
+	endpoints []*Endpoint
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewClientPool connects to all the addresses of the configured targets, and starts in
+// background the periodic resolution and health checks.
+func NewClientPool(cfg PoolConfig) (*ClientPool, error) {
+	return newClientPool(cfg, net.LookupHost)
+}
+
+func newClientPool(cfg PoolConfig, lookup func(host string) ([]string, error)) (*ClientPool, error) {
+	if len(cfg.Targets) == 0 {
+		return nil, errors.New("no collector targets provided")
+	}
+	for _, target := range cfg.Targets {
+		if _, _, err := splitTarget(target); err != nil {
+			return nil, err
+		}
+	}
+	p := &ClientPool{cfg: cfg, lookup: lookup, connect: ConnectClient, stop: make(chan struct{})}
+	if err := p.resolve(); err != nil {
+		return nil, err
+	}
+	p.wg.Add(1)
+	go p.loop()
+	return p, nil
+}
+
+// Endpoints returns all the endpoints in the pool, sorted by address
+func (p *ClientPool) Endpoints() []*Endpoint {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return append([]*Endpoint(nil), p.endpoints...)
+}
+
+// Healthy returns the healthy endpoints in the pool, sorted by address
+func (p *ClientPool) Healthy() []*Endpoint {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	healthy := make([]*Endpoint, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		if e.Healthy() {
+			healthy = append(healthy, e)
+		}
+	}
+	return healthy
+}
+
+// MarkUnhealthy excludes the endpoint from the Healthy list until it passes the next health check
+func (p *ClientPool) MarkUnhealthy(e *Endpoint) {
+	if e.healthy.CompareAndSwap(true, false) {
+		plog.WithField("endpoint", e.Address).Warn("collector endpoint marked as unhealthy")
+	}
+}
+
+// Close stops the background tasks and closes all the client connections
+func (p *ClientPool) Close() error {
+	close(p.stop)
+	p.wg.Wait()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var errs []error
+	for _, e := range p.endpoints {
+		if err := e.conn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	p.endpoints = nil
+	return errors.Join(errs...)
+}
+
+func (p *ClientPool) loop() {
+	defer p.wg.Done()
+	var resolveTicks, healthTicks <-chan time.Time
+	if p.cfg.ResolveInterval > 0 {
+		ticker := time.NewTicker(p.cfg.ResolveInterval)
+		defer ticker.Stop()
+		resolveTicks = ticker.C
+	}
+	if p.cfg.HealthCheckInterval > 0 {
+		ticker := time.NewTicker(p.cfg.HealthCheckInterval)
+		defer ticker.Stop()
+		healthTicks = ticker.C
+	}
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-resolveTicks:
+			if err := p.resolve(); err != nil {
+				plog.WithError(err).Warn("can't resolve collector targets. Keeping the previous endpoints")
+			}
+		case <-healthTicks:
+			p.checkHealth()
+		}
+	}
+}
+
+// resolve looks up the addresses of all the targets, connecting to the new ones and closing the
+// connections to the addresses that are not resolved anymore
+func (p *ClientPool) resolve() error {
+	hosts := map[string]string{}
+	for _, target := range p.cfg.Targets {
+		host, port, _ := splitTarget(target)
+		ips := []string{host}
+		if net.ParseIP(host) == nil {
+			var err error
+			if ips, err = p.lookup(host); err != nil {
+				return fmt.Errorf("resolving %s: %w", host, err)
+			}
+		}
+		for _, ip := range ips {
+			hosts[utils.GetSocket(ip, port)] = host
+		}
+	}
+	if len(hosts) == 0 {
+		return errors.New("collector targets did not resolve to any address")
+	}
+
+	// resolve is never invoked concurrently, so the endpoints can't change until they are swapped
+	current := p.Endpoints()
+	endpoints := make([]*Endpoint, 0, len(hosts))
+	var removed []*Endpoint
+	for _, e := range current {
+		if _, ok := hosts[e.Address]; ok {
+			endpoints = append(endpoints, e)
+			delete(hosts, e.Address)
+		} else {
+			removed = append(removed, e)
+		}
+	}
+	var added []*Endpoint
+	for address, host := range hosts {
+		ip, port, _ := splitTarget(address)
+		var options []ClientOption
+		if p.cfg.ClientOptions != nil {
+			options = p.cfg.ClientOptions(host)
+		}
+		conn, err := p.connect(ip, port, options...)
+		if err != nil {
+			closeEndpoints(added)
+			return fmt.Errorf("connecting to %s: %w", address, err)
+		}
+		e := &Endpoint{Address: address, Host: host, conn: conn}
+		e.healthy.Store(true)
+		added = append(added, e)
+	}
+	endpoints = append(endpoints, added...)
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].Address < endpoints[j].Address
+	})
+
+	p.mutex.Lock()
+	p.endpoints = endpoints
+	p.mutex.Unlock()
+
+	for _, e := range added {
+		plog.WithField("endpoint", e.Address).Info("adding collector endpoint")
+	}
+	for _, e := range removed {
+		plog.WithField("endpoint", e.Address).Info("removing collector endpoint")
+	}
+	closeEndpoints(removed)
+	return nil
+}
+
+func closeEndpoints(endpoints []*Endpoint) {
+	for _, e := range endpoints {
+		if err := e.conn.Close(); err != nil {
+			plog.WithError(err).WithField("endpoint", e.Address).Debug("can't close client connection")
+		}
+	}
+}
+
+func (p *ClientPool) checkHealth() {
+	for _, e := range p.Endpoints() {
+		healthy := p.isHealthy(e)
+		if e.healthy.Swap(healthy) != healthy {
+			if healthy {
+				plog.WithField("endpoint", e.Address).Info("collector endpoint is healthy again")
+			} else {
+				plog.WithField("endpoint", e.Address).Warn("collector endpoint failed its health check")
+			}
+		}
+	}
+}
+
+func (p *ClientPool) isHealthy(e *Endpoint) bool {
+	ctx := context.Background()
+	if p.cfg.HealthCheckTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.cfg.HealthCheckTimeout)
+		defer cancel()
+	}
+	resp, err := grpc_health_v1.NewHealthClient(e.conn.conn).
+		Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		// the collector does not provide the health service, but it is reachable
+		return true
+	}
+	if err != nil {
+		plog.WithError(err).WithField("endpoint", e.Address).Debug("health check failed")
+		return false
+	}
+	return resp.Status == grpc_health_v1.HealthCheckResponse_SERVING
+}
+
+func splitTarget(target string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid collector target %q: %w", target, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port in collector target %q: %w", target, err)
+	}
+	return host, port, nil
+}
@@ -0,0 +1,138 @@
+package flowgrpc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mariomac/guara/pkg/test"
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/pbflow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestClientPool_Resolution(t *testing.T) {
+	var mt sync.Mutex
+	resolved := []string{"10.0.0.2", "10.0.0.1"}
+	lookup := func(host string) ([]string, error) {
+		mt.Lock()
+		defer mt.Unlock()
+		if host != "collector" {
+			return nil, errors.New("unknown host")
+		}
+		return resolved, nil
+	}
+	pool, err := newClientPool(PoolConfig{
+		Targets:         []string{"collector:9999", "10.0.0.3:8888", "[::1]:7777"},
+		ResolveInterval: 10 * time.Millisecond,
+	}, lookup)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	assert.Equal(t, []string{"10.0.0.1:9999", "10.0.0.2:9999", "10.0.0.3:8888", "[::1]:7777"},
+		addresses(pool.Endpoints()))
+	assert.Equal(t, "collector", pool.Endpoints()[0].Host)
+
+	// endpoints are updated on the next resolution
+	mt.Lock()
+	resolved = []string{"10.0.0.2", "10.0.0.4"}
+	mt.Unlock()
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual(
+			[]string{"10.0.0.2:9999", "10.0.0.3:8888", "10.0.0.4:9999", "[::1]:7777"},
+			addresses(pool.Endpoints()))
+	}, timeout, 10*time.Millisecond)
+}
+
+func TestClientPool_ResolutionConnectError(t *testing.T) {
+	resolved := []string{"10.0.0.1", "10.0.0.2"}
+	lookup := func(_ string) ([]string, error) {
+		return resolved, nil
+	}
+	pool, err := newClientPool(PoolConfig{Targets: []string{"collector:9999"}}, lookup)
+	require.NoError(t, err)
+	defer pool.Close()
+	previous := pool.Endpoints()
+
+	// when any of the new endpoints can't be connected, only the new connections are closed
+	// and the previous endpoints are kept
+	var connected []*ClientConnection
+	pool.connect = func(hostIP string, hostPort int, options ...ClientOption) (*ClientConnection, error) {
+		if hostIP == "10.0.0.5" {
+			return nil, errors.New("connection error")
+		}
+		conn, err := ConnectClient(hostIP, hostPort, options...)
+		if err == nil {
+			connected = append(connected, conn)
+		}
+		return conn, err
+	}
+	resolved = []string{"10.0.0.3", "10.0.0.4", "10.0.0.5"}
+	require.Error(t, pool.resolve())
+	assert.Equal(t, previous, pool.Endpoints())
+	for _, conn := range connected {
+		assert.Error(t, conn.Close(), "new connection should have been already closed")
+	}
+	for _, e := range previous {
+		assert.NotEqual(t, connectivity.Shutdown, e.conn.conn.GetState())
+	}
+
+	// on success, the removed endpoints are closed
+	resolved = []string{"10.0.0.2", "10.0.0.3"}
+	require.NoError(t, pool.resolve())
+	assert.Equal(t, []string{"10.0.0.2:9999", "10.0.0.3:9999"}, addresses(pool.Endpoints()))
+	assert.Equal(t, connectivity.Shutdown, previous[0].conn.conn.GetState())
+	assert.NotEqual(t, connectivity.Shutdown, previous[1].conn.conn.GetState())
+}
+
+func TestClientPool_WrongTargets(t *testing.T) {
+	_, err := NewClientPool(PoolConfig{})
+	require.Error(t, err)
+	_, err = NewClientPool(PoolConfig{Targets: []string{"1.2.3.4"}})
+	require.Error(t, err)
+	_, err = NewClientPool(PoolConfig{Targets: []string{"1.2.3.4:port"}})
+	require.Error(t, err)
+}
+
+func TestClientPool_HealthCheck(t *testing.T) {
+	port, err := test.FreeTCPPort()
+	require.NoError(t, err)
+	coll, err := StartCollector(port, make(chan *pbflow.Records))
+	require.NoError(t, err)
+
+	pool, err := NewClientPool(PoolConfig{
+		Targets:             []string{fmt.Sprintf("127.0.0.1:%d", port)},
+		HealthCheckInterval: 10 * time.Millisecond,
+		HealthCheckTimeout:  time.Second,
+	})
+	require.NoError(t, err)
+	defer pool.Close()
+	endpoint := pool.Endpoints()[0]
+
+	// an endpoint marked as unhealthy is back after a successful health check
+	pool.MarkUnhealthy(endpoint)
+	assert.Eventually(t, endpoint.Healthy, timeout, 10*time.Millisecond)
+	assert.Len(t, pool.Healthy(), 1)
+
+	// the endpoint fails the health check when the collector is down
+	require.NoError(t, coll.Close())
+	assert.Eventually(t, func() bool { return !endpoint.Healthy() }, timeout, 10*time.Millisecond)
+	assert.Empty(t, pool.Healthy())
+
+	// and it is healthy again when the collector is back
+	coll, err = StartCollector(port, make(chan *pbflow.Records))
+	require.NoError(t, err)
+	defer coll.Close()
+	assert.Eventually(t, endpoint.Healthy, timeout, 10*time.Millisecond)
+}
+
+func addresses(endpoints []*Endpoint) []string {
+	var addrs []string
+	for _, e := range endpoints {
+		addrs = append(addrs, e.Address)
+	}
+	return addrs
+}
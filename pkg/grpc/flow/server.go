@@ -2,10 +2,15 @@ package flowgrpc
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/pbflow"
@@ -16,8 +21,14 @@ type CollectorServer struct {
 	grpcServer *grpc.Server
 }
 
+// defaultStreamCredits is the default number of batches that a streaming client can send before
+// waiting for their acknowledgement
+const defaultStreamCredits = 8
+
 type collectorOptions struct {
 	grpcServerOptions []grpc.ServerOption
+	tlsConfig         *tls.Config
+	streamCredits     uint32
 }
 
 // CollectorOption allows overriding the default configuration of the CollectorServer instance.
@@ -30,12 +41,29 @@ func WithGRPCServerOptions(options ...grpc.ServerOption) CollectorOption {
 	}
 }
 
+// WithServerTLSConfig enables TLS in the collector server. If the TLS configuration requires client
+// certificates, only clients authenticating through mutual TLS are accepted.
+func WithServerTLSConfig(tlsConfig *tls.Config) CollectorOption {
+	return func(copt *collectorOptions) {
+		copt.tlsConfig = tlsConfig
+	}
+}
+
+// WithStreamCredits sets the number of batches that each client of the Stream RPC is allowed to
+// send before they are acknowledged. The collector stops acknowledging batches while the records
+// forwarding channel is full, so clients slow down instead of overloading the collector.
+func WithStreamCredits(credits uint32) CollectorOption {
+	return func(copt *collectorOptions) {
+		copt.streamCredits = credits
+	}
+}
+
 // StartCollector listens in background for gRPC+Protobuf flows in the given port, and forwards each
 // set of *pbflow.Records by the provided channel.
 func StartCollector(
 	port int, recordForwarder chan<- *pbflow.Records, options ...CollectorOption,
 ) (*CollectorServer, error) {
-	copts := collectorOptions{}
+	copts := collectorOptions{streamCredits: defaultStreamCredits}
 	for _, opt := range options {
 		opt(&copts)
 	}
@@ -44,10 +72,16 @@ func StartCollector(
 	if err != nil {
 		return nil, err
 	}
-	grpcServer := grpc.NewServer(copts.grpcServerOptions...)
+	serverOptions := copts.grpcServerOptions
+	if copts.tlsConfig != nil {
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(copts.tlsConfig)))
+	}
+	grpcServer := grpc.NewServer(serverOptions...)
 	pbflow.RegisterCollectorServer(grpcServer, &collectorAPI{
 		recordForwarder: recordForwarder,
+		streamCredits:   copts.streamCredits,
 	})
+	grpc_health_v1.RegisterHealthServer(grpcServer, healthAPI{})
 	reflection.Register(grpcServer)
 	go func() {
 		if err := grpcServer.Serve(lis); err != nil {
@@ -67,6 +101,7 @@ func (c *CollectorServer) Close() error {
 type collectorAPI struct {
 	pbflow.UnimplementedCollectorServer
 	recordForwarder chan<- *pbflow.Records
+	streamCredits   uint32
 }
 
 var okReply = &pbflow.CollectorReply{}
@@ -75,3 +110,38 @@ func (c *collectorAPI) Send(_ context.Context, records *pbflow.Records) (*pbflow
 	c.recordForwarder <- records
 	return okReply, nil
 }
+
+// Stream grants the initial credits to the client, and then forwards each received batch,
+// acknowledging it and returning its credit once it has been accepted by the forwarding channel.
+func (c *collectorAPI) Stream(stream pbflow.Collector_StreamServer) error {
+	if err := stream.Send(&pbflow.StreamReply{Credits: c.streamCredits}); err != nil {
+		return err
+	}
+	for {
+		batch, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case c.recordForwarder <- batch.Records:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+		if err := stream.Send(&pbflow.StreamReply{AckSequence: batch.Sequence, Credits: 1}); err != nil {
+			return err
+		}
+	}
+}
+
+// healthAPI reports the collector as serving, so clients balancing flows across several
+// collectors can detect the unresponsive ones
+type healthAPI struct {
+	grpc_health_v1.UnimplementedHealthServer
+}
+
+func (healthAPI) Check(context.Context, *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
@@ -2,12 +2,14 @@ package pktgrpc
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/pbpacket"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -18,6 +20,7 @@ type CollectorServer struct {
 
 type collectorOptions struct {
 	grpcServerOptions []grpc.ServerOption
+	tlsConfig         *tls.Config
 }
 
 // CollectorOption allows overriding the default configuration of the CollectorServer instance.
@@ -30,6 +33,14 @@ func WithGRPCServerOptions(options ...grpc.ServerOption) CollectorOption {
 	}
 }
 
+// WithServerTLSConfig enables TLS in the collector server. If the TLS configuration requires client
+// certificates, only clients authenticating through mutual TLS are accepted.
+func WithServerTLSConfig(tlsConfig *tls.Config) CollectorOption {
+	return func(copt *collectorOptions) {
+		copt.tlsConfig = tlsConfig
+	}
+}
+
 // StartCollector listens in background for gRPC+Protobuf flows in the given port, and forwards each
 // set of *pbpacket.Packet by the provided channel.
 func StartCollector(
@@ -44,7 +55,11 @@ func StartCollector(
 	if err != nil {
 		return nil, err
 	}
-	grpcServer := grpc.NewServer(copts.grpcServerOptions...)
+	serverOptions := copts.grpcServerOptions
+	if copts.tlsConfig != nil {
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(copts.tlsConfig)))
+	}
+	grpcServer := grpc.NewServer(serverOptions...)
 	pbpacket.RegisterCollectorServer(grpcServer, &collectorAPI{
 		pktForwarder: pktForwarder,
 	})
@@ -0,0 +1,62 @@
+package ifaces
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+// Link holds the attributes of a network interface, as reported by netlink when the interface
+// was discovered
+type Link struct {
+	// Type is the netlink link type (e.g. device, veth, bridge, bond, vxlan, geneve). Physical
+	// NICs have the "device" type, as well as the loopback interface.
+	Type     string
+	MAC      net.HardwareAddr
+	MTU      int
+	Loopback bool
+	// MasterIndex is the index of the master device (e.g. a bridge or a bond), or 0
+	MasterIndex int
+	// Master is the name of the master device. It is resolved by the Registerer when the link
+	// is looked up.
+	Master string
+	// PeerIndex is the index of the peer of a veth interface, in the namespace of the peer, or 0
+	PeerIndex int
+}
+
+// Physical tells whether the link is likely to be a physical NIC
+func (l *Link) Physical() bool {
+	return l.Type == "device" && !l.Loopback
+}
+
+// netLink fetches the link attributes of an interface from netlink
+func netLink(iface Interface) (*Link, error) {
+	nsh := iface.NetNS
+	if nsh == 0 {
+		nsh = netns.None()
+	}
+	handle, err := netlink.NewHandleAt(nsh)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create handle for netns (%s): %w", nsh.String(), err)
+	}
+	defer handle.Delete()
+	link, err := handle.LinkByIndex(iface.Index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link %d in netns (%s): %w", iface.Index, nsh.String(), err)
+	}
+	attrs := link.Attrs()
+	l := Link{
+		Type:        link.Type(),
+		MAC:         attrs.HardwareAddr,
+		MTU:         attrs.MTU,
+		Loopback:    attrs.Flags&net.FlagLoopback != 0,
+		MasterIndex: attrs.MasterIndex,
+	}
+	// for veth interfaces, the parent link is the peer interface
+	if _, ok := link.(*netlink.Veth); ok {
+		l.PeerIndex = attrs.ParentIndex
+	}
+	return &l, nil
+}
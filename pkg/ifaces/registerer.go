@@ -3,24 +3,56 @@ package ifaces
 import (
 	"context"
 	"net"
+	"os"
+	"path/filepath"
 	"sync"
+
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
 )
 
+// ifaceKey identifies an interface by its index and the inode of its network namespace, as
+// the same index can be used by different interfaces in different namespaces
+type ifaceKey struct {
+	netns uint32
+	index int
+}
+
 // Registerer is an informer that wraps another informer implementation, and keeps track of
-// the currently existing interfaces in the system, accessible through the IfaceNameForIndex method.
+// the currently existing interfaces in the system, accessible through the IfaceNameForIndex method,
+// as well as their link attributes, accessible through the LinkForIndex method.
 type Registerer struct {
 	m      sync.RWMutex
 	inner  Informer
-	ifaces map[int]string
+	ifaces map[ifaceKey]string
+	links  map[ifaceKey]*Link
 	bufLen int
+	// hostNetNS is the inode of the agent's network namespace, to which netns.None() refers
+	hostNetNS uint32
+	// netnsNames caches the names of the network namespaces under /var/run/netns, by inode
+	netnsNames map[uint32]string
+	// netnsInode, netnsDir and link can be overridden for unit testing
+	netnsInode func(ns netns.NsHandle) (uint32, error)
+	netnsDir   string
+	link       func(iface Interface) (*Link, error)
 }
 
 func NewRegisterer(inner Informer, bufLen int) *Registerer {
-	return &Registerer{
-		inner:  inner,
-		bufLen: bufLen,
-		ifaces: map[int]string{},
+	r := &Registerer{
+		inner:      inner,
+		bufLen:     bufLen,
+		ifaces:     map[ifaceKey]string{},
+		links:      map[ifaceKey]*Link{},
+		netnsNames: map[uint32]string{},
+		netnsInode: NetNSInode,
+		netnsDir:   netnsVolume,
+		link:       netLink,
 	}
+	var err error
+	if r.hostNetNS, err = r.netnsInode(netns.None()); err != nil {
+		ilog.WithError(err).Warn("can't get the agent's network namespace. Interface names might be wrong")
+	}
+	return r
 }
 
 func (r *Registerer) Subscribe(ctx context.Context) (<-chan Event, error) {
@@ -31,18 +63,36 @@ func (r *Registerer) Subscribe(ctx context.Context) (<-chan Event, error) {
 	out := make(chan Event, r.bufLen)
 	go func() {
 		for ev := range innerCh {
+			key, err := r.keyFor(&ev.Interface)
+			if err != nil {
+				ilog.WithError(err).WithField("interface", ev.Interface).
+					Debug("can't get the interface network namespace. Ignoring")
+				out <- ev
+				continue
+			}
 			switch ev.Type {
 			case EventAdded:
+				link, err := r.link(ev.Interface)
+				if err != nil {
+					ilog.WithError(err).WithField("interface", ev.Interface).
+						Debug("can't get the interface link attributes")
+				}
 				r.m.Lock()
-				r.ifaces[ev.Interface.Index] = ev.Interface.Name
+				r.ifaces[key] = ev.Interface.Name
+				if link != nil {
+					r.links[key] = link
+				} else {
+					delete(r.links, key)
+				}
 				r.m.Unlock()
 			case EventDeleted:
 				r.m.Lock()
-				name, ok := r.ifaces[ev.Interface.Index]
+				name, ok := r.ifaces[key]
 				// prevent removing an interface with the same index but different name
 				// e.g. due to an out-of-order add/delete signaling
 				if ok && name == ev.Interface.Name {
-					delete(r.ifaces, ev.Interface.Index)
+					delete(r.ifaces, key)
+					delete(r.links, key)
 				}
 				r.m.Unlock()
 			}
@@ -52,22 +102,130 @@ func (r *Registerer) Subscribe(ctx context.Context) (<-chan Event, error) {
 	return out, nil
 }
 
-// IfaceNameForIndex gets the interface name given an index as recorded by the underlying
-// interfaces' informer. It backs up into the net.InterfaceByIndex function if the interface
-// has not been previously registered
-func (r *Registerer) IfaceNameForIndex(idx int) (string, bool) {
+// keyFor returns the registry key of an interface. Interfaces without a namespace handle belong
+// to the agent's namespace.
+func (r *Registerer) keyFor(iface *Interface) (ifaceKey, error) {
+	if iface.NetNS == 0 || iface.NetNS.Equal(netns.None()) {
+		return ifaceKey{netns: r.hostNetNS, index: iface.Index}, nil
+	}
+	ino, err := r.netnsInode(iface.NetNS)
+	return ifaceKey{netns: ino, index: iface.Index}, err
+}
+
+// IfaceNameForIndex gets the interface name given the inode of its network namespace and its
+// index, as recorded by the underlying interfaces' informer. A zero netns refers to the agent's
+// namespace. It backs up into the net.InterfaceByIndex function if the interface belongs to the
+// agent's namespace and has not been previously registered
+func (r *Registerer) IfaceNameForIndex(netnsIno uint32, idx int) (string, bool) {
+	if netnsIno == 0 {
+		netnsIno = r.hostNetNS
+	}
+	key := ifaceKey{netns: netnsIno, index: idx}
 	r.m.RLock()
-	name, ok := r.ifaces[idx]
+	name, ok := r.ifaces[key]
 	r.m.RUnlock()
 	if !ok {
+		if netnsIno != r.hostNetNS {
+			return "", false
+		}
 		iface, err := net.InterfaceByIndex(idx)
 		if err != nil {
 			return "", false
 		}
 		name = iface.Name
 		r.m.Lock()
-		r.ifaces[idx] = name
+		r.ifaces[key] = name
 		r.m.Unlock()
 	}
 	return name, ok
 }
+
+// LinkForIndex returns the link attributes of an interface given the inode of its network
+// namespace and its index. A zero netns refers to the agent's namespace. The returned link is a
+// copy whose Master name is resolved from the currently registered interfaces.
+func (r *Registerer) LinkForIndex(netnsIno uint32, idx int) (*Link, bool) {
+	if netnsIno == 0 {
+		netnsIno = r.hostNetNS
+	}
+	r.m.RLock()
+	defer r.m.RUnlock()
+	link, ok := r.links[ifaceKey{netns: netnsIno, index: idx}]
+	if !ok {
+		return nil, false
+	}
+	cp := *link
+	if cp.MasterIndex != 0 {
+		cp.Master = r.ifaces[ifaceKey{netns: netnsIno, index: cp.MasterIndex}]
+	}
+	return &cp, true
+}
+
+// LinkFor returns the link attributes of a registered interface
+func (r *Registerer) LinkFor(iface Interface) (*Link, bool) {
+	key, err := r.keyFor(&iface)
+	if err != nil {
+		return nil, false
+	}
+	return r.LinkForIndex(key.netns, key.index)
+}
+
+// NetNSName returns the name of the network namespace with the given inode, as found in
+// /var/run/netns, or an empty string if the namespace is not named there (e.g. the agent's
+// namespace)
+func (r *Registerer) NetNSName(netnsIno uint32) string {
+	if netnsIno == 0 || netnsIno == r.hostNetNS {
+		return ""
+	}
+	r.m.RLock()
+	name, ok := r.netnsNames[netnsIno]
+	r.m.RUnlock()
+	if ok {
+		return name
+	}
+	// the namespace might have been created since the last lookup
+	names := map[uint32]string{}
+	if files, err := os.ReadDir(r.netnsDir); err == nil {
+		for _, f := range files {
+			var st unix.Stat_t
+			if err := unix.Stat(filepath.Join(r.netnsDir, f.Name()), &st); err == nil {
+				names[uint32(st.Ino)] = f.Name()
+			}
+		}
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	for ino, n := range names {
+		r.netnsNames[ino] = n
+	}
+	// remember the unnamed namespaces to avoid listing the directory for each of their flows
+	if _, ok := names[netnsIno]; !ok {
+		r.netnsNames[netnsIno] = ""
+	}
+	return r.netnsNames[netnsIno]
+}
+
+// NetNSNameFor returns the name of the network namespace of an interface, as found in
+// /var/run/netns, or an empty string if the namespace is not named there
+func (r *Registerer) NetNSNameFor(iface Interface) string {
+	key, err := r.keyFor(&iface)
+	if err != nil {
+		return ""
+	}
+	return r.NetNSName(key.netns)
+}
+
+// NetNSInode returns the inode number of a network namespace, which is the namespace identifier
+// that the eBPF flows report. A zero or none handle refers to the agent's namespace.
+func NetNSInode(ns netns.NsHandle) (uint32, error) {
+	var st unix.Stat_t
+	var err error
+	if ns == 0 || ns.Equal(netns.None()) {
+		err = unix.Stat("/proc/self/ns/net", &st)
+	} else {
+		err = unix.Fstat(int(ns), &st)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return uint32(st.Ino), nil
+}
@@ -2,6 +2,10 @@ package ifaces
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -37,9 +41,9 @@ func TestRegisterer(t *testing.T) {
 	for i := 0; i < 3; i++ {
 		getEvent(t, outputEvents, timeout)
 	}
-	assert.Equal(t, "foo", registry.ifaces[1])
-	assert.Equal(t, "bar", registry.ifaces[2])
-	assert.Equal(t, "baz", registry.ifaces[3])
+	assert.Equal(t, "foo", registry.ifaces[hostIface(registry, 1)])
+	assert.Equal(t, "bar", registry.ifaces[hostIface(registry, 2)])
+	assert.Equal(t, "baz", registry.ifaces[hostIface(registry, 3)])
 
 	// updates
 	inputLinks <- upAndRunning("bae", 4, netns.None())
@@ -48,10 +52,10 @@ func TestRegisterer(t *testing.T) {
 		getEvent(t, outputEvents, timeout)
 	}
 
-	assert.Equal(t, "foo", registry.ifaces[1])
-	assert.NotContains(t, registry.ifaces, 2)
-	assert.Equal(t, "baz", registry.ifaces[3])
-	assert.Equal(t, "bae", registry.ifaces[4])
+	assert.Equal(t, "foo", registry.ifaces[hostIface(registry, 1)])
+	assert.NotContains(t, registry.ifaces, hostIface(registry, 2))
+	assert.Equal(t, "baz", registry.ifaces[hostIface(registry, 3)])
+	assert.Equal(t, "bae", registry.ifaces[hostIface(registry, 4)])
 
 	// repeated updates that do not involve a change in the current track of interfaces
 	// will be ignored
@@ -61,7 +65,121 @@ func TestRegisterer(t *testing.T) {
 		getEvent(t, outputEvents, timeout)
 	}
 
-	assert.Equal(t, "fiu", registry.ifaces[1])
-	assert.Equal(t, "baz", registry.ifaces[3])
-	assert.Equal(t, "bae", registry.ifaces[4])
+	assert.Equal(t, "fiu", registry.ifaces[hostIface(registry, 1)])
+	assert.Equal(t, "baz", registry.ifaces[hostIface(registry, 3)])
+	assert.Equal(t, "bae", registry.ifaces[hostIface(registry, 4)])
+}
+
+func TestRegisterer_NetNS(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 10)
+	registry := NewRegisterer(informerFunc(func(_ context.Context) (<-chan Event, error) {
+		return events, nil
+	}), 10)
+	// mock the namespace handles with their inode
+	registry.netnsInode = func(ns netns.NsHandle) (uint32, error) {
+		return uint32(ns), nil
+	}
+
+	outputEvents, err := registry.Subscribe(ctx)
+	require.NoError(t, err)
+
+	// the same index is used in different namespaces
+	events <- Event{Type: EventAdded, Interface: Interface{"eth0", 2, netns.NsHandle(1001)}}
+	events <- Event{Type: EventAdded, Interface: Interface{"eth0", 2, netns.NsHandle(1002)}}
+	events <- Event{Type: EventAdded, Interface: Interface{"veth1", 2, netns.NsHandle(1003)}}
+	events <- Event{Type: EventDeleted, Interface: Interface{"eth0", 2, netns.NsHandle(1002)}}
+	for i := 0; i < 4; i++ {
+		getEvent(t, outputEvents, timeout)
+	}
+
+	name, ok := registry.IfaceNameForIndex(1001, 2)
+	assert.True(t, ok)
+	assert.Equal(t, "eth0", name)
+	name, ok = registry.IfaceNameForIndex(1003, 2)
+	assert.True(t, ok)
+	assert.Equal(t, "veth1", name)
+	// deleted, and not looked up in the agent's namespace
+	_, ok = registry.IfaceNameForIndex(1002, 2)
+	assert.False(t, ok)
+}
+
+func TestRegisterer_NetNSName(t *testing.T) {
+	registry := NewRegisterer(nil, 10)
+	registry.netnsDir = t.TempDir()
+	inode := func(name string) uint32 {
+		var st syscall.Stat_t
+		require.NoError(t, syscall.Stat(filepath.Join(registry.netnsDir, name), &st))
+		return uint32(st.Ino)
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(registry.netnsDir, "cni-1234"), nil, 0o600))
+	assert.Equal(t, "cni-1234", registry.NetNSName(inode("cni-1234")))
+	assert.Empty(t, registry.NetNSName(registry.hostNetNS))
+
+	// namespaces created after the first lookup are found too
+	require.NoError(t, os.WriteFile(filepath.Join(registry.netnsDir, "cni-5678"), nil, 0o600))
+	assert.Equal(t, "cni-5678", registry.NetNSName(inode("cni-5678")))
+}
+
+func TestRegisterer_Links(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 10)
+	registry := NewRegisterer(informerFunc(func(_ context.Context) (<-chan Event, error) {
+		return events, nil
+	}), 10)
+	registry.netnsInode = func(ns netns.NsHandle) (uint32, error) {
+		return uint32(ns), nil
+	}
+	links := map[string]*Link{
+		"br0":   {Type: "bridge", MTU: 1500},
+		"veth1": {Type: "veth", MTU: 1450, MasterIndex: 3, PeerIndex: 2},
+	}
+	registry.link = func(iface Interface) (*Link, error) {
+		if link, ok := links[iface.Name]; ok {
+			return link, nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	outputEvents, err := registry.Subscribe(ctx)
+	require.NoError(t, err)
+
+	events <- Event{Type: EventAdded, Interface: Interface{"veth1", 4, netns.NsHandle(1001)}}
+	events <- Event{Type: EventAdded, Interface: Interface{"br0", 3, netns.NsHandle(1001)}}
+	events <- Event{Type: EventAdded, Interface: Interface{"eth0", 2, netns.NsHandle(1001)}}
+	for i := 0; i < 3; i++ {
+		getEvent(t, outputEvents, timeout)
+	}
+
+	// the master name is resolved even if the master was registered after its slave
+	link, ok := registry.LinkForIndex(1001, 4)
+	require.True(t, ok)
+	assert.Equal(t, &Link{Type: "veth", MTU: 1450, MasterIndex: 3, Master: "br0", PeerIndex: 2}, link)
+	assert.Empty(t, links["veth1"].Master, "the registered link is not modified")
+	link, ok = registry.LinkFor(Interface{"br0", 3, netns.NsHandle(1001)})
+	require.True(t, ok)
+	assert.Equal(t, "bridge", link.Type)
+	// unknown attributes
+	_, ok = registry.LinkForIndex(1001, 2)
+	assert.False(t, ok)
+
+	events <- Event{Type: EventDeleted, Interface: Interface{"veth1", 4, netns.NsHandle(1001)}}
+	getEvent(t, outputEvents, timeout)
+	_, ok = registry.LinkForIndex(1001, 4)
+	assert.False(t, ok)
+}
+
+func hostIface(r *Registerer, index int) ifaceKey {
+	return ifaceKey{netns: r.hostNetNS, index: index}
+}
+
+type informerFunc func(ctx context.Context) (<-chan Event, error)
+
+func (f informerFunc) Subscribe(ctx context.Context) (<-chan Event, error) {
+	return f(ctx)
 }
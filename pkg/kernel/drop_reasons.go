@@ -0,0 +1,96 @@
+package kernel
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/cilium/ebpf/btf"
+)
+
+// skbDropReasonEnum is the kernel enum of the core drop reasons
+const skbDropReasonEnum = "skb_drop_reason"
+
+// subsystemDropReasons are the kernel modules that define their own drop reasons, and the names
+// of their enums. Their values already encode the subsystem in the high bits.
+var subsystemDropReasons = []struct {
+	module string
+	enum   string
+}{
+	{module: "openvswitch", enum: "ovs_drop_reason"},
+	{module: "mac80211", enum: "mac80211_drop_reason"},
+}
+
+// DropReasons returns the names of the packet drop reasons of the running kernel, from the
+// skb_drop_reason enum of its BTF and the drop reason enums of the subsystems whose modules
+// provide BTF.
+func DropReasons() (map[uint32]string, error) {
+	// the kernel BTF is only needed at startup
+	defer btf.FlushKernelSpec()
+	spec, err := btf.LoadKernelSpec()
+	if err != nil {
+		return nil, fmt.Errorf("loading kernel BTF: %w", err)
+	}
+	core, err := enumByName(spec, skbDropReasonEnum)
+	if err != nil {
+		return nil, err
+	}
+	enums := []*btf.Enum{core}
+	for _, sub := range subsystemDropReasons {
+		modSpec, err := btf.LoadKernelModuleSpec(sub.module)
+		if errors.Is(err, fs.ErrNotExist) {
+			// the module is not loaded
+			continue
+		}
+		if err != nil {
+			log.WithError(err).Debugf("can't load BTF of module %s", sub.module)
+			continue
+		}
+		enum, err := enumByName(modSpec, sub.enum)
+		if err != nil {
+			log.WithError(err).Debugf("can't get drop reasons of module %s", sub.module)
+			continue
+		}
+		enums = append(enums, enum)
+	}
+	return dropReasonNames(enums...), nil
+}
+
+func enumByName(spec *btf.Spec, name string) (*btf.Enum, error) {
+	var enum *btf.Enum
+	if err := spec.TypeByName(name, &enum); err != nil {
+		return nil, fmt.Errorf("looking up enum %s: %w", name, err)
+	}
+	return enum, nil
+}
+
+// dropReasonNames maps the values of the drop reason enums to their names. The markers of the
+// enums (e.g. SKB_DROP_REASON_MAX, or __OVS_DROP_REASON for the first value of a subsystem) are
+// not drop reasons, so they are ignored.
+func dropReasonNames(enums ...*btf.Enum) map[uint32]string {
+	names := map[uint32]string{}
+	for _, enum := range enums {
+		for _, value := range enum.Values {
+			if isDropReasonMarker(value.Name) {
+				continue
+			}
+			if _, ok := names[uint32(value.Value)]; !ok {
+				names[uint32(value.Value)] = value.Name
+			}
+		}
+	}
+	return names
+}
+
+func isDropReasonMarker(name string) bool {
+	if strings.HasPrefix(name, "__") {
+		return true
+	}
+	for _, suffix := range []string{"_MAX", "_FIRST", "_MASK", "_NUM"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
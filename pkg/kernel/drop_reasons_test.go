@@ -0,0 +1,41 @@
+package kernel
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDropReasonNames(t *testing.T) {
+	core := &btf.Enum{Name: "skb_drop_reason", Values: []btf.EnumValue{
+		{Name: "SKB_NOT_DROPPED_YET", Value: 0},
+		{Name: "SKB_CONSUMED", Value: 1},
+		{Name: "SKB_DROP_REASON_NOT_SPECIFIED", Value: 2},
+		{Name: "SKB_DROP_REASON_NO_SOCKET", Value: 3},
+		{Name: "SKB_DROP_REASON_QDISC_DROP", Value: 39},
+		{Name: "SKB_DROP_REASON_MAX", Value: 40},
+		{Name: "SKB_DROP_REASON_SUBSYS_MASK", Value: 0xffff0000},
+	}}
+	ovs := &btf.Enum{Name: "ovs_drop_reason", Values: []btf.EnumValue{
+		{Name: "__OVS_DROP_REASON", Value: 3 << 16},
+		{Name: "OVS_DROP_LAST_ACTION", Value: 3<<16 + 1},
+		{Name: "OVS_DROP_EXPLICIT", Value: 3<<16 + 2},
+		{Name: "OVS_DROP_MAX", Value: 3<<16 + 3},
+	}}
+	mac80211 := &btf.Enum{Name: "mac80211_drop_reason", Values: []btf.EnumValue{
+		{Name: "___RX_DROP_UNUSABLE", Value: 1 << 16},
+		{Name: "RX_DROP_U_MIC_FAIL", Value: 1<<16 + 1},
+	}}
+
+	assert.Equal(t, map[uint32]string{
+		0:         "SKB_NOT_DROPPED_YET",
+		1:         "SKB_CONSUMED",
+		2:         "SKB_DROP_REASON_NOT_SPECIFIED",
+		3:         "SKB_DROP_REASON_NO_SOCKET",
+		39:        "SKB_DROP_REASON_QDISC_DROP",
+		3<<16 + 1: "OVS_DROP_LAST_ACTION",
+		3<<16 + 2: "OVS_DROP_EXPLICIT",
+		1<<16 + 1: "RX_DROP_U_MIC_FAIL",
+	}, dropReasonNames(core, ovs, mac80211))
+}
@@ -0,0 +1,101 @@
+package kernel
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const kallsymsPath = "/proc/kallsyms"
+
+// Symbols resolves kernel addresses into the names of the kernel functions that contain them
+type Symbols struct {
+	// addrs is sorted in ascending order. names[i] is the name of the symbol at addrs[i].
+	addrs []uint64
+	names []string
+}
+
+// LoadSymbols reads the text symbols of the running kernel and its modules from /proc/kallsyms.
+// It requires privileges to see the real addresses of the symbols.
+func LoadSymbols() (*Symbols, error) {
+	file, err := os.Open(kallsymsPath)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", kallsymsPath, err)
+	}
+	defer file.Close()
+	return readSymbols(file)
+}
+
+// readSymbols parses the kallsyms format: one "address type name [module]" line per symbol
+func readSymbols(reader io.Reader) (*Symbols, error) {
+	type symbol struct {
+		addr uint64
+		name string
+	}
+	var symbols []symbol
+	hidden := true
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		// only the text (code) symbols can be drop locations
+		switch fields[1] {
+		case "t", "T", "w", "W":
+		default:
+			continue
+		}
+		addr, err := strconv.ParseUint(fields[0], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("wrong address in kallsyms line %q: %w", scanner.Text(), err)
+		}
+		if addr != 0 {
+			hidden = false
+		}
+		symbols = append(symbols, symbol{addr: addr, name: fields[2]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading kallsyms: %w", err)
+	}
+	if hidden {
+		return nil, errors.New("the kernel symbol addresses are hidden: check kernel.kptr_restrict and the agent privileges")
+	}
+	sort.SliceStable(symbols, func(i, j int) bool {
+		return symbols[i].addr < symbols[j].addr
+	})
+	s := &Symbols{
+		addrs: make([]uint64, 0, len(symbols)),
+		names: make([]string, 0, len(symbols)),
+	}
+	for _, sym := range symbols {
+		s.addrs = append(s.addrs, sym.addr)
+		s.names = append(s.names, sym.name)
+	}
+	return s, nil
+}
+
+// Resolve returns the name of the kernel function that contains an address, with the offset of
+// the address in the function (e.g. tcp_v4_rcv+0x8a), or an empty string if it is unknown.
+func (s *Symbols) Resolve(addr uint64) string {
+	if s == nil || addr == 0 {
+		return ""
+	}
+	// index of the first symbol after the address
+	i := sort.Search(len(s.addrs), func(i int) bool {
+		return s.addrs[i] > addr
+	})
+	if i == 0 {
+		return ""
+	}
+	offset := addr - s.addrs[i-1]
+	if offset == 0 {
+		return s.names[i-1]
+	}
+	return fmt.Sprintf("%s+0x%x", s.names[i-1], offset)
+}
@@ -0,0 +1,35 @@
+package kernel
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSymbols_Resolve(t *testing.T) {
+	symbols, err := readSymbols(strings.NewReader(
+		"ffffffff81a2b000 T tcp_v4_rcv\n" +
+			"ffffffff81000000 T _stext\n" +
+			"ffffffff82000000 D init_net\n" +
+			"ffffffff81a2c000 t tcp_v4_fill_cb\n" +
+			"ffffffffc0a01000 t nf_hook_slow_mod\t[nf_tables]\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "tcp_v4_rcv", symbols.Resolve(0xffffffff81a2b000))
+	assert.Equal(t, "tcp_v4_rcv+0x8a", symbols.Resolve(0xffffffff81a2b08a))
+	assert.Equal(t, "tcp_v4_fill_cb+0x10", symbols.Resolve(0xffffffff81a2c010))
+	// the data symbols are ignored
+	assert.Equal(t, "tcp_v4_fill_cb+0x5d4000", symbols.Resolve(0xffffffff82000000))
+	assert.Equal(t, "nf_hook_slow_mod+0x4", symbols.Resolve(0xffffffffc0a01004))
+	assert.Empty(t, symbols.Resolve(0x1000))
+	assert.Empty(t, symbols.Resolve(0))
+}
+
+func TestSymbols_Hidden(t *testing.T) {
+	_, err := readSymbols(strings.NewReader(
+		"0000000000000000 T _stext\n" +
+			"0000000000000000 T tcp_v4_rcv\n"))
+	assert.Error(t, err)
+}
@@ -116,6 +116,57 @@ var (
 		"component",
 		"error",
 	)
+	// The labels of the flow metrics are configurable, and set when they are created
+	flowBytesTotal = defineMetric(
+		"flow_bytes_total",
+		"Bytes of the observed flows",
+		TypeCounter,
+	)
+	flowPacketsTotal = defineMetric(
+		"flow_packets_total",
+		"Packets of the observed flows",
+		TypeCounter,
+	)
+	flowDropBytesTotal = defineMetric(
+		"flow_drop_bytes_total",
+		"Bytes of the observed flows dropped by the kernel",
+		TypeCounter,
+	)
+	flowDropPacketsTotal = defineMetric(
+		"flow_drop_packets_total",
+		"Packets of the observed flows dropped by the kernel",
+		TypeCounter,
+	)
+	flowDNSLatencySeconds = defineMetric(
+		"flow_dns_latency_seconds",
+		"DNS latency of the observed flows",
+		TypeHistogram,
+	)
+	flowRTTSeconds = defineMetric(
+		"flow_rtt_seconds",
+		"TCP round-trip time of the observed flows",
+		TypeHistogram,
+	)
+	flowTransitLatencySeconds = defineMetric(
+		"flow_transit_latency_seconds",
+		"Time that the packets of the observed flows took to cross the node",
+		TypeHistogram,
+	)
+	flowQdiscLatencySeconds = defineMetric(
+		"flow_qdisc_latency_seconds",
+		"Mean time that the packets of the observed flows waited in the egress qdisc",
+		TypeHistogram,
+	)
+	flowQdiscDropBytesTotal = defineMetric(
+		"flow_qdisc_drop_bytes_total",
+		"Bytes of the observed flows dropped by the egress qdisc",
+		TypeCounter,
+	)
+	flowQdiscDropPacketsTotal = defineMetric(
+		"flow_qdisc_drop_packets_total",
+		"Packets of the observed flows dropped by the egress qdisc",
+		TypeCounter,
+	)
 )
 
 func (def *MetricDefinition) mapLabels(labels []string) prometheus.Labels {
@@ -233,6 +284,24 @@ func (m *Metrics) NewHistogram(def *MetricDefinition, buckets []float64, labels
 	return c
 }
 
+func (m *Metrics) NewHistogramVec(def *MetricDefinition, buckets []float64) *prometheus.HistogramVec {
+	verifyMetricType(def, TypeHistogram)
+	fullName := m.Settings.Prefix + def.Name
+	h := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    fullName,
+		Help:    def.Help,
+		Buckets: buckets,
+	}, def.Labels)
+	m.register(h, fullName)
+	return h
+}
+
+// withLabels returns a copy of the metric definition with the given labels
+func (def MetricDefinition) withLabels(labels ...string) *MetricDefinition {
+	def.Labels = append(append([]string{}, def.Labels...), labels...)
+	return &def
+}
+
 // EvictionCounter provides syntactic sugar hidding prom's counter for eviction purpose
 type EvictionCounter struct {
 	vec *prometheus.CounterVec
@@ -274,3 +343,41 @@ type ErrorCounter struct {
 func (c *ErrorCounter) WithErrorName(component, errName string) prometheus.Counter {
 	return c.vec.WithLabelValues(component, errName)
 }
+
+// FlowMetrics are the metrics derived from the observed flows. The drop metrics have an extra
+// "cause" label, after the configured ones, and the qdisc drop metrics an extra "kind" label.
+type FlowMetrics struct {
+	Bytes       *prometheus.CounterVec
+	Packets     *prometheus.CounterVec
+	DropBytes   *prometheus.CounterVec
+	DropPackets *prometheus.CounterVec
+	DNSLatency  *prometheus.HistogramVec
+	RTT         *prometheus.HistogramVec
+	// TransitLatency is only observed for the flows seen from several interfaces by the deduper
+	TransitLatency *prometheus.HistogramVec
+	// the qdisc metrics are only observed when the qdisc tracking is enabled
+	QdiscLatency     *prometheus.HistogramVec
+	QdiscDropBytes   *prometheus.CounterVec
+	QdiscDropPackets *prometheus.CounterVec
+}
+
+func (m *Metrics) CreateFlowMetrics(labels []string) *FlowMetrics {
+	dropLabels := append(append([]string{}, labels...), "cause")
+	qdiscDropLabels := append(append([]string{}, labels...), "kind")
+	return &FlowMetrics{
+		Bytes:       m.NewCounterVec(flowBytesTotal.withLabels(labels...)),
+		Packets:     m.NewCounterVec(flowPacketsTotal.withLabels(labels...)),
+		DropBytes:   m.NewCounterVec(flowDropBytesTotal.withLabels(dropLabels...)),
+		DropPackets: m.NewCounterVec(flowDropPacketsTotal.withLabels(dropLabels...)),
+		DNSLatency: m.NewHistogramVec(flowDNSLatencySeconds.withLabels(labels...),
+			[]float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}),
+		RTT: m.NewHistogramVec(flowRTTSeconds.withLabels(labels...),
+			[]float64{.0001, .0005, .001, .005, .01, .025, .05, .1, .25, .5, 1}),
+		TransitLatency: m.NewHistogramVec(flowTransitLatencySeconds.withLabels(labels...),
+			[]float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1}),
+		QdiscLatency: m.NewHistogramVec(flowQdiscLatencySeconds.withLabels(labels...),
+			[]float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1, .5}),
+		QdiscDropBytes:   m.NewCounterVec(flowQdiscDropBytesTotal.withLabels(qdiscDropLabels...)),
+		QdiscDropPackets: m.NewCounterVec(flowQdiscDropPacketsTotal.withLabels(qdiscDropLabels...)),
+	}
+}
@@ -19,7 +19,8 @@ import (
 const _ = grpc.SupportPackageIsVersion8
 
 const (
-	Collector_Send_FullMethodName = "/pbflow.Collector/Send"
+	Collector_Send_FullMethodName   = "/pbflow.Collector/Send"
+	Collector_Stream_FullMethodName = "/pbflow.Collector/Stream"
 )
 
 // CollectorClient is the client API for Collector service.
@@ -27,6 +28,9 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type CollectorClient interface {
 	Send(ctx context.Context, in *Records, opts ...grpc.CallOption) (*CollectorReply, error)
+	// Stream submits batches of records through a long-lived bidirectional stream. The collector
+	// grants flow-control credits to the client and acknowledges each batch once it is processed.
+	Stream(ctx context.Context, opts ...grpc.CallOption) (Collector_StreamClient, error)
 }
 
 type collectorClient struct {
@@ -47,11 +51,46 @@ func (c *collectorClient) Send(ctx context.Context, in *Records, opts ...grpc.Ca
 	return out, nil
 }
 
+func (c *collectorClient) Stream(ctx context.Context, opts ...grpc.CallOption) (Collector_StreamClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Collector_ServiceDesc.Streams[0], Collector_Stream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &collectorStreamClient{ClientStream: stream}
+	return x, nil
+}
+
+type Collector_StreamClient interface {
+	Send(*RecordsBatch) error
+	Recv() (*StreamReply, error)
+	grpc.ClientStream
+}
+
+type collectorStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *collectorStreamClient) Send(m *RecordsBatch) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *collectorStreamClient) Recv() (*StreamReply, error) {
+	m := new(StreamReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // CollectorServer is the server API for Collector service.
 // All implementations must embed UnimplementedCollectorServer
 // for forward compatibility
 type CollectorServer interface {
 	Send(context.Context, *Records) (*CollectorReply, error)
+	// Stream submits batches of records through a long-lived bidirectional stream. The collector
+	// grants flow-control credits to the client and acknowledges each batch once it is processed.
+	Stream(Collector_StreamServer) error
 	mustEmbedUnimplementedCollectorServer()
 }
 
@@ -62,6 +101,9 @@ type UnimplementedCollectorServer struct {
 func (UnimplementedCollectorServer) Send(context.Context, *Records) (*CollectorReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Send not implemented")
 }
+func (UnimplementedCollectorServer) Stream(Collector_StreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method Stream not implemented")
+}
 func (UnimplementedCollectorServer) mustEmbedUnimplementedCollectorServer() {}
 
 // UnsafeCollectorServer may be embedded to opt out of forward compatibility for this service.
@@ -93,6 +135,32 @@ func _Collector_Send_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Collector_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CollectorServer).Stream(&collectorStreamServer{ServerStream: stream})
+}
+
+type Collector_StreamServer interface {
+	Send(*StreamReply) error
+	Recv() (*RecordsBatch, error)
+	grpc.ServerStream
+}
+
+type collectorStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *collectorStreamServer) Send(m *StreamReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *collectorStreamServer) Recv() (*RecordsBatch, error) {
+	m := new(RecordsBatch)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // Collector_ServiceDesc is the grpc.ServiceDesc for Collector service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -105,6 +173,13 @@ var Collector_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _Collector_Send_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _Collector_Stream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "proto/flow.proto",
 }
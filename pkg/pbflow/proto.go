@@ -3,6 +3,7 @@ package pbflow
 import (
 	"encoding/binary"
 	"net"
+	"time"
 
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/ebpf"
 	"github.com/netobserv/netobserv-ebpf-agent/pkg/flow"
@@ -63,11 +64,14 @@ func FlowToPB(fr *flow.Record) *Record {
 		AgentIp:                agentIP(fr.AgentIP),
 		Flags:                  uint32(fr.Metrics.Flags),
 		Interface:              fr.Interface,
+		Netns:                  fr.Id.Netns,
+		NetnsName:              fr.NetNSName,
 		PktDropBytes:           fr.Metrics.PktDrops.Bytes,
 		PktDropPackets:         uint64(fr.Metrics.PktDrops.Packets),
 		PktDropLatestFlags:     uint32(fr.Metrics.PktDrops.LatestFlags),
 		PktDropLatestState:     uint32(fr.Metrics.PktDrops.LatestState),
 		PktDropLatestDropCause: fr.Metrics.PktDrops.LatestDropCause,
+		PktDropLatestLocation:  fr.Metrics.PktDrops.LatestDropLocation,
 		DnsId:                  uint32(fr.Metrics.DnsRecord.Id),
 		DnsFlags:               uint32(fr.Metrics.DnsRecord.Flags),
 		DnsErrno:               uint32(fr.Metrics.DnsRecord.Errno),
@@ -76,15 +80,50 @@ func FlowToPB(fr *flow.Record) *Record {
 	if fr.Metrics.DnsRecord.Latency != 0 {
 		pbflowRecord.DnsLatency = durationpb.New(fr.DNSLatency)
 	}
+	pbflowRecord.PktDropLatestDropCauseName = fr.DropCause
+	pbflowRecord.PktDropLatestFunction = fr.DropLocation
+	pbflowRecord.PktDropLatestStack = fr.DropStack
+	if fr.TransitLatency != 0 {
+		pbflowRecord.TransitLatency = durationpb.New(fr.TransitLatency)
+	}
+	if fr.Metrics.Qdisc.Packets != 0 {
+		pbflowRecord.QdiscTime = durationpb.New(time.Duration(fr.Metrics.Qdisc.Time))
+		pbflowRecord.QdiscPackets = uint64(fr.Metrics.Qdisc.Packets)
+	}
+	pbflowRecord.QdiscDropBytes = fr.Metrics.Qdisc.DropBytes
+	pbflowRecord.QdiscDropPackets = uint64(fr.Metrics.Qdisc.DropPackets)
+	pbflowRecord.QdiscKind = fr.QdiscKind()
 	if len(fr.DupList) != 0 {
-		pbflowRecord.DupList = make([]*DupMapEntry, 0)
-		for _, m := range fr.DupList {
-			for key, value := range m {
-				pbflowRecord.DupList = append(pbflowRecord.DupList, &DupMapEntry{
-					Interface: key,
-					Direction: Direction(value),
-				})
-			}
+		pbflowRecord.DupList = make([]*DupEntry, 0, len(fr.DupList))
+		for i := range fr.DupList {
+			dup := &fr.DupList[i]
+			pbflowRecord.DupList = append(pbflowRecord.DupList, &DupEntry{
+				Interface:      dup.Interface,
+				Direction:      Direction(dup.Direction),
+				Bytes:          dup.Bytes,
+				Packets:        uint64(dup.Packets),
+				PktDropBytes:   dup.PktDropBytes,
+				PktDropPackets: uint64(dup.PktDropPackets),
+			})
+		}
+	}
+	if fr.Reverse != nil {
+		pbflowRecord.Reverse = &Reverse{
+			Bytes:           fr.Reverse.Bytes,
+			Packets:         uint64(fr.Reverse.Packets),
+			Flags:           uint32(fr.Reverse.Flags),
+			PktDropBytes:    fr.Reverse.PktDropBytes,
+			PktDropPackets:  uint64(fr.Reverse.PktDropPackets),
+			BiflowDirection: uint32(fr.Reverse.BiflowDirection),
+		}
+	}
+	if fr.InterfaceLink != nil {
+		pbflowRecord.InterfaceLink = &InterfaceLink{
+			Type:        fr.InterfaceLink.Type,
+			Mac:         macToUint64((*[flow.MacLen]uint8)(&fr.InterfaceLink.MAC)),
+			Mtu:         fr.InterfaceLink.MTU,
+			Master:      fr.InterfaceLink.Master,
+			PeerIfIndex: fr.InterfaceLink.PeerIfIndex,
 		}
 	}
 	if fr.Id.EthProtocol == flow.IPv6Type {
@@ -115,6 +154,7 @@ func PBToFlow(pb *Record) *flow.Record {
 				DstPort:           uint16(pb.Transport.DstPort),
 				IcmpType:          uint8(pb.IcmpType),
 				IcmpCode:          uint8(pb.IcmpCode),
+				Netns:             pb.Netns,
 			},
 			Metrics: ebpf.BpfFlowMetrics{
 				Bytes:   pb.Bytes,
@@ -122,11 +162,12 @@ func PBToFlow(pb *Record) *flow.Record {
 				Flags:   uint16(pb.Flags),
 				Dscp:    uint8(pb.Network.Dscp),
 				PktDrops: ebpf.BpfPktDropsT{
-					Bytes:           pb.PktDropBytes,
-					Packets:         uint32(pb.PktDropPackets),
-					LatestFlags:     uint16(pb.PktDropLatestFlags),
-					LatestState:     uint8(pb.PktDropLatestState),
-					LatestDropCause: pb.PktDropLatestDropCause,
+					Bytes:              pb.PktDropBytes,
+					Packets:            uint32(pb.PktDropPackets),
+					LatestFlags:        uint16(pb.PktDropLatestFlags),
+					LatestState:        uint8(pb.PktDropLatestState),
+					LatestDropCause:    pb.PktDropLatestDropCause,
+					LatestDropLocation: pb.PktDropLatestLocation,
 				},
 				DnsRecord: ebpf.BpfDnsRecordT{
 					Id:      uint16(pb.DnsId),
@@ -134,6 +175,12 @@ func PBToFlow(pb *Record) *flow.Record {
 					Errno:   uint8(pb.DnsErrno),
 					Latency: uint64(pb.DnsLatency.AsDuration()),
 				},
+				Qdisc: ebpf.BpfQdiscT{
+					Time:        uint64(pb.QdiscTime.AsDuration()),
+					Packets:     uint32(pb.QdiscPackets),
+					DropBytes:   pb.QdiscDropBytes,
+					DropPackets: uint32(pb.QdiscDropPackets),
+				},
 			},
 		},
 		TimeFlowStart: pb.TimeFlowStart.AsTime(),
@@ -141,15 +188,48 @@ func PBToFlow(pb *Record) *flow.Record {
 		AgentIP:       pbIPToNetIP(pb.AgentIp),
 		Duplicate:     pb.Duplicate,
 		Interface:     pb.Interface,
+		NetNSName:     pb.NetnsName,
 		TimeFlowRtt:   pb.TimeFlowRtt.AsDuration(),
 		DNSLatency:    pb.DnsLatency.AsDuration(),
 	}
+	out.DropCause = pb.PktDropLatestDropCauseName
+	out.DropLocation = pb.PktDropLatestFunction
+	out.DropStack = pb.PktDropLatestStack
+	if pb.TransitLatency != nil {
+		out.TransitLatency = pb.TransitLatency.AsDuration()
+	}
+	copy(out.Metrics.Qdisc.LatestKind[:], pb.QdiscKind)
 
 	if len(pb.GetDupList()) != 0 {
+		out.DupList = make([]flow.DupEntry, 0, len(pb.GetDupList()))
 		for _, entry := range pb.GetDupList() {
-			intf := entry.Interface
-			dir := uint8(entry.Direction)
-			out.DupList = append(out.DupList, map[string]uint8{intf: dir})
+			out.DupList = append(out.DupList, flow.DupEntry{
+				Interface:      entry.Interface,
+				Direction:      uint8(entry.Direction),
+				Bytes:          entry.Bytes,
+				Packets:        uint32(entry.Packets),
+				PktDropBytes:   entry.PktDropBytes,
+				PktDropPackets: uint32(entry.PktDropPackets),
+			})
+		}
+	}
+	if pb.Reverse != nil {
+		out.Reverse = &flow.ReverseMetrics{
+			Bytes:           pb.Reverse.Bytes,
+			Packets:         uint32(pb.Reverse.Packets),
+			Flags:           uint16(pb.Reverse.Flags),
+			PktDropBytes:    pb.Reverse.PktDropBytes,
+			PktDropPackets:  uint32(pb.Reverse.PktDropPackets),
+			BiflowDirection: uint8(pb.Reverse.BiflowDirection),
+		}
+	}
+	if pb.InterfaceLink != nil {
+		out.InterfaceLink = &flow.InterfaceLink{
+			Type:        pb.InterfaceLink.Type,
+			MAC:         macToUint8(pb.InterfaceLink.Mac),
+			MTU:         pb.InterfaceLink.Mtu,
+			Master:      pb.InterfaceLink.Master,
+			PeerIfIndex: pb.InterfaceLink.PeerIfIndex,
 		}
 	}
 	return &out
@@ -31,12 +31,12 @@ func NewTracerFake() *TracerFake {
 func (m *TracerFake) Close() error {
 	return nil
 }
-func (m *TracerFake) Register(iface ifaces.Interface) error {
+func (m *TracerFake) Register(iface ifaces.Interface, _ *ebpf.InterfaceProfile) error {
 	m.interfaces[iface] = struct{}{}
 	return nil
 }
 
-func (m *TracerFake) AttachTCX(_ ifaces.Interface) error {
+func (m *TracerFake) AttachTCX(_ ifaces.Interface, _ *ebpf.InterfaceProfile) error {
 	return nil
 }
 
@@ -49,6 +49,10 @@ func (m *TracerFake) LookupAndDeleteMap(_ *metrics.Metrics) map[ebpf.BpfFlowId][
 	}
 }
 
+func (m *TracerFake) LookupDropStack(_ uint32) ([]uint64, error) {
+	return nil, nil
+}
+
 func (m *TracerFake) DeleteMapsStaleEntries(_ time.Duration) {
 }
 
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var tlog = logrus.WithField("component", "utils.CertReloader")
+
+// CertReloader provides TLS configurations whose certificates are read from disk, and reloaded
+// whenever any of the underlying files is modified. This allows rotating the certificates
+// without restarting the client or server that uses them.
+type CertReloader struct {
+	caPath   string
+	certPath string
+	keyPath  string
+
+	mutex    sync.Mutex
+	loaded   bool
+	modTimes [3]time.Time
+	caPool   *x509.CertPool
+	cert     *tls.Certificate
+}
+
+// NewCertReloader loads the given CA, certificate and key files. Any of them can be left empty.
+// If the certificate path is set, the key path must be set too.
+func NewCertReloader(caPath, certPath, keyPath string) (*CertReloader, error) {
+	if (certPath == "") != (keyPath == "") {
+		return nil, errors.New("certificate and key paths must be both set or both empty")
+	}
+	r := &CertReloader{caPath: caPath, certPath: certPath, keyPath: keyPath}
+	if err := r.reloadIfChanged(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reloadIfChanged re-reads the certificate files if their modification time has changed since
+// the last load. On error, the previously loaded certificates are kept.
+func (r *CertReloader) reloadIfChanged() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var modTimes [3]time.Time
+	for i, path := range []string{r.caPath, r.certPath, r.keyPath} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	if r.loaded && modTimes == r.modTimes {
+		return nil
+	}
+
+	var caPool *x509.CertPool
+	if r.caPath != "" {
+		caCert, err := os.ReadFile(r.caPath)
+		if err != nil {
+			return err
+		}
+		caPool = x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caCert) {
+			return fmt.Errorf("no valid certificates found in %s", r.caPath)
+		}
+	}
+	var cert *tls.Certificate
+	if r.certPath != "" {
+		pair, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+		if err != nil {
+			return err
+		}
+		cert = &pair
+	}
+	r.caPool = caPool
+	r.cert = cert
+	r.modTimes = modTimes
+	r.loaded = true
+	return nil
+}
+
+func (r *CertReloader) current() (*x509.CertPool, *tls.Certificate) {
+	if err := r.reloadIfChanged(); err != nil {
+		tlog.WithError(err).Warn("can't reload TLS certificates. Keeping the previous ones")
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.caPool, r.cert
+}
+
+// ClientConfig returns a TLS configuration for clients. The server certificate is verified
+// against the configured CA (or the system CAs if no CA is configured) and the provided server
+// name (host name or IP), unless insecureSkipVerify is true. If a certificate and key are configured, they are presented to the
+// server for mutual TLS authentication.
+func (r *CertReloader) ClientConfig(serverName string, insecureSkipVerify bool) *tls.Config {
+	return &tls.Config{
+		ServerName: serverName,
+		// verification is done in VerifyConnection, so the CA can be reloaded at any moment
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if insecureSkipVerify {
+				return nil
+			}
+			caPool, _ := r.current()
+			return verifyPeer(cs, caPool, serverName, x509.ExtKeyUsageServerAuth)
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if _, cert := r.current(); cert != nil {
+				return cert, nil
+			}
+			// no client certificate is sent
+			return &tls.Certificate{}, nil
+		},
+		MinVersion: tls.VersionTLS12,
+	}
+}
+
+// ServerConfig returns a TLS configuration for servers. The configured certificate and key are
+// mandatory. If a CA is configured, clients are required to present a certificate signed by it
+// (mutual TLS).
+func (r *CertReloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			caPool, cert := r.current()
+			if cert == nil {
+				return nil, errors.New("no server certificate configured")
+			}
+			cfg := &tls.Config{
+				Certificates: []tls.Certificate{*cert},
+				MinVersion:   tls.VersionTLS12,
+			}
+			if caPool != nil {
+				cfg.ClientCAs = caPool
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return cfg, nil
+		},
+		MinVersion: tls.VersionTLS12,
+	}
+}
+
+func verifyPeer(cs tls.ConnectionState, roots *x509.CertPool, serverName string, usage x509.ExtKeyUsage) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("no peer certificates presented")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		DNSName:       serverName,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
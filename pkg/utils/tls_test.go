@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"crypto/tls"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/netobserv/netobserv-ebpf-agent/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertReloader_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	certs := test.GenerateCerts(t, dir)
+	reloader, err := NewCertReloader(certs.CACert, certs.ClientCert, certs.ClientKey)
+	require.NoError(t, err)
+
+	cfg := reloader.ClientConfig("localhost", false)
+	first, err := cfg.GetClientCertificate(&tls.CertificateRequestInfo{})
+	require.NoError(t, err)
+
+	// rotate the certificates and make sure the file modification time changes
+	rotated := test.GenerateCerts(t, t.TempDir())
+	for src, dst := range map[string]string{
+		rotated.CACert: certs.CACert, rotated.ClientCert: certs.ClientCert, rotated.ClientKey: certs.ClientKey,
+	} {
+		content, err := os.ReadFile(src)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(dst, content, 0o600))
+		future := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(dst, future, future))
+	}
+
+	second, err := cfg.GetClientCertificate(&tls.CertificateRequestInfo{})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Certificate[0], second.Certificate[0])
+
+	// if the new files are wrong, the previous certificates are kept
+	require.NoError(t, os.WriteFile(certs.ClientKey, []byte("wrong"), 0o600))
+	future := time.Now().Add(2 * time.Minute)
+	require.NoError(t, os.Chtimes(certs.ClientKey, future, future))
+	third, err := cfg.GetClientCertificate(&tls.CertificateRequestInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, second.Certificate[0], third.Certificate[0])
+}
+
+func TestCertReloader_MissingKey(t *testing.T) {
+	_, err := NewCertReloader("", "cert.pem", "")
+	require.Error(t, err)
+}